@@ -0,0 +1,105 @@
+package initial
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/model"
+)
+
+const bootstrapAdminUsage = "usage: godemo [-c configFile] bootstrap-admin userName [email]\n" +
+	"the password is read from the " + bootstrapAdminPasswordEnv + " environment variable or the first line of stdin"
+
+const (
+	bootstrapAdminPasswordEnv = "GODEMO_ADMIN_PASSWORD"
+	// the seeded role that holds all the permissions
+	bootstrapAdminRoleCode = "super_admin"
+)
+
+// RunBootstrapAdmin run the bootstrap-admin command, args are the arguments after "bootstrap-admin".
+// It creates the first administrator of a new installation and grants it the super_admin role, as every
+// /api/v1 route requires a token and a permission. It refuses if a user already has the role, so that it
+// is a one-shot step that cannot be used to take over an installation.
+func RunBootstrapAdmin(args []string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] == "" {
+		return errors.New(bootstrapAdminUsage)
+	}
+	userName := args[0]
+	email := ""
+	if len(args) > 1 {
+		email = args[1]
+	}
+
+	password, err := readBootstrapAdminPassword()
+	if err != nil {
+		return err
+	}
+	// the same rule as the password of types.CreateUsersRequest
+	if n := utf8.RuneCountInString(password); n < 6 || n > 64 || len(password) > auth.MaxPasswordBytes {
+		return fmt.Errorf("the password must have 6 to 64 characters and at most %d bytes", auth.MaxPasswordBytes)
+	}
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	db := database.GetDB()
+	usersDao := dao.NewUsersDao(db, cache.NewUsersCache(database.GetCacheType()), cache.NewTokenCache(database.GetCacheType()))
+	userRolesDao := dao.NewUserRolesDao(db, cache.NewUserRolesCache(database.GetCacheType()), cache.NewUserPermissionsCache(database.GetCacheType()))
+	ctx := context.Background()
+
+	user := &model.Users{UserName: userName, Password: hashed, UserEmail: email, Status: model.StatusEnabled}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		role := &model.Roles{}
+		err := tx.Where("role_code = ?", bootstrapAdminRoleCode).Limit(1).Find(role).Error
+		if err != nil {
+			return err
+		}
+		if role.ID == 0 {
+			return fmt.Errorf("role %s not found, run 'godemo migrate up' first", bootstrapAdminRoleCode)
+		}
+
+		var count int64
+		err = tx.Model(&model.UserRoles{}).Where("role_id = ?", role.ID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("a user already has the %s role, the administrator is already bootstrapped", bootstrapAdminRoleCode)
+		}
+
+		if _, err = usersDao.CreateByTx(ctx, tx, user); err != nil {
+			return err
+		}
+		return userRolesDao.ReplaceByTx(ctx, tx, user.ID, []uint64{role.ID})
+	})
+	if err != nil {
+		return err
+	}
+	_ = userRolesDao.DeleteCacheByUserID(ctx, user.ID)
+
+	fmt.Printf("created the administrator %s (id %d) with the %s role\n", userName, user.ID, bootstrapAdminRoleCode)
+	return nil
+}
+
+func readBootstrapAdminPassword() (string, error) {
+	if password := os.Getenv(bootstrapAdminPasswordEnv); password != "" {
+		return password, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New(bootstrapAdminUsage)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/tracer"
 
 	"godemo/configs"
+	"godemo/internal/audit"
 	"godemo/internal/config"
 	"godemo/internal/database"
 )
@@ -42,7 +43,9 @@ func InitApp() {
 	if err != nil {
 		panic(err)
 	}
-	logger.Debug(config.Show())
+	// the jwt sign key can forge the tokens of any user and the two-factor encrypt key decrypts
+	// the stored totp secrets, they are never logged
+	logger.Debug(config.Show(`"signKey"`, `"encryptKey"`))
 	logger.Info("[logger] was initialized")
 
 	// initializing tracing
@@ -75,6 +78,9 @@ func InitApp() {
 	if cfg.App.CacheType != "" {
 		logger.Infof("[%s] was initialized", cfg.App.CacheType)
 	}
+
+	// the changes made through the dao are written to the audit log
+	audit.Enable()
 }
 
 func initConfig() {
@@ -98,4 +104,8 @@ func getConfigFromLocal() {
 	if err != nil {
 		panic("init config error: " + err.Error())
 	}
+	err = config.Get().Check()
+	if err != nil {
+		panic("check config error: " + err.Error())
+	}
 }
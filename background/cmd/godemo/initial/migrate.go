@@ -0,0 +1,114 @@
+package initial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/config"
+	"godemo/internal/database"
+	"godemo/internal/migration"
+)
+
+const migrateUsage = "usage: godemo [-c configFile] migrate up|down [steps]|status"
+
+// RunMigrate run the migrate command, args are the arguments after "migrate":
+//
+//	up            apply all the pending migrations
+//	down [steps]  roll back the last steps applied migrations, default is 1
+//	status        list the migrations and whether they are applied
+func RunMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migration.NewMigrator(database.GetDB(), config.Get().Database.Driver)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		for _, m := range done {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		done, err := migrator.Down(ctx, steps)
+		for _, m := range done {
+			fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("no applied migrations")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// CheckMigrations refuse to start the service when there are pending migrations,
+// the check is skipped if database.skipMigrationCheck is true in the configuration file.
+func CheckMigrations() {
+	if config.Get().Database.SkipMigrationCheck {
+		logger.Warn("[migration] check was skipped")
+		return
+	}
+
+	migrator, err := migration.NewMigrator(database.GetDB(), config.Get().Database.Driver)
+	if err != nil {
+		panic("check migrations error: " + err.Error())
+	}
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		panic("check migrations error: " + err.Error())
+	}
+	if len(pending) > 0 {
+		panic(fmt.Sprintf("there are %d pending migrations, the first is %d_%s, run 'godemo migrate up' "+
+			"or set database.skipMigrationCheck to true in the configuration file",
+			len(pending), pending[0].Version, pending[0].Name))
+	}
+	logger.Info("[migration] schema is up to date")
+}
@@ -2,6 +2,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/go-dev-frame/sponge/pkg/app"
 
 	"godemo/cmd/godemo/initial"
@@ -18,6 +22,25 @@ import (
 // @description Type Bearer your-jwt-token to Value
 func main() {
 	initial.InitApp()
+
+	// godemo migrate up|down|status runs the database migrations instead of the service
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := initial.RunMigrate(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	// godemo bootstrap-admin creates the first administrator of a new installation
+	if args := flag.Args(); len(args) > 0 && args[0] == "bootstrap-admin" {
+		if err := initial.RunBootstrapAdmin(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	initial.CheckMigrations()
+
 	services := initial.CreateServices()
 	closes := initial.Close(services)
 
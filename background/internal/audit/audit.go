@@ -0,0 +1,146 @@
+// Package audit provides the actor of a change and the field diff that are written to the audit log
+// by the dao layer.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync/atomic"
+)
+
+// the actions of the audit log
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// Redacted replaces the values of the secret fields in a diff
+const Redacted = "[REDACTED]"
+
+var (
+	enabled atomic.Bool
+
+	// json names of the fields whose values are never written to the audit log, only that they changed
+	secretFields = map[string]bool{
+		"password":          true,
+		"totpSecret":        true,
+		"totpRecoveryCodes": true,
+		"keyHash":           true,
+	}
+
+	// json names of the fields that change with every update and are left out of a diff
+	ignoredFields = map[string]bool{
+		"updatedAt": true,
+		"version":   true,
+	}
+)
+
+// Enable turn the audit log on, it is off by default so that the dao can be used without the
+// audit_logs table, such as in unit tests.
+func Enable() {
+	enabled.Store(true)
+}
+
+// Disable turn the audit log off
+func Disable() {
+	enabled.Store(false)
+}
+
+// Enabled report whether the changes are written to the audit log
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Actor is the logged-in user who made a change
+type Actor struct {
+	UserID   uint64
+	UserName string
+}
+
+type actorKey struct{}
+
+// WithActor return a copy of ctx that carries the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext get the actor from ctx, ok is false for an anonymous request such as a login
+func ActorFromContext(ctx context.Context) (actor Actor, ok bool) {
+	actor, ok = ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// Change is the old and new value of a field, a value is nil if the field did not exist
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Diff compare the json fields of before and after, before is nil for a create and after is nil for
+// a purge. Only the changed fields are returned, the values of the secret fields are redacted.
+func Diff(before interface{}, after interface{}) (map[string]Change, error) {
+	oldFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make(map[string]Change)
+	for _, name := range names {
+		if ignoredFields[name] {
+			continue
+		}
+		oldValue, newValue := oldFields[name], newFields[name]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if secretFields[name] {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+		changes[name] = Change{Old: oldValue, New: newValue}
+	}
+	return changes, nil
+}
+
+// convert a record to its json fields, so that the names and values are the same as in the api
+func toFields(record interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if record == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// an empty secret stays visible, so that setting and clearing a secret can be told apart
+func redact(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return Redacted
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRecord struct {
+	ID        uint64 `json:"id"`
+	UpdatedAt string `json:"updatedAt"`
+	NickName  string `json:"nickName"`
+	Password  string `json:"password"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &testRecord{ID: 1, UpdatedAt: "a", NickName: "tom", Password: "hash1"}
+	after := &testRecord{ID: 1, UpdatedAt: "b", NickName: "jerry", Password: "hash2"}
+	changes, err := Diff(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Change{
+		"nickName": {Old: "tom", New: "jerry"},
+		"password": {Old: Redacted, New: Redacted},
+	}, changes)
+
+	// create, the empty secret is not redacted
+	changes, err = Diff(nil, &testRecord{ID: 2, NickName: "tom"})
+	assert.NoError(t, err)
+	assert.Equal(t, Change{Old: nil, New: float64(2)}, changes["id"])
+	assert.Equal(t, Change{Old: nil, New: ""}, changes["password"])
+	assert.NotContains(t, changes, "updatedAt")
+
+	// purge
+	changes, err = Diff(before, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Change{Old: Redacted, New: nil}, changes["password"])
+
+	// nothing changed
+	changes, err = Diff(before, before)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = Diff(make(chan int), nil)
+	assert.Error(t, err)
+}
+
+func TestActorFromContext(t *testing.T) {
+	_, ok := ActorFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := WithActor(context.Background(), Actor{UserID: 1, UserName: "admin"})
+	actor, ok := ActorFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, Actor{UserID: 1, UserName: "admin"}, actor)
+}
+
+func TestEnable(t *testing.T) {
+	assert.False(t, Enabled())
+	Enable()
+	assert.True(t, Enabled())
+	Disable()
+	assert.False(t, Enabled())
+}
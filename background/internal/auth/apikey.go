@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+const (
+	// TokenTypeAPIKey the claims of a request authenticated by an api key instead of an access token,
+	// they are built by the auth middleware and never signed.
+	TokenTypeAPIKey = "apiKey"
+
+	// APIKeyPrefix every api key starts with it, so that an api key can be told from a jwt and found
+	// by secret scanners.
+	APIKeyPrefix = "gdk_"
+	// APIKeyHeader the request header that carries an api key, an api key is also accepted as the
+	// bearer token of the Authorization header.
+	APIKeyHeader = "X-API-Key"
+
+	// the prefix and the first characters of a key are stored in clear, to tell the keys apart
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+	fieldAPIKeyID    = "apiKeyID"
+	fieldPermissions = "permissions"
+)
+
+// NewAPIKey create a random api key, the key is shown to the user once, only its hash and its
+// display prefix are stored.
+func NewAPIKey() (key string, displayPrefix string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey the stored hash of an api key, the key is random, so a fast hash is enough
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey check whether the token is an api key rather than a jwt
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// NewAPIKeyClaims the claims of a request authenticated by the api key, handlers read the user id
+// from them like from the claims of an access token.
+func NewAPIKeyClaims(userID uint64, userName string, keyID uint64, permissions []string) *jwt.Claims {
+	return &jwt.Claims{
+		UID: utils.Uint64ToStr(userID),
+		Fields: map[string]interface{}{
+			fieldTokenType:   TokenTypeAPIKey,
+			fieldUserName:    userName,
+			fieldAPIKeyID:    keyID,
+			fieldPermissions: permissions,
+		},
+	}
+}
+
+// IsAPIKeyClaims check whether the claims are built from an api key
+func IsAPIKeyClaims(claims *jwt.Claims) bool {
+	return CheckTokenType(claims, TokenTypeAPIKey) == nil
+}
+
+// GetAPIKeyPermissions get the permission codes the api key of the claims is limited to,
+// it is empty if the claims are not built from an api key.
+func GetAPIKeyPermissions(claims *jwt.Claims) []string {
+	if !IsAPIKeyClaims(claims) {
+		return nil
+	}
+	v, _ := claims.Get(fieldPermissions)
+	permissions, _ := v.([]string)
+	return permissions
+}
+
+// SplitPermissions split the comma separated permission codes stored with an api key
+func SplitPermissions(permissions string) []string {
+	codes := []string{}
+	for _, v := range strings.Split(permissions, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			codes = append(codes, v)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, IsAPIKey(key))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Len(t, prefix, len(APIKeyPrefix)+8)
+	assert.Equal(t, hash, HashAPIKey(key))
+	assert.NotContains(t, hash, key)
+
+	key2, _, hash2, _ := NewAPIKey()
+	assert.NotEqual(t, key, key2)
+	assert.NotEqual(t, hash, hash2)
+
+	// a jwt is not an api key
+	tokens, err := GenerateTokens(1, "admin", "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, IsAPIKey(tokens.AccessToken))
+}
+
+func TestAPIKeyClaims(t *testing.T) {
+	claims := NewAPIKeyClaims(2, "admin", 3, []string{"user:manage"})
+	userID, err := GetUserID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), userID)
+	assert.True(t, IsAPIKeyClaims(claims))
+	assert.Equal(t, []string{"user:manage"}, GetAPIKeyPermissions(claims))
+	_, err = GetFamilyID(claims)
+	assert.ErrorIs(t, err, ErrTokenID)
+
+	// the claims of an access token
+	tokens, _ := GenerateTokens(1, "admin", "f1")
+	claims, err = ParseToken(tokens.AccessToken, TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, IsAPIKeyClaims(claims))
+	assert.Nil(t, GetAPIKeyPermissions(claims))
+}
+
+func TestSplitPermissions(t *testing.T) {
+	assert.Equal(t, []string{}, SplitPermissions(""))
+	assert.Equal(t, []string{"a:b", "c:d"}, SplitPermissions("a:b, ,c:d,"))
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"godemo/internal/cache"
+	"godemo/internal/config"
+)
+
+const (
+	defaultLoginFailureWindow = 15 * time.Minute
+	defaultLoginDelayAfter    = 3
+	defaultLoginMaxDelay      = 5 * time.Second
+	defaultLoginLockAfter     = 10
+	defaultLoginIPLockAfter   = 50
+	defaultLoginLockDuration  = 15 * time.Minute
+
+	loginSubjectUser = "user:"
+	loginSubjectIP   = "ip:"
+)
+
+var (
+	// ErrAccountLocked the user name has too many failed logins
+	ErrAccountLocked = errors.New("account is temporarily locked")
+	// ErrIPBlocked the client ip has too many failed logins
+	ErrIPBlocked = errors.New("client ip is temporarily blocked")
+)
+
+// LoginLimiter protect the login against brute-force attacks. Failed logins are counted per user
+// name and per client ip within a window, after some failures of a user name each login is delayed
+// a little more, and after more failures the user name or the client ip is locked for a while.
+type LoginLimiter struct {
+	failures cache.LoginFailuresCache
+}
+
+// NewLoginLimiter create a login limiter that keeps the counters in the cache
+func NewLoginLimiter(failures cache.LoginFailuresCache) *LoginLimiter {
+	return &LoginLimiter{failures: failures}
+}
+
+func loginFailureWindow() time.Duration {
+	if v := config.Get().Login.FailureWindow; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultLoginFailureWindow
+}
+
+func loginDelayAfter() int64 {
+	if v := config.Get().Login.DelayAfter; v > 0 {
+		return int64(v)
+	}
+	return defaultLoginDelayAfter
+}
+
+func loginMaxDelay() time.Duration {
+	if v := config.Get().Login.MaxDelay; v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return defaultLoginMaxDelay
+}
+
+func loginLockAfter() int64 {
+	if v := config.Get().Login.LockAfter; v > 0 {
+		return int64(v)
+	}
+	return defaultLoginLockAfter
+}
+
+func loginIPLockAfter() int64 {
+	if v := config.Get().Login.IPLockAfter; v > 0 {
+		return int64(v)
+	}
+	return defaultLoginIPLockAfter
+}
+
+func loginLockDuration() time.Duration {
+	if v := config.Get().Login.LockDuration; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultLoginLockDuration
+}
+
+// Check whether the user name and the client ip may try to login, it returns ErrAccountLocked or
+// ErrIPBlocked if they are locked, otherwise the delay to wait before verifying the password.
+func (l *LoginLimiter) Check(ctx context.Context, userName string, ip string) (time.Duration, error) {
+	ipFailures, err := l.failures.Get(ctx, loginSubjectIP+ip)
+	if err != nil {
+		return 0, err
+	}
+	if ipFailures >= loginIPLockAfter() {
+		return 0, ErrIPBlocked
+	}
+
+	userFailures, err := l.failures.Get(ctx, loginSubjectUser+userName)
+	if err != nil {
+		return 0, err
+	}
+	if userFailures >= loginLockAfter() {
+		return 0, ErrAccountLocked
+	}
+
+	return loginDelay(userFailures), nil
+}
+
+// one more second for every failure after delayAfter, up to maxDelay
+func loginDelay(failures int64) time.Duration {
+	n := failures - loginDelayAfter()
+	if n <= 0 {
+		return 0
+	}
+	delay := time.Duration(n) * time.Second
+	if maxDelay := loginMaxDelay(); delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// Fail count a failed login of the user name and the client ip, the counter that reaches its
+// threshold is kept for lockDuration from now.
+func (l *LoginLimiter) Fail(ctx context.Context, userName string, ip string) error {
+	window := loginFailureWindow()
+
+	userFailures, err := l.failures.Incr(ctx, loginSubjectUser+userName, window)
+	if err != nil {
+		return err
+	}
+	if userFailures >= loginLockAfter() {
+		if err = l.failures.Expire(ctx, loginSubjectUser+userName, loginLockDuration()); err != nil {
+			return err
+		}
+	}
+
+	ipFailures, err := l.failures.Incr(ctx, loginSubjectIP+ip, window)
+	if err != nil {
+		return err
+	}
+	if ipFailures >= loginIPLockAfter() {
+		return l.failures.Expire(ctx, loginSubjectIP+ip, loginLockDuration())
+	}
+	return nil
+}
+
+// Succeed forget the failures of the user name after a successful login, the failures of the
+// client ip are kept, so that an attacker cannot reset them with an account of its own.
+func (l *LoginLimiter) Succeed(ctx context.Context, userName string) error {
+	return l.failures.Del(ctx, loginSubjectUser+userName)
+}
+
+// Unlock forget the failures of the user name, so it can login again immediately
+func (l *LoginLimiter) Unlock(ctx context.Context, userName string) error {
+	return l.failures.Del(ctx, loginSubjectUser+userName)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+)
+
+func newTestLoginLimiter() *LoginLimiter {
+	// the memory cache is shared by the process, use distinct user names and ips in each test
+	return NewLoginLimiter(cache.NewLoginFailuresCache(&database.CacheType{CType: "memory"}))
+}
+
+func TestLoginLimiter_User(t *testing.T) {
+	l := newTestLoginLimiter()
+	ctx := context.Background()
+
+	delay, err := l.Check(ctx, "user1", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+
+	for i := 0; i < defaultLoginDelayAfter+1; i++ {
+		assert.NoError(t, l.Fail(ctx, "user1", "10.0.0.1"))
+	}
+	delay, err = l.Check(ctx, "user1", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, delay)
+
+	// the delay grows up to the maximum, then the user name is locked from any ip
+	for i := defaultLoginDelayAfter + 1; i < defaultLoginLockAfter-1; i++ {
+		assert.NoError(t, l.Fail(ctx, "user1", "10.0.0.1"))
+	}
+	delay, err = l.Check(ctx, "user1", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultLoginMaxDelay, delay)
+	assert.NoError(t, l.Fail(ctx, "user1", "10.0.0.1"))
+	_, err = l.Check(ctx, "user1", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// other user names from the same ip are not locked
+	_, err = l.Check(ctx, "user2", "10.0.0.1")
+	assert.NoError(t, err)
+
+	// unlock
+	assert.NoError(t, l.Unlock(ctx, "user1"))
+	delay, err = l.Check(ctx, "user1", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+
+	// a successful login resets the failures of the user name
+	assert.NoError(t, l.Fail(ctx, "user3", "10.0.0.3"))
+	assert.NoError(t, l.Succeed(ctx, "user3"))
+	n, _ := l.failures.Get(ctx, loginSubjectUser+"user3")
+	assert.Zero(t, n)
+	n, _ = l.failures.Get(ctx, loginSubjectIP+"10.0.0.3")
+	assert.Equal(t, int64(1), n)
+}
+
+func TestLoginLimiter_IP(t *testing.T) {
+	l := newTestLoginLimiter()
+	ctx := context.Background()
+
+	// many user names from one ip
+	for i := 0; i < defaultLoginIPLockAfter; i++ {
+		assert.NoError(t, l.Fail(ctx, fmt.Sprintf("ipUser%d", i), "10.0.1.1"))
+	}
+	_, err := l.Check(ctx, "ipUser", "10.0.1.1")
+	assert.ErrorIs(t, err, ErrIPBlocked)
+
+	_, err = l.Check(ctx, "ipUser", "10.0.1.2")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+	"sync"
+
+	"github.com/go-dev-frame/sponge/pkg/gocrypto"
+)
+
+// MaxPasswordBytes the longest password in bytes that bcrypt accepts
+const MaxPasswordBytes = 72
+
+// bcrypt hash prefixes, a stored password without one of them is a legacy plaintext password
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string // a hash with the same cost as the stored passwords, it matches no password of a user
+)
+
+// HashPassword hash the password with a random salt using bcrypt
+func HashPassword(password string) (string, error) {
+	return gocrypto.HashAndSaltPassword(password)
+}
+
+// IsHashedPassword check whether the stored password is a bcrypt hash
+func IsHashedPassword(stored string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyPassword check the password against the stored value. Legacy plaintext values are
+// still accepted, in which case needRehash is true and the caller should store a new hash.
+func VerifyPassword(password string, stored string) (ok bool, needRehash bool) {
+	if IsHashedPassword(stored) {
+		return gocrypto.VerifyPassword(password, stored), false
+	}
+
+	if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+		return false, false
+	}
+	return true, true
+}
+
+// VerifyDummyPassword check the password against a dummy hash, which takes as long as VerifyPassword, it is
+// called when the user does not exist, so that the response time of a login does not tell which user names exist.
+func VerifyDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("dummy password of a user that does not exist")
+	})
+	_ = gocrypto.VerifyPassword(password, dummyHash)
+}
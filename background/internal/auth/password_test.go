@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	hashed, err := HashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, IsHashedPassword(hashed))
+
+	ok, needRehash := VerifyPassword("123456", hashed)
+	assert.True(t, ok)
+	assert.False(t, needRehash)
+
+	ok, _ = VerifyPassword("654321", hashed)
+	assert.False(t, ok)
+
+	// legacy plaintext password
+	ok, needRehash = VerifyPassword("123456", "123456")
+	assert.True(t, ok)
+	assert.True(t, needRehash)
+
+	ok, needRehash = VerifyPassword("", "")
+	assert.False(t, ok)
+	assert.False(t, needRehash)
+}
+
+func TestVerifyDummyPassword(t *testing.T) {
+	VerifyDummyPassword("123456")
+	assert.True(t, IsHashedPassword(dummyHash))
+	ok, _ := VerifyPassword("123456", dummyHash)
+	assert.False(t, ok)
+}
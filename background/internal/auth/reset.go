@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"godemo/internal/cache"
+	"godemo/internal/config"
+)
+
+const (
+	defaultPasswordResetExpire      = 30 * time.Minute
+	defaultPasswordResetLimitWindow = time.Hour
+	defaultPasswordResetEmailLimit  = 3
+	defaultPasswordResetIPLimit     = 20
+
+	resetSubjectEmail = "resetEmail:"
+	resetSubjectIP    = "resetIP:"
+)
+
+// NewResetToken create a random password reset token, the token is sent to the user and only
+// its hash is stored, so that a leaked cache does not allow resetting passwords.
+func NewResetToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashResetToken(token), nil
+}
+
+// HashResetToken the stored hash of a password reset token, the token is random, so a fast hash is enough
+func HashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// PasswordResetExpire the lifetime of a password reset token
+func PasswordResetExpire() time.Duration {
+	if v := config.Get().PasswordReset.Expire; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultPasswordResetExpire
+}
+
+// PasswordResetURL the link of the web client page that resets the password with the token,
+// it is empty if passwordReset.url is not configured.
+func PasswordResetURL(token string) string {
+	u := config.Get().PasswordReset.URL
+	if u == "" {
+		return ""
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + "token=" + url.QueryEscape(token)
+}
+
+func passwordResetLimitWindow() time.Duration {
+	if v := config.Get().PasswordReset.LimitWindow; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultPasswordResetLimitWindow
+}
+
+func passwordResetEmailLimit() int64 {
+	if v := config.Get().PasswordReset.EmailLimit; v > 0 {
+		return int64(v)
+	}
+	return defaultPasswordResetEmailLimit
+}
+
+func passwordResetIPLimit() int64 {
+	if v := config.Get().PasswordReset.IPLimit; v > 0 {
+		return int64(v)
+	}
+	return defaultPasswordResetIPLimit
+}
+
+// ResetLimiter limit the password reset requests per email and per client ip within a window, so that
+// the forgot password api cannot be used to send unlimited emails to an address. The counters are kept
+// in the same cache as the failed logins under their own subjects.
+type ResetLimiter struct {
+	counters cache.LoginFailuresCache
+}
+
+// NewResetLimiter create a password reset limiter that keeps the counters in the cache
+func NewResetLimiter(counters cache.LoginFailuresCache) *ResetLimiter {
+	return &ResetLimiter{counters: counters}
+}
+
+// AllowIP count a request of the client ip, it returns false if the ip has reached its limit
+func (l *ResetLimiter) AllowIP(ctx context.Context, ip string) (bool, error) {
+	n, err := l.counters.Incr(ctx, resetSubjectIP+ip, passwordResetLimitWindow())
+	if err != nil {
+		return false, err
+	}
+	return n <= passwordResetIPLimit(), nil
+}
+
+// AllowEmail count a reset email to the address, it returns false if the address has reached its limit,
+// the email is compared case-insensitively.
+func (l *ResetLimiter) AllowEmail(ctx context.Context, email string) (bool, error) {
+	subject := resetSubjectEmail + strings.ToLower(strings.TrimSpace(email))
+	n, err := l.counters.Incr(ctx, subject, passwordResetLimitWindow())
+	if err != nil {
+		return false, err
+	}
+	return n <= passwordResetEmailLimit(), nil
+}
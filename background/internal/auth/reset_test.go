@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/cache"
+	"godemo/internal/config"
+	"godemo/internal/database"
+)
+
+func TestNewResetToken(t *testing.T) {
+	token, hash, err := NewResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, token, 43)
+	assert.Equal(t, hash, HashResetToken(token))
+	assert.Equal(t, hash, HashResetToken(" "+token+" "))
+	assert.NotEqual(t, hash, token)
+
+	token2, hash2, _ := NewResetToken()
+	assert.NotEqual(t, token, token2)
+	assert.NotEqual(t, hash, hash2)
+}
+
+func TestPasswordResetURL(t *testing.T) {
+	cfg := config.Get()
+	defer func() { cfg.PasswordReset = config.PasswordReset{} }()
+
+	assert.Equal(t, defaultPasswordResetExpire, PasswordResetExpire())
+	assert.Equal(t, "", PasswordResetURL("abc"))
+
+	cfg.PasswordReset = config.PasswordReset{Expire: 10, URL: "http://localhost:9527/#/reset-password"}
+	assert.Equal(t, "10m0s", PasswordResetExpire().String())
+	assert.Equal(t, "http://localhost:9527/#/reset-password?token=a-b_c", PasswordResetURL("a-b_c"))
+
+	cfg.PasswordReset.URL = "http://localhost/reset?lang=en"
+	assert.Equal(t, "http://localhost/reset?lang=en&token=abc", PasswordResetURL("abc"))
+}
+
+func TestResetLimiter(t *testing.T) {
+	// the memory cache is shared by the process, use distinct emails and ips in each test
+	l := NewResetLimiter(cache.NewLoginFailuresCache(&database.CacheType{CType: "memory"}))
+	ctx := context.Background()
+
+	for i := 0; i < defaultPasswordResetEmailLimit; i++ {
+		ok, err := l.AllowEmail(ctx, "reset@example.com")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	// the email is counted case-insensitively
+	ok, err := l.AllowEmail(ctx, " Reset@Example.com")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, _ = l.AllowEmail(ctx, "other@example.com")
+	assert.True(t, ok)
+
+	for i := 0; i < defaultPasswordResetIPLimit; i++ {
+		ok, err = l.AllowIP(ctx, "10.0.1.1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err = l.AllowIP(ctx, "10.0.1.1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, _ = l.AllowIP(ctx, "10.0.1.2")
+	assert.True(t, ok)
+}
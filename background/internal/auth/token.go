@@ -0,0 +1,213 @@
+// Package auth provides the token and password helpers used by the /auth apis.
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/config"
+)
+
+const (
+	// TokenTypeAccess access token, carried in the Authorization header of every request
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh refresh token, only accepted by the refreshToken api
+	TokenTypeRefresh = "refresh"
+	// TokenTypeChallenge challenge token, returned by the login api instead of the tokens when the
+	// password is correct but two-factor authentication is pending
+	TokenTypeChallenge = "challenge"
+
+	// ChallengeVerify the user must verify a two-factor code
+	ChallengeVerify = "verify"
+	// ChallengeEnroll the user must enroll two-factor authentication, required by one of its roles
+	ChallengeEnroll = "enroll"
+
+	fieldTokenType = "tokenType"
+	fieldUserName  = "userName"
+	fieldFamilyID  = "familyID"
+	fieldTokenID   = "tokenID"
+	fieldPurpose   = "purpose"
+
+	defaultAccessTokenExpire    = 2 * time.Hour
+	defaultRefreshTokenExpire   = 7 * 24 * time.Hour
+	defaultChallengeTokenExpire = 5 * time.Minute
+)
+
+var (
+	// ErrTokenType the token is valid, but not of the expected type
+	ErrTokenType = errors.New("token type is not match")
+	// ErrTokenUID the token does not carry a valid user id
+	ErrTokenUID = errors.New("token uid is invalid")
+	// ErrTokenID the token does not carry a family id or token id
+	ErrTokenID = errors.New("token id is invalid")
+)
+
+// Tokens a pair of access token and refresh token. Both belong to a token family that starts at login,
+// RefreshTokenID identifies the refresh token within the family, it changes on every refresh.
+type Tokens struct {
+	AccessToken    string
+	RefreshToken   string
+	RefreshTokenID string
+}
+
+// SignKey jwt sign key, the service does not start without one, see config.Config.Check
+func SignKey() []byte {
+	return []byte(config.Get().JWT.SignKey)
+}
+
+func accessTokenExpire() time.Duration {
+	if v := config.Get().JWT.AccessTokenExpire; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultAccessTokenExpire
+}
+
+// RefreshTokenExpire the lifetime of a refresh token, it is also the lifetime of the token family
+func RefreshTokenExpire() time.Duration {
+	if v := config.Get().JWT.RefreshTokenExpire; v > 0 {
+		return time.Duration(v) * time.Hour
+	}
+	return defaultRefreshTokenExpire
+}
+
+func challengeTokenExpire() time.Duration {
+	if v := config.Get().TwoFactor.ChallengeExpire; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return defaultChallengeTokenExpire
+}
+
+func generateToken(uid string, fields map[string]interface{}, expire time.Duration) (string, error) {
+	opts := []jwt.GenerateTokenOption{
+		jwt.WithGenerateTokenFields(fields),
+		jwt.WithGenerateTokenClaims(jwt.WithExpires(expire)),
+	}
+	if key := SignKey(); len(key) > 0 {
+		opts = append(opts, jwt.WithGenerateTokenSignKey(key))
+	}
+
+	_, token, err := jwt.GenerateToken(uid, opts...)
+	return token, err
+}
+
+// NewTokenID create a random id for a token family or a refresh token
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateTokens create an access token and a refresh token of the token family for the user
+func GenerateTokens(userID uint64, userName string, familyID string) (*Tokens, error) {
+	uid := utils.Uint64ToStr(userID)
+
+	accessToken, err := generateToken(uid, map[string]interface{}{
+		fieldTokenType: TokenTypeAccess,
+		fieldUserName:  userName,
+		fieldFamilyID:  familyID,
+	}, accessTokenExpire())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenID, err := NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := generateToken(uid, map[string]interface{}{
+		fieldTokenType: TokenTypeRefresh,
+		fieldFamilyID:  familyID,
+		fieldTokenID:   refreshTokenID,
+	}, RefreshTokenExpire())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		RefreshTokenID: refreshTokenID,
+	}, nil
+}
+
+// GenerateChallengeToken create a short-lived challenge token for the second step of a login,
+// purpose is ChallengeVerify or ChallengeEnroll.
+func GenerateChallengeToken(userID uint64, userName string, purpose string) (string, error) {
+	return generateToken(utils.Uint64ToStr(userID), map[string]interface{}{
+		fieldTokenType: TokenTypeChallenge,
+		fieldUserName:  userName,
+		fieldPurpose:   purpose,
+	}, challengeTokenExpire())
+}
+
+// ParseChallengeToken validate the challenge token, and check that it is issued for the purpose
+func ParseChallengeToken(tokenString string, purpose string) (*jwt.Claims, error) {
+	claims, err := ParseToken(tokenString, TokenTypeChallenge)
+	if err != nil {
+		return nil, err
+	}
+	if p, _ := claims.GetString(fieldPurpose); p != purpose {
+		return nil, ErrTokenType
+	}
+	return claims, nil
+}
+
+// ParseToken validate the token signature and expiration, and check that it is of the given type
+func ParseToken(tokenString string, tokenType string) (*jwt.Claims, error) {
+	claims, err := jwt.ValidateToken(tokenString, jwt.WithValidateTokenSignKey(SignKey()))
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckTokenType(claims, tokenType); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CheckTokenType check that the claims belong to a token of the given type
+func CheckTokenType(claims *jwt.Claims, tokenType string) error {
+	if t, _ := claims.GetString(fieldTokenType); t != tokenType {
+		return ErrTokenType
+	}
+	return nil
+}
+
+// GetUserID get the user id from claims
+func GetUserID(claims *jwt.Claims) (uint64, error) {
+	id, err := utils.StrToUint64E(claims.UID)
+	if err != nil || id == 0 {
+		return 0, ErrTokenUID
+	}
+	return id, nil
+}
+
+// GetUserName get the user name from claims
+func GetUserName(claims *jwt.Claims) string {
+	userName, _ := claims.GetString(fieldUserName)
+	return userName
+}
+
+// GetFamilyID get the token family id from claims
+func GetFamilyID(claims *jwt.Claims) (string, error) {
+	familyID, _ := claims.GetString(fieldFamilyID)
+	if familyID == "" {
+		return "", ErrTokenID
+	}
+	return familyID, nil
+}
+
+// GetTokenID get the refresh token id from claims
+func GetTokenID(claims *jwt.Claims) (string, error) {
+	tokenID, _ := claims.GetString(fieldTokenID)
+	if tokenID == "" {
+		return "", ErrTokenID
+	}
+	return tokenID, nil
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/config"
+)
+
+func init() {
+	config.Set(&config.Config{
+		JWT: config.JWT{
+			SignKey:            "test-sign-key",
+			AccessTokenExpire:  10,
+			RefreshTokenExpire: 1,
+		},
+		TwoFactor: config.TwoFactor{
+			EncryptKey: "test-encrypt-key",
+			Issuer:     "godemo",
+		},
+	})
+}
+
+func TestGenerateTokens(t *testing.T) {
+	tokens, err := GenerateTokens(1, "admin", "family1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseToken(tokens.AccessToken, TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := GetUserID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+	assert.Equal(t, "admin", GetUserName(claims))
+
+	familyID, err := GetFamilyID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "family1", familyID)
+
+	claims, err = ParseToken(tokens.RefreshToken, TokenTypeRefresh)
+	assert.NoError(t, err)
+	familyID, err = GetFamilyID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "family1", familyID)
+	tokenID, err := GetTokenID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, tokens.RefreshTokenID, tokenID)
+
+	// the refresh token id changes every time
+	tokens2, err := GenerateTokens(1, "admin", "family1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshTokenID, tokens2.RefreshTokenID)
+}
+
+func TestParseToken(t *testing.T) {
+	tokens, err := GenerateTokens(1, "admin", "family1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an access token cannot be used as a refresh token, and vice versa
+	_, err = ParseToken(tokens.AccessToken, TokenTypeRefresh)
+	assert.ErrorIs(t, err, ErrTokenType)
+	_, err = ParseToken(tokens.RefreshToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrTokenType)
+
+	// illegal token
+	_, err = ParseToken("illegal token", TokenTypeAccess)
+	assert.Error(t, err)
+}
+
+func TestChallengeToken(t *testing.T) {
+	token, err := GenerateChallengeToken(1, "admin", ChallengeVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseChallengeToken(token, ChallengeVerify)
+	assert.NoError(t, err)
+	userID, err := GetUserID(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+
+	// wrong purpose
+	_, err = ParseChallengeToken(token, ChallengeEnroll)
+	assert.ErrorIs(t, err, ErrTokenType)
+	// a challenge token is not an access token
+	_, err = ParseToken(token, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrTokenType)
+	// an access token is not a challenge token
+	tokens, _ := GenerateTokens(1, "admin", "family1")
+	_, err = ParseChallengeToken(tokens.AccessToken, ChallengeVerify)
+	assert.ErrorIs(t, err, ErrTokenType)
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 default algorithm, supported by all authenticator apps
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"godemo/internal/config"
+)
+
+const (
+	totpDigits = 6
+	totpModulo = 1000000 // 10^totpDigits
+	totpPeriod = 30 * time.Second
+	// codes of the adjacent periods are accepted too, to tolerate clock drift
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrNoEncryptKey twoFactor.encryptKey is not configured
+	ErrNoEncryptKey = errors.New("two-factor encrypt key is not configured")
+	// ErrInvalidSecret the stored secret cannot be decrypted
+	ErrInvalidSecret = errors.New("two-factor secret is invalid")
+
+	base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// NewTOTPSecret create a random base32 secret of 160 bits, as recommended by RFC 4226
+func NewTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(buf), nil
+}
+
+// TOTPURL the otpauth url of the secret, authenticator apps enroll it by scanning a qr code of the url
+func TOTPURL(accountName string, secret string) string {
+	issuer := config.Get().TwoFactor.Issuer
+	if issuer == "" {
+		issuer = config.Get().App.Name
+	}
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+accountName) + "?" + params.Encode()
+}
+
+// TOTPCode the code of the secret at the time t, RFC 6238 with HMAC-SHA1
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCode(secret, uint64(t.Unix())/uint64(totpPeriod.Seconds()))
+}
+
+func totpCode(secret string, counter uint64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo), nil
+}
+
+// VerifyTOTP check the code against the secret at the time t
+func VerifyTOTP(secret string, code string, t time.Time) bool {
+	_, ok := MatchTOTP(secret, code, t)
+	return ok
+}
+
+// MatchTOTP check the code against the secret at the time t, it returns the time step of the code.
+// A code is valid for several steps, the caller rejects a step at or before the last accepted one,
+// so that a code cannot be replayed.
+func MatchTOTP(secret string, code string, t time.Time) (step uint64, ok bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	counter := uint64(t.Unix()) / uint64(totpPeriod.Seconds())
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step = counter + uint64(int64(i))
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func secretKey() ([]byte, error) {
+	key := config.Get().TwoFactor.EncryptKey
+	if key == "" {
+		return nil, ErrNoEncryptKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	return sum[:], nil
+}
+
+// EncryptSecret encrypt the secret with AES-GCM using twoFactor.encryptKey, the result is base64 encoded
+func EncryptSecret(secret string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypt a secret encrypted by EncryptSecret
+func DecryptSecret(encrypted string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	return string(plain), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	key, err := secretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewRecoveryCodes create the one-time recovery codes, the codes are shown to the user once,
+// only the comma separated hashes are stored.
+func NewRecoveryCodes() (codes []string, hashes string, err error) {
+	hashList := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		code := strings.ToLower(base32NoPadding.EncodeToString(buf)) // 8 characters
+		code = code[:4] + "-" + code[4:]
+		codes = append(codes, code)
+		hashList = append(hashList, hashRecoveryCode(code))
+	}
+	return codes, strings.Join(hashList, ","), nil
+}
+
+// recovery codes are random, so a fast hash is enough
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// UseRecoveryCode check the code against the stored hashes, if it matches, the remaining hashes
+// without it are returned and must be stored, so that the code cannot be used again.
+func UseRecoveryCode(hashes string, code string) (remaining string, ok bool) {
+	if hashes == "" || code == "" {
+		return hashes, false
+	}
+	hashed := hashRecoveryCode(code)
+	list := strings.Split(hashes, ",")
+	for i, v := range list {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(hashed)) == 1 {
+			list = append(list[:i], list[i+1:]...)
+			return strings.Join(list, ","), true
+		}
+	}
+	return hashes, false
+}
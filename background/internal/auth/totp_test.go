@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the ascii secret "12345678901234567890" of the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// the last 6 digits of the SHA1 test vectors of RFC 6238 appendix B
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range tests {
+		code, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+
+	_, err := TOTPCode("not base32!", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, _ := TOTPCode(secret, now)
+	assert.True(t, VerifyTOTP(secret, code, now))
+	assert.True(t, VerifyTOTP(secret, " "+code[:3]+" "+code[3:], now))
+
+	// adjacent periods are accepted, older ones are not
+	assert.True(t, VerifyTOTP(secret, code, now.Add(totpPeriod)))
+	assert.False(t, VerifyTOTP(secret, code, now.Add(3*totpPeriod)))
+
+	assert.False(t, VerifyTOTP(secret, "", now))
+	assert.False(t, VerifyTOTP(secret, "12345", now))
+	assert.False(t, VerifyTOTP("not base32!", "123456", now))
+}
+
+func TestMatchTOTP(t *testing.T) {
+	// the code of step 37037037 is accepted in the adjacent steps too, and always returns its own step
+	code, _ := TOTPCode(rfcSecret, time.Unix(1111111111, 0))
+	for _, unix := range []int64{1111111111, 1111111111 + 30} {
+		step, ok := MatchTOTP(rfcSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok)
+		assert.Equal(t, uint64(37037037), step)
+	}
+
+	step, ok := MatchTOTP(rfcSecret, "000000", time.Unix(59, 0))
+	assert.False(t, ok)
+	assert.Zero(t, step)
+}
+
+func TestTOTPURL(t *testing.T) {
+	u := TOTPURL("admin", rfcSecret)
+	assert.True(t, strings.HasPrefix(u, "otpauth://totp/godemo:admin?"))
+	assert.Contains(t, u, "secret="+rfcSecret)
+	assert.Contains(t, u, "issuer=godemo")
+}
+
+func TestEncryptSecret(t *testing.T) {
+	encrypted, err := EncryptSecret(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, encrypted, rfcSecret)
+
+	// random nonce
+	encrypted2, _ := EncryptSecret(rfcSecret)
+	assert.NotEqual(t, encrypted, encrypted2)
+
+	secret, err := DecryptSecret(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, rfcSecret, secret)
+
+	_, err = DecryptSecret("bad")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+	_, err = DecryptSecret(encrypted[:len(encrypted)-4] + "AAAA")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.NotContains(t, hashes, codes[0])
+
+	// a code can be used once, case and separators are ignored
+	remaining, ok := UseRecoveryCode(hashes, strings.ToUpper(strings.ReplaceAll(codes[3], "-", "")))
+	assert.True(t, ok)
+	assert.Len(t, strings.Split(remaining, ","), recoveryCodeCount-1)
+	_, ok = UseRecoveryCode(remaining, codes[3])
+	assert.False(t, ok)
+
+	_, ok = UseRecoveryCode(remaining, "")
+	assert.False(t, ok)
+	_, ok = UseRecoveryCode("", codes[0])
+	assert.False(t, ok)
+}
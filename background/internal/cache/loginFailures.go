@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-dev-frame/sponge/pkg/goredis"
+
+	"godemo/internal/database"
+)
+
+const (
+	// cache prefix key, must end with a colon
+	loginFailuresCachePrefixKey = "loginFailures:"
+)
+
+var _ LoginFailuresCache = (*loginFailuresRedisCache)(nil)
+var _ LoginFailuresCache = (*loginFailuresMemoryCache)(nil)
+
+// LoginFailuresCache counts the failed logins of a subject, such as a user name or a client ip.
+// A counter is forgotten when it expires, like the token cache it is not a copy of the database,
+// so it is not built on the sponge cache.
+type LoginFailuresCache interface {
+	// Get the number of failures of the subject, 0 if there is none
+	Get(ctx context.Context, subject string) (int64, error)
+	// Incr add a failure to the subject and return the new number, a new counter expires after window
+	Incr(ctx context.Context, subject string, window time.Duration) (int64, error)
+	// Expire reset the expiration of the counter of the subject
+	Expire(ctx context.Context, subject string, duration time.Duration) error
+	// Del forget the failures of the subject
+	Del(ctx context.Context, subject string) error
+}
+
+// NewLoginFailuresCache new a login failures cache, the memory cache is used if the cache type is not
+// redis, it is shared by the whole process.
+func NewLoginFailuresCache(cacheType *database.CacheType) LoginFailuresCache {
+	if strings.ToLower(cacheType.CType) == "redis" {
+		return &loginFailuresRedisCache{rdb: cacheType.Rdb}
+	}
+	return getLoginFailuresMemoryCache()
+}
+
+func getLoginFailuresCacheKey(subject string) string {
+	return loginFailuresCachePrefixKey + subject
+}
+
+// ---------------------------------------- redis ----------------------------------------
+
+type loginFailuresRedisCache struct {
+	rdb *goredis.Client
+}
+
+func (c *loginFailuresRedisCache) Get(ctx context.Context, subject string) (int64, error) {
+	n, err := c.rdb.Get(ctx, getLoginFailuresCacheKey(subject)).Int64()
+	if errors.Is(err, database.ErrCacheNotFound) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// the expiration is only set when the counter is created, so that the window is not extended by new failures
+var incrLoginFailuresScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (c *loginFailuresRedisCache) Incr(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	return incrLoginFailuresScript.Run(ctx, c.rdb, []string{getLoginFailuresCacheKey(subject)}, window.Milliseconds()).Int64()
+}
+
+func (c *loginFailuresRedisCache) Expire(ctx context.Context, subject string, duration time.Duration) error {
+	return c.rdb.PExpire(ctx, getLoginFailuresCacheKey(subject), duration).Err()
+}
+
+func (c *loginFailuresRedisCache) Del(ctx context.Context, subject string) error {
+	return c.rdb.Del(ctx, getLoginFailuresCacheKey(subject)).Err()
+}
+
+// ---------------------------------------- memory ----------------------------------------
+
+var (
+	loginFailuresMemory     *loginFailuresMemoryCache
+	loginFailuresMemoryOnce sync.Once
+)
+
+func getLoginFailuresMemoryCache() *loginFailuresMemoryCache {
+	loginFailuresMemoryOnce.Do(func() {
+		loginFailuresMemory = newLoginFailuresMemoryCache()
+	})
+	return loginFailuresMemory
+}
+
+type loginFailures struct {
+	count     int64
+	expiresAt time.Time
+}
+
+type loginFailuresMemoryCache struct {
+	mu        sync.Mutex
+	counters  map[string]*loginFailures
+	lastPrune time.Time
+}
+
+func newLoginFailuresMemoryCache() *loginFailuresMemoryCache {
+	return &loginFailuresMemoryCache{
+		counters:  make(map[string]*loginFailures),
+		lastPrune: time.Now(),
+	}
+}
+
+func (c *loginFailuresMemoryCache) Get(_ context.Context, subject string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter, ok := c.get(subject); ok {
+		return counter.count, nil
+	}
+	return 0, nil
+}
+
+func (c *loginFailuresMemoryCache) Incr(_ context.Context, subject string, window time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPrune) > time.Minute {
+		c.prune(now)
+	}
+
+	counter, ok := c.get(subject)
+	if !ok {
+		counter = &loginFailures{expiresAt: now.Add(window)}
+		c.counters[subject] = counter
+	}
+	counter.count++
+	return counter.count, nil
+}
+
+func (c *loginFailuresMemoryCache) Expire(_ context.Context, subject string, duration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter, ok := c.get(subject); ok {
+		counter.expiresAt = time.Now().Add(duration)
+	}
+	return nil
+}
+
+func (c *loginFailuresMemoryCache) Del(_ context.Context, subject string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.counters, subject)
+	return nil
+}
+
+// get an unexpired counter, the caller must hold the lock
+func (c *loginFailuresMemoryCache) get(subject string) (*loginFailures, bool) {
+	counter, ok := c.counters[subject]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(counter.expiresAt) {
+		delete(c.counters, subject)
+		return nil, false
+	}
+	return counter, true
+}
+
+// remove the expired counters, the caller must hold the lock
+func (c *loginFailuresMemoryCache) prune(now time.Time) {
+	for subject, counter := range c.counters {
+		if now.After(counter.expiresAt) {
+			delete(c.counters, subject)
+		}
+	}
+	c.lastPrune = now
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"godemo/internal/database"
+)
+
+func testLoginFailuresCache(t *testing.T, c LoginFailuresCache) {
+	ctx := context.Background()
+
+	n, err := c.Get(ctx, "user:admin")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = c.Incr(ctx, "user:admin", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = c.Incr(ctx, "user:admin", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	n, err = c.Get(ctx, "user:admin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	err = c.Expire(ctx, "user:admin", time.Hour)
+	assert.NoError(t, err)
+
+	err = c.Del(ctx, "user:admin")
+	assert.NoError(t, err)
+	n, err = c.Get(ctx, "user:admin")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func Test_loginFailuresRedisCache(t *testing.T) {
+	c := gotest.NewCache(map[string]interface{}{})
+	defer c.Close()
+
+	testLoginFailuresCache(t, NewLoginFailuresCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	}))
+}
+
+func Test_loginFailuresMemoryCache(t *testing.T) {
+	testLoginFailuresCache(t, newLoginFailuresMemoryCache())
+
+	// expired
+	c := newLoginFailuresMemoryCache()
+	ctx := context.Background()
+	_, _ = c.Incr(ctx, "ip:10.0.0.1", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	n, _ := c.Get(ctx, "ip:10.0.0.1")
+	assert.Zero(t, n)
+
+	c.lastPrune = time.Now().Add(-time.Hour)
+	c.counters["ip:10.0.0.2"] = &loginFailures{count: 1, expiresAt: time.Now().Add(-time.Second)}
+	_, _ = c.Incr(ctx, "ip:10.0.0.3", time.Hour)
+	assert.Len(t, c.counters, 1)
+}
+
+func TestNewLoginFailuresCache(t *testing.T) {
+	c := NewLoginFailuresCache(&database.CacheType{CType: "memory"})
+	assert.Equal(t, c, NewLoginFailuresCache(&database.CacheType{}))
+}
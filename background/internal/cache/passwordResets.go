@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-dev-frame/sponge/pkg/goredis"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/database"
+)
+
+const (
+	// cache prefix key, must end with a colon
+	passwordResetCachePrefixKey     = "passwordReset:"
+	userPasswordResetCachePrefixKey = "userPasswordReset:"
+)
+
+var _ PasswordResetCache = (*passwordResetRedisCache)(nil)
+var _ PasswordResetCache = (*passwordResetMemoryCache)(nil)
+
+// PasswordResetCache keeps the pending password resets, a reset is found by the hash of its token,
+// the token itself is only sent to the user. A user has at most one pending reset, and a reset can
+// only be taken once, so like the token cache it is not built on the sponge cache.
+type PasswordResetCache interface {
+	// Set start a password reset of the user, the previous pending reset of the user is dropped
+	Set(ctx context.Context, userID uint64, tokenHash string, duration time.Duration) error
+	// Take get the user of the reset and delete the reset, it returns database.ErrCacheNotFound
+	// if the reset does not exist or has expired.
+	Take(ctx context.Context, tokenHash string) (uint64, error)
+}
+
+// NewPasswordResetCache new a password reset cache, the memory cache is used if the cache type is not
+// redis, it is shared by the whole process.
+func NewPasswordResetCache(cacheType *database.CacheType) PasswordResetCache {
+	if strings.ToLower(cacheType.CType) == "redis" {
+		return &passwordResetRedisCache{rdb: cacheType.Rdb}
+	}
+	return getPasswordResetMemoryCache()
+}
+
+func getPasswordResetCacheKey(tokenHash string) string {
+	return passwordResetCachePrefixKey + tokenHash
+}
+
+func getUserPasswordResetCacheKey(userID uint64) string {
+	return userPasswordResetCachePrefixKey + utils.Uint64ToStr(userID)
+}
+
+// ---------------------------------------- redis ----------------------------------------
+
+type passwordResetRedisCache struct {
+	rdb *goredis.Client
+}
+
+// KEYS[1] reset key, KEYS[2] user key, ARGV[1] prefix of the reset keys, ARGV[2] user id,
+// ARGV[3] token hash, ARGV[4] duration in milliseconds
+var setPasswordResetScript = redis.NewScript(`
+local old = redis.call('GET', KEYS[2])
+if old then
+	redis.call('DEL', ARGV[1] .. old)
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[4])
+redis.call('SET', KEYS[2], ARGV[3], 'PX', ARGV[4])
+return 1
+`)
+
+func (c *passwordResetRedisCache) Set(ctx context.Context, userID uint64, tokenHash string, duration time.Duration) error {
+	keys := []string{getPasswordResetCacheKey(tokenHash), getUserPasswordResetCacheKey(userID)}
+	return setPasswordResetScript.Run(ctx, c.rdb, keys,
+		passwordResetCachePrefixKey, userID, tokenHash, duration.Milliseconds()).Err()
+}
+
+// KEYS[1] reset key, ARGV[1] prefix of the user keys, returns the user id or false
+var takePasswordResetScript = redis.NewScript(`
+local userID = redis.call('GET', KEYS[1])
+if not userID then
+	return false
+end
+redis.call('DEL', KEYS[1])
+redis.call('DEL', ARGV[1] .. userID)
+return userID
+`)
+
+func (c *passwordResetRedisCache) Take(ctx context.Context, tokenHash string) (uint64, error) {
+	userID, err := takePasswordResetScript.Run(ctx, c.rdb, []string{getPasswordResetCacheKey(tokenHash)},
+		userPasswordResetCachePrefixKey).Text()
+	if err != nil {
+		return 0, err
+	}
+	return utils.StrToUint64E(userID)
+}
+
+// ---------------------------------------- memory ----------------------------------------
+
+var (
+	passwordResetMemory     *passwordResetMemoryCache
+	passwordResetMemoryOnce sync.Once
+)
+
+func getPasswordResetMemoryCache() *passwordResetMemoryCache {
+	passwordResetMemoryOnce.Do(func() {
+		passwordResetMemory = newPasswordResetMemoryCache()
+	})
+	return passwordResetMemory
+}
+
+type passwordReset struct {
+	userID    uint64
+	expiresAt time.Time
+}
+
+type passwordResetMemoryCache struct {
+	mu        sync.Mutex
+	resets    map[string]*passwordReset
+	userReset map[uint64]string
+	lastPrune time.Time
+}
+
+func newPasswordResetMemoryCache() *passwordResetMemoryCache {
+	return &passwordResetMemoryCache{
+		resets:    make(map[string]*passwordReset),
+		userReset: make(map[uint64]string),
+		lastPrune: time.Now(),
+	}
+}
+
+func (c *passwordResetMemoryCache) Set(_ context.Context, userID uint64, tokenHash string, duration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPrune) > time.Minute {
+		c.prune(now)
+	}
+
+	if old, ok := c.userReset[userID]; ok {
+		delete(c.resets, old)
+	}
+	c.resets[tokenHash] = &passwordReset{userID: userID, expiresAt: now.Add(duration)}
+	c.userReset[userID] = tokenHash
+	return nil
+}
+
+func (c *passwordResetMemoryCache) Take(_ context.Context, tokenHash string) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reset, ok := c.resets[tokenHash]
+	if !ok {
+		return 0, database.ErrCacheNotFound
+	}
+	c.del(tokenHash)
+	if time.Now().After(reset.expiresAt) {
+		return 0, database.ErrCacheNotFound
+	}
+	return reset.userID, nil
+}
+
+// the caller must hold the lock
+func (c *passwordResetMemoryCache) del(tokenHash string) {
+	reset, ok := c.resets[tokenHash]
+	if !ok {
+		return
+	}
+	delete(c.resets, tokenHash)
+	if c.userReset[reset.userID] == tokenHash {
+		delete(c.userReset, reset.userID)
+	}
+}
+
+// remove the expired resets, the caller must hold the lock
+func (c *passwordResetMemoryCache) prune(now time.Time) {
+	for tokenHash, reset := range c.resets {
+		if now.After(reset.expiresAt) {
+			c.del(tokenHash)
+		}
+	}
+	c.lastPrune = now
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"godemo/internal/database"
+)
+
+func testPasswordResetCache(t *testing.T, c PasswordResetCache) {
+	ctx := context.Background()
+
+	err := c.Set(ctx, 1, "h1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := c.Take(ctx, "h1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+
+	// a reset can only be taken once
+	_, err = c.Take(ctx, "h1")
+	assert.ErrorIs(t, err, database.ErrCacheNotFound)
+
+	// a new reset drops the previous one of the user
+	_ = c.Set(ctx, 1, "h2", time.Hour)
+	_ = c.Set(ctx, 1, "h3", time.Hour)
+	_ = c.Set(ctx, 2, "h4", time.Hour)
+	_, err = c.Take(ctx, "h2")
+	assert.ErrorIs(t, err, database.ErrCacheNotFound)
+	userID, err = c.Take(ctx, "h3")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+	userID, err = c.Take(ctx, "h4")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), userID)
+}
+
+func Test_passwordResetRedisCache(t *testing.T) {
+	c := gotest.NewCache(map[string]interface{}{})
+	defer c.Close()
+
+	testPasswordResetCache(t, NewPasswordResetCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	}))
+}
+
+func Test_passwordResetMemoryCache(t *testing.T) {
+	testPasswordResetCache(t, newPasswordResetMemoryCache())
+
+	// expired
+	c := newPasswordResetMemoryCache()
+	ctx := context.Background()
+	_ = c.Set(ctx, 1, "h1", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	_, err := c.Take(ctx, "h1")
+	assert.ErrorIs(t, err, database.ErrCacheNotFound)
+	assert.Empty(t, c.userReset)
+
+	c.lastPrune = time.Now().Add(-time.Hour)
+	c.resets["h2"] = &passwordReset{userID: 2, expiresAt: time.Now().Add(-time.Second)}
+	c.userReset[2] = "h2"
+	_ = c.Set(ctx, 1, "h3", time.Hour)
+	assert.Len(t, c.resets, 1)
+	assert.Len(t, c.userReset, 1)
+}
+
+func TestNewPasswordResetCache(t *testing.T) {
+	c := NewPasswordResetCache(&database.CacheType{CType: "memory"})
+	assert.Equal(t, c, NewPasswordResetCache(&database.CacheType{}))
+}
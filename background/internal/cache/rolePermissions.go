@@ -2,7 +2,6 @@ package cache
 
 import (
 	"context"
-	"errors"
 	"strings"
 	"time"
 
@@ -11,7 +10,6 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"godemo/internal/database"
-	"godemo/internal/model"
 )
 
 const (
@@ -23,15 +21,11 @@ const (
 
 var _ RolePermissionsCache = (*rolePermissionsCache)(nil)
 
-// RolePermissionsCache cache interface
+// RolePermissionsCache cache interface, the value is all the permission ids of a role
 type RolePermissionsCache interface {
-	Set(ctx context.Context, roleID uint64, data *model.RolePermissions, duration time.Duration) error
-	Get(ctx context.Context, roleID uint64) (*model.RolePermissions, error)
-	MultiGet(ctx context.Context, roleIDs []uint64) (map[uint64]*model.RolePermissions, error)
-	MultiSet(ctx context.Context, data []*model.RolePermissions, duration time.Duration) error
+	Set(ctx context.Context, roleID uint64, permissionIDs []uint64, duration time.Duration) error
+	Get(ctx context.Context, roleID uint64) ([]uint64, error)
 	Del(ctx context.Context, roleID uint64) error
-	SetPlaceholder(ctx context.Context, roleID uint64) error
-	IsPlaceholderErr(err error) bool
 }
 
 // rolePermissionsCache define a cache struct
@@ -48,12 +42,12 @@ func NewRolePermissionsCache(cacheType *database.CacheType) RolePermissionsCache
 	switch cType {
 	case "redis":
 		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
-			return &model.RolePermissions{}
+			return &[]uint64{}
 		})
 		return &rolePermissionsCache{cache: c}
 	case "memory":
 		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
-			return &model.RolePermissions{}
+			return &[]uint64{}
 		})
 		return &rolePermissionsCache{cache: c}
 	}
@@ -66,88 +60,34 @@ func (c *rolePermissionsCache) GetRolePermissionsCacheKey(roleID uint64) string
 	return rolePermissionsCachePrefixKey + utils.Uint64ToStr(roleID)
 }
 
-// Set write to cache
-func (c *rolePermissionsCache) Set(ctx context.Context, roleID uint64, data *model.RolePermissions, duration time.Duration) error {
-	if data == nil {
+// Set write to cache, an empty set is also cached
+func (c *rolePermissionsCache) Set(ctx context.Context, roleID uint64, permissionIDs []uint64, duration time.Duration) error {
+	if roleID == 0 {
 		return nil
 	}
-	cacheKey := c.GetRolePermissionsCacheKey(roleID)
-	err := c.cache.Set(ctx, cacheKey, data, duration)
-	if err != nil {
-		return err
+	if permissionIDs == nil {
+		permissionIDs = []uint64{}
 	}
-	return nil
+	cacheKey := c.GetRolePermissionsCacheKey(roleID)
+	return c.cache.Set(ctx, cacheKey, &permissionIDs, duration)
 }
 
 // Get cache value
-func (c *rolePermissionsCache) Get(ctx context.Context, roleID uint64) (*model.RolePermissions, error) {
-	var data *model.RolePermissions
+func (c *rolePermissionsCache) Get(ctx context.Context, roleID uint64) ([]uint64, error) {
+	var permissionIDs []uint64
 	cacheKey := c.GetRolePermissionsCacheKey(roleID)
-	err := c.cache.Get(ctx, cacheKey, &data)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
-}
-
-// MultiSet multiple set cache
-func (c *rolePermissionsCache) MultiSet(ctx context.Context, data []*model.RolePermissions, duration time.Duration) error {
-	valMap := make(map[string]interface{})
-	for _, v := range data {
-		cacheKey := c.GetRolePermissionsCacheKey(v.RoleID)
-		valMap[cacheKey] = v
-	}
-
-	err := c.cache.MultiSet(ctx, valMap, duration)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// MultiGet multiple get cache, return key in map is roleID value
-func (c *rolePermissionsCache) MultiGet(ctx context.Context, roleIDs []uint64) (map[uint64]*model.RolePermissions, error) {
-	var keys []string
-	for _, v := range roleIDs {
-		cacheKey := c.GetRolePermissionsCacheKey(v)
-		keys = append(keys, cacheKey)
-	}
-
-	itemMap := make(map[string]*model.RolePermissions)
-	err := c.cache.MultiGet(ctx, keys, itemMap)
+	err := c.cache.Get(ctx, cacheKey, &permissionIDs)
 	if err != nil {
 		return nil, err
 	}
-
-	retMap := make(map[uint64]*model.RolePermissions)
-	for _, roleID := range roleIDs {
-		val, ok := itemMap[c.GetRolePermissionsCacheKey(roleID)]
-		if ok {
-			retMap[roleID] = val
-		}
+	if permissionIDs == nil {
+		permissionIDs = []uint64{}
 	}
-
-	return retMap, nil
+	return permissionIDs, nil
 }
 
 // Del delete cache
 func (c *rolePermissionsCache) Del(ctx context.Context, roleID uint64) error {
 	cacheKey := c.GetRolePermissionsCacheKey(roleID)
-	err := c.cache.Del(ctx, cacheKey)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// SetPlaceholder set placeholder value to cache
-func (c *rolePermissionsCache) SetPlaceholder(ctx context.Context, roleID uint64) error {
-	cacheKey := c.GetRolePermissionsCacheKey(roleID)
-	return c.cache.SetCacheWithNotFound(ctx, cacheKey)
-}
-
-// IsPlaceholderErr check if cache is placeholder error
-func (c *rolePermissionsCache) IsPlaceholderErr(err error) bool {
-	return errors.Is(err, cache.ErrPlaceholder)
+	return c.cache.Del(ctx, cacheKey)
 }
@@ -0,0 +1,476 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-dev-frame/sponge/pkg/goredis"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/database"
+)
+
+const (
+	// cache prefix key, must end with a colon
+	tokenFamilyCachePrefixKey       = "tokenFamily:"
+	userTokenFamiliesCachePrefixKey = "userTokenFamilies:"
+	// all the families, a sorted set scored by the time they expire
+	tokenFamiliesCacheKey = "tokenFamilies"
+)
+
+// ErrTokenReused the refresh token has already been rotated, the token family is revoked
+var ErrTokenReused = errors.New("refresh token has already been used")
+
+var _ TokenCache = (*tokenRedisCache)(nil)
+var _ TokenCache = (*tokenMemoryCache)(nil)
+
+// TokenFamily is a login session, all the tokens issued from one login belong to it
+type TokenFamily struct {
+	ID          string
+	UserID      uint64
+	UserName    string
+	IP          string
+	UserAgent   string
+	CreatedAt   time.Time // time of the login
+	RefreshedAt time.Time // last time the tokens were refreshed, the time of the login until then
+	ExpiresAt   time.Time // the family ends if it is not refreshed before this time
+}
+
+// TokenCache keeps the server side state of the login sessions. All the tokens issued from one login
+// belong to a token family, the family records the only refresh token that can be used next, so a
+// refresh token is single use. Deleting a family revokes all its tokens immediately.
+//
+// Unlike the other caches the state is not a copy of the database, it must not be evicted and the
+// rotation must be atomic, so it is not built on the sponge cache.
+type TokenCache interface {
+	// CreateFamily start a token family with its first refresh token, the ID, UserID, UserName, IP and
+	// UserAgent of family must be set, the times are set by CreateFamily.
+	CreateFamily(ctx context.Context, family *TokenFamily, tokenID string, duration time.Duration) error
+	// Rotate replace the refresh token of the family, it returns database.ErrCacheNotFound if the family
+	// does not exist, and ErrTokenReused after revoking the family if oldTokenID is not the current token.
+	Rotate(ctx context.Context, familyID string, oldTokenID string, newTokenID string, duration time.Duration) error
+	// ExistsFamily check whether the family is still active
+	ExistsFamily(ctx context.Context, familyID string) (bool, error)
+	// DelFamily revoke the tokens of the family
+	DelFamily(ctx context.Context, familyID string) error
+	// DelUserFamilies revoke the tokens of all the families of the user
+	DelUserFamilies(ctx context.Context, userID uint64) error
+	// GetFamily get an active family, it returns database.ErrCacheNotFound if the family does not exist
+	GetFamily(ctx context.Context, familyID string) (*TokenFamily, error)
+	// ListFamilies list the active families of the user, or of all the users if userID is 0,
+	// the latest login comes first.
+	ListFamilies(ctx context.Context, userID uint64) ([]*TokenFamily, error)
+}
+
+// NewTokenCache new a token cache, the memory cache is used if the cache type is not redis,
+// it is shared by the whole process.
+func NewTokenCache(cacheType *database.CacheType) TokenCache {
+	if strings.ToLower(cacheType.CType) == "redis" {
+		return &tokenRedisCache{rdb: cacheType.Rdb}
+	}
+	return getTokenMemoryCache()
+}
+
+func getTokenFamilyCacheKey(familyID string) string {
+	return tokenFamilyCachePrefixKey + familyID
+}
+
+func getUserTokenFamiliesCacheKey(userID uint64) string {
+	return userTokenFamiliesCachePrefixKey + utils.Uint64ToStr(userID)
+}
+
+// ---------------------------------------- redis ----------------------------------------
+
+type tokenRedisCache struct {
+	rdb *goredis.Client
+}
+
+// a family is a hash of its fields, the families of a user are a set, and all the families are a sorted set
+func (c *tokenRedisCache) CreateFamily(ctx context.Context, family *TokenFamily, tokenID string, duration time.Duration) error {
+	now := time.Now()
+	family.CreatedAt = now
+	family.RefreshedAt = now
+	family.ExpiresAt = now.Add(duration)
+
+	familyKey := getTokenFamilyCacheKey(family.ID)
+	userKey := getUserTokenFamiliesCacheKey(family.UserID)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, familyKey,
+			"userID", family.UserID,
+			"tokenID", tokenID,
+			"userName", family.UserName,
+			"ip", family.IP,
+			"userAgent", family.UserAgent,
+			"createdAt", family.CreatedAt.UnixMilli(),
+			"refreshedAt", family.RefreshedAt.UnixMilli(),
+			"expiresAt", family.ExpiresAt.UnixMilli(),
+		)
+		pipe.PExpire(ctx, familyKey, duration)
+		pipe.SAdd(ctx, userKey, family.ID)
+		pipe.PExpire(ctx, userKey, duration) // no family of the user lives longer than the last one created or refreshed
+		pipe.ZAdd(ctx, tokenFamiliesCacheKey, redis.Z{Score: float64(family.ExpiresAt.UnixMilli()), Member: family.ID})
+		return nil
+	})
+	return err
+}
+
+// KEYS[1] family key, KEYS[2] all the families, KEYS[3] the families of the user, ARGV[1] old token id,
+// ARGV[2] new token id, ARGV[3] duration in milliseconds, ARGV[4] now in milliseconds, ARGV[5] family id.
+// Returns 1 rotated, 0 not found, -1 reused and revoked.
+var rotateTokenScript = redis.NewScript(`
+local tokenID = redis.call('HGET', KEYS[1], 'tokenID')
+if not tokenID then
+	redis.call('ZREM', KEYS[2], ARGV[5])
+	return 0
+end
+if tokenID ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	redis.call('ZREM', KEYS[2], ARGV[5])
+	redis.call('SREM', KEYS[3], ARGV[5])
+	return -1
+end
+local expiresAt = tonumber(ARGV[4]) + tonumber(ARGV[3])
+redis.call('HSET', KEYS[1], 'tokenID', ARGV[2], 'refreshedAt', ARGV[4], 'expiresAt', expiresAt)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('PEXPIRE', KEYS[3], ARGV[3])
+redis.call('ZADD', KEYS[2], expiresAt, ARGV[5])
+return 1
+`)
+
+// the user of the family is read before the script, so that all the keys of the script are declared in KEYS
+// as redis cluster requires, the user of a family never changes.
+func (c *tokenRedisCache) Rotate(ctx context.Context, familyID string, oldTokenID string, newTokenID string, duration time.Duration) error {
+	familyKey := getTokenFamilyCacheKey(familyID)
+	userID, err := c.rdb.HGet(ctx, familyKey, "userID").Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			_ = c.rdb.ZRem(ctx, tokenFamiliesCacheKey, familyID).Err()
+			return database.ErrCacheNotFound
+		}
+		return err
+	}
+
+	keys := []string{familyKey, tokenFamiliesCacheKey, getUserTokenFamiliesCacheKey(userID)}
+	result, err := rotateTokenScript.Run(ctx, c.rdb, keys, oldTokenID, newTokenID, duration.Milliseconds(),
+		time.Now().UnixMilli(), familyID).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		return ErrTokenReused
+	default:
+		return database.ErrCacheNotFound
+	}
+}
+
+func (c *tokenRedisCache) ExistsFamily(ctx context.Context, familyID string) (bool, error) {
+	n, err := c.rdb.Exists(ctx, getTokenFamilyCacheKey(familyID)).Result()
+	return n > 0, err
+}
+
+func (c *tokenRedisCache) DelFamily(ctx context.Context, familyID string) error {
+	familyKey := getTokenFamilyCacheKey(familyID)
+	userID, err := c.rdb.HGet(ctx, familyKey, "userID").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, familyKey)
+		pipe.ZRem(ctx, tokenFamiliesCacheKey, familyID)
+		if userID != "" {
+			pipe.SRem(ctx, userTokenFamiliesCachePrefixKey+userID, familyID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *tokenRedisCache) DelUserFamilies(ctx context.Context, userID uint64) error {
+	userKey := getUserTokenFamiliesCacheKey(userID)
+	familyIDs, err := c.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userKey}
+	members := make([]interface{}, 0, len(familyIDs))
+	for _, familyID := range familyIDs {
+		keys = append(keys, getTokenFamilyCacheKey(familyID))
+		members = append(members, familyID)
+	}
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		if len(members) > 0 {
+			pipe.ZRem(ctx, tokenFamiliesCacheKey, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *tokenRedisCache) GetFamily(ctx context.Context, familyID string) (*TokenFamily, error) {
+	fields, err := c.rdb.HGetAll(ctx, getTokenFamilyCacheKey(familyID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, database.ErrCacheNotFound
+	}
+	return parseTokenFamily(familyID, fields), nil
+}
+
+func (c *tokenRedisCache) ListFamilies(ctx context.Context, userID uint64) ([]*TokenFamily, error) {
+	var familyIDs []string
+	var err error
+	if userID > 0 {
+		familyIDs, err = c.rdb.SMembers(ctx, getUserTokenFamiliesCacheKey(userID)).Result()
+	} else {
+		// the expired families are removed from the sorted set first
+		now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		if err = c.rdb.ZRemRangeByScore(ctx, tokenFamiliesCacheKey, "-inf", now).Err(); err != nil {
+			return nil, err
+		}
+		familyIDs, err = c.rdb.ZRange(ctx, tokenFamiliesCacheKey, 0, -1).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(familyIDs))
+	_, err = c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, familyID := range familyIDs {
+			cmds[i] = pipe.HGetAll(ctx, getTokenFamilyCacheKey(familyID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	families := make([]*TokenFamily, 0, len(familyIDs))
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			continue // revoked or expired
+		}
+		families = append(families, parseTokenFamily(familyIDs[i], cmd.Val()))
+	}
+	sortTokenFamilies(families)
+	return families, nil
+}
+
+func parseTokenFamily(familyID string, fields map[string]string) *TokenFamily {
+	userID, _ := utils.StrToUint64E(fields["userID"])
+	return &TokenFamily{
+		ID:          familyID,
+		UserID:      userID,
+		UserName:    fields["userName"],
+		IP:          fields["ip"],
+		UserAgent:   fields["userAgent"],
+		CreatedAt:   parseUnixMilli(fields["createdAt"]),
+		RefreshedAt: parseUnixMilli(fields["refreshedAt"]),
+		ExpiresAt:   parseUnixMilli(fields["expiresAt"]),
+	}
+}
+
+func parseUnixMilli(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// the latest login comes first
+func sortTokenFamilies(families []*TokenFamily) {
+	sort.Slice(families, func(i, j int) bool {
+		if families[i].CreatedAt.Equal(families[j].CreatedAt) {
+			return families[i].ID < families[j].ID
+		}
+		return families[i].CreatedAt.After(families[j].CreatedAt)
+	})
+}
+
+// ---------------------------------------- memory ----------------------------------------
+
+var (
+	tokenMemory     *tokenMemoryCache
+	tokenMemoryOnce sync.Once
+)
+
+func getTokenMemoryCache() *tokenMemoryCache {
+	tokenMemoryOnce.Do(func() {
+		tokenMemory = newTokenMemoryCache()
+	})
+	return tokenMemory
+}
+
+type tokenFamily struct {
+	TokenFamily
+	tokenID string
+}
+
+type tokenMemoryCache struct {
+	mu         sync.Mutex
+	families   map[string]*tokenFamily
+	userFamily map[uint64]map[string]struct{}
+	lastPrune  time.Time
+}
+
+func newTokenMemoryCache() *tokenMemoryCache {
+	return &tokenMemoryCache{
+		families:   make(map[string]*tokenFamily),
+		userFamily: make(map[uint64]map[string]struct{}),
+		lastPrune:  time.Now(),
+	}
+}
+
+func (c *tokenMemoryCache) CreateFamily(_ context.Context, family *TokenFamily, tokenID string, duration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPrune) > time.Minute {
+		c.prune(now)
+	}
+
+	family.CreatedAt = now
+	family.RefreshedAt = now
+	family.ExpiresAt = now.Add(duration)
+	c.families[family.ID] = &tokenFamily{TokenFamily: *family, tokenID: tokenID}
+	if c.userFamily[family.UserID] == nil {
+		c.userFamily[family.UserID] = make(map[string]struct{})
+	}
+	c.userFamily[family.UserID][family.ID] = struct{}{}
+	return nil
+}
+
+func (c *tokenMemoryCache) Rotate(_ context.Context, familyID string, oldTokenID string, newTokenID string, duration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	family, ok := c.get(familyID)
+	if !ok {
+		return database.ErrCacheNotFound
+	}
+	if family.tokenID != oldTokenID {
+		c.del(familyID)
+		return ErrTokenReused
+	}
+	now := time.Now()
+	family.tokenID = newTokenID
+	family.RefreshedAt = now
+	family.ExpiresAt = now.Add(duration)
+	return nil
+}
+
+func (c *tokenMemoryCache) ExistsFamily(_ context.Context, familyID string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.get(familyID)
+	return ok, nil
+}
+
+func (c *tokenMemoryCache) DelFamily(_ context.Context, familyID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.del(familyID)
+	return nil
+}
+
+func (c *tokenMemoryCache) DelUserFamilies(_ context.Context, userID uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for familyID := range c.userFamily[userID] {
+		delete(c.families, familyID)
+	}
+	delete(c.userFamily, userID)
+	return nil
+}
+
+func (c *tokenMemoryCache) GetFamily(_ context.Context, familyID string) (*TokenFamily, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	family, ok := c.get(familyID)
+	if !ok {
+		return nil, database.ErrCacheNotFound
+	}
+	result := family.TokenFamily
+	return &result, nil
+}
+
+func (c *tokenMemoryCache) ListFamilies(_ context.Context, userID uint64) ([]*TokenFamily, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var familyIDs []string
+	if userID > 0 {
+		for familyID := range c.userFamily[userID] {
+			familyIDs = append(familyIDs, familyID)
+		}
+	} else {
+		for familyID := range c.families {
+			familyIDs = append(familyIDs, familyID)
+		}
+	}
+
+	families := make([]*TokenFamily, 0, len(familyIDs))
+	for _, familyID := range familyIDs {
+		if family, ok := c.get(familyID); ok {
+			result := family.TokenFamily
+			families = append(families, &result)
+		}
+	}
+	sortTokenFamilies(families)
+	return families, nil
+}
+
+// get an unexpired family, the caller must hold the lock
+func (c *tokenMemoryCache) get(familyID string) (*tokenFamily, bool) {
+	family, ok := c.families[familyID]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(family.ExpiresAt) {
+		c.del(familyID)
+		return nil, false
+	}
+	return family, true
+}
+
+// the caller must hold the lock
+func (c *tokenMemoryCache) del(familyID string) {
+	family, ok := c.families[familyID]
+	if !ok {
+		return
+	}
+	delete(c.families, familyID)
+	if ids := c.userFamily[family.UserID]; ids != nil {
+		delete(ids, familyID)
+		if len(ids) == 0 {
+			delete(c.userFamily, family.UserID)
+		}
+	}
+}
+
+// remove the expired families, the caller must hold the lock
+func (c *tokenMemoryCache) prune(now time.Time) {
+	for familyID, family := range c.families {
+		if now.After(family.ExpiresAt) {
+			c.del(familyID)
+		}
+	}
+	c.lastPrune = now
+}
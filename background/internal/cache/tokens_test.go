@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"godemo/internal/database"
+)
+
+func testTokenCache(t *testing.T, c TokenCache) {
+	ctx := context.Background()
+
+	err := c.CreateFamily(ctx, &TokenFamily{ID: "f1", UserID: 1}, "t1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := c.ExistsFamily(ctx, "f1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// rotate
+	err = c.Rotate(ctx, "f1", "t1", "t2", time.Hour)
+	assert.NoError(t, err)
+	err = c.Rotate(ctx, "f1", "t2", "t3", time.Hour)
+	assert.NoError(t, err)
+
+	// reusing a rotated token revokes the family
+	err = c.Rotate(ctx, "f1", "t2", "t4", time.Hour)
+	assert.ErrorIs(t, err, ErrTokenReused)
+	ok, err = c.ExistsFamily(ctx, "f1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	err = c.Rotate(ctx, "f1", "t3", "t4", time.Hour)
+	assert.ErrorIs(t, err, database.ErrCacheNotFound)
+
+	// delete a family
+	err = c.CreateFamily(ctx, &TokenFamily{ID: "f2", UserID: 1}, "t1", time.Hour)
+	assert.NoError(t, err)
+	err = c.DelFamily(ctx, "f2")
+	assert.NoError(t, err)
+	ok, _ = c.ExistsFamily(ctx, "f2")
+	assert.False(t, ok)
+
+	// delete all the families of a user
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f3", UserID: 1}, "t1", time.Hour)
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f4", UserID: 1}, "t1", time.Hour)
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f5", UserID: 2}, "t1", time.Hour)
+	err = c.DelUserFamilies(ctx, 1)
+	assert.NoError(t, err)
+	ok, _ = c.ExistsFamily(ctx, "f3")
+	assert.False(t, ok)
+	ok, _ = c.ExistsFamily(ctx, "f4")
+	assert.False(t, ok)
+	ok, _ = c.ExistsFamily(ctx, "f5")
+	assert.True(t, ok)
+	err = c.DelUserFamilies(ctx, 3)
+	assert.NoError(t, err)
+
+	// the sessions, the times are stored in milliseconds
+	time.Sleep(time.Millisecond * 2)
+	family := &TokenFamily{ID: "f6", UserID: 2, UserName: "tom", IP: "127.0.0.1", UserAgent: "curl/8.0"}
+	err = c.CreateFamily(ctx, family, "t1", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, family.CreatedAt.IsZero())
+	got, err := c.GetFamily(ctx, "f6")
+	assert.NoError(t, err)
+	assert.Equal(t, "tom", got.UserName)
+	assert.Equal(t, "127.0.0.1", got.IP)
+	assert.Equal(t, "curl/8.0", got.UserAgent)
+	assert.Equal(t, family.CreatedAt.UnixMilli(), got.CreatedAt.UnixMilli())
+	time.Sleep(time.Millisecond * 2)
+	err = c.Rotate(ctx, "f6", "t1", "t2", time.Hour)
+	assert.NoError(t, err)
+	got, _ = c.GetFamily(ctx, "f6")
+	assert.True(t, got.RefreshedAt.After(got.CreatedAt))
+	assert.True(t, got.ExpiresAt.After(family.ExpiresAt))
+	_, err = c.GetFamily(ctx, "f1")
+	assert.ErrorIs(t, err, database.ErrCacheNotFound)
+
+	families, err := c.ListFamilies(ctx, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, families, 2) {
+		assert.Equal(t, "f6", families[0].ID) // the latest login comes first
+		assert.Equal(t, "f5", families[1].ID)
+	}
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f7", UserID: 3}, "t1", time.Hour)
+	families, err = c.ListFamilies(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, families, 3)
+	_ = c.DelFamily(ctx, "f5")
+	_ = c.DelUserFamilies(ctx, 3)
+	families, err = c.ListFamilies(ctx, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, families, 1) {
+		assert.Equal(t, "f6", families[0].ID)
+	}
+	families, err = c.ListFamilies(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, families)
+}
+
+func Test_tokenRedisCache(t *testing.T) {
+	c := gotest.NewCache(map[string]interface{}{})
+	defer c.Close()
+
+	tokenCache := NewTokenCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	})
+	testTokenCache(t, tokenCache)
+
+	// a reused family is removed from the families of its user
+	ctx := context.Background()
+	err := tokenCache.CreateFamily(ctx, &TokenFamily{ID: "r1", UserID: 3}, "t1", time.Hour)
+	assert.NoError(t, err)
+	err = tokenCache.Rotate(ctx, "r1", "t0", "t2", time.Hour)
+	assert.ErrorIs(t, err, ErrTokenReused)
+	ok, err := c.RedisClient.SIsMember(ctx, getUserTokenFamiliesCacheKey(3), "r1").Result()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func Test_tokenMemoryCache(t *testing.T) {
+	testTokenCache(t, newTokenMemoryCache())
+
+	// expired
+	c := newTokenMemoryCache()
+	ctx := context.Background()
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f1", UserID: 1}, "t1", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	ok, _ := c.ExistsFamily(ctx, "f1")
+	assert.False(t, ok)
+	assert.Empty(t, c.userFamily)
+
+	c.lastPrune = time.Now().Add(-time.Hour)
+	c.families["f2"] = &tokenFamily{TokenFamily: TokenFamily{ID: "f2", UserID: 2, ExpiresAt: time.Now().Add(-time.Second)}, tokenID: "t1"}
+	c.userFamily[2] = map[string]struct{}{"f2": {}}
+	_ = c.CreateFamily(ctx, &TokenFamily{ID: "f3", UserID: 1}, "t1", time.Hour)
+	assert.Len(t, c.families, 1)
+}
+
+func TestNewTokenCache(t *testing.T) {
+	c := NewTokenCache(&database.CacheType{CType: "memory"})
+	assert.Equal(t, c, NewTokenCache(&database.CacheType{}))
+}
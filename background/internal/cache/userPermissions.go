@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/cache"
+	"github.com/go-dev-frame/sponge/pkg/encoding"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/database"
+)
+
+const (
+	// cache prefix key, must end with a colon
+	userPermissionsCachePrefixKey = "userPermissions:"
+	// UserPermissionsExpireTime expire time
+	UserPermissionsExpireTime = 5 * time.Minute
+)
+
+var _ UserPermissionsCache = (*userPermissionsCache)(nil)
+
+// UserPermissionsCache cache interface, the value is the permission codes of a user
+type UserPermissionsCache interface {
+	Set(ctx context.Context, userID uint64, codes []string, duration time.Duration) error
+	Get(ctx context.Context, userID uint64) ([]string, error)
+	MultiDel(ctx context.Context, userIDs []uint64) error
+	Del(ctx context.Context, userID uint64) error
+}
+
+// userPermissionsCache define a cache struct
+type userPermissionsCache struct {
+	cache cache.Cache
+}
+
+// NewUserPermissionsCache new a cache
+func NewUserPermissionsCache(cacheType *database.CacheType) UserPermissionsCache {
+	jsonEncoding := encoding.JSONEncoding{}
+	cachePrefix := ""
+
+	cType := strings.ToLower(cacheType.CType)
+	switch cType {
+	case "redis":
+		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
+			return &[]string{}
+		})
+		return &userPermissionsCache{cache: c}
+	case "memory":
+		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
+			return &[]string{}
+		})
+		return &userPermissionsCache{cache: c}
+	}
+
+	return nil // no cache
+}
+
+// GetUserPermissionsCacheKey cache key
+func (c *userPermissionsCache) GetUserPermissionsCacheKey(userID uint64) string {
+	return userPermissionsCachePrefixKey + utils.Uint64ToStr(userID)
+}
+
+// Set write to cache, an empty codes is also cached, so that users without permissions do not hit the database
+func (c *userPermissionsCache) Set(ctx context.Context, userID uint64, codes []string, duration time.Duration) error {
+	if userID == 0 {
+		return nil
+	}
+	if codes == nil {
+		codes = []string{}
+	}
+	cacheKey := c.GetUserPermissionsCacheKey(userID)
+	return c.cache.Set(ctx, cacheKey, &codes, duration)
+}
+
+// Get cache value
+func (c *userPermissionsCache) Get(ctx context.Context, userID uint64) ([]string, error) {
+	var codes []string
+	cacheKey := c.GetUserPermissionsCacheKey(userID)
+	err := c.cache.Get(ctx, cacheKey, &codes)
+	if err != nil {
+		return nil, err
+	}
+	if codes == nil {
+		codes = []string{}
+	}
+	return codes, nil
+}
+
+// MultiDel multiple delete cache
+func (c *userPermissionsCache) MultiDel(ctx context.Context, userIDs []uint64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, c.GetUserPermissionsCacheKey(id))
+	}
+	return c.cache.Del(ctx, keys...)
+}
+
+// Del delete cache
+func (c *userPermissionsCache) Del(ctx context.Context, userID uint64) error {
+	cacheKey := c.GetUserPermissionsCacheKey(userID)
+	return c.cache.Del(ctx, cacheKey)
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"godemo/internal/database"
+)
+
+func newUserPermissionsCache() *gotest.Cache {
+	testData := map[string]interface{}{
+		"1": &[]string{"user:manage", "role:manage"},
+	}
+
+	c := gotest.NewCache(testData)
+	c.ICache = NewUserPermissionsCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	})
+	return c
+}
+
+func Test_userPermissionsCache_Set(t *testing.T) {
+	c := newUserPermissionsCache()
+	defer c.Close()
+
+	err := c.ICache.(UserPermissionsCache).Set(c.Ctx, 1, []string{"user:manage"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// zero user id
+	err = c.ICache.(UserPermissionsCache).Set(c.Ctx, 0, nil, time.Hour)
+	assert.NoError(t, err)
+}
+
+func Test_userPermissionsCache_Get(t *testing.T) {
+	c := newUserPermissionsCache()
+	defer c.Close()
+
+	codes := []string{"user:manage", "role:manage"}
+	err := c.ICache.(UserPermissionsCache).Set(c.Ctx, 1, codes, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ICache.(UserPermissionsCache).Get(c.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, codes, got)
+
+	// an empty set is cached too
+	err = c.ICache.(UserPermissionsCache).Set(c.Ctx, 2, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = c.ICache.(UserPermissionsCache).Get(c.Ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{}, got)
+
+	// not found
+	_, err = c.ICache.(UserPermissionsCache).Get(c.Ctx, 3)
+	assert.Error(t, err)
+}
+
+func Test_userPermissionsCache_Del(t *testing.T) {
+	c := newUserPermissionsCache()
+	defer c.Close()
+
+	err := c.ICache.(UserPermissionsCache).Set(c.Ctx, 1, []string{"user:manage"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.ICache.(UserPermissionsCache).Del(c.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ICache.(UserPermissionsCache).Get(c.Ctx, 1)
+	assert.Error(t, err)
+}
+
+func Test_userPermissionsCache_MultiDel(t *testing.T) {
+	c := newUserPermissionsCache()
+	defer c.Close()
+
+	for _, id := range []uint64{1, 2} {
+		err := c.ICache.(UserPermissionsCache).Set(c.Ctx, id, []string{"user:manage"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := c.ICache.(UserPermissionsCache).MultiDel(c.Ctx, []uint64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ICache.(UserPermissionsCache).Get(c.Ctx, 2)
+	assert.Error(t, err)
+
+	// no user
+	err = c.ICache.(UserPermissionsCache).MultiDel(c.Ctx, nil)
+	assert.NoError(t, err)
+}
+
+func TestNewUserPermissionsCache(t *testing.T) {
+	c := NewUserPermissionsCache(&database.CacheType{
+		CType: "",
+	})
+	assert.Nil(t, c)
+	c = NewUserPermissionsCache(&database.CacheType{
+		CType: "memory",
+	})
+	assert.NotNil(t, c)
+	c = NewUserPermissionsCache(&database.CacheType{
+		CType: "redis",
+	})
+	assert.NotNil(t, c)
+}
@@ -2,7 +2,6 @@ package cache
 
 import (
 	"context"
-	"errors"
 	"strings"
 	"time"
 
@@ -11,7 +10,6 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"godemo/internal/database"
-	"godemo/internal/model"
 )
 
 const (
@@ -23,15 +21,11 @@ const (
 
 var _ UserRolesCache = (*userRolesCache)(nil)
 
-// UserRolesCache cache interface
+// UserRolesCache cache interface, the value is all the role ids of a user
 type UserRolesCache interface {
-	Set(ctx context.Context, userID uint64, data *model.UserRoles, duration time.Duration) error
-	Get(ctx context.Context, userID uint64) (*model.UserRoles, error)
-	MultiGet(ctx context.Context, userIDs []uint64) (map[uint64]*model.UserRoles, error)
-	MultiSet(ctx context.Context, data []*model.UserRoles, duration time.Duration) error
+	Set(ctx context.Context, userID uint64, roleIDs []uint64, duration time.Duration) error
+	Get(ctx context.Context, userID uint64) ([]uint64, error)
 	Del(ctx context.Context, userID uint64) error
-	SetPlaceholder(ctx context.Context, userID uint64) error
-	IsPlaceholderErr(err error) bool
 }
 
 // userRolesCache define a cache struct
@@ -48,12 +42,12 @@ func NewUserRolesCache(cacheType *database.CacheType) UserRolesCache {
 	switch cType {
 	case "redis":
 		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
-			return &model.UserRoles{}
+			return &[]uint64{}
 		})
 		return &userRolesCache{cache: c}
 	case "memory":
 		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
-			return &model.UserRoles{}
+			return &[]uint64{}
 		})
 		return &userRolesCache{cache: c}
 	}
@@ -66,88 +60,34 @@ func (c *userRolesCache) GetUserRolesCacheKey(userID uint64) string {
 	return userRolesCachePrefixKey + utils.Uint64ToStr(userID)
 }
 
-// Set write to cache
-func (c *userRolesCache) Set(ctx context.Context, userID uint64, data *model.UserRoles, duration time.Duration) error {
-	if data == nil {
+// Set write to cache, an empty set is also cached
+func (c *userRolesCache) Set(ctx context.Context, userID uint64, roleIDs []uint64, duration time.Duration) error {
+	if userID == 0 {
 		return nil
 	}
-	cacheKey := c.GetUserRolesCacheKey(userID)
-	err := c.cache.Set(ctx, cacheKey, data, duration)
-	if err != nil {
-		return err
+	if roleIDs == nil {
+		roleIDs = []uint64{}
 	}
-	return nil
+	cacheKey := c.GetUserRolesCacheKey(userID)
+	return c.cache.Set(ctx, cacheKey, &roleIDs, duration)
 }
 
 // Get cache value
-func (c *userRolesCache) Get(ctx context.Context, userID uint64) (*model.UserRoles, error) {
-	var data *model.UserRoles
+func (c *userRolesCache) Get(ctx context.Context, userID uint64) ([]uint64, error) {
+	var roleIDs []uint64
 	cacheKey := c.GetUserRolesCacheKey(userID)
-	err := c.cache.Get(ctx, cacheKey, &data)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
-}
-
-// MultiSet multiple set cache
-func (c *userRolesCache) MultiSet(ctx context.Context, data []*model.UserRoles, duration time.Duration) error {
-	valMap := make(map[string]interface{})
-	for _, v := range data {
-		cacheKey := c.GetUserRolesCacheKey(v.UserID)
-		valMap[cacheKey] = v
-	}
-
-	err := c.cache.MultiSet(ctx, valMap, duration)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// MultiGet multiple get cache, return key in map is userID value
-func (c *userRolesCache) MultiGet(ctx context.Context, userIDs []uint64) (map[uint64]*model.UserRoles, error) {
-	var keys []string
-	for _, v := range userIDs {
-		cacheKey := c.GetUserRolesCacheKey(v)
-		keys = append(keys, cacheKey)
-	}
-
-	itemMap := make(map[string]*model.UserRoles)
-	err := c.cache.MultiGet(ctx, keys, itemMap)
+	err := c.cache.Get(ctx, cacheKey, &roleIDs)
 	if err != nil {
 		return nil, err
 	}
-
-	retMap := make(map[uint64]*model.UserRoles)
-	for _, userID := range userIDs {
-		val, ok := itemMap[c.GetUserRolesCacheKey(userID)]
-		if ok {
-			retMap[userID] = val
-		}
+	if roleIDs == nil {
+		roleIDs = []uint64{}
 	}
-
-	return retMap, nil
+	return roleIDs, nil
 }
 
 // Del delete cache
 func (c *userRolesCache) Del(ctx context.Context, userID uint64) error {
 	cacheKey := c.GetUserRolesCacheKey(userID)
-	err := c.cache.Del(ctx, cacheKey)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// SetPlaceholder set placeholder value to cache
-func (c *userRolesCache) SetPlaceholder(ctx context.Context, userID uint64) error {
-	cacheKey := c.GetUserRolesCacheKey(userID)
-	return c.cache.SetCacheWithNotFound(ctx, cacheKey)
-}
-
-// IsPlaceholderErr check if cache is placeholder error
-func (c *userRolesCache) IsPlaceholderErr(err error) bool {
-	return errors.Is(err, cache.ErrPlaceholder)
+	return c.cache.Del(ctx, cacheKey)
 }
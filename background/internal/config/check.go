@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// the sign key of configs/godemo.yml, it is public and must be replaced
+const placeholderSignKey = "change-me-to-a-long-random-string"
+
+// the shortest jwt sign key in bytes, a shorter HMAC key can be brute forced
+const minSignKeyLength = 32
+
+// Check the settings that have no safe default, the service must not start if it fails.
+// The default key of the jwt package is public, so jwt.signKey is required.
+func (c *Config) Check() error {
+	key := c.JWT.SignKey
+	switch {
+	case key == "":
+		return errors.New("jwt.signKey is empty")
+	case key == placeholderSignKey:
+		return errors.New("jwt.signKey is the placeholder of the sample config, replace it with a random string")
+	case len(key) < minSignKeyLength:
+		return fmt.Errorf("jwt.signKey must have at least %d bytes", minSignKeyLength)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Check(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"placeholder": placeholderSignKey,
+		"too short":   strings.Repeat("k", minSignKeyLength-1),
+	}
+	for name, key := range tests {
+		c := &Config{JWT: JWT{SignKey: key}}
+		assert.Error(t, c.Check(), name)
+	}
+
+	c := &Config{JWT: JWT{SignKey: strings.Repeat("k", minSignKeyLength)}}
+	assert.NoError(t, c.Check())
+}
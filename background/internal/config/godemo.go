@@ -29,17 +29,24 @@ func Set(conf *Config) {
 }
 
 type Config struct {
-	App        App          `yaml:"app" json:"app"`
-	Consul     Consul       `yaml:"consul" json:"consul"`
-	Database   Database     `yaml:"database" json:"database"`
-	Etcd       Etcd         `yaml:"etcd" json:"etcd"`
-	Grpc       Grpc         `yaml:"grpc" json:"grpc"`
-	GrpcClient []GrpcClient `yaml:"grpcClient" json:"grpcClient"`
-	HTTP       HTTP         `yaml:"http" json:"http"`
-	Jaeger     Jaeger       `yaml:"jaeger" json:"jaeger"`
-	Logger     Logger       `yaml:"logger" json:"logger"`
-	NacosRd    NacosRd      `yaml:"nacosRd" json:"nacosRd"`
-	Redis      Redis        `yaml:"redis" json:"redis"`
+	App           App           `yaml:"app" json:"app"`
+	Consul        Consul        `yaml:"consul" json:"consul"`
+	Database      Database      `yaml:"database" json:"database"`
+	Etcd          Etcd          `yaml:"etcd" json:"etcd"`
+	Grpc          Grpc          `yaml:"grpc" json:"grpc"`
+	GrpcClient    []GrpcClient  `yaml:"grpcClient" json:"grpcClient"`
+	HTTP          HTTP          `yaml:"http" json:"http"`
+	Jaeger        Jaeger        `yaml:"jaeger" json:"jaeger"`
+	JWT           JWT           `yaml:"jwt" json:"jwt"`
+	Logger        Logger        `yaml:"logger" json:"logger"`
+	Login         Login         `yaml:"login" json:"login"`
+	Mail          Mail          `yaml:"mail" json:"mail"`
+	NacosRd       NacosRd       `yaml:"nacosRd" json:"nacosRd"`
+	PasswordReset PasswordReset `yaml:"passwordReset" json:"passwordReset"`
+	Redis         Redis         `yaml:"redis" json:"redis"`
+	Route         Route         `yaml:"route" json:"route"`
+	Storage       Storage       `yaml:"storage" json:"storage"`
+	TwoFactor     TwoFactor     `yaml:"twoFactor" json:"twoFactor"`
 }
 
 type Consul struct {
@@ -55,6 +62,69 @@ type Jaeger struct {
 	AgentPort int    `yaml:"agentPort" json:"agentPort"`
 }
 
+type JWT struct {
+	AccessTokenExpire  int      `yaml:"accessTokenExpire" json:"accessTokenExpire"`
+	AnonymousRoutes    []string `yaml:"anonymousRoutes" json:"anonymousRoutes"`
+	RefreshTokenExpire int      `yaml:"refreshTokenExpire" json:"refreshTokenExpire"`
+	SignKey            string   `yaml:"signKey" json:"signKey"`
+}
+
+type Login struct {
+	DelayAfter    int `yaml:"delayAfter" json:"delayAfter"`
+	FailureWindow int `yaml:"failureWindow" json:"failureWindow"`
+	IPLockAfter   int `yaml:"ipLockAfter" json:"ipLockAfter"`
+	LockAfter     int `yaml:"lockAfter" json:"lockAfter"`
+	LockDuration  int `yaml:"lockDuration" json:"lockDuration"`
+	MaxDelay      int `yaml:"maxDelay" json:"maxDelay"`
+}
+
+type Mail struct {
+	Driver string   `yaml:"driver" json:"driver"`
+	File   FileMail `yaml:"file" json:"file"`
+	From   string   `yaml:"from" json:"from"`
+	SMTP   SMTPMail `yaml:"smtp" json:"smtp"`
+}
+
+type FileMail struct {
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+type SMTPMail struct {
+	Host     string `yaml:"host" json:"host"`
+	Password string `yaml:"password" json:"password"`
+	Port     int    `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+}
+
+type PasswordReset struct {
+	EmailLimit  int    `yaml:"emailLimit" json:"emailLimit"`
+	Expire      int    `yaml:"expire" json:"expire"`
+	IPLimit     int    `yaml:"ipLimit" json:"ipLimit"`
+	LimitWindow int    `yaml:"limitWindow" json:"limitWindow"`
+	URL         string `yaml:"url" json:"url"`
+}
+
+type Route struct {
+	Home string `yaml:"home" json:"home"`
+}
+
+type Storage struct {
+	Driver        string       `yaml:"driver" json:"driver"`
+	Local         LocalStorage `yaml:"local" json:"local"`
+	MaxUploadSize int          `yaml:"maxUploadSize" json:"maxUploadSize"`
+	RedirectHosts []string     `yaml:"redirectHosts" json:"redirectHosts"`
+}
+
+type LocalStorage struct {
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+type TwoFactor struct {
+	ChallengeExpire int    `yaml:"challengeExpire" json:"challengeExpire"`
+	EncryptKey      string `yaml:"encryptKey" json:"encryptKey"`
+	Issuer          string `yaml:"issuer" json:"issuer"`
+}
+
 type ClientToken struct {
 	AppID  string `yaml:"appID" json:"appID"`
 	AppKey string `yaml:"appKey" json:"appKey"`
@@ -136,11 +206,12 @@ type Redis struct {
 }
 
 type Database struct {
-	Driver     string     `yaml:"driver" json:"driver"`
-	Mongodb    Mongodb    `yaml:"mongodb" json:"mongodb"`
-	Mysql      Mysql      `yaml:"mysql" json:"mysql"`
-	Postgresql Postgresql `yaml:"postgresql" json:"postgresql"`
-	Sqlite     Sqlite     `yaml:"sqlite" json:"sqlite"`
+	Driver             string     `yaml:"driver" json:"driver"`
+	Mongodb            Mongodb    `yaml:"mongodb" json:"mongodb"`
+	Mysql              Mysql      `yaml:"mysql" json:"mysql"`
+	Postgresql         Postgresql `yaml:"postgresql" json:"postgresql"`
+	SkipMigrationCheck bool       `yaml:"skipMigrationCheck" json:"skipMigrationCheck"`
+	Sqlite             Sqlite     `yaml:"sqlite" json:"sqlite"`
 }
 
 type Mongodb struct {
@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"godemo/internal/database"
+	"godemo/internal/model"
+)
+
+var _ APIKeysDao = (*apiKeysDao)(nil)
+
+// APIKeysDao defining the dao interface, the keys are read by hash on every request that carries one,
+// the key_hash column is unique so no cache is used.
+type APIKeysDao interface {
+	Create(ctx context.Context, table *model.APIKeys) error
+	DeleteByUserIDAndID(ctx context.Context, userID uint64, id uint64) error
+	DeleteByUserID(ctx context.Context, userID uint64) error
+	GetByKeyHash(ctx context.Context, keyHash string) (*model.APIKeys, error)
+	GetByUserID(ctx context.Context, userID uint64) ([]*model.APIKeys, error)
+	UpdateLastUsedAt(ctx context.Context, id uint64, lastUsedAt time.Time) error
+}
+
+type apiKeysDao struct {
+	db *gorm.DB
+}
+
+// NewAPIKeysDao creating the dao interface
+func NewAPIKeysDao(db *gorm.DB) APIKeysDao {
+	return &apiKeysDao{db: db}
+}
+
+// Create a new api key, insert the record and the id value is written back to the table
+func (d *apiKeysDao) Create(ctx context.Context, table *model.APIKeys) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// DeleteByUserIDAndID revoke an api key of the user, the key is deleted immediately.
+// It returns database.ErrRecordNotFound if the user has no such key.
+func (d *apiKeysDao) DeleteByUserIDAndID(ctx context.Context, userID uint64, id uint64) error {
+	if userID < 1 || id < 1 {
+		return errors.New("userID and id cannot be 0")
+	}
+	result := d.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.APIKeys{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return database.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID delete all the api keys of the user
+func (d *apiKeysDao) DeleteByUserID(ctx context.Context, userID uint64) error {
+	return d.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.APIKeys{}).Error
+}
+
+// GetByKeyHash get an api key by the hash of the key
+func (d *apiKeysDao) GetByKeyHash(ctx context.Context, keyHash string) (*model.APIKeys, error) {
+	record := &model.APIKeys{}
+	err := d.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetByUserID get all the api keys of the user, the newest first
+func (d *apiKeysDao) GetByUserID(ctx context.Context, userID uint64) ([]*model.APIKeys, error) {
+	records := []*model.APIKeys{}
+	err := d.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&records).Error
+	return records, err
+}
+
+// UpdateLastUsedAt record the time the api key was last used
+func (d *apiKeysDao) UpdateLastUsedAt(ctx context.Context, id uint64, lastUsedAt time.Time) error {
+	return d.db.WithContext(ctx).Model(&model.APIKeys{}).Where("id = ?", id).
+		UpdateColumn("last_used_at", lastUsedAt).Error
+}
@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/database"
+	"godemo/internal/model"
+)
+
+func newAPIKeysDao() *gotest.Dao {
+	d := gotest.NewDao(nil, nil)
+	d.IDao = NewAPIKeysDao(d.DB)
+	return d
+}
+
+func Test_apiKeysDao_Create(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+
+	record := &model.APIKeys{UserID: 1, Name: "ci", KeyPrefix: "gdk_abcd", KeyHash: "hash", Permissions: "user:manage"}
+	err := d.IDao.(APIKeysDao).Create(d.Ctx, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(1), record.ID)
+}
+
+func Test_apiKeysDao_DeleteByUserIDAndID(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	d.SQLMock.ExpectCommit()
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(APIKeysDao).DeleteByUserIDAndID(d.Ctx, 1, 2)
+	assert.NoError(t, err)
+
+	// the user has no such key
+	err = d.IDao.(APIKeysDao).DeleteByUserIDAndID(d.Ctx, 1, 3)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+
+	// zero id error test
+	err = d.IDao.(APIKeysDao).DeleteByUserIDAndID(d.Ctx, 1, 0)
+	assert.Error(t, err)
+}
+
+func Test_apiKeysDao_DeleteByUserID(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(APIKeysDao).DeleteByUserID(d.Ctx, 1)
+	assert.NoError(t, err)
+}
+
+func Test_apiKeysDao_GetByKeyHash(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "key_hash"}).
+		AddRow(1, 2, "hash")
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs("hash", 1).
+		WillReturnRows(rows)
+
+	record, err := d.IDao.(APIKeysDao).GetByKeyHash(d.Ctx, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(2), record.UserID)
+
+	// not found test
+	_, err = d.IDao.(APIKeysDao).GetByKeyHash(d.Ctx, "unknown")
+	assert.Error(t, err)
+}
+
+func Test_apiKeysDao_GetByUserID(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id"}).
+		AddRow(2, 1).
+		AddRow(1, 1)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	records, err := d.IDao.(APIKeysDao).GetByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, records, 2)
+}
+
+func Test_apiKeysDao_UpdateLastUsedAt(t *testing.T) {
+	d := newAPIKeysDao()
+	defer d.Close()
+
+	now := time.Now()
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(APIKeysDao).UpdateLastUsedAt(d.Ctx, 1, now)
+	assert.NoError(t, err)
+}
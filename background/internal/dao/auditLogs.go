@@ -0,0 +1,156 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/audit"
+	"godemo/internal/model"
+)
+
+var _ AuditLogsDao = (*auditLogsDao)(nil)
+
+// AuditLogsDao defining the dao interface, the audit logs are written by the other daos in the same
+// transaction as the change, so only reading is provided here.
+type AuditLogsDao interface {
+	GetByColumns(ctx context.Context, params *query.Params) ([]*model.AuditLogs, int64, error)
+}
+
+type auditLogsDao struct {
+	db *gorm.DB
+}
+
+// NewAuditLogsDao creating the dao interface
+func NewAuditLogsDao(db *gorm.DB) AuditLogsDao {
+	return &auditLogsDao{db: db}
+}
+
+// GetByColumns get a paginated list of audit logs by custom conditions.
+// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
+func (d *auditLogsDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.AuditLogs, int64, error) {
+	queryStr, args, err := convertQueryParams(params, model.AuditLogsColumnNames)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if params.Sort != "ignore count" { // determine if count is required
+		err = d.db.WithContext(ctx).Model(&model.AuditLogs{}).Where(queryStr, args...).Count(&total).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		if total == 0 {
+			return nil, total, nil
+		}
+	}
+
+	records := []*model.AuditLogs{}
+	order, limit, offset := params.ConvertToPage()
+	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, err
+}
+
+// auditedChange is a change of a record that is written to the audit log. The record is loaded before
+// and after apply in the same transaction, so that the diff is exactly what apply changed.
+type auditedChange struct {
+	entity string
+	action string
+	id     uint64 // 0 for a create, the id returned by apply is used
+
+	// load the record by id, it returns nil if the record does not exist
+	load func(ctx context.Context, tx *gorm.DB, id uint64) (interface{}, error)
+	// make the change, it returns the id of the record
+	apply func(tx *gorm.DB) (uint64, error)
+}
+
+// newAuditedChange create a change of a table record, the soft deleted record is loaded too
+func newAuditedChange[T any](entity string, action string, id uint64, apply func(tx *gorm.DB) (uint64, error)) *auditedChange {
+	return &auditedChange{
+		entity: entity,
+		action: action,
+		id:     id,
+		load: func(ctx context.Context, tx *gorm.DB, id uint64) (interface{}, error) {
+			record := new(T)
+			result := tx.WithContext(ctx).Unscoped().Where("id = ?", id).Limit(1).Find(record)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil, nil
+			}
+			return record, nil
+		},
+		apply: apply,
+	}
+}
+
+// run the change in a new transaction, if the audit log is disabled apply is called directly
+func (a *auditedChange) run(ctx context.Context, db *gorm.DB) error {
+	if !audit.Enabled() {
+		_, err := a.apply(db)
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return a.record(ctx, tx)
+	})
+}
+
+// runByTx run the change using the provided transaction
+func (a *auditedChange) runByTx(ctx context.Context, tx *gorm.DB) error {
+	if !audit.Enabled() {
+		_, err := a.apply(tx)
+		return err
+	}
+	return a.record(ctx, tx)
+}
+
+func (a *auditedChange) record(ctx context.Context, tx *gorm.DB) error {
+	var before interface{}
+	var err error
+	if a.id > 0 {
+		if before, err = a.load(ctx, tx, a.id); err != nil {
+			return err
+		}
+	}
+
+	id, err := a.apply(tx)
+	if err != nil {
+		return err
+	}
+
+	after, err := a.load(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil // nothing was changed, such as deleting a record that does not exist
+	}
+	diff, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	actor, _ := audit.ActorFromContext(ctx)
+	return tx.WithContext(ctx).Create(&model.AuditLogs{
+		ActorID:   actor.UserID,
+		ActorName: actor.UserName,
+		RequestID: middleware.CtxRequestID(ctx),
+		Entity:    a.entity,
+		EntityID:  id,
+		Action:    a.action,
+		Diff:      string(diff),
+	}).Error
+}
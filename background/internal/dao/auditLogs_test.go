@@ -0,0 +1,205 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+
+	"godemo/internal/audit"
+	"godemo/internal/database"
+	"godemo/internal/migration"
+	"godemo/internal/model"
+)
+
+func newAuditLogsDao() *gotest.Dao {
+	d := gotest.NewDao(nil, nil)
+	d.IDao = NewAuditLogsDao(d.DB)
+	return d
+}
+
+func Test_auditLogsDao_GetByColumns(t *testing.T) {
+	d := newAuditLogsDao()
+	defer d.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "entity", "diff"}).
+		AddRow(1, "users", "{}")
+	d.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	records, _, err := d.IDao.(AuditLogsDao).GetByColumns(d.Ctx, &query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "ignore count", // ignore test count(*)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, records, 1)
+
+	// the diff cannot be queried
+	_, _, err = d.IDao.(AuditLogsDao).GetByColumns(d.Ctx, &query.Params{
+		Page:  0,
+		Limit: 10,
+		Columns: []query.Column{
+			{Name: "diff", Value: "x"},
+		},
+	})
+	assert.Error(t, err)
+}
+
+// a database with the schema created by the migrations, and the audit log enabled
+func newAuditTestDB(t *testing.T) *gorm.DB {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "audit.db"), sqlite.WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sgorm.CloseDB(db) })
+	m, err := migration.NewMigrator(db, sgorm.DBDriverSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	audit.Enable()
+	t.Cleanup(audit.Disable)
+	return db
+}
+
+func getAuditLogs(t *testing.T, db *gorm.DB, entity string, entityID uint64) []*model.AuditLogs {
+	var records []*model.AuditLogs
+	err := db.Where("entity = ? AND entity_id = ?", entity, entityID).Order("id").Find(&records).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func getAuditDiff(t *testing.T, record *model.AuditLogs) map[string]audit.Change {
+	changes := map[string]audit.Change{}
+	if err := json.Unmarshal([]byte(record.Diff), &changes); err != nil {
+		t.Fatal(err)
+	}
+	return changes
+}
+
+func Test_auditedChange(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserID: 9, UserName: "admin"})
+	ctx = context.WithValue(ctx, middleware.ContextRequestIDKey, "req-1") //nolint
+	usersDao := NewUsersDao(db, nil, nil)
+
+	user := &model.Users{UserName: "tom", Password: "hash", NickName: "tom"}
+	assert.NoError(t, usersDao.Create(ctx, user))
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: user.ID, NickName: "jerry", Password: "hash2"}))
+	assert.NoError(t, usersDao.DeleteByID(ctx, user.ID))
+	assert.NoError(t, usersDao.RestoreByID(ctx, user.ID))
+	// a failed change is not recorded
+	assert.ErrorIs(t, usersDao.RestoreByID(ctx, user.ID), database.ErrRecordNotFound)
+	assert.NoError(t, usersDao.DeleteByID(ctx, user.ID))
+	assert.NoError(t, usersDao.PurgeByID(ctx, user.ID))
+	// deleting a record that does not exist changes nothing
+	assert.NoError(t, usersDao.DeleteByID(ctx, user.ID))
+
+	records := getAuditLogs(t, db, usersEntity, user.ID)
+	var actions []string
+	for _, record := range records {
+		actions = append(actions, record.Action)
+		assert.Equal(t, uint64(9), record.ActorID)
+		assert.Equal(t, "admin", record.ActorName)
+		assert.Equal(t, "req-1", record.RequestID)
+	}
+	assert.Equal(t, []string{
+		audit.ActionCreate, audit.ActionUpdate, audit.ActionDelete,
+		audit.ActionRestore, audit.ActionDelete, audit.ActionPurge,
+	}, actions)
+
+	created := getAuditDiff(t, records[0])
+	assert.Equal(t, audit.Change{New: "tom"}, created["userName"])
+	assert.Equal(t, audit.Change{New: audit.Redacted}, created["password"])
+
+	updated := getAuditDiff(t, records[1])
+	assert.Equal(t, map[string]audit.Change{
+		"nickName": {Old: "tom", New: "jerry"},
+		"password": {Old: audit.Redacted, New: audit.Redacted},
+	}, updated)
+
+	deleted := getAuditDiff(t, records[2])
+	assert.Len(t, deleted, 1)
+	assert.Nil(t, deleted["deletedAt"].Old)
+	assert.NotNil(t, deleted["deletedAt"].New)
+
+	purged := getAuditDiff(t, records[5])
+	assert.Equal(t, audit.Change{Old: "jerry"}, purged["nickName"])
+}
+
+func Test_auditedChange_ByTx(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	rolesDao := NewRolesDao(db, nil)
+	rolePermissionsDao := NewRolePermissionsDao(db, nil, nil)
+
+	// the audit log is rolled back with the transaction
+	errRollback := errors.New("rollback")
+	var roleID uint64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		id, err := rolesDao.CreateByTx(ctx, tx, &model.Roles{RoleName: "test", RoleCode: "test"})
+		if err != nil {
+			return err
+		}
+		roleID = id
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Empty(t, getAuditLogs(t, db, rolesEntity, roleID))
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		id, err := rolesDao.CreateByTx(ctx, tx, &model.Roles{RoleName: "test", RoleCode: "test"})
+		if err != nil {
+			return err
+		}
+		roleID = id
+		return rolePermissionsDao.ReplaceByTx(ctx, tx, id, []uint64{2, 1})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, roleID, []uint64{1, 3}))
+	assert.NoError(t, rolePermissionsDao.DeleteByRoleID(ctx, roleID))
+
+	// anonymous
+	records := getAuditLogs(t, db, rolesEntity, roleID)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, uint64(0), records[0].ActorID)
+		assert.Equal(t, "", records[0].RequestID)
+	}
+
+	records = getAuditLogs(t, db, rolePermissionsEntity, roleID)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, `{"permissionIDs":{"old":[],"new":[1,2]}}`, records[0].Diff)
+		assert.Equal(t, `{"permissionIDs":{"old":[1,2],"new":[1,3]}}`, records[1].Diff)
+		assert.Equal(t, audit.ActionDelete, records[2].Action)
+		assert.Equal(t, `{"permissionIDs":{"old":[1,3],"new":[]}}`, records[2].Diff)
+	}
+
+	list, total, err := NewAuditLogsDao(db).GetByColumns(ctx, &query.Params{
+		Page:  0,
+		Limit: 10,
+		Columns: []query.Column{
+			{Name: "entity", Value: rolePermissionsEntity},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, list, 3)
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"maps"
 
 	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
@@ -11,20 +12,27 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const filesEntity = "files"
+
 var _ FilesDao = (*filesDao)(nil)
 
 // FilesDao defining the dao interface
 type FilesDao interface {
 	Create(ctx context.Context, table *model.Files) error
-	DeleteByID(ctx context.Context, id uint64) error
-	UpdateByID(ctx context.Context, table *model.Files) error
-	GetByID(ctx context.Context, id uint64) (*model.Files, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.Files, int64, error)
+	DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error
+	UpdateByID(ctx context.Context, table *model.Files, opts ...UpdateOption) error
+	PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Files, error)
+	GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Files, int64, error)
+	RestoreByID(ctx context.Context, id uint64) error
+	PurgeByID(ctx context.Context, id uint64) error
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Files) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
@@ -58,12 +66,14 @@ func (d *filesDao) deleteCache(ctx context.Context, id uint64) error {
 
 // Create a new files, insert the record and the id value is written back to the table
 func (d *filesDao) Create(ctx context.Context, table *model.Files) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	return d.createChange(ctx, table).run(ctx, d.db)
 }
 
-// DeleteByID delete a files by id
-func (d *filesDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Files{}).Error
+// DeleteByID soft delete a files by id, the deleted_at column is set
+func (d *filesDao) DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.deleteChange(ctx, id, o).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
@@ -75,8 +85,10 @@ func (d *filesDao) DeleteByID(ctx context.Context, id uint64) error {
 }
 
 // UpdateByID update a files by id, support partial update
-func (d *filesDao) UpdateByID(ctx context.Context, table *model.Files) error {
-	err := d.updateDataByID(ctx, d.db, table)
+func (d *filesDao) UpdateByID(ctx context.Context, table *model.Files, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.updateChange(ctx, table, o).run(ctx, d.db)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -84,11 +96,27 @@ func (d *filesDao) UpdateByID(ctx context.Context, table *model.Files) error {
 	return err
 }
 
-func (d *filesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Files) error {
-	if table.ID < 1 {
-		return errors.New("id cannot be 0")
+// PatchByID update the columns of a files by id, unlike UpdateByID the zero values are written too.
+// Only the columns of model.FilesColumnNames can be patched.
+func (d *filesDao) PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	if err := checkPatchColumns(columns, model.FilesColumnNames, "storage_key"); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
 	}
 
+	err := d.patchChange(ctx, id, columns, o).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+func (d *filesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Files, o *updateOptions) error {
 	update := map[string]interface{}{}
 
 	if table.Filename != "" {
@@ -107,15 +135,64 @@ func (d *filesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model
 		update["user_id"] = table.UserID
 	}
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return d.updateColumnsByID(ctx, db, table.ID, update, o)
+}
+
+// write the columns of a files, the zero values in update are written too and the version is increased
+func (d *filesDao) updateColumnsByID(ctx context.Context, db *gorm.DB, id uint64, update map[string]interface{}, o *updateOptions) error {
+	if id < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	update = maps.Clone(update)
+	update["version"] = gorm.Expr("version + 1")
+	result := o.scope(db.WithContext(ctx).Model(&model.Files{ID: id})).Updates(update)
+	if result.Error != nil {
+		return result.Error
+	}
+	return o.checkRowsAffected(result.RowsAffected)
+}
+
+// the changes that are written to the audit log, they are shared by the methods with and without a transaction
+
+func (d *filesDao) createChange(ctx context.Context, table *model.Files) *auditedChange {
+	return newAuditedChange[model.Files](filesEntity, audit.ActionCreate, 0, func(tx *gorm.DB) (uint64, error) {
+		err := tx.WithContext(ctx).Create(table).Error
+		return table.ID, err
+	})
+}
+
+func (d *filesDao) deleteChange(ctx context.Context, id uint64, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Files](filesEntity, audit.ActionDelete, id, func(tx *gorm.DB) (uint64, error) {
+		result := o.scope(tx.WithContext(ctx).Where("id = ?", id)).Delete(&model.Files{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return id, o.checkRowsAffected(result.RowsAffected)
+	})
+}
+
+func (d *filesDao) updateChange(ctx context.Context, table *model.Files, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Files](filesEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		return table.ID, d.updateDataByID(ctx, tx, table, o)
+	})
+}
+
+func (d *filesDao) patchChange(ctx context.Context, id uint64, columns map[string]interface{}, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Files](filesEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		return id, d.updateColumnsByID(ctx, tx, id, columns, o)
+	})
 }
 
 // GetByID get a files by id
-func (d *filesDao) GetByID(ctx context.Context, id uint64) (*model.Files, error) {
-	// no cache
-	if d.cache == nil {
+func (d *filesDao) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Files, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	// no cache, or the soft deleted records are required
+	if d.cache == nil || o.includeDeleted {
 		record := &model.Files{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		err := o.scope(d.db.WithContext(ctx)).Where("id = ?", id).First(record).Error
 		return record, err
 	}
 
@@ -166,15 +243,18 @@ func (d *filesDao) GetByID(ctx context.Context, id uint64) (*model.Files, error)
 
 // GetByColumns get a paginated list of filess by custom conditions.
 // For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *filesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.Files, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.FilesColumnNames))
+func (d *filesDao) GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Files, int64, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	queryStr, args, err := convertQueryParams(params, model.FilesColumnNames)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
 	}
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.Files{}).Where(queryStr, args...).Count(&total).Error
+		err = o.scope(d.db.WithContext(ctx)).Model(&model.Files{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -185,7 +265,7 @@ func (d *filesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 
 	records := []*model.Files{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = o.scope(d.db.WithContext(ctx)).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -193,15 +273,63 @@ func (d *filesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 	return records, total, err
 }
 
+// RestoreByID restore a soft deleted files by id
+func (d *filesDao) RestoreByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Files](filesEntity, audit.ActionRestore, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().Model(&model.Files{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache, a placeholder may have been cached for the deleted record
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// PurgeByID permanently delete a soft deleted files by id, a record that is not soft deleted is not purged
+func (d *filesDao) PurgeByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Files](filesEntity, audit.ActionPurge, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Delete(&model.Files{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *filesDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Files) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
+	err := d.createChange(ctx, table).runByTx(ctx, tx)
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx soft delete a record by id in the database using the provided transaction
 func (d *filesDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
-	err := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.Files{}).Error
+	err := d.deleteChange(ctx, id, defaultUpdateOptions()).runByTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -214,7 +342,7 @@ func (d *filesDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 
 // UpdateByTx update a record by id in the database using the provided transaction
 func (d *filesDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Files) error {
-	err := d.updateDataByID(ctx, tx, table)
+	err := d.updateChange(ctx, table, defaultUpdateOptions()).runByTx(ctx, tx)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -58,11 +58,11 @@ func Test_filesDao_DeleteByID(t *testing.T) {
 	d := newFilesDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Files)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -76,6 +76,60 @@ func Test_filesDao_DeleteByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_filesDao_RestoreByID(t *testing.T) {
+	d := newFilesDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Files)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(FilesDao).RestoreByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(FilesDao).RestoreByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
+func Test_filesDao_PurgeByID(t *testing.T) {
+	d := newFilesDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Files)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(FilesDao).PurgeByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(FilesDao).PurgeByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
 func Test_filesDao_UpdateByID(t *testing.T) {
 	d := newFilesDao()
 	defer d.Close()
@@ -201,11 +255,11 @@ func Test_filesDao_DeleteByTx(t *testing.T) {
 	d := newFilesDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Files)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
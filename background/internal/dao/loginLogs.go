@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/model"
+)
+
+var _ LoginLogsDao = (*loginLogsDao)(nil)
+
+// LoginLogsDao defining the dao interface, the login logs are written by the auth handler
+type LoginLogsDao interface {
+	Create(ctx context.Context, table *model.LoginLogs) error
+	GetByColumns(ctx context.Context, params *query.Params) ([]*model.LoginLogs, int64, error)
+}
+
+type loginLogsDao struct {
+	db *gorm.DB
+}
+
+// NewLoginLogsDao creating the dao interface
+func NewLoginLogsDao(db *gorm.DB) LoginLogsDao {
+	return &loginLogsDao{db: db}
+}
+
+// Create a new login log, insert the record and the id value is written back to the table
+func (d *loginLogsDao) Create(ctx context.Context, table *model.LoginLogs) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// GetByColumns get a paginated list of login logs by custom conditions.
+// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
+func (d *loginLogsDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.LoginLogs, int64, error) {
+	queryStr, args, err := convertQueryParams(params, model.LoginLogsColumnNames)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if params.Sort != "ignore count" { // determine if count is required
+		err = d.db.WithContext(ctx).Model(&model.LoginLogs{}).Where(queryStr, args...).Count(&total).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		if total == 0 {
+			return nil, total, nil
+		}
+	}
+
+	records := []*model.LoginLogs{}
+	order, limit, offset := params.ConvertToPage()
+	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, err
+}
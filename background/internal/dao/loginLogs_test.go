@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/model"
+)
+
+func newLoginLogsDao() *gotest.Dao {
+	testData := &model.LoginLogs{UserID: 1, UserName: "admin", IP: "127.0.0.1", Result: "success"}
+	testData.ID = 1
+
+	d := gotest.NewDao(nil, testData)
+	d.IDao = NewLoginLogsDao(d.DB)
+	return d
+}
+
+func Test_loginLogsDao_Create(t *testing.T) {
+	d := newLoginLogsDao()
+	defer d.Close()
+	testData := d.TestData.(*model.LoginLogs)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(LoginLogsDao).Create(d.Ctx, testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_loginLogsDao_GetByColumns(t *testing.T) {
+	d := newLoginLogsDao()
+	defer d.Close()
+	testData := d.TestData.(*model.LoginLogs)
+
+	rows := sqlmock.NewRows([]string{"id", "user_name", "result"}).
+		AddRow(testData.ID, testData.UserName, testData.Result)
+	d.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	records, _, err := d.IDao.(LoginLogsDao).GetByColumns(d.Ctx, &query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "ignore count", // ignore test count(*)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, records, 1)
+
+	// err test
+	_, _, err = d.IDao.(LoginLogsDao).GetByColumns(d.Ctx, &query.Params{
+		Page:  0,
+		Limit: 10,
+		Columns: []query.Column{
+			{Name: "unknown-column", Value: "x"},
+		},
+	})
+	assert.Error(t, err)
+}
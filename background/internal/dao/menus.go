@@ -3,28 +3,38 @@ package dao
 import (
 	"context"
 	"errors"
+	"maps"
 
 	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const menusEntity = "menus"
+
 var _ MenusDao = (*menusDao)(nil)
 
 // MenusDao defining the dao interface
 type MenusDao interface {
 	Create(ctx context.Context, table *model.Menus) error
-	DeleteByID(ctx context.Context, id uint64) error
-	UpdateByID(ctx context.Context, table *model.Menus) error
-	GetByID(ctx context.Context, id uint64) (*model.Menus, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.Menus, int64, error)
+	DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error
+	UpdateByID(ctx context.Context, table *model.Menus, opts ...UpdateOption) error
+	PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Menus, error)
+	GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Menus, int64, error)
+	RestoreByID(ctx context.Context, id uint64) error
+	PurgeByID(ctx context.Context, id uint64) error
+	GetAll(ctx context.Context) ([]*model.Menus, error)
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Menus) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
@@ -58,12 +68,14 @@ func (d *menusDao) deleteCache(ctx context.Context, id uint64) error {
 
 // Create a new menus, insert the record and the id value is written back to the table
 func (d *menusDao) Create(ctx context.Context, table *model.Menus) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	return d.createChange(ctx, table).run(ctx, d.db)
 }
 
-// DeleteByID delete a menus by id
-func (d *menusDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Menus{}).Error
+// DeleteByID soft delete a menus by id, the deleted_at column is set
+func (d *menusDao) DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.deleteChange(ctx, id, o).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
@@ -75,8 +87,10 @@ func (d *menusDao) DeleteByID(ctx context.Context, id uint64) error {
 }
 
 // UpdateByID update a menus by id, support partial update
-func (d *menusDao) UpdateByID(ctx context.Context, table *model.Menus) error {
-	err := d.updateDataByID(ctx, d.db, table)
+func (d *menusDao) UpdateByID(ctx context.Context, table *model.Menus, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.updateChange(ctx, table, o).run(ctx, d.db)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -84,11 +98,27 @@ func (d *menusDao) UpdateByID(ctx context.Context, table *model.Menus) error {
 	return err
 }
 
-func (d *menusDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Menus) error {
-	if table.ID < 1 {
-		return errors.New("id cannot be 0")
+// PatchByID update the columns of a menus by id, unlike UpdateByID the zero values are written too.
+// Only the columns of model.MenusColumnNames can be patched.
+func (d *menusDao) PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	if err := checkPatchColumns(columns, model.MenusColumnNames); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
 	}
 
+	err := d.patchChange(ctx, id, columns, o).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+func (d *menusDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Menus, o *updateOptions) error {
 	update := map[string]interface{}{}
 
 	if table.Name != "" {
@@ -106,16 +136,68 @@ func (d *menusDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model
 	if table.Order != 0 {
 		update["order"] = table.Order
 	}
+	if table.Permission != "" {
+		update["permission"] = table.Permission
+	}
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return d.updateColumnsByID(ctx, db, table.ID, update, o)
+}
+
+// write the columns of a menus, the zero values in update are written too and the version is increased
+func (d *menusDao) updateColumnsByID(ctx context.Context, db *gorm.DB, id uint64, update map[string]interface{}, o *updateOptions) error {
+	if id < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	update = maps.Clone(update)
+	update["version"] = gorm.Expr("version + 1")
+	result := o.scope(db.WithContext(ctx).Model(&model.Menus{ID: id})).Updates(update)
+	if result.Error != nil {
+		return result.Error
+	}
+	return o.checkRowsAffected(result.RowsAffected)
+}
+
+// the changes that are written to the audit log, they are shared by the methods with and without a transaction
+
+func (d *menusDao) createChange(ctx context.Context, table *model.Menus) *auditedChange {
+	return newAuditedChange[model.Menus](menusEntity, audit.ActionCreate, 0, func(tx *gorm.DB) (uint64, error) {
+		err := tx.WithContext(ctx).Create(table).Error
+		return table.ID, err
+	})
+}
+
+func (d *menusDao) deleteChange(ctx context.Context, id uint64, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Menus](menusEntity, audit.ActionDelete, id, func(tx *gorm.DB) (uint64, error) {
+		result := o.scope(tx.WithContext(ctx).Where("id = ?", id)).Delete(&model.Menus{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return id, o.checkRowsAffected(result.RowsAffected)
+	})
+}
+
+func (d *menusDao) updateChange(ctx context.Context, table *model.Menus, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Menus](menusEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		return table.ID, d.updateDataByID(ctx, tx, table, o)
+	})
+}
+
+func (d *menusDao) patchChange(ctx context.Context, id uint64, columns map[string]interface{}, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Menus](menusEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		return id, d.updateColumnsByID(ctx, tx, id, columns, o)
+	})
 }
 
 // GetByID get a menus by id
-func (d *menusDao) GetByID(ctx context.Context, id uint64) (*model.Menus, error) {
-	// no cache
-	if d.cache == nil {
+func (d *menusDao) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Menus, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	// no cache, or the soft deleted records are required
+	if d.cache == nil || o.includeDeleted {
 		record := &model.Menus{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		err := o.scope(d.db.WithContext(ctx)).Where("id = ?", id).First(record).Error
 		return record, err
 	}
 
@@ -166,15 +248,18 @@ func (d *menusDao) GetByID(ctx context.Context, id uint64) (*model.Menus, error)
 
 // GetByColumns get a paginated list of menuss by custom conditions.
 // For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *menusDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.Menus, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.MenusColumnNames))
+func (d *menusDao) GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Menus, int64, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	queryStr, args, err := convertQueryParams(params, model.MenusColumnNames)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
 	}
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.Menus{}).Where(queryStr, args...).Count(&total).Error
+		err = o.scope(d.db.WithContext(ctx)).Model(&model.Menus{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -185,7 +270,7 @@ func (d *menusDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 
 	records := []*model.Menus{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = o.scope(d.db.WithContext(ctx)).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -193,15 +278,76 @@ func (d *menusDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 	return records, total, err
 }
 
+// GetAll get all menus that are not soft deleted, sorted by order and id, the menus table is small
+// enough to be loaded as a whole to build the route tree.
+func (d *menusDao) GetAll(ctx context.Context) ([]*model.Menus, error) {
+	records := []*model.Menus{}
+	err := d.db.WithContext(ctx).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: "order"}},
+			{Column: clause.Column{Name: "id"}},
+		}}).
+		Find(&records).Error
+	return records, err
+}
+
+// RestoreByID restore a soft deleted menus by id
+func (d *menusDao) RestoreByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Menus](menusEntity, audit.ActionRestore, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().Model(&model.Menus{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache, a placeholder may have been cached for the deleted record
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// PurgeByID permanently delete a soft deleted menus by id, a record that is not soft deleted is not purged
+func (d *menusDao) PurgeByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Menus](menusEntity, audit.ActionPurge, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Delete(&model.Menus{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *menusDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Menus) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
+	err := d.createChange(ctx, table).runByTx(ctx, tx)
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx soft delete a record by id in the database using the provided transaction
 func (d *menusDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
-	err := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.Menus{}).Error
+	err := d.deleteChange(ctx, id, defaultUpdateOptions()).runByTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -214,7 +360,7 @@ func (d *menusDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 
 // UpdateByTx update a record by id in the database using the provided transaction
 func (d *menusDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Menus) error {
-	err := d.updateDataByID(ctx, tx, table)
+	err := d.updateChange(ctx, table, defaultUpdateOptions()).runByTx(ctx, tx)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
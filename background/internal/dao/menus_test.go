@@ -58,11 +58,11 @@ func Test_menusDao_DeleteByID(t *testing.T) {
 	d := newMenusDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Menus)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -76,6 +76,60 @@ func Test_menusDao_DeleteByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_menusDao_RestoreByID(t *testing.T) {
+	d := newMenusDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Menus)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(MenusDao).RestoreByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(MenusDao).RestoreByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
+func Test_menusDao_PurgeByID(t *testing.T) {
+	d := newMenusDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Menus)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(MenusDao).PurgeByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(MenusDao).PurgeByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
 func Test_menusDao_UpdateByID(t *testing.T) {
 	d := newMenusDao()
 	defer d.Close()
@@ -180,6 +234,32 @@ func Test_menusDao_GetByColumns(t *testing.T) {
 	t.Log(err)
 }
 
+func Test_menusDao_GetAll(t *testing.T) {
+	d := newMenusDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Menus)
+
+	rows := sqlmock.NewRows([]string{"id"}).
+		AddRow(testData.ID)
+
+	d.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	menus, err := d.IDao.(MenusDao).GetAll(d.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, menus, 1)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	_, err = d.IDao.(MenusDao).GetAll(d.Ctx)
+	assert.Error(t, err)
+}
+
 func Test_menusDao_CreateByTx(t *testing.T) {
 	d := newMenusDao()
 	defer d.Close()
@@ -201,11 +281,11 @@ func Test_menusDao_DeleteByTx(t *testing.T) {
 	d := newMenusDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Menus)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// QueryOption set options of the get methods
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	includeDeleted bool
+}
+
+func defaultQueryOptions() *queryOptions {
+	return &queryOptions{}
+}
+
+func (o *queryOptions) apply(opts ...QueryOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// scope the soft deleted records are excluded by gorm unless includeDeleted is set
+func (o *queryOptions) scope(db *gorm.DB) *gorm.DB {
+	if o.includeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// WithIncludeDeleted also return the soft deleted records, the cache is not used in this case
+func WithIncludeDeleted(includeDeleted bool) QueryOption {
+	return func(o *queryOptions) {
+		o.includeDeleted = includeDeleted
+	}
+}
+
+// ErrVersionMismatch the record does not exist or has been changed since the version of the precondition
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// UpdateOption set options of the update and delete methods
+type UpdateOption func(*updateOptions)
+
+type updateOptions struct {
+	conditional bool
+	versions    []uint64
+}
+
+func defaultUpdateOptions() *updateOptions {
+	return &updateOptions{}
+}
+
+func (o *updateOptions) apply(opts ...UpdateOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// scope only the record with one of the versions is changed
+func (o *updateOptions) scope(db *gorm.DB) *gorm.DB {
+	if len(o.versions) > 0 {
+		return db.Where("version IN ?", o.versions)
+	}
+	return db
+}
+
+// checkRowsAffected a conditional change that affects no record does not meet its precondition,
+// the version is increased by every update so that an affected record is always changed
+func (o *updateOptions) checkRowsAffected(rowsAffected int64) error {
+	if o.conditional && rowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// WithVersion change the record only if its version is one of versions, or only if it exists if versions
+// is empty, otherwise ErrVersionMismatch is returned. The check is part of the update statement, so that
+// a concurrent change is detected too.
+func WithVersion(versions ...uint64) UpdateOption {
+	return func(o *updateOptions) {
+		o.conditional = true
+		o.versions = versions
+	}
+}
@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/model"
+)
+
+func Test_WithVersion(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	rolesDao := NewRolesDao(db, nil)
+	getVersion := func(id uint64) uint64 {
+		record, err := rolesDao.GetByID(ctx, id, WithIncludeDeleted(true))
+		assert.NoError(t, err)
+		return record.Version
+	}
+
+	role := &model.Roles{RoleName: "editor", RoleCode: "editor"}
+	assert.NoError(t, rolesDao.Create(ctx, role))
+	assert.Equal(t, uint64(1), getVersion(role.ID))
+
+	// every change increases the version
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: role.ID, RoleName: "editors"}, WithVersion(1)))
+	assert.Equal(t, uint64(2), getVersion(role.ID))
+	assert.NoError(t, rolesDao.PatchByID(ctx, role.ID, map[string]interface{}{"role_desc": ""}))
+	assert.Equal(t, uint64(3), getVersion(role.ID))
+
+	// a stale version is not changed
+	assert.ErrorIs(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: role.ID, RoleName: "x"}, WithVersion(1, 2)), ErrVersionMismatch)
+	assert.ErrorIs(t, rolesDao.PatchByID(ctx, role.ID, map[string]interface{}{"role_name": "x"}, WithVersion(2)), ErrVersionMismatch)
+	assert.ErrorIs(t, rolesDao.DeleteByID(ctx, role.ID, WithVersion(2)), ErrVersionMismatch)
+	record, err := rolesDao.GetByID(ctx, role.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "editors", record.RoleName)
+
+	// any version of an existing record
+	assert.NoError(t, rolesDao.PatchByID(ctx, role.ID, map[string]interface{}{"role_name": "writers"}, WithVersion(2, 3)))
+	assert.ErrorIs(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: 999, RoleName: "x"}, WithVersion()), ErrVersionMismatch)
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: 999, RoleName: "x"})) // unconditional
+
+	assert.NoError(t, rolesDao.DeleteByID(ctx, role.ID, WithVersion(4)))
+	assert.ErrorIs(t, rolesDao.DeleteByID(ctx, role.ID, WithVersion()), ErrVersionMismatch) // already deleted
+	assert.NoError(t, rolesDao.RestoreByID(ctx, role.ID))
+	assert.Equal(t, uint64(5), getVersion(role.ID))
+}
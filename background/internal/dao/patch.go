@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrPatchColumn a column of a patch is not in the whitelist of the table
+var ErrPatchColumn = errors.New("column cannot be patched")
+
+// the columns that are maintained by gorm and the dao, they are in the whitelists for the queries but never patched
+var systemColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"version":    true,
+}
+
+// checkPatchColumns check that the columns of a patch are in the whitelist of the table, such as
+// model.UsersColumnNames, readOnly are the columns of the whitelist that are changed by other methods.
+func checkPatchColumns(columns map[string]interface{}, whitelist map[string]bool, readOnly ...string) error {
+	for column := range columns {
+		if !whitelist[column] || systemColumns[column] || slices.Contains(readOnly, column) {
+			return fmt.Errorf("%w: %s", ErrPatchColumn, column)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/model"
+)
+
+func Test_usersDao_PatchByID(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	usersDao := NewUsersDao(db, nil, nil)
+
+	tom := &model.Users{UserName: "tom", Password: "hash", NickName: "Tom", UserEmail: "tom@example.com"}
+	assert.NoError(t, usersDao.Create(ctx, tom))
+	jerry := &model.Users{UserName: "jerry", Password: "hash", UserEmail: "jerry@example.com"}
+	assert.NoError(t, usersDao.Create(ctx, jerry))
+
+	// the zero values are written
+	assert.NoError(t, usersDao.PatchByID(ctx, tom.ID, map[string]interface{}{"nick_name": "", "user_email": ""}))
+	record, err := usersDao.GetByID(ctx, tom.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "", record.NickName)
+	assert.Equal(t, "", record.UserEmail)
+	assert.Equal(t, "tom", record.UserName)
+	assert.NoError(t, usersDao.PatchByID(ctx, tom.ID, nil))
+
+	assert.Equal(t, &ConflictError{Field: "userName"}, usersDao.PatchByID(ctx, tom.ID, map[string]interface{}{"user_name": "jerry"}))
+
+	for _, column := range []string{"password", "totp_enabled", "id", "updated_at", "unknown"} {
+		err = usersDao.PatchByID(ctx, tom.ID, map[string]interface{}{column: ""})
+		assert.ErrorIs(t, err, ErrPatchColumn, column)
+	}
+}
+
+func Test_rolesDao_PatchByID(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	rolesDao := NewRolesDao(db, nil)
+
+	parent := &model.Roles{RoleName: "parent", RoleCode: "parent"}
+	assert.NoError(t, rolesDao.Create(ctx, parent))
+	child := &model.Roles{RoleName: "child", RoleCode: "child", ParentID: &parent.ID}
+	assert.NoError(t, rolesDao.Create(ctx, child))
+
+	assert.ErrorIs(t, rolesDao.PatchByID(ctx, parent.ID, map[string]interface{}{"parent_id": child.ID}), ErrRoleParentCycle)
+	assert.NoError(t, rolesDao.PatchByID(ctx, child.ID, map[string]interface{}{"parent_id": uint64(0), "require_two_factor": false}))
+	record, err := rolesDao.GetByID(ctx, child.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), *record.ParentID)
+}
+
+func Test_filesDao_PatchByID(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	filesDao := NewFilesDao(db, nil)
+
+	file := &model.Files{Filename: "a.txt", URL: "2024/01/02/a.txt", StorageKey: "2024/01/02/a.txt"}
+	assert.NoError(t, filesDao.Create(ctx, file))
+
+	// the storage key is written only by an upload
+	err := filesDao.PatchByID(ctx, file.ID, map[string]interface{}{"storage_key": "2024/01/02/b.txt"})
+	assert.ErrorIs(t, err, ErrPatchColumn)
+	assert.NoError(t, filesDao.PatchByID(ctx, file.ID, map[string]interface{}{"url": "2024/01/02/b.txt"}))
+	record, err := filesDao.GetByID(ctx, file.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024/01/02/a.txt", record.StorageKey)
+}
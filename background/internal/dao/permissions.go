@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"maps"
 
 	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
@@ -11,20 +12,29 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const permissionsEntity = "permissions"
+
 var _ PermissionsDao = (*permissionsDao)(nil)
 
 // PermissionsDao defining the dao interface
 type PermissionsDao interface {
 	Create(ctx context.Context, table *model.Permissions) error
-	DeleteByID(ctx context.Context, id uint64) error
-	UpdateByID(ctx context.Context, table *model.Permissions) error
-	GetByID(ctx context.Context, id uint64) (*model.Permissions, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.Permissions, int64, error)
+	DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error
+	UpdateByID(ctx context.Context, table *model.Permissions, opts ...UpdateOption) error
+	PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Permissions, error)
+	GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Permissions, int64, error)
+	RestoreByID(ctx context.Context, id uint64) error
+	PurgeByID(ctx context.Context, id uint64) error
+	GetByIDs(ctx context.Context, ids []uint64) (map[uint64]*model.Permissions, error)
+	CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error)
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Permissions) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
@@ -58,12 +68,14 @@ func (d *permissionsDao) deleteCache(ctx context.Context, id uint64) error {
 
 // Create a new permissions, insert the record and the id value is written back to the table
 func (d *permissionsDao) Create(ctx context.Context, table *model.Permissions) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	return d.createChange(ctx, table).run(ctx, d.db)
 }
 
-// DeleteByID delete a permissions by id
-func (d *permissionsDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Permissions{}).Error
+// DeleteByID soft delete a permissions by id, the deleted_at column is set
+func (d *permissionsDao) DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.deleteChange(ctx, id, o).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
@@ -75,8 +87,10 @@ func (d *permissionsDao) DeleteByID(ctx context.Context, id uint64) error {
 }
 
 // UpdateByID update a permissions by id, support partial update
-func (d *permissionsDao) UpdateByID(ctx context.Context, table *model.Permissions) error {
-	err := d.updateDataByID(ctx, d.db, table)
+func (d *permissionsDao) UpdateByID(ctx context.Context, table *model.Permissions, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.updateChange(ctx, table, o).run(ctx, d.db)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -84,11 +98,27 @@ func (d *permissionsDao) UpdateByID(ctx context.Context, table *model.Permission
 	return err
 }
 
-func (d *permissionsDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Permissions) error {
-	if table.ID < 1 {
-		return errors.New("id cannot be 0")
+// PatchByID update the columns of a permissions by id, unlike UpdateByID the zero values are written too.
+// Only the columns of model.PermissionsColumnNames can be patched.
+func (d *permissionsDao) PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	if err := checkPatchColumns(columns, model.PermissionsColumnNames); err != nil {
+		return err
 	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	err := d.patchChange(ctx, id, columns, o).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
 
+func (d *permissionsDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Permissions, o *updateOptions) error {
 	update := map[string]interface{}{}
 
 	if table.Name != "" {
@@ -101,15 +131,71 @@ func (d *permissionsDao) updateDataByID(ctx context.Context, db *gorm.DB, table
 		update["description"] = table.Description
 	}
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return d.updateColumnsByID(ctx, db, table.ID, update, o)
+}
+
+// write the columns of a permissions, the zero values in update are written too and the version is increased
+func (d *permissionsDao) updateColumnsByID(ctx context.Context, db *gorm.DB, id uint64, update map[string]interface{}, o *updateOptions) error {
+	if id < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	if err := checkUnique(ctx, db, permissionsEntity, permissionsUniqueFields, update, id); err != nil {
+		return err
+	}
+
+	update = maps.Clone(update)
+	update["version"] = gorm.Expr("version + 1")
+	result := o.scope(db.WithContext(ctx).Model(&model.Permissions{ID: id})).Updates(update)
+	if result.Error != nil {
+		return translateUniqueError(result.Error, permissionsEntity, permissionsUniqueFields)
+	}
+	return o.checkRowsAffected(result.RowsAffected)
+}
+
+// the changes that are written to the audit log, they are shared by the methods with and without a transaction
+
+func (d *permissionsDao) createChange(ctx context.Context, table *model.Permissions) *auditedChange {
+	return newAuditedChange[model.Permissions](permissionsEntity, audit.ActionCreate, 0, func(tx *gorm.DB) (uint64, error) {
+		if err := checkUnique(ctx, tx, permissionsEntity, permissionsUniqueFields, map[string]interface{}{"code": table.Code}, 0); err != nil {
+			return 0, err
+		}
+		err := tx.WithContext(ctx).Create(table).Error
+		return table.ID, translateUniqueError(err, permissionsEntity, permissionsUniqueFields)
+	})
+}
+
+func (d *permissionsDao) deleteChange(ctx context.Context, id uint64, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Permissions](permissionsEntity, audit.ActionDelete, id, func(tx *gorm.DB) (uint64, error) {
+		result := o.scope(tx.WithContext(ctx).Where("id = ?", id)).Delete(&model.Permissions{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return id, o.checkRowsAffected(result.RowsAffected)
+	})
+}
+
+func (d *permissionsDao) updateChange(ctx context.Context, table *model.Permissions, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Permissions](permissionsEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		return table.ID, d.updateDataByID(ctx, tx, table, o)
+	})
+}
+
+func (d *permissionsDao) patchChange(ctx context.Context, id uint64, columns map[string]interface{}, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Permissions](permissionsEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		return id, d.updateColumnsByID(ctx, tx, id, columns, o)
+	})
 }
 
 // GetByID get a permissions by id
-func (d *permissionsDao) GetByID(ctx context.Context, id uint64) (*model.Permissions, error) {
-	// no cache
-	if d.cache == nil {
+func (d *permissionsDao) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Permissions, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	// no cache, or the soft deleted records are required
+	if d.cache == nil || o.includeDeleted {
 		record := &model.Permissions{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		err := o.scope(d.db.WithContext(ctx)).Where("id = ?", id).First(record).Error
 		return record, err
 	}
 
@@ -160,15 +246,18 @@ func (d *permissionsDao) GetByID(ctx context.Context, id uint64) (*model.Permiss
 
 // GetByColumns get a paginated list of permissionss by custom conditions.
 // For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *permissionsDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.Permissions, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.PermissionsColumnNames))
+func (d *permissionsDao) GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Permissions, int64, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	queryStr, args, err := convertQueryParams(params, model.PermissionsColumnNames)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
 	}
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.Permissions{}).Where(queryStr, args...).Count(&total).Error
+		err = o.scope(d.db.WithContext(ctx)).Model(&model.Permissions{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -179,7 +268,7 @@ func (d *permissionsDao) GetByColumns(ctx context.Context, params *query.Params)
 
 	records := []*model.Permissions{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = o.scope(d.db.WithContext(ctx)).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -187,15 +276,143 @@ func (d *permissionsDao) GetByColumns(ctx context.Context, params *query.Params)
 	return records, total, err
 }
 
+// GetByIDs batch get permissions by ids
+func (d *permissionsDao) GetByIDs(ctx context.Context, ids []uint64) (map[uint64]*model.Permissions, error) {
+	// no cache
+	if d.cache == nil {
+		var records []*model.Permissions
+		err := d.db.WithContext(ctx).Where("id IN (?)", ids).Find(&records).Error
+		if err != nil {
+			return nil, err
+		}
+		itemMap := make(map[uint64]*model.Permissions)
+		for _, record := range records {
+			itemMap[record.ID] = record
+		}
+		return itemMap, nil
+	}
+
+	// get form cache
+	itemMap, err := d.cache.MultiGet(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var missedIDs []uint64
+	for _, id := range ids {
+		if _, ok := itemMap[id]; !ok {
+			missedIDs = append(missedIDs, id)
+		}
+	}
+
+	// get missed data
+	if len(missedIDs) > 0 {
+		// find the id of an active placeholder, i.e. an id that does not exist in database
+		var realMissedIDs []uint64
+		for _, id := range missedIDs {
+			_, err = d.cache.Get(ctx, id)
+			if d.cache.IsPlaceholderErr(err) {
+				continue
+			}
+			realMissedIDs = append(realMissedIDs, id)
+		}
+
+		// get missed id from database
+		if len(realMissedIDs) > 0 {
+			var records []*model.Permissions
+			var recordIDMap = make(map[uint64]struct{})
+			err = d.db.WithContext(ctx).Where("id IN (?)", realMissedIDs).Find(&records).Error
+			if err != nil {
+				return nil, err
+			}
+			if len(records) > 0 {
+				for _, record := range records {
+					itemMap[record.ID] = record
+					recordIDMap[record.ID] = struct{}{}
+				}
+				if err = d.cache.MultiSet(ctx, records, cache.PermissionsExpireTime); err != nil {
+					logger.Warn("cache.MultiSet error", logger.Err(err), logger.Any("ids", records))
+				}
+				if len(records) == len(realMissedIDs) {
+					return itemMap, nil
+				}
+			}
+			for _, id := range realMissedIDs {
+				if _, ok := recordIDMap[id]; !ok {
+					if err = d.cache.SetPlaceholder(ctx, id); err != nil {
+						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("id", id))
+					}
+				}
+			}
+		}
+	}
+
+	return itemMap, nil
+}
+
+// RestoreByID restore a soft deleted permissions by id
+func (d *permissionsDao) RestoreByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Permissions](permissionsEntity, audit.ActionRestore, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().Model(&model.Permissions{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache, a placeholder may have been cached for the deleted record
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// PurgeByID permanently delete a soft deleted permissions by id, a record that is not soft deleted is not purged
+func (d *permissionsDao) PurgeByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Permissions](permissionsEntity, audit.ActionPurge, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Delete(&model.Permissions{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// CheckAvailability check that the value of a unique field, such as code, is not used by another record
+// than excludeID, the soft deleted records are included. It returns ErrUnknownUniqueField if field is not unique.
+func (d *permissionsDao) CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error) {
+	return checkUniqueField(ctx, d.db, permissionsEntity, permissionsUniqueFields, field, value, excludeID)
+}
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *permissionsDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Permissions) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
+	err := d.createChange(ctx, table).runByTx(ctx, tx)
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx soft delete a record by id in the database using the provided transaction
 func (d *permissionsDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
-	err := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.Permissions{}).Error
+	err := d.deleteChange(ctx, id, defaultUpdateOptions()).runByTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -208,7 +425,7 @@ func (d *permissionsDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64)
 
 // UpdateByTx update a record by id in the database using the provided transaction
 func (d *permissionsDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Permissions) error {
-	err := d.updateDataByID(ctx, tx, table)
+	err := d.updateChange(ctx, table, defaultUpdateOptions()).runByTx(ctx, tx)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -58,11 +58,11 @@ func Test_permissionsDao_DeleteByID(t *testing.T) {
 	d := newPermissionsDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Permissions)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -76,6 +76,60 @@ func Test_permissionsDao_DeleteByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_permissionsDao_RestoreByID(t *testing.T) {
+	d := newPermissionsDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Permissions)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(PermissionsDao).RestoreByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(PermissionsDao).RestoreByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
+func Test_permissionsDao_PurgeByID(t *testing.T) {
+	d := newPermissionsDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Permissions)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(PermissionsDao).PurgeByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(PermissionsDao).PurgeByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
 func Test_permissionsDao_UpdateByID(t *testing.T) {
 	d := newPermissionsDao()
 	defer d.Close()
@@ -180,6 +234,32 @@ func Test_permissionsDao_GetByColumns(t *testing.T) {
 	t.Log(err)
 }
 
+func Test_permissionsDao_GetByIDs(t *testing.T) {
+	d := newPermissionsDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Permissions)
+
+	rows := sqlmock.NewRows([]string{"id"}).
+		AddRow(testData.ID)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID).
+		WillReturnRows(rows)
+
+	_, err := d.IDao.(PermissionsDao).GetByIDs(d.Ctx, []uint64{testData.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.IDao.(PermissionsDao).GetByIDs(d.Ctx, []uint64{111})
+	assert.Error(t, err)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func Test_permissionsDao_CreateByTx(t *testing.T) {
 	d := newPermissionsDao()
 	defer d.Close()
@@ -201,11 +281,11 @@ func Test_permissionsDao_DeleteByTx(t *testing.T) {
 	d := newPermissionsDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Permissions)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/dberr"
+)
+
+// QueryParamsError a column of the query params of a paginated list cannot be converted to query conditions,
+// errors.Is(err, dberr.ErrQueryParams) is true
+type QueryParamsError struct {
+	Field  string // json path of the field, such as columns[0].name
+	Reason string // unknown if the column is not in the whitelist, invalid otherwise
+	Err    error  // conversion error, only logged
+}
+
+func (e *QueryParamsError) Error() string {
+	return e.Field + " is " + e.Reason + ": " + e.Err.Error()
+}
+
+// Is report whether target is dberr.ErrQueryParams
+func (e *QueryParamsError) Is(target error) bool {
+	return target == dberr.ErrQueryParams
+}
+
+// Unwrap return the conversion error
+func (e *QueryParamsError) Unwrap() error {
+	return e.Err
+}
+
+// convertQueryParams convert the columns of params to gorm conditions, only the column names in whitelist are
+// allowed, the error is a *QueryParamsError
+func convertQueryParams(params *query.Params, whitelist map[string]bool) (string, []interface{}, error) {
+	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(whitelist))
+	if err != nil {
+		return "", nil, newQueryParamsError(params.Columns, whitelist, err)
+	}
+	return queryStr, args, nil
+}
+
+// the conversion error does not tell which column is not valid, so the columns are converted one by one to find it
+func newQueryParamsError(columns []query.Column, whitelist map[string]bool, err error) *QueryParamsError {
+	for i, column := range columns {
+		field := fmt.Sprintf("columns[%d]", i)
+		if !whitelist[column.Name] {
+			return &QueryParamsError{Field: field + ".name", Reason: "unknown", Err: err}
+		}
+		one := &query.Params{Columns: []query.Column{column}}
+		if _, _, e := one.ConvertToGormConditions(); e != nil {
+			return &QueryParamsError{Field: field, Reason: "invalid", Err: err}
+		}
+	}
+	return &QueryParamsError{Field: "columns", Reason: "invalid", Err: err}
+}
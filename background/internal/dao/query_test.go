@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/dberr"
+)
+
+func Test_convertQueryParams(t *testing.T) {
+	whitelist := map[string]bool{"user_name": true, "status": true}
+
+	queryStr, args, err := convertQueryParams(&query.Params{Columns: []query.Column{
+		{Name: "user_name", Value: "tom"},
+	}}, whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "user_name = ?", queryStr)
+	assert.Equal(t, []interface{}{"tom"}, args)
+
+	tests := []struct {
+		name    string
+		columns []query.Column
+		field   string
+		reason  string
+	}{
+		{"not in the whitelist", []query.Column{{Name: "user_name", Value: "tom"}, {Name: "password", Value: "x"}}, "columns[1].name", "unknown"},
+		{"empty name", []query.Column{{Value: "tom"}}, "columns[0].name", "unknown"},
+		{"unsupported exp", []query.Column{{Name: "status", Exp: "~", Value: "1"}}, "columns[0]", "invalid"},
+		{"nil value", []query.Column{{Name: "status", Value: "1"}, {Name: "user_name"}}, "columns[1]", "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := convertQueryParams(&query.Params{Columns: tt.columns}, whitelist)
+			var queryParamsErr *QueryParamsError
+			assert.True(t, errors.As(err, &queryParamsErr))
+			assert.True(t, errors.Is(err, dberr.ErrQueryParams))
+			assert.Equal(t, tt.field, queryParamsErr.Field)
+			assert.Equal(t, tt.reason, queryParamsErr.Reason)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"godemo/internal/model"
+)
+
+var (
+	// ErrRoleParentNotFound the parent role does not exist
+	ErrRoleParentNotFound = errors.New("parent role not found")
+	// ErrRoleParentCycle the parent role is the role itself or one of its descendants
+	ErrRoleParentCycle = errors.New("parent role makes a cycle")
+)
+
+// EffectivePermission is a permission of the effective set of a role, it is granted to the role itself
+// or inherited from an ancestor
+type EffectivePermission struct {
+	model.Permissions
+	RoleID uint64 // the role the permission is granted to, the nearest one if several roles of the chain have it
+}
+
+// roleHierarchy is the tree of the roles that are not soft deleted, linked by parent_id. The roles table is
+// small, so the whole tree is loaded instead of walking it with recursive queries that differ by database.
+type roleHierarchy struct {
+	roles    map[uint64]*model.Roles
+	children map[uint64][]uint64
+}
+
+func loadRoleHierarchy(ctx context.Context, db *gorm.DB) (*roleHierarchy, error) {
+	records := []*model.Roles{}
+	err := db.WithContext(ctx).Select("id", "status", "parent_id").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	h := &roleHierarchy{
+		roles:    make(map[uint64]*model.Roles, len(records)),
+		children: make(map[uint64][]uint64),
+	}
+	for _, record := range records {
+		h.roles[record.ID] = record
+		if parentID := roleParentID(record); parentID > 0 {
+			h.children[parentID] = append(h.children[parentID], record.ID)
+		}
+	}
+	return h, nil
+}
+
+// chain get the role and the ancestors whose permissions it inherits, the nearest first. The chain
+// stops at a parent that is deleted or disabled, a disabled role passes nothing on.
+func (h *roleHierarchy) chain(id uint64) []uint64 {
+	if _, ok := h.roles[id]; !ok {
+		return nil
+	}
+	ids := []uint64{id}
+	seen := map[uint64]bool{id: true}
+	for parentID := roleParentID(h.roles[id]); parentID > 0 && !seen[parentID]; {
+		parent, ok := h.roles[parentID]
+		if !ok || parent.Status == model.StatusDisabled {
+			break
+		}
+		ids = append(ids, parentID)
+		seen[parentID] = true
+		parentID = roleParentID(parent)
+	}
+	return ids
+}
+
+// descendants get the role and all the roles that inherit from it, the role does not have to exist,
+// so that the descendants of a deleted role can be found.
+func (h *roleHierarchy) descendants(id uint64) []uint64 {
+	ids := []uint64{id}
+	seen := map[uint64]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, childID := range h.children[ids[i]] {
+			if !seen[childID] {
+				seen[childID] = true
+				ids = append(ids, childID)
+			}
+		}
+	}
+	return ids
+}
+
+// checkParent check that parentID can be the parent of the role id, id is 0 for a new role
+func (h *roleHierarchy) checkParent(id uint64, parentID uint64) error {
+	if parentID == 0 {
+		return nil
+	}
+	if _, ok := h.roles[parentID]; !ok {
+		return ErrRoleParentNotFound
+	}
+	if id == 0 {
+		return nil
+	}
+	for _, descendantID := range h.descendants(id) {
+		if descendantID == parentID {
+			return ErrRoleParentCycle
+		}
+	}
+	return nil
+}
+
+// check that parentID can be the parent of the role id, it returns ErrRoleParentNotFound or ErrRoleParentCycle
+func checkRoleParent(ctx context.Context, db *gorm.DB, id uint64, parentID uint64) error {
+	if parentID == 0 {
+		return nil
+	}
+	h, err := loadRoleHierarchy(ctx, db)
+	if err != nil {
+		return err
+	}
+	return h.checkParent(id, parentID)
+}
+
+// get the effective permissions of the roles of the chain, sorted by permission id
+func getChainPermissions(ctx context.Context, db *gorm.DB, chain []uint64) ([]*EffectivePermission, error) {
+	if len(chain) == 0 {
+		return []*EffectivePermission{}, nil
+	}
+
+	links := []*model.RolePermissions{}
+	err := db.WithContext(ctx).Where("role_id IN (?)", chain).Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[uint64]int, len(chain))
+	for i, roleID := range chain {
+		order[roleID] = i
+	}
+	grantedBy := make(map[uint64]uint64, len(links))
+	permissionIDs := make([]uint64, 0, len(links))
+	for _, link := range links {
+		roleID, ok := grantedBy[link.PermissionID]
+		if !ok {
+			permissionIDs = append(permissionIDs, link.PermissionID)
+		}
+		if !ok || order[link.RoleID] < order[roleID] {
+			grantedBy[link.PermissionID] = link.RoleID
+		}
+	}
+	if len(permissionIDs) == 0 {
+		return []*EffectivePermission{}, nil
+	}
+
+	// soft deleted permissions are excluded by gorm
+	permissions := []*model.Permissions{}
+	err = db.WithContext(ctx).Where("id IN (?)", permissionIDs).Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].ID < permissions[j].ID })
+	effective := make([]*EffectivePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		effective = append(effective, &EffectivePermission{
+			Permissions: *permission,
+			RoleID:      grantedBy[permission.ID],
+		})
+	}
+	return effective, nil
+}
+
+func roleParentID(role *model.Roles) uint64 {
+	if role.ParentID == nil {
+		return 0
+	}
+	return *role.ParentID
+}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/model"
+)
+
+func newTestRoleHierarchy(roles ...*model.Roles) *roleHierarchy {
+	h := &roleHierarchy{roles: map[uint64]*model.Roles{}, children: map[uint64][]uint64{}}
+	for _, role := range roles {
+		h.roles[role.ID] = role
+		if parentID := roleParentID(role); parentID > 0 {
+			h.children[parentID] = append(h.children[parentID], role.ID)
+		}
+	}
+	return h
+}
+
+func newTestRole(id uint64, parentID uint64, status string) *model.Roles {
+	return &model.Roles{ID: id, ParentID: &parentID, Status: status}
+}
+
+func Test_roleHierarchy(t *testing.T) {
+	h := newTestRoleHierarchy(
+		newTestRole(1, 0, model.StatusEnabled),
+		newTestRole(2, 1, model.StatusEnabled),
+		newTestRole(3, 2, ""),
+		newTestRole(4, 1, model.StatusDisabled),
+		newTestRole(5, 4, model.StatusEnabled),
+		newTestRole(6, 99, model.StatusEnabled), // the parent is deleted
+	)
+
+	assert.Equal(t, []uint64{3, 2, 1}, h.chain(3))
+	assert.Equal(t, []uint64{4, 1}, h.chain(4))
+	assert.Equal(t, []uint64{5}, h.chain(5)) // a disabled parent passes nothing on
+	assert.Equal(t, []uint64{6}, h.chain(6))
+	assert.Empty(t, h.chain(99))
+
+	assert.ElementsMatch(t, []uint64{1, 2, 3, 4, 5}, h.descendants(1))
+	assert.Equal(t, []uint64{3}, h.descendants(3))
+	assert.Equal(t, []uint64{99, 6}, h.descendants(99))
+
+	assert.NoError(t, h.checkParent(3, 0))
+	assert.NoError(t, h.checkParent(0, 1))
+	assert.NoError(t, h.checkParent(5, 3))
+	assert.ErrorIs(t, h.checkParent(1, 99), ErrRoleParentNotFound)
+	assert.ErrorIs(t, h.checkParent(1, 1), ErrRoleParentCycle)
+	assert.ErrorIs(t, h.checkParent(1, 3), ErrRoleParentCycle)
+
+	// a cycle written to the table directly does not loop forever
+	h = newTestRoleHierarchy(newTestRole(1, 2, ""), newTestRole(2, 1, ""))
+	assert.Equal(t, []uint64{1, 2}, h.chain(1))
+	assert.Equal(t, []uint64{1, 2}, h.descendants(1))
+}
+
+func Test_roleHierarchy_Permissions(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	rolesDao := NewRolesDao(db, nil)
+	rolePermissionsDao := NewRolePermissionsDao(db, nil, nil)
+	userPermissionsDao := NewUserPermissionsDao(db, nil)
+	parentOf := func(id uint64) *uint64 { return &id }
+
+	// base <- manager <- owner
+	base := &model.Roles{RoleName: "base", RoleCode: "base"}
+	assert.NoError(t, rolesDao.Create(ctx, base))
+	manager := &model.Roles{RoleName: "manager", RoleCode: "manager", ParentID: parentOf(base.ID)}
+	assert.NoError(t, rolesDao.Create(ctx, manager))
+	owner := &model.Roles{RoleName: "owner", RoleCode: "owner", ParentID: parentOf(manager.ID)}
+	assert.NoError(t, rolesDao.Create(ctx, owner))
+	assert.ErrorIs(t, rolesDao.Create(ctx, &model.Roles{RoleName: "x", RoleCode: "x", ParentID: parentOf(999)}), ErrRoleParentNotFound)
+
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, base.ID, []uint64{1}))
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, manager.ID, []uint64{1, 2}))
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, owner.ID, []uint64{3}))
+
+	permissions, err := rolePermissionsDao.GetEffectiveByRoleID(ctx, owner.ID)
+	assert.NoError(t, err)
+	var codes []string
+	for _, permission := range permissions {
+		codes = append(codes, permission.Code)
+	}
+	assert.Equal(t, []string{"user:manage", "role:manage", "permission:manage"}, codes)
+	assert.Equal(t, manager.ID, permissions[0].RoleID) // the nearest role that has it
+	assert.Equal(t, manager.ID, permissions[1].RoleID)
+	assert.Equal(t, owner.ID, permissions[2].RoleID)
+
+	user := &model.Users{UserName: "tom", Password: "hash"}
+	assert.NoError(t, NewUsersDao(db, nil, nil).Create(ctx, user))
+	assert.NoError(t, db.Create(&model.UserRoles{UserID: user.ID, RoleID: owner.ID}).Error)
+	userCodes, err := userPermissionsDao.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, codes, userCodes)
+
+	// the parent cannot be the role itself or a descendant
+	assert.ErrorIs(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: base.ID, ParentID: parentOf(owner.ID)}), ErrRoleParentCycle)
+	assert.ErrorIs(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: base.ID, ParentID: parentOf(base.ID)}), ErrRoleParentCycle)
+	assert.ErrorIs(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: base.ID, ParentID: parentOf(999)}), ErrRoleParentNotFound)
+
+	// a disabled parent passes nothing on
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: manager.ID, Status: model.StatusDisabled}))
+	userCodes, err = userPermissionsDao.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"permission:manage"}, userCodes)
+
+	// removing the parent
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: manager.ID, Status: model.StatusEnabled}))
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: owner.ID, ParentID: parentOf(0)}))
+	permissions, err = rolePermissionsDao.GetEffectiveByRoleID(ctx, owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 1)
+}
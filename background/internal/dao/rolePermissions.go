@@ -8,195 +8,163 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/go-dev-frame/sponge/pkg/logger"
-	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const rolePermissionsEntity = "rolePermissions"
+
 var _ RolePermissionsDao = (*rolePermissionsDao)(nil)
 
-// RolePermissionsDao defining the dao interface
+// RolePermissionsDao defining the dao interface, the permissions of a role are handled as a set
 type RolePermissionsDao interface {
-	Create(ctx context.Context, table *model.RolePermissions) error
+	GetPermissionIDsByRoleID(ctx context.Context, roleID uint64) ([]uint64, error)
+	GetEffectiveByRoleID(ctx context.Context, roleID uint64) ([]*EffectivePermission, error)
+	ReplaceByRoleID(ctx context.Context, roleID uint64, permissionIDs []uint64) error
 	DeleteByRoleID(ctx context.Context, roleID uint64) error
-	UpdateByRoleID(ctx context.Context, table *model.RolePermissions) error
-	GetByRoleID(ctx context.Context, roleID uint64) (*model.RolePermissions, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.RolePermissions, int64, error)
+	DeleteCacheByRoleID(ctx context.Context, roleID uint64) error
 
-	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.RolePermissions) (uint64, error)
+	ReplaceByTx(ctx context.Context, tx *gorm.DB, roleID uint64, permissionIDs []uint64) error
 	DeleteByTx(ctx context.Context, tx *gorm.DB, roleID uint64) error
-	UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.RolePermissions) error
 }
 
 type rolePermissionsDao struct {
-	db    *gorm.DB
-	cache cache.RolePermissionsCache // if nil, the cache is not used.
-	sfg   *singleflight.Group        // if cache is nil, the sfg is not used.
+	db          *gorm.DB
+	cache       cache.RolePermissionsCache // if nil, the cache is not used.
+	sfg         *singleflight.Group        // if cache is nil, the sfg is not used.
+	permissions *userPermissionsDao        // the cached permission codes of the users of a changed role are deleted
 }
 
-// NewRolePermissionsDao creating the dao interface
-func NewRolePermissionsDao(db *gorm.DB, xCache cache.RolePermissionsCache) RolePermissionsDao {
+// NewRolePermissionsDao creating the dao interface, the cached permission codes in permissionsCache are deleted
+// when the permissions of a role change, permissionsCache can be nil
+func NewRolePermissionsDao(db *gorm.DB, xCache cache.RolePermissionsCache, permissionsCache cache.UserPermissionsCache) RolePermissionsDao {
+	permissions := NewUserPermissionsDao(db, permissionsCache).(*userPermissionsDao)
 	if xCache == nil {
-		return &rolePermissionsDao{db: db}
+		return &rolePermissionsDao{db: db, permissions: permissions}
 	}
 	return &rolePermissionsDao{
-		db:    db,
-		cache: xCache,
-		sfg:   new(singleflight.Group),
+		db:          db,
+		cache:       xCache,
+		sfg:         new(singleflight.Group),
+		permissions: permissions,
 	}
 }
 
+// delete the cached permissions of the role and of the users who have it or inherit from it
 func (d *rolePermissionsDao) deleteCache(ctx context.Context, roleID uint64) error {
+	var err error
 	if d.cache != nil {
-		return d.cache.Del(ctx, roleID)
+		err = d.cache.Del(ctx, roleID)
 	}
-	return nil
-}
-
-// Create a new rolePermissions, insert the record and the roleID value is written back to the table
-func (d *rolePermissionsDao) Create(ctx context.Context, table *model.RolePermissions) error {
-	return d.db.WithContext(ctx).Create(table).Error
-}
-
-// DeleteByRoleID delete a rolePermissions by roleID
-func (d *rolePermissionsDao) DeleteByRoleID(ctx context.Context, roleID uint64) error {
-	err := d.db.WithContext(ctx).Where("role_id = ?", roleID).Delete(&model.RolePermissions{}).Error
-	if err != nil {
-		return err
+	if e := d.permissions.deleteCacheByRoleID(ctx, d.db, roleID); e != nil {
+		logger.Warn("DeleteCacheByRoleID error", logger.Err(e), logger.Any("roleID", roleID))
+		err = e
 	}
-
-	// delete cache
-	_ = d.deleteCache(ctx, roleID)
-
-	return nil
-}
-
-// UpdateByRoleID update a rolePermissions by roleID
-func (d *rolePermissionsDao) UpdateByRoleID(ctx context.Context, table *model.RolePermissions) error {
-	err := d.updateDataByRoleID(ctx, d.db, table)
-
-	// delete cache
-	_ = d.deleteCache(ctx, table.RoleID)
-
 	return err
 }
 
-func (d *rolePermissionsDao) updateDataByRoleID(ctx context.Context, db *gorm.DB, table *model.RolePermissions) error {
-	if table.RoleID < 1 {
-		return errors.New("roleID cannot be 0")
-	}
-
-	update := map[string]interface{}{}
-
-	if table.RoleID != 0 {
-		update["role_id"] = table.RoleID
-	}
-	if table.PermissionID != 0 {
-		update["permission_id"] = table.PermissionID
-	}
-
-	return db.WithContext(ctx).Model(table).Updates(update).Error
-}
-
-// GetByRoleID get a rolePermissions by roleID
-func (d *rolePermissionsDao) GetByRoleID(ctx context.Context, roleID uint64) (*model.RolePermissions, error) {
+// GetPermissionIDsByRoleID get all the permission ids of the role, an empty slice is returned if there is none
+func (d *rolePermissionsDao) GetPermissionIDsByRoleID(ctx context.Context, roleID uint64) ([]uint64, error) {
 	// no cache
 	if d.cache == nil {
-		record := &model.RolePermissions{}
-		err := d.db.WithContext(ctx).Where("role_id = ?", roleID).First(record).Error
-		return record, err
+		return d.getPermissionIDsFromDB(ctx, roleID)
 	}
 
 	// get from cache
-	record, err := d.cache.Get(ctx, roleID)
+	permissionIDs, err := d.cache.Get(ctx, roleID)
 	if err == nil {
-		return record, nil
+		return permissionIDs, nil
 	}
 
 	// get from database
 	if errors.Is(err, database.ErrCacheNotFound) {
 		// for the same roleID, prevent high concurrent simultaneous access to database
 		val, err, _ := d.sfg.Do(utils.Uint64ToStr(roleID), func() (interface{}, error) {
-
-			table := &model.RolePermissions{}
-			err = d.db.WithContext(ctx).Where("role_id = ?", roleID).First(table).Error
+			permissionIDs, err := d.getPermissionIDsFromDB(ctx, roleID)
 			if err != nil {
-				// set placeholder cache to prevent cache penetration, default expiration time 10 minutes
-				if errors.Is(err, database.ErrRecordNotFound) {
-					if err = d.cache.SetPlaceholder(ctx, roleID); err != nil {
-						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("roleID", roleID))
-					}
-					return nil, database.ErrRecordNotFound
-				}
 				return nil, err
 			}
 			// set cache
-			if err = d.cache.Set(ctx, roleID, table, cache.RolePermissionsExpireTime); err != nil {
+			if err = d.cache.Set(ctx, roleID, permissionIDs, cache.RolePermissionsExpireTime); err != nil {
 				logger.Warn("cache.Set error", logger.Err(err), logger.Any("roleID", roleID))
 			}
-			return table, nil
+			return permissionIDs, nil
 		})
 		if err != nil {
 			return nil, err
 		}
-		table, ok := val.(*model.RolePermissions)
-		if !ok {
-			return nil, database.ErrRecordNotFound
-		}
-		return table, nil
-	}
-
-	if d.cache.IsPlaceholderErr(err) {
-		return nil, database.ErrRecordNotFound
+		permissionIDs, _ := val.([]uint64)
+		return permissionIDs, nil
 	}
 
 	return nil, err
 }
 
-// GetByColumns get a paginated list of rolePermissions by custom conditions.
-// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *rolePermissionsDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.RolePermissions, int64, error) {
-	if params.Sort == "" {
-		params.Sort = "-role_id"
-	}
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.RolePermissionsColumnNames))
+func (d *rolePermissionsDao) getPermissionIDsFromDB(ctx context.Context, roleID uint64) ([]uint64, error) {
+	permissionIDs := []uint64{}
+	err := d.db.WithContext(ctx).Model(&model.RolePermissions{}).
+		Where("role_id = ?", roleID).
+		Order("permission_id").
+		Pluck("permission_id", &permissionIDs).Error
+	return permissionIDs, err
+}
+
+// GetEffectiveByRoleID get the permissions of the role and the ones it inherits from its ancestors, sorted by
+// permission id. It is what requirePermission checks, an empty slice is returned if the role does not exist.
+func (d *rolePermissionsDao) GetEffectiveByRoleID(ctx context.Context, roleID uint64) ([]*EffectivePermission, error) {
+	h, err := loadRoleHierarchy(ctx, d.db)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, err
 	}
+	return getChainPermissions(ctx, d.db, h.chain(roleID))
+}
 
-	var total int64
-	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.RolePermissions{}).Where(queryStr, args...).Count(&total).Error
-		if err != nil {
-			return nil, 0, err
-		}
-		if total == 0 {
-			return nil, total, nil
-		}
+// ReplaceByRoleID replace all the permissions of the role with permissionIDs in one transaction, an empty permissionIDs removes all of them
+func (d *rolePermissionsDao) ReplaceByRoleID(ctx context.Context, roleID uint64, permissionIDs []uint64) error {
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return d.replaceChange(ctx, roleID, permissionIDs).runByTx(ctx, tx)
+	})
+
+	// delete cache
+	_ = d.deleteCache(ctx, roleID)
+
+	return err
+}
+
+func (d *rolePermissionsDao) replace(ctx context.Context, tx *gorm.DB, roleID uint64, permissionIDs []uint64) error {
+	if roleID < 1 {
+		return errors.New("roleID cannot be 0")
 	}
 
-	records := []*model.RolePermissions{}
-	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err := tx.WithContext(ctx).Where("role_id = ?", roleID).Delete(&model.RolePermissions{}).Error
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 
-	return records, total, err
-}
+	records := make([]*model.RolePermissions, 0, len(permissionIDs))
+	seen := make(map[uint64]struct{}, len(permissionIDs))
+	for _, id := range permissionIDs {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		records = append(records, &model.RolePermissions{RoleID: roleID, PermissionID: id})
+	}
+	if len(records) == 0 {
+		return nil
+	}
 
-// CreateByTx create a record in the database using the provided transaction
-func (d *rolePermissionsDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.RolePermissions) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
-	return table.RoleID, err
+	return tx.WithContext(ctx).Create(&records).Error
 }
 
-// DeleteByTx delete a record by roleID in the database using the provided transaction
-func (d *rolePermissionsDao) DeleteByTx(ctx context.Context, tx *gorm.DB, roleID uint64) error {
-	err := tx.WithContext(ctx).Where("role_id = ?", roleID).Delete(&model.RolePermissions{}).Error
+// DeleteByRoleID delete all the permissions of the role
+func (d *rolePermissionsDao) DeleteByRoleID(ctx context.Context, roleID uint64) error {
+	err := d.deleteChange(ctx, roleID).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
@@ -207,12 +175,54 @@ func (d *rolePermissionsDao) DeleteByTx(ctx context.Context, tx *gorm.DB, roleID
 	return nil
 }
 
-// UpdateByTx update a record by roleID in the database using the provided transaction
-func (d *rolePermissionsDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.RolePermissions) error {
-	err := d.updateDataByRoleID(ctx, tx, table)
+// DeleteCacheByRoleID delete the cached permissions of the role and of the users who have it or inherit from it,
+// call it after the transaction of ReplaceByTx or DeleteByTx commits, as a read before the commit would cache
+// the old permissions again
+func (d *rolePermissionsDao) DeleteCacheByRoleID(ctx context.Context, roleID uint64) error {
+	return d.deleteCache(ctx, roleID)
+}
 
-	// delete cache
-	_ = d.deleteCache(ctx, table.RoleID)
+// ReplaceByTx replace all the permissions of the role using the provided transaction,
+// call DeleteCacheByRoleID after the transaction commits
+func (d *rolePermissionsDao) ReplaceByTx(ctx context.Context, tx *gorm.DB, roleID uint64, permissionIDs []uint64) error {
+	return d.replaceChange(ctx, roleID, permissionIDs).runByTx(ctx, tx)
+}
 
-	return err
+// DeleteByTx delete all the permissions of the role using the provided transaction,
+// call DeleteCacheByRoleID after the transaction commits
+func (d *rolePermissionsDao) DeleteByTx(ctx context.Context, tx *gorm.DB, roleID uint64) error {
+	return d.deleteChange(ctx, roleID).runByTx(ctx, tx)
+}
+
+// the permissions of a role are written to the audit log as a change of its permissionIDs field
+
+func (d *rolePermissionsDao) replaceChange(ctx context.Context, roleID uint64, permissionIDs []uint64) *auditedChange {
+	return d.newChange(audit.ActionUpdate, roleID, func(tx *gorm.DB) error {
+		return d.replace(ctx, tx, roleID, permissionIDs)
+	})
+}
+
+func (d *rolePermissionsDao) deleteChange(ctx context.Context, roleID uint64) *auditedChange {
+	return d.newChange(audit.ActionDelete, roleID, func(tx *gorm.DB) error {
+		return tx.WithContext(ctx).Where("role_id = ?", roleID).Delete(&model.RolePermissions{}).Error
+	})
+}
+
+func (d *rolePermissionsDao) newChange(action string, roleID uint64, apply func(tx *gorm.DB) error) *auditedChange {
+	return &auditedChange{
+		entity: rolePermissionsEntity,
+		action: action,
+		id:     roleID,
+		load: func(ctx context.Context, tx *gorm.DB, roleID uint64) (interface{}, error) {
+			permissionIDs := []uint64{}
+			err := tx.WithContext(ctx).Model(&model.RolePermissions{}).
+				Where("role_id = ?", roleID).
+				Order("permission_id").
+				Pluck("permission_id", &permissionIDs).Error
+			return map[string][]uint64{"permissionIDs": permissionIDs}, err
+		},
+		apply: func(tx *gorm.DB) (uint64, error) {
+			return roleID, apply(tx)
+		},
+	}
 }
@@ -0,0 +1,205 @@
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+)
+
+func newRolePermissionsDao() *gotest.Dao {
+	// init mock cache
+	c := gotest.NewCache(map[string]interface{}{"1": &[]uint64{1, 2}})
+	c.ICache = cache.NewRolePermissionsCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	})
+
+	// init mock dao
+	d := gotest.NewDao(c, nil)
+	d.IDao = NewRolePermissionsDao(d.DB, c.ICache.(cache.RolePermissionsCache), nil)
+
+	return d
+}
+
+func Test_rolePermissionsDao_GetPermissionIDsByRoleID(t *testing.T) {
+	d := newRolePermissionsDao()
+	defer d.Close()
+
+	rows := sqlmock.NewRows([]string{"permission_id"}).
+		AddRow(1).
+		AddRow(2)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	ids, err := d.IDao.(RolePermissionsDao).GetPermissionIDsByRoleID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids)
+
+	// get from cache
+	ids, err = d.IDao.(RolePermissionsDao).GetPermissionIDsByRoleID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	_, err = d.IDao.(RolePermissionsDao).GetPermissionIDsByRoleID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_rolePermissionsDao_ReplaceByRoleID(t *testing.T) {
+	d := newRolePermissionsDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WithArgs(1, 2, 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	// duplicate and zero ids are ignored
+	err := d.IDao.(RolePermissionsDao).ReplaceByRoleID(d.Ctx, 1, []uint64{2, 3, 2, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// empty set, only delete
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(RolePermissionsDao).ReplaceByRoleID(d.Ctx, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// insert error, rollback
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectRollback()
+
+	err = d.IDao.(RolePermissionsDao).ReplaceByRoleID(d.Ctx, 1, []uint64{2})
+	assert.Error(t, err)
+
+	// zero id error
+	err = d.IDao.(RolePermissionsDao).ReplaceByRoleID(d.Ctx, 0, []uint64{2})
+	assert.Error(t, err)
+}
+
+func Test_rolePermissionsDao_DeleteByRoleID(t *testing.T) {
+	d := newRolePermissionsDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(RolePermissionsDao).DeleteByRoleID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	err = d.IDao.(RolePermissionsDao).DeleteByRoleID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_rolePermissionsDao_ReplaceByTx(t *testing.T) {
+	d := newRolePermissionsDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	d.SQLMock.ExpectCommit()
+
+	tx := d.DB.Begin()
+	err := d.IDao.(RolePermissionsDao).ReplaceByTx(d.Ctx, tx, 1, []uint64{2})
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	tx.Commit()
+}
+
+func Test_rolePermissionsDao_DeleteByTx(t *testing.T) {
+	d := newRolePermissionsDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	tx := d.DB.Begin()
+	err := d.IDao.(RolePermissionsDao).DeleteByTx(d.Ctx, tx, 1)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	tx.Commit()
+}
+
+func Test_rolePermissionsDao_DeletePermissionsCache(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	// the memory cache is shared by the process, use user ids that other tests do not use
+	permissionsCache := cache.NewUserPermissionsCache(&database.CacheType{CType: "memory"})
+	rolePermissionsDao := NewRolePermissionsDao(db, nil, permissionsCache)
+	assert.NoError(t, NewUserRolesDao(db, nil, nil).ReplaceByUserID(ctx, 9201, []uint64{3}))
+	assert.NoError(t, NewUserRolesDao(db, nil, nil).ReplaceByUserID(ctx, 9202, []uint64{2}))
+	isCached := func(userID uint64) bool {
+		_, err := permissionsCache.Get(ctx, userID)
+		return err == nil
+	}
+
+	// only the users of the changed role are affected
+	assert.NoError(t, permissionsCache.Set(ctx, 9201, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, permissionsCache.Set(ctx, 9202, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, 3, []uint64{1}))
+	assert.False(t, isCached(9201))
+	assert.True(t, isCached(9202))
+
+	assert.NoError(t, permissionsCache.Set(ctx, 9201, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, rolePermissionsDao.DeleteByTx(ctx, db, 3))
+	assert.True(t, isCached(9201))
+	assert.NoError(t, rolePermissionsDao.DeleteCacheByRoleID(ctx, 3))
+	assert.False(t, isCached(9201))
+	assert.NoError(t, rolePermissionsDao.DeleteByRoleID(ctx, 2))
+	assert.False(t, isCached(9202))
+}
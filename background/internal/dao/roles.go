@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"maps"
 
 	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
@@ -11,24 +12,34 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const rolesEntity = "roles"
+
 var _ RolesDao = (*rolesDao)(nil)
 
 // RolesDao defining the dao interface
 type RolesDao interface {
 	Create(ctx context.Context, table *model.Roles) error
-	DeleteByID(ctx context.Context, id uint64) error
-	UpdateByID(ctx context.Context, table *model.Roles) error
-	GetByID(ctx context.Context, id uint64) (*model.Roles, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.Roles, int64, error)
+	DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error
+	UpdateByID(ctx context.Context, table *model.Roles, opts ...UpdateOption) error
+	PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Roles, error)
+	GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Roles, int64, error)
+	RestoreByID(ctx context.Context, id uint64) error
+	PurgeByID(ctx context.Context, id uint64) error
+	GetByIDs(ctx context.Context, ids []uint64) (map[uint64]*model.Roles, error)
+	CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error)
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Roles) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 	UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Roles) error
+	PurgeByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 }
 
 type rolesDao struct {
@@ -58,12 +69,14 @@ func (d *rolesDao) deleteCache(ctx context.Context, id uint64) error {
 
 // Create a new roles, insert the record and the id value is written back to the table
 func (d *rolesDao) Create(ctx context.Context, table *model.Roles) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	return d.createChange(ctx, table).run(ctx, d.db)
 }
 
-// DeleteByID delete a roles by id
-func (d *rolesDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Roles{}).Error
+// DeleteByID soft delete a roles by id, the deleted_at column is set
+func (d *rolesDao) DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.deleteChange(ctx, id, o).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
@@ -75,8 +88,10 @@ func (d *rolesDao) DeleteByID(ctx context.Context, id uint64) error {
 }
 
 // UpdateByID update a roles by id, support partial update
-func (d *rolesDao) UpdateByID(ctx context.Context, table *model.Roles) error {
-	err := d.updateDataByID(ctx, d.db, table)
+func (d *rolesDao) UpdateByID(ctx context.Context, table *model.Roles, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.updateChange(ctx, table, o).run(ctx, d.db)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
@@ -84,11 +99,27 @@ func (d *rolesDao) UpdateByID(ctx context.Context, table *model.Roles) error {
 	return err
 }
 
-func (d *rolesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Roles) error {
-	if table.ID < 1 {
-		return errors.New("id cannot be 0")
+// PatchByID update the columns of a roles by id, unlike UpdateByID the zero values are written too.
+// Only the columns of model.RolesColumnNames can be patched.
+func (d *rolesDao) PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	if err := checkPatchColumns(columns, model.RolesColumnNames); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
 	}
 
+	err := d.patchChange(ctx, id, columns, o).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+func (d *rolesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Roles, o *updateOptions) error {
 	update := map[string]interface{}{}
 
 	if table.RoleName != "" {
@@ -103,16 +134,89 @@ func (d *rolesDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model
 	if table.Status != "" {
 		update["status"] = table.Status
 	}
+	if table.RequireTwoFactor != nil {
+		update["require_two_factor"] = *table.RequireTwoFactor
+	}
+	if table.ParentID != nil {
+		update["parent_id"] = *table.ParentID
+	}
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return d.updateColumnsByID(ctx, db, table.ID, update, o)
+}
+
+// write the columns of a roles, the zero values in update are written too and the version is increased
+func (d *rolesDao) updateColumnsByID(ctx context.Context, db *gorm.DB, id uint64, update map[string]interface{}, o *updateOptions) error {
+	if id < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	if parentID, ok := update["parent_id"].(uint64); ok {
+		if err := checkRoleParent(ctx, db, id, parentID); err != nil {
+			return err
+		}
+	}
+
+	if err := checkUnique(ctx, db, rolesEntity, rolesUniqueFields, update, id); err != nil {
+		return err
+	}
+
+	update = maps.Clone(update)
+	update["version"] = gorm.Expr("version + 1")
+	result := o.scope(db.WithContext(ctx).Model(&model.Roles{ID: id})).Updates(update)
+	if result.Error != nil {
+		return translateUniqueError(result.Error, rolesEntity, rolesUniqueFields)
+	}
+	return o.checkRowsAffected(result.RowsAffected)
+}
+
+// the changes that are written to the audit log, they are shared by the methods with and without a transaction
+
+func (d *rolesDao) createChange(ctx context.Context, table *model.Roles) *auditedChange {
+	return newAuditedChange[model.Roles](rolesEntity, audit.ActionCreate, 0, func(tx *gorm.DB) (uint64, error) {
+		if table.ParentID != nil {
+			if err := checkRoleParent(ctx, tx, 0, *table.ParentID); err != nil {
+				return 0, err
+			}
+		}
+		if err := checkUnique(ctx, tx, rolesEntity, rolesUniqueFields, map[string]interface{}{"role_code": table.RoleCode}, 0); err != nil {
+			return 0, err
+		}
+		err := tx.WithContext(ctx).Create(table).Error
+		return table.ID, translateUniqueError(err, rolesEntity, rolesUniqueFields)
+	})
+}
+
+func (d *rolesDao) deleteChange(ctx context.Context, id uint64, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Roles](rolesEntity, audit.ActionDelete, id, func(tx *gorm.DB) (uint64, error) {
+		result := o.scope(tx.WithContext(ctx).Where("id = ?", id)).Delete(&model.Roles{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return id, o.checkRowsAffected(result.RowsAffected)
+	})
+}
+
+func (d *rolesDao) updateChange(ctx context.Context, table *model.Roles, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Roles](rolesEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		return table.ID, d.updateDataByID(ctx, tx, table, o)
+	})
+}
+
+func (d *rolesDao) patchChange(ctx context.Context, id uint64, columns map[string]interface{}, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Roles](rolesEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		return id, d.updateColumnsByID(ctx, tx, id, columns, o)
+	})
 }
 
 // GetByID get a roles by id
-func (d *rolesDao) GetByID(ctx context.Context, id uint64) (*model.Roles, error) {
-	// no cache
-	if d.cache == nil {
+func (d *rolesDao) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Roles, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	// no cache, or the soft deleted records are required
+	if d.cache == nil || o.includeDeleted {
 		record := &model.Roles{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		err := o.scope(d.db.WithContext(ctx)).Where("id = ?", id).First(record).Error
 		return record, err
 	}
 
@@ -163,15 +267,18 @@ func (d *rolesDao) GetByID(ctx context.Context, id uint64) (*model.Roles, error)
 
 // GetByColumns get a paginated list of roless by custom conditions.
 // For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *rolesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.Roles, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.RolesColumnNames))
+func (d *rolesDao) GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Roles, int64, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	queryStr, args, err := convertQueryParams(params, model.RolesColumnNames)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
 	}
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.Roles{}).Where(queryStr, args...).Count(&total).Error
+		err = o.scope(d.db.WithContext(ctx)).Model(&model.Roles{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -182,7 +289,7 @@ func (d *rolesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 
 	records := []*model.Roles{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = o.scope(d.db.WithContext(ctx)).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -190,15 +297,147 @@ func (d *rolesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 	return records, total, err
 }
 
+// GetByIDs batch get roles by ids
+func (d *rolesDao) GetByIDs(ctx context.Context, ids []uint64) (map[uint64]*model.Roles, error) {
+	// no cache
+	if d.cache == nil {
+		var records []*model.Roles
+		err := d.db.WithContext(ctx).Where("id IN (?)", ids).Find(&records).Error
+		if err != nil {
+			return nil, err
+		}
+		itemMap := make(map[uint64]*model.Roles)
+		for _, record := range records {
+			itemMap[record.ID] = record
+		}
+		return itemMap, nil
+	}
+
+	// get form cache
+	itemMap, err := d.cache.MultiGet(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var missedIDs []uint64
+	for _, id := range ids {
+		if _, ok := itemMap[id]; !ok {
+			missedIDs = append(missedIDs, id)
+		}
+	}
+
+	// get missed data
+	if len(missedIDs) > 0 {
+		// find the id of an active placeholder, i.e. an id that does not exist in database
+		var realMissedIDs []uint64
+		for _, id := range missedIDs {
+			_, err = d.cache.Get(ctx, id)
+			if d.cache.IsPlaceholderErr(err) {
+				continue
+			}
+			realMissedIDs = append(realMissedIDs, id)
+		}
+
+		// get missed id from database
+		if len(realMissedIDs) > 0 {
+			var records []*model.Roles
+			var recordIDMap = make(map[uint64]struct{})
+			err = d.db.WithContext(ctx).Where("id IN (?)", realMissedIDs).Find(&records).Error
+			if err != nil {
+				return nil, err
+			}
+			if len(records) > 0 {
+				for _, record := range records {
+					itemMap[record.ID] = record
+					recordIDMap[record.ID] = struct{}{}
+				}
+				if err = d.cache.MultiSet(ctx, records, cache.RolesExpireTime); err != nil {
+					logger.Warn("cache.MultiSet error", logger.Err(err), logger.Any("ids", records))
+				}
+				if len(records) == len(realMissedIDs) {
+					return itemMap, nil
+				}
+			}
+			for _, id := range realMissedIDs {
+				if _, ok := recordIDMap[id]; !ok {
+					if err = d.cache.SetPlaceholder(ctx, id); err != nil {
+						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("id", id))
+					}
+				}
+			}
+		}
+	}
+
+	return itemMap, nil
+}
+
+// RestoreByID restore a soft deleted roles by id
+func (d *rolesDao) RestoreByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Roles](rolesEntity, audit.ActionRestore, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().Model(&model.Roles{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache, a placeholder may have been cached for the deleted record
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// PurgeByID permanently delete a soft deleted roles by id, a record that is not soft deleted is not purged
+func (d *rolesDao) PurgeByID(ctx context.Context, id uint64) error {
+	err := d.purgeChange(ctx, id).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+func (d *rolesDao) purgeChange(ctx context.Context, id uint64) *auditedChange {
+	return newAuditedChange[model.Roles](rolesEntity, audit.ActionPurge, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Delete(&model.Roles{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+// CheckAvailability check that the value of a unique field, such as roleCode, is not used by another record
+// than excludeID, the soft deleted records are included. It returns ErrUnknownUniqueField if field is not unique.
+func (d *rolesDao) CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error) {
+	return checkUniqueField(ctx, d.db, rolesEntity, rolesUniqueFields, field, value, excludeID)
+}
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *rolesDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Roles) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
+	err := d.createChange(ctx, table).runByTx(ctx, tx)
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx soft delete a record by id in the database using the provided transaction
 func (d *rolesDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
-	err := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.Roles{}).Error
+	err := d.deleteChange(ctx, id, defaultUpdateOptions()).runByTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -211,10 +450,23 @@ func (d *rolesDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 
 // UpdateByTx update a record by id in the database using the provided transaction
 func (d *rolesDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Roles) error {
-	err := d.updateDataByID(ctx, tx, table)
+	err := d.updateChange(ctx, table, defaultUpdateOptions()).runByTx(ctx, tx)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
 
 	return err
 }
+
+// PurgeByTx permanently delete a soft deleted record by id in the database using the provided transaction
+func (d *rolesDao) PurgeByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
+	err := d.purgeChange(ctx, id).runByTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
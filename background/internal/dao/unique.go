@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"godemo/internal/dberr"
+)
+
+var (
+	// ErrConflict the value of a unique field is used by another record, the error is a *ConflictError
+	ErrConflict = errors.New("unique field conflict")
+	// ErrUnknownUniqueField the field is not a unique field of the table
+	ErrUnknownUniqueField = errors.New("unknown unique field")
+)
+
+// ConflictError the value of a unique field is used by another record, errors.Is(err, ErrConflict) is true
+type ConflictError struct {
+	Field string // json name of the field, such as userName
+}
+
+func (e *ConflictError) Error() string {
+	return e.Field + " already exists"
+}
+
+// Is report whether target is ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// uniqueField is a column with a unique index, the index is named uk_<table>_<column> in the migrations.
+// An empty value is not unique, such as the email of the users that have none.
+type uniqueField struct {
+	column string
+	field  string // json name of the column
+}
+
+var (
+	usersUniqueFields       = []uniqueField{{column: "user_name", field: "userName"}, {column: "user_email", field: "userEmail"}}
+	rolesUniqueFields       = []uniqueField{{column: "role_code", field: "roleCode"}}
+	permissionsUniqueFields = []uniqueField{{column: "code", field: "code"}}
+)
+
+// checkUnique check that the values of the unique columns are not used by another record than excludeID,
+// values are the columns to write, such as the update map. The soft deleted records are included as in the
+// unique index, so that a deleted record can always be restored.
+func checkUnique(ctx context.Context, db *gorm.DB, table string, fields []uniqueField, values map[string]interface{}, excludeID uint64) error {
+	for _, f := range fields {
+		value, ok := values[f.column]
+		if !ok || value == "" {
+			continue
+		}
+		available, err := isUniqueValueAvailable(ctx, db, table, f.column, value, excludeID)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return &ConflictError{Field: f.field}
+		}
+	}
+	return nil
+}
+
+// checkUniqueField check the value of a unique field by its json name, it is used by the availability
+// check of the forms, excludeID is the record that is being edited, 0 for a new record.
+func checkUniqueField(ctx context.Context, db *gorm.DB, table string, fields []uniqueField, field string, value string, excludeID uint64) (bool, error) {
+	for _, f := range fields {
+		if f.field == field {
+			if value == "" {
+				return true, nil
+			}
+			return isUniqueValueAvailable(ctx, db, table, f.column, value, excludeID)
+		}
+	}
+	return false, ErrUnknownUniqueField
+}
+
+func isUniqueValueAvailable(ctx context.Context, db *gorm.DB, table string, column string, value interface{}, excludeID uint64) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Unscoped().Table(table).
+		Where(column+" = ? AND id <> ?", value, excludeID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// translateUniqueError translate the duplicate key error of the unique index of a field into a
+// *ConflictError, a value written by a concurrent request passes checkUnique but not the index.
+func translateUniqueError(err error, table string, fields []uniqueField) error {
+	if !dberr.IsDuplicateKey(err) {
+		return err
+	}
+	msg := err.Error()
+	for _, f := range fields {
+		// mysql and postgresql name the index, sqlite names the column
+		if strings.Contains(msg, "uk_"+table+"_"+f.column) || strings.Contains(msg, table+"."+f.column) {
+			return &ConflictError{Field: f.field}
+		}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/model"
+)
+
+func Test_checkUnique(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	usersDao := NewUsersDao(db, nil, nil)
+
+	tom := &model.Users{UserName: "tom", Password: "hash", UserEmail: "tom@example.com"}
+	assert.NoError(t, usersDao.Create(ctx, tom))
+	jerry := &model.Users{UserName: "jerry", Password: "hash"}
+	assert.NoError(t, usersDao.Create(ctx, jerry))
+	assert.NoError(t, usersDao.Create(ctx, &model.Users{UserName: "spike", Password: "hash"})) // an empty email is not unique
+
+	err := usersDao.Create(ctx, &model.Users{UserName: "tom", Password: "hash"})
+	assert.ErrorIs(t, err, ErrConflict)
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, "userName", conflictErr.Field)
+
+	err = usersDao.UpdateByID(ctx, &model.Users{ID: jerry.ID, UserEmail: "tom@example.com"})
+	assert.Equal(t, &ConflictError{Field: "userEmail"}, err)
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: tom.ID, UserName: "tom"})) // its own value
+
+	// the soft deleted records keep their values, so that they can be restored
+	assert.NoError(t, usersDao.DeleteByID(ctx, tom.ID))
+	assert.ErrorIs(t, usersDao.Create(ctx, &model.Users{UserName: "tom", Password: "hash"}), ErrConflict)
+	assert.NoError(t, usersDao.RestoreByID(ctx, tom.ID))
+
+	available, err := usersDao.CheckAvailability(ctx, "userName", "tom", 0)
+	assert.NoError(t, err)
+	assert.False(t, available)
+	available, err = usersDao.CheckAvailability(ctx, "userName", "tom", tom.ID)
+	assert.NoError(t, err)
+	assert.True(t, available)
+	available, err = usersDao.CheckAvailability(ctx, "userEmail", "jerry@example.com", 0)
+	assert.NoError(t, err)
+	assert.True(t, available)
+	_, err = usersDao.CheckAvailability(ctx, "nickName", "tom", 0)
+	assert.ErrorIs(t, err, ErrUnknownUniqueField)
+
+	// the seeded role and permission codes
+	assert.Equal(t, &ConflictError{Field: "roleCode"}, NewRolesDao(db, nil).Create(ctx, &model.Roles{RoleName: "x", RoleCode: "super_admin"}))
+	available, err = NewPermissionsDao(db, nil).CheckAvailability(ctx, "code", "user:manage", 0)
+	assert.NoError(t, err)
+	assert.False(t, available)
+}
+
+func Test_translateUniqueError(t *testing.T) {
+	db := newAuditTestDB(t)
+
+	// a value written by a concurrent request is caught by the unique index
+	err := db.Create(&model.Permissions{Name: "x", Code: "user:manage"}).Error
+	assert.Equal(t, &ConflictError{Field: "code"}, translateUniqueError(err, permissionsEntity, permissionsUniqueFields))
+
+	tests := []struct {
+		err   error
+		field string
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'tom' for key 'users.uk_users_user_name'"}, "userName"},
+		{&pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "uk_users_user_email"`}, "userEmail"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, &ConflictError{Field: tt.field}, translateUniqueError(tt.err, usersEntity, usersUniqueFields))
+	}
+
+	otherErr := &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'user_name' at row 1"}
+	assert.Equal(t, error(otherErr), translateUniqueError(otherErr, usersEntity, usersUniqueFields))
+	assert.NoError(t, translateUniqueError(nil, usersEntity, usersUniqueFields))
+}
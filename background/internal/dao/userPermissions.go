@@ -0,0 +1,196 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+	"godemo/internal/model"
+)
+
+var _ UserPermissionsDao = (*userPermissionsDao)(nil)
+
+// UserPermissionsDao defining the dao interface, it resolves the permission codes of a user through
+// user_roles -> role_permissions -> permissions, the result is cached per user. A role has the permissions
+// of its own and of its ancestors. A disabled user has no permissions, and a disabled role contributes none.
+type UserPermissionsDao interface {
+	GetCodesByUserID(ctx context.Context, userID uint64) ([]string, error)
+	DeleteCacheByUserID(ctx context.Context, userID uint64) error
+	DeleteCacheByRoleID(ctx context.Context, roleID uint64) error
+	DeleteCacheByPermissionID(ctx context.Context, permissionID uint64) error
+}
+
+type userPermissionsDao struct {
+	db    *gorm.DB
+	cache cache.UserPermissionsCache // if nil, the cache is not used.
+	sfg   *singleflight.Group        // if cache is nil, the sfg is not used.
+}
+
+// NewUserPermissionsDao creating the dao interface
+func NewUserPermissionsDao(db *gorm.DB, xCache cache.UserPermissionsCache) UserPermissionsDao {
+	if xCache == nil {
+		return &userPermissionsDao{db: db}
+	}
+	return &userPermissionsDao{
+		db:    db,
+		cache: xCache,
+		sfg:   new(singleflight.Group),
+	}
+}
+
+// GetCodesByUserID get the deduplicated permission codes of all roles of the user
+func (d *userPermissionsDao) GetCodesByUserID(ctx context.Context, userID uint64) ([]string, error) {
+	// no cache
+	if d.cache == nil {
+		return d.getCodesFromDB(ctx, userID)
+	}
+
+	// get from cache
+	codes, err := d.cache.Get(ctx, userID)
+	if err == nil {
+		return codes, nil
+	}
+
+	// get from database
+	if errors.Is(err, database.ErrCacheNotFound) {
+		// for the same userID, prevent high concurrent simultaneous access to database
+		val, err, _ := d.sfg.Do(utils.Uint64ToStr(userID), func() (interface{}, error) {
+			codes, err := d.getCodesFromDB(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			// set cache
+			if err = d.cache.Set(ctx, userID, codes, cache.UserPermissionsExpireTime); err != nil {
+				logger.Warn("cache.Set error", logger.Err(err), logger.Any("userID", userID))
+			}
+			return codes, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		codes, _ := val.([]string)
+		return codes, nil
+	}
+
+	return nil, err
+}
+
+func (d *userPermissionsDao) getCodesFromDB(ctx context.Context, userID uint64) ([]string, error) {
+	// soft deleted or disabled roles and users are excluded, a null status is enabled
+	roleIDs := []uint64{}
+	err := d.db.WithContext(ctx).Model(&model.UserRoles{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.user_id = ? AND roles.deleted_at IS NULL AND users.deleted_at IS NULL", userID).
+		Where("(roles.status IS NULL OR roles.status <> ?) AND (users.status IS NULL OR users.status <> ?)",
+			model.StatusDisabled, model.StatusDisabled).
+		Pluck("user_roles.role_id", &roleIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	codes := []string{}
+	if len(roleIDs) == 0 {
+		return codes, nil
+	}
+
+	// each role inherits the permissions of its ancestors
+	h, err := loadRoleHierarchy(ctx, d.db)
+	if err != nil {
+		return nil, err
+	}
+	chainIDs := []uint64{}
+	seen := make(map[uint64]bool)
+	for _, roleID := range roleIDs {
+		for _, id := range h.chain(roleID) {
+			if !seen[id] {
+				seen[id] = true
+				chainIDs = append(chainIDs, id)
+			}
+		}
+	}
+
+	// soft deleted permissions are excluded by gorm
+	err = d.db.WithContext(ctx).Model(&model.Permissions{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id IN (?)", chainIDs).
+		Distinct().
+		Pluck("permissions.code", &codes).Error
+	return codes, err
+}
+
+// DeleteCacheByUserID delete the cached permission codes of the user, call it after the roles or the status of the user are changed
+func (d *userPermissionsDao) DeleteCacheByUserID(ctx context.Context, userID uint64) error {
+	if d.cache == nil {
+		return nil
+	}
+	return d.cache.Del(ctx, userID)
+}
+
+// DeleteCacheByRoleID delete the cached permission codes of all users who have the role or a role that
+// inherits from it, call it after the permissions, the status or the parent of the role are changed
+func (d *userPermissionsDao) DeleteCacheByRoleID(ctx context.Context, roleID uint64) error {
+	return d.deleteCacheByRoleID(ctx, d.db, roleID)
+}
+
+// the users of the role are read with db, which is the transaction of the change that calls it if there is one
+func (d *userPermissionsDao) deleteCacheByRoleID(ctx context.Context, db *gorm.DB, roleID uint64) error {
+	if d.cache == nil {
+		return nil
+	}
+
+	h, err := loadRoleHierarchy(ctx, db)
+	if err != nil {
+		return err
+	}
+	var userIDs []uint64
+	err = db.WithContext(ctx).Model(&model.UserRoles{}).
+		Where("role_id IN (?)", h.descendants(roleID)).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return err
+	}
+
+	return d.cache.MultiDel(ctx, userIDs)
+}
+
+// DeleteCacheByPermissionID delete the cached permission codes of all users who have a role that holds the
+// permission or inherits it, call it after the permission is changed, deleted, restored or purged
+func (d *userPermissionsDao) DeleteCacheByPermissionID(ctx context.Context, permissionID uint64) error {
+	if d.cache == nil {
+		return nil
+	}
+
+	var roleIDs []uint64
+	err := d.db.WithContext(ctx).Model(&model.RolePermissions{}).
+		Where("permission_id = ?", permissionID).
+		Pluck("role_id", &roleIDs).Error
+	if err != nil || len(roleIDs) == 0 {
+		return err
+	}
+	h, err := loadRoleHierarchy(ctx, d.db)
+	if err != nil {
+		return err
+	}
+	descendantIDs := []uint64{}
+	for _, roleID := range roleIDs {
+		descendantIDs = append(descendantIDs, h.descendants(roleID)...)
+	}
+	var userIDs []uint64
+	err = d.db.WithContext(ctx).Model(&model.UserRoles{}).
+		Where("role_id IN (?)", descendantIDs).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return err
+	}
+
+	return d.cache.MultiDel(ctx, userIDs)
+}
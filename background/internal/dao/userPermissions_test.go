@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+	"godemo/internal/model"
+)
+
+func newUserPermissionsDao() *gotest.Dao {
+	// init mock cache
+	c := gotest.NewCache(map[string]interface{}{"1": &[]string{"user:manage"}})
+	c.ICache = cache.NewUserPermissionsCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	})
+
+	// init mock dao
+	d := gotest.NewDao(c, nil)
+	d.IDao = NewUserPermissionsDao(d.DB, c.ICache.(cache.UserPermissionsCache))
+
+	return d
+}
+
+func Test_userPermissionsDao_GetCodesByUserID(t *testing.T) {
+	d := newUserPermissionsDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectQuery("SELECT .*user_roles.*").
+		WithArgs(1, model.StatusDisabled, model.StatusDisabled).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(2))
+	d.SQLMock.ExpectQuery("SELECT .*roles.*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "parent_id"}).
+			AddRow(1, model.StatusEnabled, 0).
+			AddRow(2, model.StatusEnabled, 1))
+	rows := sqlmock.NewRows([]string{"code"}).
+		AddRow("user:manage").
+		AddRow("role:manage")
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*").
+		WithArgs(2, 1). // the role and its parent
+		WillReturnRows(rows)
+
+	codes, err := d.IDao.(UserPermissionsDao).GetCodesByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"user:manage", "role:manage"}, codes)
+
+	// get from cache
+	codes, err = d.IDao.(UserPermissionsDao).GetCodesByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"user:manage", "role:manage"}, codes)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	_, err = d.IDao.(UserPermissionsDao).GetCodesByUserID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_userPermissionsDao_GetCodesByUserID_Status(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	d := NewUserPermissionsDao(db, nil)
+	usersDao := NewUsersDao(db, nil, nil)
+	rolesDao := NewRolesDao(db, nil)
+
+	user := &model.Users{UserName: "tom", Password: "hash", Status: model.StatusEnabled}
+	assert.NoError(t, usersDao.Create(ctx, user))
+	assert.NoError(t, db.Create(&model.UserRoles{UserID: user.ID, RoleID: 1}).Error) // the seeded super admin
+	codes, err := d.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Contains(t, codes, "user:manage")
+
+	// a disabled role contributes no permissions
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: 1, Status: model.StatusDisabled}))
+	codes, err = d.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, codes)
+	assert.NoError(t, rolesDao.UpdateByID(ctx, &model.Roles{ID: 1, Status: model.StatusEnabled}))
+
+	// a disabled user has no permissions
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: user.ID, Status: model.StatusDisabled}))
+	codes, err = d.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, codes)
+
+	// a null status is enabled
+	assert.NoError(t, db.Model(&model.Users{}).Where("id = ?", user.ID).Update("status", nil).Error)
+	codes, err = d.GetCodesByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, codes)
+}
+
+func Test_userPermissionsDao_DeleteCacheByUserID(t *testing.T) {
+	d := newUserPermissionsDao()
+	defer d.Close()
+
+	err := d.Cache.ICache.(cache.UserPermissionsCache).Set(d.Ctx, 1, []string{"user:manage"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.Cache.ICache.(cache.UserPermissionsCache).Get(d.Ctx, 1)
+	assert.Error(t, err)
+
+	// no cache
+	err = NewUserPermissionsDao(d.DB, nil).DeleteCacheByUserID(d.Ctx, 1)
+	assert.NoError(t, err)
+}
+
+func Test_userPermissionsDao_DeleteCacheByRoleID(t *testing.T) {
+	d := newUserPermissionsDao()
+	defer d.Close()
+
+	err := d.Cache.ICache.(cache.UserPermissionsCache).Set(d.Ctx, 1, []string{"user:manage"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.SQLMock.ExpectQuery("SELECT .*roles.*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "parent_id"}).
+			AddRow(1, model.StatusEnabled, 0).
+			AddRow(2, model.StatusEnabled, 1))
+	rows := sqlmock.NewRows([]string{"user_id"}).AddRow(1)
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*").
+		WithArgs(1, 2). // the role and the role that inherits from it
+		WillReturnRows(rows)
+
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByRoleID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.Cache.ICache.(cache.UserPermissionsCache).Get(d.Ctx, 1)
+	assert.Error(t, err)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByRoleID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_userPermissionsDao_DeleteCacheByPermissionID(t *testing.T) {
+	d := newUserPermissionsDao()
+	defer d.Close()
+
+	err := d.Cache.ICache.(cache.UserPermissionsCache).Set(d.Ctx, 1, []string{"user:manage"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.SQLMock.ExpectQuery("SELECT .*role_permissions.*").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(1))
+	d.SQLMock.ExpectQuery("SELECT .*roles.*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "parent_id"}).
+			AddRow(1, model.StatusEnabled, 0).
+			AddRow(2, model.StatusEnabled, 1))
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*").
+		WithArgs(1, 2). // the role that holds the permission and the role that inherits from it
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByPermissionID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.Cache.ICache.(cache.UserPermissionsCache).Get(d.Ctx, 1)
+	assert.Error(t, err)
+
+	// a permission that no role holds
+	d.SQLMock.ExpectQuery("SELECT .*role_permissions.*").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}))
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByPermissionID(d.Ctx, 2)
+	assert.NoError(t, err)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	err = d.IDao.(UserPermissionsDao).DeleteCacheByPermissionID(d.Ctx, 3)
+	assert.Error(t, err)
+}
@@ -8,211 +8,236 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/go-dev-frame/sponge/pkg/logger"
-	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const userRolesEntity = "userRoles"
+
 var _ UserRolesDao = (*userRolesDao)(nil)
 
-// UserRolesDao defining the dao interface
+// UserRolesDao defining the dao interface, the roles of a user are handled as a set
 type UserRolesDao interface {
-	Create(ctx context.Context, table *model.UserRoles) error
+	GetRoleIDsByUserID(ctx context.Context, userID uint64) ([]uint64, error)
+	ReplaceByUserID(ctx context.Context, userID uint64, roleIDs []uint64) error
 	DeleteByUserID(ctx context.Context, userID uint64) error
-	UpdateByUserID(ctx context.Context, table *model.UserRoles) error
-	GetByUserID(ctx context.Context, userID uint64) (*model.UserRoles, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserRoles, int64, error)
 
-	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.UserRoles) (uint64, error)
+	DeleteCacheByUserID(ctx context.Context, userID uint64) error
+
+	ReplaceByTx(ctx context.Context, tx *gorm.DB, userID uint64, roleIDs []uint64) error
 	DeleteByTx(ctx context.Context, tx *gorm.DB, userID uint64) error
-	UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserRoles) error
+	DeleteRoleByTx(ctx context.Context, tx *gorm.DB, roleID uint64) ([]uint64, error)
 }
 
 type userRolesDao struct {
-	db    *gorm.DB
-	cache cache.UserRolesCache // if nil, the cache is not used.
-	sfg   *singleflight.Group  // if cache is nil, the sfg is not used.
+	db          *gorm.DB
+	cache       cache.UserRolesCache // if nil, the cache is not used.
+	sfg         *singleflight.Group  // if cache is nil, the sfg is not used.
+	permissions *userPermissionsDao  // the cached permission codes of the users whose roles are changed are deleted
 }
 
-// NewUserRolesDao creating the dao interface
-func NewUserRolesDao(db *gorm.DB, xCache cache.UserRolesCache) UserRolesDao {
+// NewUserRolesDao creating the dao interface, the cached permission codes in permissionsCache are deleted when
+// the roles of a user change, permissionsCache can be nil
+func NewUserRolesDao(db *gorm.DB, xCache cache.UserRolesCache, permissionsCache cache.UserPermissionsCache) UserRolesDao {
+	permissions := NewUserPermissionsDao(db, permissionsCache).(*userPermissionsDao)
 	if xCache == nil {
-		return &userRolesDao{db: db}
+		return &userRolesDao{db: db, permissions: permissions}
 	}
 	return &userRolesDao{
-		db:    db,
-		cache: xCache,
-		sfg:   new(singleflight.Group),
+		db:          db,
+		cache:       xCache,
+		sfg:         new(singleflight.Group),
+		permissions: permissions,
 	}
 }
 
 func (d *userRolesDao) deleteCache(ctx context.Context, userID uint64) error {
+	var err error
 	if d.cache != nil {
-		return d.cache.Del(ctx, userID)
+		err = d.cache.Del(ctx, userID)
 	}
-	return nil
-}
-
-// Create a new userRoles, insert the record and the userID value is written back to the table
-func (d *userRolesDao) Create(ctx context.Context, table *model.UserRoles) error {
-	return d.db.WithContext(ctx).Create(table).Error
-}
-
-// DeleteByUserID delete a userRoles by userID
-func (d *userRolesDao) DeleteByUserID(ctx context.Context, userID uint64) error {
-	err := d.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserRoles{}).Error
-	if err != nil {
-		return err
+	// the permission codes of a user are the union of the codes of its roles
+	if e := d.permissions.DeleteCacheByUserID(ctx, userID); e != nil {
+		logger.Warn("DeleteCacheByUserID error", logger.Err(e), logger.Any("userID", userID))
+		err = e
 	}
-
-	// delete cache
-	_ = d.deleteCache(ctx, userID)
-
-	return nil
-}
-
-// UpdateByUserID update a userRoles by userID
-func (d *userRolesDao) UpdateByUserID(ctx context.Context, table *model.UserRoles) error {
-	err := d.updateDataByUserID(ctx, d.db, table)
-
-	// delete cache
-	_ = d.deleteCache(ctx, table.UserID)
-
 	return err
 }
 
-func (d *userRolesDao) updateDataByUserID(ctx context.Context, db *gorm.DB, table *model.UserRoles) error {
-	if table.UserID < 1 {
-		return errors.New("userID cannot be 0")
-	}
-
-	update := map[string]interface{}{}
-
-	if table.UserID != 0 {
-		update["user_id"] = table.UserID
-	}
-	if table.RoleID != 0 {
-		update["role_id"] = table.RoleID
-	}
-
-	return db.WithContext(ctx).Model(table).Updates(update).Error
-}
-
-// GetByUserID get a userRoles by userID
-func (d *userRolesDao) GetByUserID(ctx context.Context, userID uint64) (*model.UserRoles, error) {
+// GetRoleIDsByUserID get all the role ids of the user, an empty slice is returned if there is none
+func (d *userRolesDao) GetRoleIDsByUserID(ctx context.Context, userID uint64) ([]uint64, error) {
 	// no cache
 	if d.cache == nil {
-		record := &model.UserRoles{}
-		err := d.db.WithContext(ctx).Where("user_id = ?", userID).First(record).Error
-		return record, err
+		return d.getRoleIDsFromDB(ctx, userID)
 	}
 
 	// get from cache
-	record, err := d.cache.Get(ctx, userID)
+	roleIDs, err := d.cache.Get(ctx, userID)
 	if err == nil {
-		return record, nil
+		return roleIDs, nil
 	}
 
 	// get from database
 	if errors.Is(err, database.ErrCacheNotFound) {
 		// for the same userID, prevent high concurrent simultaneous access to database
 		val, err, _ := d.sfg.Do(utils.Uint64ToStr(userID), func() (interface{}, error) {
-
-			table := &model.UserRoles{}
-			err = d.db.WithContext(ctx).Where("user_id = ?", userID).First(table).Error
+			roleIDs, err := d.getRoleIDsFromDB(ctx, userID)
 			if err != nil {
-				// set placeholder cache to prevent cache penetration, default expiration time 10 minutes
-				if errors.Is(err, database.ErrRecordNotFound) {
-					if err = d.cache.SetPlaceholder(ctx, userID); err != nil {
-						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("userID", userID))
-					}
-					return nil, database.ErrRecordNotFound
-				}
 				return nil, err
 			}
 			// set cache
-			if err = d.cache.Set(ctx, userID, table, cache.UserRolesExpireTime); err != nil {
+			if err = d.cache.Set(ctx, userID, roleIDs, cache.UserRolesExpireTime); err != nil {
 				logger.Warn("cache.Set error", logger.Err(err), logger.Any("userID", userID))
 			}
-			return table, nil
+			return roleIDs, nil
 		})
 		if err != nil {
 			return nil, err
 		}
-		table, ok := val.(*model.UserRoles)
-		if !ok {
-			return nil, database.ErrRecordNotFound
-		}
-		return table, nil
-	}
-
-	if d.cache.IsPlaceholderErr(err) {
-		return nil, database.ErrRecordNotFound
+		roleIDs, _ := val.([]uint64)
+		return roleIDs, nil
 	}
 
 	return nil, err
 }
 
-// GetByColumns get a paginated list of userRoles by custom conditions.
-// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *userRolesDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserRoles, int64, error) {
-	if params.Sort == "" {
-		params.Sort = "-user_id"
+func (d *userRolesDao) getRoleIDsFromDB(ctx context.Context, userID uint64) ([]uint64, error) {
+	roleIDs := []uint64{}
+	err := d.db.WithContext(ctx).Model(&model.UserRoles{}).
+		Where("user_id = ?", userID).
+		Order("role_id").
+		Pluck("role_id", &roleIDs).Error
+	return roleIDs, err
+}
+
+// ReplaceByUserID replace all the roles of the user with roleIDs in one transaction, an empty roleIDs removes all of them
+func (d *userRolesDao) ReplaceByUserID(ctx context.Context, userID uint64, roleIDs []uint64) error {
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return d.replaceChange(ctx, userID, roleIDs).runByTx(ctx, tx)
+	})
+
+	// delete cache
+	_ = d.deleteCache(ctx, userID)
+
+	return err
+}
+
+func (d *userRolesDao) replace(ctx context.Context, tx *gorm.DB, userID uint64, roleIDs []uint64) error {
+	if userID < 1 {
+		return errors.New("userID cannot be 0")
 	}
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.UserRolesColumnNames))
+
+	err := tx.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserRoles{}).Error
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return err
 	}
 
-	var total int64
-	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.UserRoles{}).Where(queryStr, args...).Count(&total).Error
-		if err != nil {
-			return nil, 0, err
-		}
-		if total == 0 {
-			return nil, total, nil
+	records := make([]*model.UserRoles, 0, len(roleIDs))
+	seen := make(map[uint64]struct{}, len(roleIDs))
+	for _, id := range roleIDs {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
 		}
+		seen[id] = struct{}{}
+		records = append(records, &model.UserRoles{UserID: userID, RoleID: id})
 	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Create(&records).Error
+}
 
-	records := []*model.UserRoles{}
-	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+// DeleteByUserID delete all the roles of the user
+func (d *userRolesDao) DeleteByUserID(ctx context.Context, userID uint64) error {
+	err := d.deleteChange(ctx, userID).run(ctx, d.db)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 
-	return records, total, err
+	// delete cache
+	_ = d.deleteCache(ctx, userID)
+
+	return nil
+}
+
+// DeleteCacheByUserID delete the cached roles and permission codes of the user, call it after the transaction
+// of ReplaceByTx, DeleteByTx or DeleteRoleByTx commits, as a read before the commit would cache the old roles again
+func (d *userRolesDao) DeleteCacheByUserID(ctx context.Context, userID uint64) error {
+	return d.deleteCache(ctx, userID)
 }
 
-// CreateByTx create a record in the database using the provided transaction
-func (d *userRolesDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.UserRoles) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
-	return table.UserID, err
+// ReplaceByTx replace all the roles of the user using the provided transaction,
+// call DeleteCacheByUserID after the transaction commits
+func (d *userRolesDao) ReplaceByTx(ctx context.Context, tx *gorm.DB, userID uint64, roleIDs []uint64) error {
+	return d.replaceChange(ctx, userID, roleIDs).runByTx(ctx, tx)
 }
 
-// DeleteByTx delete a record by userID in the database using the provided transaction
+// DeleteByTx delete all the roles of the user using the provided transaction,
+// call DeleteCacheByUserID after the transaction commits
 func (d *userRolesDao) DeleteByTx(ctx context.Context, tx *gorm.DB, userID uint64) error {
-	err := tx.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserRoles{}).Error
+	return d.deleteChange(ctx, userID).runByTx(ctx, tx)
+}
+
+// DeleteRoleByTx remove the role from all the users who have it using the provided transaction, such as
+// when the role is purged, it returns the ids of the users, call DeleteCacheByUserID for each of them after
+// the transaction commits
+func (d *userRolesDao) DeleteRoleByTx(ctx context.Context, tx *gorm.DB, roleID uint64) ([]uint64, error) {
+	var userIDs []uint64
+	err := tx.WithContext(ctx).Model(&model.UserRoles{}).
+		Where("role_id = ?", roleID).
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// delete cache
-	_ = d.deleteCache(ctx, userID)
+	for _, userID := range userIDs {
+		err = d.newChange(audit.ActionUpdate, userID, func(tx *gorm.DB) error {
+			return tx.WithContext(ctx).Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&model.UserRoles{}).Error
+		}).runByTx(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	return userIDs, nil
 }
 
-// UpdateByTx update a record by userID in the database using the provided transaction
-func (d *userRolesDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserRoles) error {
-	err := d.updateDataByUserID(ctx, tx, table)
+// the roles of a user are written to the audit log as a change of its roleIDs field
 
-	// delete cache
-	_ = d.deleteCache(ctx, table.UserID)
+func (d *userRolesDao) replaceChange(ctx context.Context, userID uint64, roleIDs []uint64) *auditedChange {
+	return d.newChange(audit.ActionUpdate, userID, func(tx *gorm.DB) error {
+		return d.replace(ctx, tx, userID, roleIDs)
+	})
+}
 
-	return err
+func (d *userRolesDao) deleteChange(ctx context.Context, userID uint64) *auditedChange {
+	return d.newChange(audit.ActionDelete, userID, func(tx *gorm.DB) error {
+		return tx.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserRoles{}).Error
+	})
+}
+
+func (d *userRolesDao) newChange(action string, userID uint64, apply func(tx *gorm.DB) error) *auditedChange {
+	return &auditedChange{
+		entity: userRolesEntity,
+		action: action,
+		id:     userID,
+		load: func(ctx context.Context, tx *gorm.DB, userID uint64) (interface{}, error) {
+			roleIDs := []uint64{}
+			err := tx.WithContext(ctx).Model(&model.UserRoles{}).
+				Where("user_id = ?", userID).
+				Order("role_id").
+				Pluck("role_id", &roleIDs).Error
+			return map[string][]uint64{"roleIDs": roleIDs}, err
+		},
+		apply: func(tx *gorm.DB) (uint64, error) {
+			return userID, apply(tx)
+		},
+	}
 }
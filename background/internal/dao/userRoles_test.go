@@ -0,0 +1,239 @@
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+)
+
+func newUserRolesDao() *gotest.Dao {
+	// init mock cache
+	c := gotest.NewCache(map[string]interface{}{"1": &[]uint64{1, 2}})
+	c.ICache = cache.NewUserRolesCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	})
+
+	// init mock dao
+	d := gotest.NewDao(c, nil)
+	d.IDao = NewUserRolesDao(d.DB, c.ICache.(cache.UserRolesCache), nil)
+
+	return d
+}
+
+func Test_userRolesDao_GetRoleIDsByUserID(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	rows := sqlmock.NewRows([]string{"role_id"}).
+		AddRow(1).
+		AddRow(2)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	ids, err := d.IDao.(UserRolesDao).GetRoleIDsByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids)
+
+	// get from cache
+	ids, err = d.IDao.(UserRolesDao).GetRoleIDsByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	_, err = d.IDao.(UserRolesDao).GetRoleIDsByUserID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_userRolesDao_ReplaceByUserID(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WithArgs(1, 2, 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	// duplicate and zero ids are ignored
+	err := d.IDao.(UserRolesDao).ReplaceByUserID(d.Ctx, 1, []uint64{2, 3, 2, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// empty set, only delete
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(UserRolesDao).ReplaceByUserID(d.Ctx, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// insert error, rollback
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectRollback()
+
+	err = d.IDao.(UserRolesDao).ReplaceByUserID(d.Ctx, 1, []uint64{2})
+	assert.Error(t, err)
+
+	// zero id error
+	err = d.IDao.(UserRolesDao).ReplaceByUserID(d.Ctx, 0, []uint64{2})
+	assert.Error(t, err)
+}
+
+func Test_userRolesDao_DeleteByUserID(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(UserRolesDao).DeleteByUserID(d.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// error test
+	err = d.IDao.(UserRolesDao).DeleteByUserID(d.Ctx, 2)
+	assert.Error(t, err)
+}
+
+func Test_userRolesDao_ReplaceByTx(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectExec("INSERT INTO .*").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	d.SQLMock.ExpectCommit()
+
+	tx := d.DB.Begin()
+	err := d.IDao.(UserRolesDao).ReplaceByTx(d.Ctx, tx, 1, []uint64{2})
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	tx.Commit()
+}
+
+func Test_userRolesDao_DeleteByTx(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	d.SQLMock.ExpectCommit()
+
+	tx := d.DB.Begin()
+	err := d.IDao.(UserRolesDao).DeleteByTx(d.Ctx, tx, 1)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	tx.Commit()
+}
+
+func Test_userRolesDao_DeleteRoleByTx(t *testing.T) {
+	d := newUserRolesDao()
+	defer d.Close()
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	d.SQLMock.ExpectCommit()
+
+	tx := d.DB.Begin()
+	userIDs, err := d.IDao.(UserRolesDao).DeleteRoleByTx(d.Ctx, tx, 2)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	tx.Commit()
+	assert.Equal(t, []uint64{1}, userIDs)
+}
+
+func Test_userRolesDao_DeletePermissionsCache(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	// the memory cache is shared by the process, use user ids that other tests do not use
+	permissionsCache := cache.NewUserPermissionsCache(&database.CacheType{CType: "memory"})
+	userRolesDao := NewUserRolesDao(db, nil, permissionsCache)
+	isCached := func(userID uint64) bool {
+		_, err := permissionsCache.Get(ctx, userID)
+		return err == nil
+	}
+
+	// the writes without and with a transaction delete the cached permission codes of the user
+	assert.NoError(t, permissionsCache.Set(ctx, 9101, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, userRolesDao.ReplaceByUserID(ctx, 9101, []uint64{2}))
+	assert.False(t, isCached(9101))
+
+	// a write with a transaction keeps the cache until DeleteCacheByUserID is called after the commit
+	assert.NoError(t, permissionsCache.Set(ctx, 9102, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return userRolesDao.ReplaceByTx(ctx, tx, 9102, []uint64{2})
+	}))
+	assert.True(t, isCached(9102))
+	assert.NoError(t, userRolesDao.DeleteCacheByUserID(ctx, 9102))
+	assert.False(t, isCached(9102))
+
+	assert.NoError(t, permissionsCache.Set(ctx, 9101, []string{"user:manage"}, time.Minute))
+	assert.NoError(t, userRolesDao.DeleteByUserID(ctx, 9101))
+	assert.False(t, isCached(9101))
+
+	assert.NoError(t, permissionsCache.Set(ctx, 9102, []string{"user:manage"}, time.Minute))
+	userIDs, err := userRolesDao.DeleteRoleByTx(ctx, db, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{9102}, userIDs)
+	for _, userID := range userIDs {
+		assert.NoError(t, userRolesDao.DeleteCacheByUserID(ctx, userID))
+	}
+	assert.False(t, isCached(9102))
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"maps"
 
 	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
@@ -11,20 +12,37 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
 )
 
+// the entity name of the audit log
+const usersEntity = "users"
+
 var _ UsersDao = (*usersDao)(nil)
 
+// ErrTwoFactorCodeUsed the totp code or the recovery code is already used, such as by a concurrent request
+var ErrTwoFactorCodeUsed = errors.New("two-factor code is already used")
+
 // UsersDao defining the dao interface
 type UsersDao interface {
 	Create(ctx context.Context, table *model.Users) error
-	DeleteByID(ctx context.Context, id uint64) error
-	UpdateByID(ctx context.Context, table *model.Users) error
-	GetByID(ctx context.Context, id uint64) (*model.Users, error)
-	GetByColumns(ctx context.Context, params *query.Params) ([]*model.Users, int64, error)
+	DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error
+	UpdateByID(ctx context.Context, table *model.Users, opts ...UpdateOption) error
+	PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Users, error)
+	GetByUserName(ctx context.Context, userName string) (*model.Users, error)
+	GetByUserEmail(ctx context.Context, userEmail string) (*model.Users, error)
+	GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Users, int64, error)
+	RestoreByID(ctx context.Context, id uint64) error
+	PurgeByID(ctx context.Context, id uint64) error
+	RehashPassword(ctx context.Context, id uint64, stored string, hashed string) error
+	UpdateTwoFactor(ctx context.Context, table *model.Users) error
+	UseTOTPStep(ctx context.Context, id uint64, step uint64) error
+	UseRecoveryCode(ctx context.Context, id uint64, codes string, remaining string) error
+	CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error)
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Users) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
@@ -32,20 +50,23 @@ type UsersDao interface {
 }
 
 type usersDao struct {
-	db    *gorm.DB
-	cache cache.UsersCache    // if nil, the cache is not used.
-	sfg   *singleflight.Group // if cache is nil, the sfg is not used.
+	db         *gorm.DB
+	cache      cache.UsersCache    // if nil, the cache is not used.
+	sfg        *singleflight.Group // if cache is nil, the sfg is not used.
+	tokenCache cache.TokenCache    // if nil, the tokens of the user are not revoked.
 }
 
-// NewUsersDao creating the dao interface
-func NewUsersDao(db *gorm.DB, xCache cache.UsersCache) UsersDao {
+// NewUsersDao creating the dao interface, the tokens issued to a user are revoked in tokenCache
+// when the user is deleted or its password or status is changed.
+func NewUsersDao(db *gorm.DB, xCache cache.UsersCache, tokenCache cache.TokenCache) UsersDao {
 	if xCache == nil {
-		return &usersDao{db: db}
+		return &usersDao{db: db, tokenCache: tokenCache}
 	}
 	return &usersDao{
-		db:    db,
-		cache: xCache,
-		sfg:   new(singleflight.Group),
+		db:         db,
+		cache:      xCache,
+		sfg:        new(singleflight.Group),
+		tokenCache: tokenCache,
 	}
 }
 
@@ -56,39 +77,93 @@ func (d *usersDao) deleteCache(ctx context.Context, id uint64) error {
 	return nil
 }
 
+// revoke the tokens of the user, the user must login again
+func (d *usersDao) revokeTokens(ctx context.Context, id uint64) {
+	if d.tokenCache == nil {
+		return
+	}
+	if err := d.tokenCache.DelUserFamilies(ctx, id); err != nil {
+		logger.Warn("DelUserFamilies error", logger.Err(err), logger.Any("userID", id))
+	}
+}
+
+// the tokens are revoked when the password of the user changes or the status moves to a value that is
+// not active, stored is the record before the update. Writing the same status again, such as an admin
+// UI that always sends it, does not log the user out.
+func needRevokeTokens(stored *model.Users, update map[string]interface{}) bool {
+	if password, ok := update["password"]; ok && password != stored.Password {
+		return true
+	}
+	if status, ok := update["status"].(string); ok && status != stored.Status {
+		return status != model.StatusEnabled && status != ""
+	}
+	return false
+}
+
 // Create a new users, insert the record and the id value is written back to the table
 func (d *usersDao) Create(ctx context.Context, table *model.Users) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	return d.createChange(ctx, table).run(ctx, d.db)
 }
 
-// DeleteByID delete a users by id
-func (d *usersDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Users{}).Error
+// DeleteByID soft delete a users by id, the deleted_at column is set
+func (d *usersDao) DeleteByID(ctx context.Context, id uint64, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	err := d.deleteChange(ctx, id, o).run(ctx, d.db)
 	if err != nil {
 		return err
 	}
 
 	// delete cache
 	_ = d.deleteCache(ctx, id)
+	d.revokeTokens(ctx, id)
 
 	return nil
 }
 
 // UpdateByID update a users by id, support partial update
-func (d *usersDao) UpdateByID(ctx context.Context, table *model.Users) error {
-	err := d.updateDataByID(ctx, d.db, table)
+func (d *usersDao) UpdateByID(ctx context.Context, table *model.Users, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	var revoke bool
+	err := d.updateChange(ctx, table, o, &revoke).run(ctx, d.db)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
 
+	if err == nil && revoke {
+		d.revokeTokens(ctx, table.ID)
+	}
+
 	return err
 }
 
-func (d *usersDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Users) error {
-	if table.ID < 1 {
-		return errors.New("id cannot be 0")
+// PatchByID update the columns of a users by id, unlike UpdateByID the zero values are written too.
+// Only the columns of model.UsersColumnNames can be patched, the password and two-factor columns have their own methods.
+func (d *usersDao) PatchByID(ctx context.Context, id uint64, columns map[string]interface{}, opts ...UpdateOption) error {
+	o := defaultUpdateOptions()
+	o.apply(opts...)
+	if err := checkPatchColumns(columns, model.UsersColumnNames, "totp_enabled"); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
 	}
 
+	var revoke bool
+	err := d.patchChange(ctx, id, columns, o, &revoke).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	if err == nil && revoke {
+		d.revokeTokens(ctx, id)
+	}
+
+	return err
+}
+
+func (d *usersDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model.Users, o *updateOptions) (bool, error) {
 	update := map[string]interface{}{}
 
 	if table.UserName != "" {
@@ -113,15 +188,89 @@ func (d *usersDao) updateDataByID(ctx context.Context, db *gorm.DB, table *model
 		update["status"] = table.Status
 	}
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return d.updateColumnsByID(ctx, db, table.ID, update, o)
+}
+
+// write the columns of a users, the zero values in update are written too and the version is increased.
+// It returns whether the tokens of the user must be revoked, which is decided by the stored record.
+func (d *usersDao) updateColumnsByID(ctx context.Context, db *gorm.DB, id uint64, update map[string]interface{}, o *updateOptions) (bool, error) {
+	if id < 1 {
+		return false, errors.New("id cannot be 0")
+	}
+
+	if err := checkUnique(ctx, db, usersEntity, usersUniqueFields, update, id); err != nil {
+		return false, err
+	}
+
+	revoke := false
+	_, hasPassword := update["password"]
+	_, hasStatus := update["status"]
+	if hasPassword || hasStatus {
+		stored := &model.Users{}
+		err := db.WithContext(ctx).Select("password", "status").Where("id = ?", id).Limit(1).Find(stored).Error
+		if err != nil {
+			return false, err
+		}
+		revoke = needRevokeTokens(stored, update)
+	}
+
+	update = maps.Clone(update)
+	update["version"] = gorm.Expr("version + 1")
+	result := o.scope(db.WithContext(ctx).Model(&model.Users{ID: id})).Updates(update)
+	if result.Error != nil {
+		return false, translateUniqueError(result.Error, usersEntity, usersUniqueFields)
+	}
+	return revoke, o.checkRowsAffected(result.RowsAffected)
+}
+
+// the changes that are written to the audit log, they are shared by the methods with and without a transaction
+
+func (d *usersDao) createChange(ctx context.Context, table *model.Users) *auditedChange {
+	return newAuditedChange[model.Users](usersEntity, audit.ActionCreate, 0, func(tx *gorm.DB) (uint64, error) {
+		if err := checkUnique(ctx, tx, usersEntity, usersUniqueFields, map[string]interface{}{"user_name": table.UserName, "user_email": table.UserEmail}, 0); err != nil {
+			return 0, err
+		}
+		err := tx.WithContext(ctx).Create(table).Error
+		return table.ID, translateUniqueError(err, usersEntity, usersUniqueFields)
+	})
+}
+
+func (d *usersDao) deleteChange(ctx context.Context, id uint64, o *updateOptions) *auditedChange {
+	return newAuditedChange[model.Users](usersEntity, audit.ActionDelete, id, func(tx *gorm.DB) (uint64, error) {
+		result := o.scope(tx.WithContext(ctx).Where("id = ?", id)).Delete(&model.Users{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return id, o.checkRowsAffected(result.RowsAffected)
+	})
+}
+
+// revoke is set to whether the tokens of the user must be revoked after the change
+func (d *usersDao) updateChange(ctx context.Context, table *model.Users, o *updateOptions, revoke *bool) *auditedChange {
+	return newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		var err error
+		*revoke, err = d.updateDataByID(ctx, tx, table, o)
+		return table.ID, err
+	})
+}
+
+func (d *usersDao) patchChange(ctx context.Context, id uint64, columns map[string]interface{}, o *updateOptions, revoke *bool) *auditedChange {
+	return newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		var err error
+		*revoke, err = d.updateColumnsByID(ctx, tx, id, columns, o)
+		return id, err
+	})
 }
 
 // GetByID get a users by id
-func (d *usersDao) GetByID(ctx context.Context, id uint64) (*model.Users, error) {
-	// no cache
-	if d.cache == nil {
+func (d *usersDao) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (*model.Users, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	// no cache, or the soft deleted records are required
+	if d.cache == nil || o.includeDeleted {
 		record := &model.Users{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		err := o.scope(d.db.WithContext(ctx)).Where("id = ?", id).First(record).Error
 		return record, err
 	}
 
@@ -170,17 +319,40 @@ func (d *usersDao) GetByID(ctx context.Context, id uint64) (*model.Users, error)
 	return nil, err
 }
 
+// GetByUserName get a users by user name, the user name is used as the login account
+func (d *usersDao) GetByUserName(ctx context.Context, userName string) (*model.Users, error) {
+	record := &model.Users{}
+	err := d.db.WithContext(ctx).Where("user_name = ?", userName).First(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetByUserEmail get a users by email, a non-empty email belongs to at most one user
+func (d *usersDao) GetByUserEmail(ctx context.Context, userEmail string) (*model.Users, error) {
+	record := &model.Users{}
+	err := d.db.WithContext(ctx).Where("user_email = ?", userEmail).First(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
 // GetByColumns get a paginated list of userss by custom conditions.
 // For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-func (d *usersDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.Users, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(model.UsersColumnNames))
+func (d *usersDao) GetByColumns(ctx context.Context, params *query.Params, opts ...QueryOption) ([]*model.Users, int64, error) {
+	o := defaultQueryOptions()
+	o.apply(opts...)
+
+	queryStr, args, err := convertQueryParams(params, model.UsersColumnNames)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
 	}
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.Users{}).Where(queryStr, args...).Count(&total).Error
+		err = o.scope(d.db.WithContext(ctx)).Model(&model.Users{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -191,7 +363,7 @@ func (d *usersDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 
 	records := []*model.Users{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = o.scope(d.db.WithContext(ctx)).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -199,31 +371,189 @@ func (d *usersDao) GetByColumns(ctx context.Context, params *query.Params) ([]*m
 	return records, total, err
 }
 
+// RestoreByID restore a soft deleted users by id
+func (d *usersDao) RestoreByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionRestore, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().Model(&model.Users{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache, a placeholder may have been cached for the deleted record
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// PurgeByID permanently delete a soft deleted users by id, a record that is not soft deleted is not purged
+func (d *usersDao) PurgeByID(ctx context.Context, id uint64) error {
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionPurge, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Delete(&model.Users{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, database.ErrRecordNotFound
+		}
+		return id, nil
+	}).run(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return nil
+}
+
+// RehashPassword replace the stored password of a users by a new hash of the same password, such as when a
+// legacy plaintext password is upgraded at login. It is not a change of the password, so the tokens of the user
+// are not revoked, and it is only written if stored is still the password, so that a concurrent change is kept.
+func (d *usersDao) RehashPassword(ctx context.Context, id uint64, stored string, hashed string) error {
+	if id < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		return id, tx.WithContext(ctx).Model(&model.Users{}).
+			Where("id = ? AND password = ?", id, stored).
+			Updates(map[string]interface{}{
+				"password": hashed,
+				"version":  gorm.Expr("version + 1"),
+			}).Error
+	}).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+// UpdateTwoFactor update the totp secret, totp enabled, recovery codes and the step of the last accepted totp code
+// of a users by id, unlike UpdateByID the zero values are written too, so that two-factor authentication can be reset.
+func (d *usersDao) UpdateTwoFactor(ctx context.Context, table *model.Users) error {
+	if table.ID < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, table.ID, func(tx *gorm.DB) (uint64, error) {
+		return table.ID, tx.WithContext(ctx).Model(table).Updates(map[string]interface{}{
+			"totp_secret":         table.TotpSecret,
+			"totp_enabled":        table.TotpEnabled,
+			"totp_recovery_codes": table.TotpRecoveryCodes,
+			"totp_last_step":      table.TotpLastStep,
+			"version":             gorm.Expr("version + 1"),
+		}).Error
+	}).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, table.ID)
+
+	return err
+}
+
+// UseTOTPStep record the time step of an accepted totp code, it returns ErrTwoFactorCodeUsed if a code of
+// the step or a later one is already accepted. The check and the write are one conditional update, so that
+// concurrent requests cannot accept the same code twice.
+func (d *usersDao) UseTOTPStep(ctx context.Context, id uint64, step uint64) error {
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Model(&model.Users{}).
+			Where("id = ? AND totp_last_step < ?", id, step).
+			Updates(map[string]interface{}{
+				"totp_last_step": step,
+				"version":        gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, ErrTwoFactorCodeUsed
+		}
+		return id, nil
+	}).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+// UseRecoveryCode replace the recovery codes of a users by the remaining codes after one is used, codes are
+// the stored codes the remaining codes were computed from. It returns ErrTwoFactorCodeUsed if the stored codes
+// have changed, such as when a concurrent request used a code, so that a code cannot be used twice.
+func (d *usersDao) UseRecoveryCode(ctx context.Context, id uint64, codes string, remaining string) error {
+	err := newAuditedChange[model.Users](usersEntity, audit.ActionUpdate, id, func(tx *gorm.DB) (uint64, error) {
+		result := tx.WithContext(ctx).Model(&model.Users{}).
+			Where("id = ? AND totp_recovery_codes = ?", id, codes).
+			Updates(map[string]interface{}{
+				"totp_recovery_codes": remaining,
+				"version":             gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, ErrTwoFactorCodeUsed
+		}
+		return id, nil
+	}).run(ctx, d.db)
+
+	// delete cache
+	_ = d.deleteCache(ctx, id)
+
+	return err
+}
+
+// CheckAvailability check that the value of a unique field, such as userName, is not used by another record
+// than excludeID, the soft deleted records are included. It returns ErrUnknownUniqueField if field is not unique.
+func (d *usersDao) CheckAvailability(ctx context.Context, field string, value string, excludeID uint64) (bool, error) {
+	return checkUniqueField(ctx, d.db, usersEntity, usersUniqueFields, field, value, excludeID)
+}
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *usersDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.Users) (uint64, error) {
-	err := tx.WithContext(ctx).Create(table).Error
+	err := d.createChange(ctx, table).runByTx(ctx, tx)
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx soft delete a record by id in the database using the provided transaction
 func (d *usersDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
-	err := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.Users{}).Error
+	err := d.deleteChange(ctx, id, defaultUpdateOptions()).runByTx(ctx, tx)
 	if err != nil {
 		return err
 	}
 
 	// delete cache
 	_ = d.deleteCache(ctx, id)
+	d.revokeTokens(ctx, id)
 
 	return nil
 }
 
 // UpdateByTx update a record by id in the database using the provided transaction
 func (d *usersDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.Users) error {
-	err := d.updateDataByID(ctx, tx, table)
+	var revoke bool
+	err := d.updateChange(ctx, table, defaultUpdateOptions(), &revoke).runByTx(ctx, tx)
 
 	// delete cache
 	_ = d.deleteCache(ctx, table.ID)
 
+	if err == nil && revoke {
+		d.revokeTokens(ctx, table.ID)
+	}
+
 	return err
 }
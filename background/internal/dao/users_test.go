@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-dev-frame/sponge/pkg/gotest"
@@ -10,6 +11,7 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/utils"
 	"github.com/stretchr/testify/assert"
 
+	"godemo/internal/audit"
 	"godemo/internal/cache"
 	"godemo/internal/database"
 	"godemo/internal/model"
@@ -32,7 +34,7 @@ func newUsersDao() *gotest.Dao {
 
 	// init mock dao
 	d := gotest.NewDao(c, testData)
-	d.IDao = NewUsersDao(d.DB, c.ICache.(cache.UsersCache))
+	d.IDao = NewUsersDao(d.DB, c.ICache.(cache.UsersCache), nil)
 
 	return d
 }
@@ -58,11 +60,11 @@ func Test_usersDao_DeleteByID(t *testing.T) {
 	d := newUsersDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Users)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -76,6 +78,60 @@ func Test_usersDao_DeleteByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_usersDao_RestoreByID(t *testing.T) {
+	d := newUsersDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Users)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(UsersDao).RestoreByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(UsersDao).RestoreByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
+func Test_usersDao_PurgeByID(t *testing.T) {
+	d := newUsersDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Users)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(UsersDao).PurgeByID(d.Ctx, testData.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not soft deleted error
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+
+	err = d.IDao.(UsersDao).PurgeByID(d.Ctx, testData.ID)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+}
+
 func Test_usersDao_UpdateByID(t *testing.T) {
 	d := newUsersDao()
 	defer d.Close()
@@ -98,6 +154,152 @@ func Test_usersDao_UpdateByID(t *testing.T) {
 
 }
 
+func Test_needRevokeTokens(t *testing.T) {
+	stored := &model.Users{Password: "hash", Status: model.StatusEnabled}
+
+	// the same values are written again
+	assert.False(t, needRevokeTokens(stored, map[string]interface{}{"nick_name": "Tom", "status": model.StatusEnabled}))
+	assert.False(t, needRevokeTokens(stored, map[string]interface{}{"password": "hash"}))
+
+	// the password is changed, or the status moves to disabled
+	assert.True(t, needRevokeTokens(stored, map[string]interface{}{"password": "new hash"}))
+	assert.True(t, needRevokeTokens(stored, map[string]interface{}{"status": model.StatusDisabled}))
+
+	// the status moves back to enabled
+	disabled := &model.Users{Password: "hash", Status: model.StatusDisabled}
+	assert.False(t, needRevokeTokens(disabled, map[string]interface{}{"status": model.StatusEnabled}))
+}
+
+func Test_usersDao_UpdateByID_RevokeTokens(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	tokenCache := cache.NewTokenCache(&database.CacheType{CType: "memory"})
+	usersDao := NewUsersDao(db, nil, tokenCache)
+
+	user := &model.Users{UserName: "revoke_tokens", Password: "hash", Status: model.StatusEnabled}
+	assert.NoError(t, usersDao.Create(ctx, user))
+	login := func() string {
+		family := &cache.TokenFamily{ID: "revoke_tokens_" + utils.Uint64ToStr(user.Version), UserID: user.ID, UserName: user.UserName}
+		assert.NoError(t, tokenCache.CreateFamily(ctx, family, "token", time.Hour))
+		user.Version++
+		return family.ID
+	}
+	isActive := func(familyID string) bool {
+		ok, err := tokenCache.ExistsFamily(ctx, familyID)
+		assert.NoError(t, err)
+		return ok
+	}
+
+	// the status is sent with the other fields but not changed
+	familyID := login()
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: user.ID, NickName: "Tom", Status: model.StatusEnabled}))
+	assert.True(t, isActive(familyID))
+	assert.NoError(t, usersDao.PatchByID(ctx, user.ID, map[string]interface{}{"nick_name": "Tom", "status": model.StatusEnabled}))
+	assert.True(t, isActive(familyID))
+
+	// the password is changed
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: user.ID, Password: "new hash"}))
+	assert.False(t, isActive(familyID))
+
+	// the status moves to disabled, and back to enabled
+	familyID = login()
+	assert.NoError(t, usersDao.PatchByID(ctx, user.ID, map[string]interface{}{"status": model.StatusDisabled}))
+	assert.False(t, isActive(familyID))
+	familyID = login()
+	assert.NoError(t, usersDao.UpdateByID(ctx, &model.Users{ID: user.ID, Status: model.StatusEnabled}))
+	assert.True(t, isActive(familyID))
+}
+
+func Test_usersDao_RehashPassword(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	tokenCache := cache.NewTokenCache(&database.CacheType{CType: "memory"})
+	usersDao := NewUsersDao(db, nil, tokenCache)
+
+	user := &model.Users{UserName: "rehash_password", Password: "legacy"}
+	assert.NoError(t, usersDao.Create(ctx, user))
+	family := &cache.TokenFamily{ID: "rehash_password", UserID: user.ID, UserName: user.UserName}
+	assert.NoError(t, tokenCache.CreateFamily(ctx, family, "token", time.Hour))
+
+	assert.NoError(t, usersDao.RehashPassword(ctx, user.ID, "legacy", "hash"))
+	record, err := usersDao.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash", record.Password)
+	assert.Equal(t, user.Version+1, record.Version)
+	// the sessions of the user are kept
+	ok, err := tokenCache.ExistsFamily(ctx, family.ID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the password has been changed since it was read
+	assert.NoError(t, usersDao.RehashPassword(ctx, user.ID, "legacy", "other hash"))
+	record, err = usersDao.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash", record.Password)
+}
+
+func Test_usersDao_UpdateTwoFactor(t *testing.T) {
+	d := newUsersDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Users)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(false, 0, "", "", d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+
+	err := d.IDao.(UsersDao).UpdateTwoFactor(d.Ctx, &model.Users{ID: testData.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// zero id error
+	err = d.IDao.(UsersDao).UpdateTwoFactor(d.Ctx, &model.Users{})
+	assert.Error(t, err)
+}
+
+func Test_usersDao_UseTOTPStep(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	usersDao := NewUsersDao(db, nil, nil)
+
+	user := &model.Users{UserName: "totp_step", Password: "hash"}
+	assert.NoError(t, usersDao.Create(ctx, user))
+
+	assert.NoError(t, usersDao.UseTOTPStep(ctx, user.ID, 100))
+	// the same step or an earlier one is a replay
+	assert.ErrorIs(t, usersDao.UseTOTPStep(ctx, user.ID, 100), ErrTwoFactorCodeUsed)
+	assert.ErrorIs(t, usersDao.UseTOTPStep(ctx, user.ID, 99), ErrTwoFactorCodeUsed)
+	assert.NoError(t, usersDao.UseTOTPStep(ctx, user.ID, 101))
+	record, err := usersDao.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), record.TotpLastStep)
+	assert.Equal(t, user.Version+2, record.Version)
+
+	// the replays are not recorded
+	records := getAuditLogs(t, db, usersEntity, user.ID)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, audit.Change{Old: float64(100), New: float64(101)}, getAuditDiff(t, records[2])["totpLastStep"])
+	}
+}
+
+func Test_usersDao_UseRecoveryCode(t *testing.T) {
+	db := newAuditTestDB(t)
+	ctx := context.Background()
+	usersDao := NewUsersDao(db, nil, nil)
+
+	user := &model.Users{UserName: "recovery_code", Password: "hash", TotpRecoveryCodes: "a,b,c"}
+	assert.NoError(t, usersDao.Create(ctx, user))
+
+	assert.NoError(t, usersDao.UseRecoveryCode(ctx, user.ID, "a,b,c", "b,c"))
+	// a concurrent request computed its remaining codes from the same stored codes
+	assert.ErrorIs(t, usersDao.UseRecoveryCode(ctx, user.ID, "a,b,c", "a,c"), ErrTwoFactorCodeUsed)
+	record, err := usersDao.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "b,c", record.TotpRecoveryCodes)
+}
+
 func Test_usersDao_GetByID(t *testing.T) {
 	d := newUsersDao()
 	defer d.Close()
@@ -135,6 +337,54 @@ func Test_usersDao_GetByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_usersDao_GetByUserName(t *testing.T) {
+	d := newUsersDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Users)
+	testData.UserName = "admin"
+
+	rows := sqlmock.NewRows([]string{"id", "user_name"}).
+		AddRow(testData.ID, testData.UserName)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.UserName, 1).
+		WillReturnRows(rows)
+
+	record, err := d.IDao.(UsersDao).GetByUserName(d.Ctx, testData.UserName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, testData.ID, record.ID)
+
+	// not found test
+	_, err = d.IDao.(UsersDao).GetByUserName(d.Ctx, "unknown")
+	assert.Error(t, err)
+}
+
+func Test_usersDao_GetByUserEmail(t *testing.T) {
+	d := newUsersDao()
+	defer d.Close()
+	testData := d.TestData.(*model.Users)
+	testData.UserEmail = "admin@example.com"
+
+	rows := sqlmock.NewRows([]string{"id", "user_email"}).
+		AddRow(testData.ID, testData.UserEmail)
+
+	d.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.UserEmail, 1).
+		WillReturnRows(rows)
+
+	record, err := d.IDao.(UsersDao).GetByUserEmail(d.Ctx, testData.UserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, testData.ID, record.ID)
+
+	// not found test
+	_, err = d.IDao.(UsersDao).GetByUserEmail(d.Ctx, "unknown@example.com")
+	assert.Error(t, err)
+}
+
 func Test_usersDao_GetByColumns(t *testing.T) {
 	d := newUsersDao()
 	defer d.Close()
@@ -201,11 +451,11 @@ func Test_usersDao_DeleteByTx(t *testing.T) {
 	d := newUsersDao()
 	defer d.Close()
 	testData := d.TestData.(*model.Users)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	d.SQLMock.ExpectBegin()
 	d.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID).
+		WithArgs(d.AnyTime, testData.ID).
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	d.SQLMock.ExpectCommit()
 
@@ -23,6 +23,10 @@ func InitDB() {
 	switch strings.ToLower(dbDriver) {
 	case sgorm.DBDriverMysql, sgorm.DBDriverTidb:
 		gdb = InitMysql()
+	case sgorm.DBDriverPostgresql:
+		gdb = InitPostgresql()
+	case sgorm.DBDriverSqlite:
+		gdb = InitSqlite()
 	default:
 		panic("InitDB error, please modify the correct 'database' configuration at yaml file. " +
 			"Refer to https://godemo/blob/main/configs/godemo.yml#L85")
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+
+	"godemo/internal/config"
+	"godemo/internal/model"
+)
+
+// the models must not depend on mysql column types, they are migrated and used on sqlite here
+func TestInitSqlite(t *testing.T) {
+	config.Set(&config.Config{
+		Database: config.Database{
+			Driver: "sqlite",
+			Sqlite: config.Sqlite{
+				DBFile:       filepath.Join(t.TempDir(), "godemo.db"),
+				MaxIdleConns: 1,
+				MaxOpenConns: 1,
+			},
+		},
+	})
+	defer config.Set(nil)
+
+	db := InitSqlite()
+	defer sgorm.CloseDB(db) //nolint
+
+	err := db.AutoMigrate(
+		&model.Users{}, &model.Roles{}, &model.Permissions{}, &model.Menus{}, &model.Files{},
+		&model.UserRoles{}, &model.RolePermissions{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.Users{UserName: "tom", Password: "x"}
+	assert.NoError(t, db.Create(user).Error)
+	assert.NotZero(t, user.ID)
+	assert.NoError(t, db.Create(&model.UserRoles{UserID: user.ID, RoleID: 1}).Error)
+	assert.Error(t, db.Create(&model.UserRoles{UserID: user.ID, RoleID: 1}).Error) // composite primary key
+
+	assert.NoError(t, db.Delete(&model.Users{}, user.ID).Error)
+	assert.ErrorIs(t, db.First(&model.Users{}, user.ID).Error, ErrRecordNotFound)
+	assert.NoError(t, db.Unscoped().First(&model.Users{}, user.ID).Error)
+}
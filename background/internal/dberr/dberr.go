@@ -0,0 +1,176 @@
+// Package dberr classifies the errors of the dao layer and the database drivers of mysql, postgresql and
+// sqlite, so that the handlers respond a meaningful error code instead of an internal server error.
+// The message of a classified error is safe to show to the client, the raw error is only logged.
+package dberr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+
+	"godemo/internal/ecode"
+)
+
+// ErrQueryParams the query params of a paginated list cannot be converted to query conditions, such as a
+// column that is not in the whitelist, the error of the dao is a *dao.QueryParamsError that names the column.
+var ErrQueryParams = errors.New("query params error")
+
+// Error is a classified error
+type Error struct {
+	Code     *errcode.Error // error code of the response, the message is safe to show to the client
+	Internal bool           // the server is at fault rather than the request, it is logged as an error instead of a warning
+	Err      error          // raw error, only logged
+}
+
+func (e *Error) Error() string {
+	return e.Code.Msg() + ": " + e.Err.Error()
+}
+
+// Unwrap return the raw error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// the classes of the errors, a class is the error code and the safe message
+type class struct {
+	code     *errcode.Error
+	msg      string
+	internal bool
+}
+
+var (
+	notFound     = class{code: ecode.NotFound, msg: "record not found"}
+	queryParams  = class{code: ecode.InvalidParams, msg: "the query params are not valid"}
+	duplicate    = class{code: ecode.Conflict, msg: "the value already exists"}
+	referenced   = class{code: ecode.Conflict, msg: "the record is referenced by or references another record"}
+	tooLong      = class{code: ecode.InvalidParams, msg: "a value is too long"}
+	outOfRange   = class{code: ecode.InvalidParams, msg: "a value is out of range"}
+	badValue     = class{code: ecode.InvalidParams, msg: "a value has an invalid format"}
+	missingValue = class{code: ecode.InvalidParams, msg: "a required value is missing"}
+	notAllowed   = class{code: ecode.InvalidParams, msg: "a value is not allowed"}
+	canceled     = class{code: ecode.Canceled, msg: "the request was canceled"}
+	busy         = class{code: ecode.ServiceUnavailable, msg: "the record is busy, please try again", internal: true}
+	timeout      = class{code: ecode.DeadlineExceeded, msg: "the database did not respond in time", internal: true}
+	unavailable  = class{code: ecode.ServiceUnavailable, msg: "the database is unavailable, please try again later", internal: true}
+	internal     = class{code: ecode.InternalServerError, msg: ecode.InternalServerError.Msg(), internal: true}
+)
+
+// the mysql error numbers, see https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+var mysqlClasses = map[uint16]class{
+	1062: duplicate,    // ER_DUP_ENTRY
+	1586: duplicate,    // ER_DUP_ENTRY_WITH_KEY_NAME
+	1451: referenced,   // ER_ROW_IS_REFERENCED_2
+	1452: referenced,   // ER_NO_REFERENCED_ROW_2
+	1406: tooLong,      // ER_DATA_TOO_LONG
+	1264: outOfRange,   // ER_WARN_DATA_OUT_OF_RANGE
+	1292: badValue,     // ER_TRUNCATED_WRONG_VALUE
+	1366: badValue,     // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+	1048: missingValue, // ER_BAD_NULL_ERROR
+	1364: missingValue, // ER_NO_DEFAULT_FOR_FIELD
+	3819: notAllowed,   // ER_CHECK_CONSTRAINT_VIOLATED
+	1205: busy,         // ER_LOCK_WAIT_TIMEOUT
+	1213: busy,         // ER_LOCK_DEADLOCK
+	3024: timeout,      // ER_QUERY_TIMEOUT
+	1040: unavailable,  // ER_CON_COUNT_ERROR
+	1203: unavailable,  // ER_TOO_MANY_USER_CONNECTIONS
+	1053: unavailable,  // ER_SERVER_SHUTDOWN
+	1836: unavailable,  // ER_READ_ONLY_MODE
+	1290: unavailable,  // ER_OPTION_PREVENTS_STATEMENT, such as --read-only
+}
+
+// the postgresql sqlstate codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+var postgresClasses = map[string]class{
+	"23505": duplicate,    // unique_violation
+	"23503": referenced,   // foreign_key_violation
+	"22001": tooLong,      // string_data_right_truncation
+	"22003": outOfRange,   // numeric_value_out_of_range
+	"22007": badValue,     // invalid_datetime_format
+	"22008": badValue,     // datetime_field_overflow
+	"22P02": badValue,     // invalid_text_representation
+	"23502": missingValue, // not_null_violation
+	"23514": notAllowed,   // check_violation
+	"40001": busy,         // serialization_failure
+	"40P01": busy,         // deadlock_detected
+	"55P03": busy,         // lock_not_available
+	"57014": timeout,      // query_canceled, such as by statement_timeout
+	"53300": unavailable,  // too_many_connections
+	"57P01": unavailable,  // admin_shutdown
+	"57P03": unavailable,  // cannot_connect_now
+	"25006": unavailable,  // read_only_sql_transaction
+}
+
+// the postgresql sqlstate classes, for the codes that are not listed in postgresClasses
+var postgresClassPrefixes = map[string]class{
+	"08": unavailable, // connection exception
+	"53": unavailable, // insufficient resources
+	"22": badValue,    // data exception
+}
+
+// Classify translate err into a classified error, an error that is not recognized is an internal server error
+func Classify(err error) *Error {
+	c := classify(err)
+	return &Error{Code: c.code.RewriteMsg(c.msg), Internal: c.internal, Err: err}
+}
+
+func classify(err error) class {
+	switch {
+	case errors.Is(err, ErrQueryParams):
+		return queryParams
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, sql.ErrNoRows):
+		return notFound
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if c, ok := mysqlClasses[mysqlErr.Number]; ok {
+			return c
+		}
+		return internal
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if c, ok := postgresClasses[pgErr.Code]; ok {
+			return c
+		}
+		if c, ok := postgresClassPrefixes[pgErr.Code[:min(2, len(pgErr.Code))]]; ok {
+			return c
+		}
+		return internal
+	}
+	// the sqlite driver is only built with cgo, see dberr_sqlite.go
+	if c, ok := classifySqlite(err); ok {
+		return c
+	}
+
+	// the context of the request, checked after the drivers which may wrap it
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return timeout
+	case errors.Is(err, context.Canceled):
+		return canceled
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.As(err, &netErr) {
+		if netErr != nil && netErr.Timeout() {
+			return timeout
+		}
+		return unavailable
+	}
+
+	return internal
+}
+
+// IsDuplicateKey report whether err is the violation of a unique index or primary key
+func IsDuplicateKey(err error) bool {
+	return err != nil && classify(err) == duplicate
+}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package dberr
+
+// classifySqlite the sqlite driver requires cgo, a binary built with CGO_ENABLED=0 has no sqlite errors
+func classifySqlite(error) (class, bool) {
+	return class{}, false
+}
@@ -0,0 +1,37 @@
+//go:build cgo
+
+package dberr
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// classifySqlite classify the error of the sqlite driver, ok is false if err is not a sqlite error
+func classifySqlite(err error) (class, bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return class{}, false
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return duplicate, true
+	case sqlite3.ErrConstraintForeignKey:
+		return referenced, true
+	case sqlite3.ErrConstraintNotNull:
+		return missingValue, true
+	case sqlite3.ErrConstraintCheck:
+		return notAllowed, true
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrTooBig:
+		return tooLong, true
+	case sqlite3.ErrBusy, sqlite3.ErrLocked:
+		return busy, true
+	case sqlite3.ErrFull, sqlite3.ErrCantOpen, sqlite3.ErrReadonly:
+		return unavailable, true
+	}
+	return internal, true
+}
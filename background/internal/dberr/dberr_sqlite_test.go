@@ -0,0 +1,41 @@
+//go:build cgo
+
+package dberr
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/ecode"
+)
+
+func TestClassify_sqliteCodes(t *testing.T) {
+	e := Classify(sqlite3.Error{Code: sqlite3.ErrBusy})
+	assert.Equal(t, ecode.ServiceUnavailable.Code(), e.Code.Code())
+	assert.True(t, e.Internal)
+	e = Classify(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintCheck})
+	assert.Equal(t, ecode.InvalidParams.Code(), e.Code.Code())
+	assert.False(t, e.Internal)
+}
+
+func TestClassify_sqlite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE t (id integer PRIMARY KEY, code varchar(10) NOT NULL UNIQUE)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t (code) VALUES ('a')")
+	assert.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO t (code) VALUES ('a')")
+	assert.True(t, IsDuplicateKey(err))
+	_, err = db.Exec("INSERT INTO t (code) VALUES (NULL)")
+	assert.False(t, IsDuplicateKey(err))
+	assert.Equal(t, ecode.InvalidParams.Code(), Classify(err).Code.Code())
+	assert.False(t, IsDuplicateKey(nil))
+}
@@ -0,0 +1,56 @@
+package dberr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"godemo/internal/ecode"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     int
+		internal bool
+	}{
+		{"query params", fmt.Errorf("%w: %v", ErrQueryParams, errors.New("unknown column")), ecode.InvalidParams.Code(), false},
+		{"not found", gorm.ErrRecordNotFound, ecode.NotFound.Code(), false},
+		{"mysql duplicate", &mysql.MySQLError{Number: 1062}, ecode.Conflict.Code(), false},
+		{"mysql too long", &mysql.MySQLError{Number: 1406}, ecode.InvalidParams.Code(), false},
+		{"mysql deadlock", &mysql.MySQLError{Number: 1213}, ecode.ServiceUnavailable.Code(), true},
+		{"mysql unknown", &mysql.MySQLError{Number: 1146}, ecode.InternalServerError.Code(), true},
+		{"postgresql duplicate", &pgconn.PgError{Code: "23505"}, ecode.Conflict.Code(), false},
+		{"postgresql not null", &pgconn.PgError{Code: "23502"}, ecode.InvalidParams.Code(), false},
+		{"postgresql statement timeout", &pgconn.PgError{Code: "57014"}, ecode.DeadlineExceeded.Code(), true},
+		{"postgresql connection", &pgconn.PgError{Code: "08006"}, ecode.ServiceUnavailable.Code(), true},
+		{"postgresql data exception", &pgconn.PgError{Code: "22012"}, ecode.InvalidParams.Code(), false},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), ecode.DeadlineExceeded.Code(), true},
+		{"canceled", context.Canceled, ecode.Canceled.Code(), false},
+		{"bad connection", driver.ErrBadConn, ecode.ServiceUnavailable.Code(), true},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ecode.ServiceUnavailable.Code(), true},
+		{"unknown", errors.New("unknown"), ecode.InternalServerError.Code(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Classify(tt.err)
+			assert.Equal(t, tt.code, e.Code.Code())
+			assert.Equal(t, tt.internal, e.Internal)
+			assert.ErrorIs(t, e, tt.err)
+		})
+	}
+
+	// the raw error is not in the message for the client
+	e := Classify(&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'user_name' at row 1"})
+	assert.Equal(t, "a value is too long", e.Code.Msg())
+	assert.Contains(t, e.Error(), "user_name")
+}
@@ -0,0 +1,19 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// apiKeys business-level http error codes.
+// the apiKeysNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	apiKeysNO       = 17
+	apiKeysName     = "apiKeys"
+	apiKeysBaseCode = errcode.HCode(apiKeysNO)
+
+	ErrCreateAPIKeys     = errcode.NewError(apiKeysBaseCode+1, "failed to create "+apiKeysName)
+	ErrListAPIKeys       = errcode.NewError(apiKeysBaseCode+2, "failed to list of "+apiKeysName)
+	ErrPermissionAPIKeys = errcode.NewError(apiKeysBaseCode+3, "an api key cannot have a permission its user does not have")
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
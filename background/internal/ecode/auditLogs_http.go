@@ -0,0 +1,17 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// auditLogs business-level http error codes.
+// the auditLogsNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	auditLogsNO       = 18
+	auditLogsName     = "auditLogs"
+	auditLogsBaseCode = errcode.HCode(auditLogsNO)
+
+	ErrListAuditLogs = errcode.NewError(auditLogsBaseCode+1, "failed to list of "+auditLogsName)
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
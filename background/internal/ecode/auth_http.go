@@ -0,0 +1,36 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// auth business-level http error codes.
+// the authNO value range is 1~999, if the same error code is used, it will cause panic.
+// the web client logs out on ErrRefreshTokenAuth and ErrTokenInvalid (VITE_SERVICE_LOGOUT_CODES),
+// refreshes the token on ErrTokenExpired (VITE_SERVICE_EXPIRED_TOKEN_CODES), and shows a modal
+// before logging out on ErrTokenRevoked (VITE_SERVICE_MODAL_LOGOUT_CODES).
+var (
+	authNO       = 12
+	authName     = "auth"
+	authBaseCode = errcode.HCode(authNO)
+
+	ErrLoginAuth            = errcode.NewError(authBaseCode+1, "incorrect user name or password")
+	ErrGetUserInfoAuth      = errcode.NewError(authBaseCode+2, "failed to get "+authName+" user info")
+	ErrRefreshTokenAuth     = errcode.NewError(authBaseCode+3, "invalid or expired refresh token")
+	ErrGenerateToken        = errcode.NewError(authBaseCode+4, "failed to generate "+authName+" token")
+	ErrTokenInvalid         = errcode.NewError(authBaseCode+5, "token is missing or invalid, please login again")
+	ErrTokenExpired         = errcode.NewError(authBaseCode+6, "token has expired")
+	ErrTokenRevoked         = errcode.NewError(authBaseCode+7, "login session has ended, please login again")
+	ErrLoginLocked          = errcode.NewError(authBaseCode+8, "too many failed logins, the account is temporarily locked")
+	ErrLoginIPBlocked       = errcode.NewError(authBaseCode+9, "too many failed logins from this address, please try again later")
+	ErrTwoFactorCode        = errcode.NewError(authBaseCode+10, "incorrect two-factor code")
+	ErrTwoFactorChallenge   = errcode.NewError(authBaseCode+11, "invalid or expired two-factor challenge, please login again")
+	ErrTwoFactorEnabled     = errcode.NewError(authBaseCode+12, "two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled = errcode.NewError(authBaseCode+13, "two-factor authentication is not enrolled")
+	ErrResetTokenAuth       = errcode.NewError(authBaseCode+14, "invalid or expired password reset token")
+	ErrAPIKeyInvalid        = errcode.NewError(authBaseCode+15, "api key is invalid, expired or revoked")
+	ErrAPIKeyNotAllowed     = errcode.NewError(authBaseCode+16, "this api cannot be called with an api key")
+	ErrUserDisabled         = errcode.NewError(authBaseCode+17, "the account is disabled")
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
@@ -0,0 +1,16 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// conditional request business-level http error codes, they are shared by the resources with an entity tag.
+// the conditionalNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	conditionalNO       = 21
+	conditionalBaseCode = errcode.HCode(conditionalNO)
+
+	ErrPreconditionFailed = errcode.NewError(conditionalBaseCode+1, "the record has been changed or deleted by another request, please get it again")
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
@@ -16,6 +16,8 @@ var (
 	ErrUpdateByIDFiles = errcode.NewError(filesBaseCode+3, "failed to update "+filesName)
 	ErrGetByIDFiles    = errcode.NewError(filesBaseCode+4, "failed to get "+filesName+" details")
 	ErrListFiles       = errcode.NewError(filesBaseCode+5, "failed to list of "+filesName)
+	ErrUploadFiles     = errcode.NewError(filesBaseCode+6, "failed to upload "+filesName)
+	ErrDownloadFiles   = errcode.NewError(filesBaseCode+7, "failed to download "+filesName)
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
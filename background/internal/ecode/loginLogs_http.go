@@ -0,0 +1,17 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// loginLogs business-level http error codes.
+// the loginLogsNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	loginLogsNO       = 19
+	loginLogsName     = "loginLogs"
+	loginLogsBaseCode = errcode.HCode(loginLogsNO)
+
+	ErrListLoginLogs = errcode.NewError(loginLogsBaseCode+1, "failed to list of "+loginLogsName)
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
@@ -11,11 +11,10 @@ var (
 	rolePermissionsName     = "rolePermissions"
 	rolePermissionsBaseCode = errcode.HCode(rolePermissionsNO)
 
-	ErrCreateRolePermissions         = errcode.NewError(rolePermissionsBaseCode+1, "failed to create "+rolePermissionsName)
-	ErrDeleteByRoleIDRolePermissions = errcode.NewError(rolePermissionsBaseCode+2, "failed to delete "+rolePermissionsName)
-	ErrUpdateByRoleIDRolePermissions = errcode.NewError(rolePermissionsBaseCode+3, "failed to update "+rolePermissionsName)
-	ErrGetByRoleIDRolePermissions    = errcode.NewError(rolePermissionsBaseCode+4, "failed to get "+rolePermissionsName+" details")
-	ErrListRolePermissions           = errcode.NewError(rolePermissionsBaseCode+5, "failed to list of "+rolePermissionsName)
+	ErrGetByRoleIDRolePermissions     = errcode.NewError(rolePermissionsBaseCode+1, "failed to get "+rolePermissionsName)
+	ErrReplaceByRoleIDRolePermissions = errcode.NewError(rolePermissionsBaseCode+2, "failed to replace "+rolePermissionsName)
+	ErrGetEffectiveRolePermissions    = errcode.NewError(rolePermissionsBaseCode+3, "failed to get effective "+rolePermissionsName)
+	ErrPermissionNotGrantable         = errcode.NewError(rolePermissionsBaseCode+4, "you do not have some of the permissions")
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
@@ -11,11 +11,13 @@ var (
 	rolesName     = "roles"
 	rolesBaseCode = errcode.HCode(rolesNO)
 
-	ErrCreateRoles     = errcode.NewError(rolesBaseCode+1, "failed to create "+rolesName)
-	ErrDeleteByIDRoles = errcode.NewError(rolesBaseCode+2, "failed to delete "+rolesName)
-	ErrUpdateByIDRoles = errcode.NewError(rolesBaseCode+3, "failed to update "+rolesName)
-	ErrGetByIDRoles    = errcode.NewError(rolesBaseCode+4, "failed to get "+rolesName+" details")
-	ErrListRoles       = errcode.NewError(rolesBaseCode+5, "failed to list of "+rolesName)
+	ErrCreateRoles        = errcode.NewError(rolesBaseCode+1, "failed to create "+rolesName)
+	ErrDeleteByIDRoles    = errcode.NewError(rolesBaseCode+2, "failed to delete "+rolesName)
+	ErrUpdateByIDRoles    = errcode.NewError(rolesBaseCode+3, "failed to update "+rolesName)
+	ErrGetByIDRoles       = errcode.NewError(rolesBaseCode+4, "failed to get "+rolesName+" details")
+	ErrListRoles          = errcode.NewError(rolesBaseCode+5, "failed to list of "+rolesName)
+	ErrRoleParentNotFound = errcode.NewError(rolesBaseCode+6, "parent role not found")
+	ErrRoleParentCycle    = errcode.NewError(rolesBaseCode+7, "the parent role cannot be the role itself or one of its descendants")
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
@@ -0,0 +1,18 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// route business-level http error codes.
+// the routeNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	routeNO       = 13
+	routeName     = "route"
+	routeBaseCode = errcode.HCode(routeNO)
+
+	ErrGetUserRoutesRoute = errcode.NewError(routeBaseCode+1, "failed to get user routes of "+routeName)
+	ErrIsRouteExistRoute  = errcode.NewError(routeBaseCode+2, "failed to check whether the "+routeName+" exists")
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
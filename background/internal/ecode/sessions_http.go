@@ -0,0 +1,17 @@
+package ecode
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// sessions business-level http error codes.
+// the sessionsNO value range is 1~999, if the same error code is used, it will cause panic.
+var (
+	sessionsNO       = 20
+	sessionsName     = "sessions"
+	sessionsBaseCode = errcode.HCode(sessionsNO)
+
+	ErrListSessions = errcode.NewError(sessionsBaseCode+1, "failed to list of "+sessionsName)
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
@@ -11,11 +11,9 @@ var (
 	userRolesName     = "userRoles"
 	userRolesBaseCode = errcode.HCode(userRolesNO)
 
-	ErrCreateUserRoles         = errcode.NewError(userRolesBaseCode+1, "failed to create "+userRolesName)
-	ErrDeleteByUserIDUserRoles = errcode.NewError(userRolesBaseCode+2, "failed to delete "+userRolesName)
-	ErrUpdateByUserIDUserRoles = errcode.NewError(userRolesBaseCode+3, "failed to update "+userRolesName)
-	ErrGetByUserIDUserRoles    = errcode.NewError(userRolesBaseCode+4, "failed to get "+userRolesName+" details")
-	ErrListUserRoles           = errcode.NewError(userRolesBaseCode+5, "failed to list of "+userRolesName)
+	ErrGetByUserIDUserRoles     = errcode.NewError(userRolesBaseCode+1, "failed to get "+userRolesName)
+	ErrReplaceByUserIDUserRoles = errcode.NewError(userRolesBaseCode+2, "failed to replace "+userRolesName)
+	ErrRoleNotGrantable         = errcode.NewError(userRolesBaseCode+3, "a role has permissions that you do not have")
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
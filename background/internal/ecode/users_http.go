@@ -16,6 +16,7 @@ var (
 	ErrUpdateByIDUsers = errcode.NewError(usersBaseCode+3, "failed to update "+usersName)
 	ErrGetByIDUsers    = errcode.NewError(usersBaseCode+4, "failed to get "+usersName+" details")
 	ErrListUsers       = errcode.NewError(usersBaseCode+5, "failed to list of "+usersName)
+	ErrPasswordUsers   = errcode.NewError(usersBaseCode+6, "current password is incorrect")
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
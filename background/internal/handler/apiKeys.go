@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+var _ APIKeysHandler = (*apiKeysHandler)(nil)
+
+// APIKeysHandler defining the handler interface, the logged-in user manages its own api keys
+type APIKeysHandler interface {
+	Create(c *gin.Context)
+	DeleteByID(c *gin.Context)
+	List(c *gin.Context)
+}
+
+type apiKeysHandler struct {
+	iDao               dao.APIKeysDao
+	userPermissionsDao dao.UserPermissionsDao
+}
+
+// NewAPIKeysHandler creating the handler interface
+func NewAPIKeysHandler() APIKeysHandler {
+	return &apiKeysHandler{
+		iDao: dao.NewAPIKeysDao(database.GetDB()),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+	}
+}
+
+// Create a new api key
+// @Summary Create a new api key
+// @Description Creates an api key of the logged-in user, limited to some of the permission codes of the user. The key
+// @Description is only returned in this response, send it in the X-API-Key header, or as the bearer token of the
+// @Description Authorization header. A key never has more permissions than its user currently has.
+// @Tags apiKeys
+// @Accept json
+// @Produce json
+// @Param data body types.CreateAPIKeysRequest true "api key information"
+// @Success 200 {object} types.CreateAPIKeysReply{}
+// @Router /api/v1/apiKeys [post]
+// @Security BearerAuth
+func (h *apiKeysHandler) Create(c *gin.Context) {
+	userID, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	form := &types.CreateAPIKeysRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	userCodes, err := h.userPermissionsDao.GetCodesByUserID(ctx, userID)
+	if err != nil {
+		responseDBError(c, "GetCodesByUserID error", err, logger.Any("userID", userID))
+		return
+	}
+	permissions, ok := filterAPIKeyPermissions(form.Permissions, userCodes)
+	if !ok {
+		logger.Warn("api key permissions are not held by the user", logger.Any("userID", userID),
+			logger.Any("permissions", form.Permissions), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrPermissionAPIKeys)
+		return
+	}
+
+	key, keyPrefix, keyHash, err := auth.NewAPIKey()
+	if err != nil {
+		logger.Error("NewAPIKey error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrCreateAPIKeys)
+		return
+	}
+	expiresAt := time.Now().Add(time.Duration(form.ExpireDays) * 24 * time.Hour)
+	apiKey := &model.APIKeys{
+		UserID:      userID,
+		Name:        form.Name,
+		KeyPrefix:   keyPrefix,
+		KeyHash:     keyHash,
+		Permissions: strings.Join(permissions, ","),
+		ExpiresAt:   &expiresAt,
+	}
+	err = h.iDao.Create(ctx, apiKey)
+	if err != nil {
+		responseDBError(c, "Create error", err, logger.Any("userID", userID))
+		return
+	}
+
+	response.Success(c, &types.CreatedAPIKeysDetail{
+		APIKeysObjDetail: *convertAPIKeys(apiKey),
+		Key:              key,
+	})
+}
+
+// DeleteByID revoke an api key by id
+// @Summary Revoke an api key by id
+// @Description Revokes the api key of the logged-in user identified by the given id in the path, the key stops working immediately.
+// @Tags apiKeys
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.DeleteAPIKeysByIDReply{}
+// @Router /api/v1/apiKeys/{id} [delete]
+// @Security BearerAuth
+func (h *apiKeysHandler) DeleteByID(c *gin.Context) {
+	userID, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+	idStr := c.Param("id")
+	id, err := utils.StrToUint64E(idStr)
+	if err != nil || id == 0 {
+		logger.Warn("StrToUint64E error: ", logger.String("idStr", idStr), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	err = h.iDao.DeleteByUserIDAndID(middleware.WrapCtx(c), userID, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("DeleteByUserIDAndID not found", logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "DeleteByUserIDAndID error", err, logger.Any("id", id))
+		}
+		return
+	}
+
+	response.Success(c)
+}
+
+// List get all the api keys of the logged-in user
+// @Summary Get all the api keys of the logged-in user
+// @Description Returns the api keys of the logged-in user with their permissions, expiration and last used time, the newest first.
+// @Tags apiKeys
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.ListAPIKeysReply{}
+// @Router /api/v1/apiKeys [get]
+// @Security BearerAuth
+func (h *apiKeysHandler) List(c *gin.Context) {
+	userID, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	apiKeys, err := h.iDao.GetByUserID(middleware.WrapCtx(c), userID)
+	if err != nil {
+		responseDBError(c, "GetByUserID error", err, logger.Any("userID", userID))
+		return
+	}
+
+	data := make([]*types.APIKeysObjDetail, 0, len(apiKeys))
+	for _, v := range apiKeys {
+		data = append(data, convertAPIKeys(v))
+	}
+	response.Success(c, gin.H{"apiKeys": data})
+}
+
+func convertAPIKeys(apiKey *model.APIKeys) *types.APIKeysObjDetail {
+	return &types.APIKeysObjDetail{
+		ID:          apiKey.ID,
+		CreatedAt:   apiKey.CreatedAt,
+		Name:        apiKey.Name,
+		KeyPrefix:   apiKey.KeyPrefix,
+		Permissions: auth.SplitPermissions(apiKey.Permissions),
+		ExpiresAt:   apiKey.ExpiresAt,
+		LastUsedAt:  apiKey.LastUsedAt,
+	}
+}
+
+// deduplicate the requested permission codes, ok is false if the user does not have one of them
+func filterAPIKeyPermissions(requested []string, userCodes []string) ([]string, bool) {
+	held := make(map[string]struct{}, len(userCodes))
+	for _, v := range userCodes {
+		held[v] = struct{}{}
+	}
+
+	codes := make([]string, 0, len(requested))
+	seen := make(map[string]struct{}, len(requested))
+	for _, v := range requested {
+		v = strings.TrimSpace(v)
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		if _, ok := held[v]; !ok {
+			return nil, false
+		}
+		seen[v] = struct{}{}
+		codes = append(codes, v)
+	}
+	return codes, len(codes) > 0
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_filterAPIKeyPermissions(t *testing.T) {
+	userCodes := []string{"user:manage", "role:manage", "file:manage"}
+
+	codes, ok := filterAPIKeyPermissions([]string{"file:manage", " user:manage", "file:manage"}, userCodes)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"file:manage", "user:manage"}, codes)
+
+	// a permission the user does not have
+	_, ok = filterAPIKeyPermissions([]string{"file:manage", "data:purge"}, userCodes)
+	assert.False(t, ok)
+	_, ok = filterAPIKeyPermissions([]string{""}, userCodes)
+	assert.False(t, ok)
+	_, ok = filterAPIKeyPermissions(nil, userCodes)
+	assert.False(t, ok)
+}
+
+func TestNewAPIKeysHandler(t *testing.T) {
+	defer func() {
+		recover()
+	}()
+	_ = NewAPIKeysHandler()
+}
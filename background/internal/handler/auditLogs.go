@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+var _ AuditLogsHandler = (*auditLogsHandler)(nil)
+
+var errInvalidAuditDiff = errors.New("the diff of the audit log is not valid json")
+
+// AuditLogsHandler defining the handler interface, the audit logs are written by the dao layer and can only be read
+type AuditLogsHandler interface {
+	List(c *gin.Context)
+}
+
+type auditLogsHandler struct {
+	iDao dao.AuditLogsDao
+}
+
+// NewAuditLogsHandler creating the handler interface
+func NewAuditLogsHandler() AuditLogsHandler {
+	return &auditLogsHandler{
+		iDao: dao.NewAuditLogsDao(database.GetDB()),
+	}
+}
+
+// List get a paginated list of audit logs by custom conditions
+// @Summary Get a paginated list of audit logs by custom conditions
+// @Description Returns a paginated list of the audit logs of the create, update and delete changes, based on query filters,
+// @Description such as entity and entityID to get the history of a record, or requestID to get the changes of a request.
+// @Tags auditLogs
+// @Accept json
+// @Produce json
+// @Param data body types.Params true "query parameters"
+// @Success 200 {object} types.ListAuditLogsReply{}
+// @Router /api/v1/auditLogs/list [post]
+// @Security BearerAuth
+func (h *auditLogsHandler) List(c *gin.Context) {
+	form := &types.ListAuditLogsRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	auditLogs, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	if err != nil {
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
+		return
+	}
+
+	data, err := convertAuditLogs(auditLogs)
+	if err != nil {
+		response.Error(c, ecode.ErrListAuditLogs)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"auditLogs": data,
+		"total":     total,
+	})
+}
+
+func convertAuditLog(record *model.AuditLogs) (*types.AuditLogsObjDetail, error) {
+	data := &types.AuditLogsObjDetail{
+		ID:        record.ID,
+		CreatedAt: record.CreatedAt,
+		ActorID:   record.ActorID,
+		ActorName: record.ActorName,
+		RequestID: record.RequestID,
+		Entity:    record.Entity,
+		EntityID:  record.EntityID,
+		Action:    record.Action,
+		Diff:      json.RawMessage("{}"),
+	}
+	if record.Diff != "" {
+		if !json.Valid([]byte(record.Diff)) {
+			return nil, errInvalidAuditDiff
+		}
+		data.Diff = json.RawMessage(record.Diff)
+	}
+
+	return data, nil
+}
+
+func convertAuditLogs(fromValues []*model.AuditLogs) ([]*types.AuditLogsObjDetail, error) {
+	toValues := []*types.AuditLogsObjDetail{}
+	for _, v := range fromValues {
+		data, err := convertAuditLog(v)
+		if err != nil {
+			return nil, err
+		}
+		toValues = append(toValues, data)
+	}
+
+	return toValues, nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/httpcli"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/dao"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+func newAuditLogsHandler() *gotest.Handler {
+	testData := &model.AuditLogs{}
+	testData.ID = 1
+
+	// init mock dao
+	d := gotest.NewDao(nil, testData)
+	d.IDao = dao.NewAuditLogsDao(d.DB)
+
+	// init mock handler
+	h := gotest.NewHandler(d, testData)
+	h.IHandler = &auditLogsHandler{iDao: d.IDao.(dao.AuditLogsDao)}
+	iHandler := h.IHandler.(AuditLogsHandler)
+
+	testFns := []gotest.RouterInfo{
+		{
+			FuncName:    "List",
+			Method:      http.MethodPost,
+			Path:        "/auditLogs/list",
+			HandlerFunc: iHandler.List,
+		},
+	}
+
+	h.GoRunHTTPServer(testFns)
+
+	time.Sleep(time.Millisecond * 200)
+	return h
+}
+
+func Test_auditLogsHandler_List(t *testing.T) {
+	h := newAuditLogsHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.AuditLogs)
+
+	// column names and corresponding data
+	rows := sqlmock.NewRows([]string{"id", "entity", "action", "diff"}).
+		AddRow(testData.ID, "users", "update", `{"nickName":{"old":"a","new":"b"}}`)
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListAuditLogsRequest{Params: query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "ignore count", // ignore test count
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// nil params error test
+	err = httpcli.Post(result, h.GetRequestURL("List"), nil)
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListAuditLogsRequest{Params: query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "unknown-column",
+	}})
+	assert.Error(t, err)
+}
+
+func Test_convertAuditLog(t *testing.T) {
+	data, err := convertAuditLog(&model.AuditLogs{ID: 1, Diff: `{"name":{"old":null,"new":"a"}}`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":{"old":null,"new":"a"}}`, string(data.Diff))
+
+	data, err = convertAuditLog(&model.AuditLogs{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(data.Diff))
+
+	_, err = convertAuditLog(&model.AuditLogs{ID: 1, Diff: "{"})
+	assert.Error(t, err)
+}
+
+func TestNewAuditLogsHandler(t *testing.T) {
+	defer func() {
+		recover()
+	}()
+	_ = NewAuditLogsHandler()
+}
@@ -0,0 +1,949 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/config"
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/mailer"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+// the time limit of looking up the user and sending the email of a password reset
+const passwordResetSendTimeout = time.Minute
+
+// the results and the failure reasons of the login logs
+const (
+	loginResultSuccess = "success"
+	loginResultFailure = "failure"
+
+	loginReasonUserNotFound        = "userNotFound"
+	loginReasonWrongPassword       = "wrongPassword"
+	loginReasonAccountLocked       = "accountLocked"
+	loginReasonIPBlocked           = "ipBlocked"
+	loginReasonWrongTwoFactorCode  = "wrongTwoFactorCode"
+	loginReasonReusedTwoFactorCode = "reusedTwoFactorCode"
+	loginReasonUserDisabled        = "userDisabled"
+)
+
+// the user agent is truncated to the size of the login_logs column
+const maxUserAgentLen = 512
+
+var _ AuthHandler = (*authHandler)(nil)
+
+// AuthHandler defining the handler interface
+type AuthHandler interface {
+	Login(c *gin.Context)
+	GetUserInfo(c *gin.Context)
+	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
+	VerifyTwoFactor(c *gin.Context)
+	EnrollTwoFactor(c *gin.Context)
+	ConfirmTwoFactor(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+}
+
+type authHandler struct {
+	usersDao           dao.UsersDao
+	userRolesDao       dao.UserRolesDao
+	rolesDao           dao.RolesDao
+	userPermissionsDao dao.UserPermissionsDao
+	tokenCache         cache.TokenCache
+	loginLimiter       *auth.LoginLimiter
+	resetLimiter       *auth.ResetLimiter
+	passwordResets     cache.PasswordResetCache
+	mailer             mailer.Mailer
+	loginLogsDao       dao.LoginLogsDao
+}
+
+// NewAuthHandler creating the handler interface
+func NewAuthHandler() AuthHandler {
+	return &authHandler{
+		usersDao: dao.NewUsersDao(
+			database.GetDB(), // db driver is mysql
+			cache.NewUsersCache(database.GetCacheType()),
+			cache.NewTokenCache(database.GetCacheType()),
+		),
+		userRolesDao: dao.NewUserRolesDao(
+			database.GetDB(),
+			cache.NewUserRolesCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		rolesDao: dao.NewRolesDao(
+			database.GetDB(),
+			cache.NewRolesCache(database.GetCacheType()),
+		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		tokenCache:     cache.NewTokenCache(database.GetCacheType()),
+		loginLimiter:   auth.NewLoginLimiter(cache.NewLoginFailuresCache(database.GetCacheType())),
+		resetLimiter:   auth.NewResetLimiter(cache.NewLoginFailuresCache(database.GetCacheType())),
+		passwordResets: cache.NewPasswordResetCache(database.GetCacheType()),
+		mailer:         mailer.Get(),
+		loginLogsDao:   dao.NewLoginLogsDao(database.GetDB()),
+	}
+}
+
+// Login login by user name and password
+// @Summary Login
+// @Description Checks the user name and password, and returns an access token and a refresh token. Failed logins
+// @Description are counted per user name and client ip, too many failures delay and then temporarily lock the login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.LoginRequest true "login information"
+// @Success 200 {object} types.LoginReply{}
+// @Router /api/v1/auth/login [post]
+func (h *authHandler) Login(c *gin.Context) {
+	form := &types.LoginRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	clientIP := c.ClientIP()
+	if !h.checkLoginLimit(c, 0, form.UserName, clientIP) {
+		return
+	}
+
+	user, err := h.usersDao.GetByUserName(ctx, form.UserName)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			// take as long as a wrong password, so that the response time does not tell that the user does not exist
+			auth.VerifyDummyPassword(form.Password)
+			logger.Warn("Login user not found", logger.String("userName", form.UserName), middleware.GCtxRequestIDField(c))
+			h.loginFailed(c, form.UserName, clientIP)
+			h.writeLoginLog(c, 0, form.UserName, loginReasonUserNotFound)
+			response.Error(c, ecode.ErrLoginAuth)
+		} else {
+			responseDBError(c, "GetByUserName error", err, logger.String("userName", form.UserName))
+		}
+		return
+	}
+
+	ok, needRehash := auth.VerifyPassword(form.Password, user.Password)
+	if !ok {
+		logger.Warn("Login password mismatch", logger.String("userName", form.UserName), middleware.GCtxRequestIDField(c))
+		h.loginFailed(c, form.UserName, clientIP)
+		h.writeLoginLog(c, user.ID, user.UserName, loginReasonWrongPassword)
+		response.Error(c, ecode.ErrLoginAuth)
+		return
+	}
+	// checked after the password, so that the status of an account is not told to whoever guesses its name
+	if user.Status == model.StatusDisabled {
+		logger.Warn("Login user disabled", logger.String("userName", form.UserName), middleware.GCtxRequestIDField(c))
+		h.writeLoginLog(c, user.ID, user.UserName, loginReasonUserDisabled)
+		response.Error(c, ecode.ErrUserDisabled)
+		return
+	}
+	if needRehash {
+		// upgrade the legacy plaintext password to a hash, a failure here does not block the login
+		h.rehashPassword(ctx, user, form.Password)
+	}
+
+	// the failures are kept until the two-factor step succeeds, so that the password step
+	// cannot be used to reset the failures of guessing the code
+	purpose, err := h.getTwoFactorPurpose(ctx, user)
+	if err != nil {
+		responseDBError(c, "getTwoFactorPurpose error", err, logger.Any("userID", user.ID))
+		return
+	}
+	if purpose != "" {
+		challengeToken, err := auth.GenerateChallengeToken(user.ID, user.UserName, purpose)
+		if err != nil {
+			logger.Error("GenerateChallengeToken error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrGenerateToken)
+			return
+		}
+		response.Success(c, &types.LoginTokenDetail{
+			TwoFactor:      purpose,
+			ChallengeToken: challengeToken,
+		})
+		return
+	}
+
+	h.loginSucceeded(c, user.UserName)
+	tokens, ok := newLoginTokens(c, h.tokenCache, user)
+	if !ok {
+		return
+	}
+	h.writeLoginLog(c, user.ID, user.UserName, "")
+	response.Success(c, tokens)
+}
+
+// GetUserInfo get the information of the logged-in user
+// @Summary Get user info
+// @Description Returns the id, name, role codes and permission codes of the user the access token belongs to.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.GetUserInfoReply{}
+// @Router /api/v1/auth/getUserInfo [get]
+// @Security BearerAuth
+func (h *authHandler) GetUserInfo(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+	userID, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	user, err := h.usersDao.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.Unauthorized)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("userID", userID))
+		}
+		return
+	}
+
+	roleCodes, err := h.getRoleCodes(ctx, userID)
+	if err != nil {
+		logger.Error("getRoleCodes error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetUserInfoAuth)
+		return
+	}
+	buttons, err := h.userPermissionsDao.GetCodesByUserID(ctx, userID)
+	if err != nil {
+		logger.Error("GetCodesByUserID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetUserInfoAuth)
+		return
+	}
+
+	response.Success(c, &types.UserInfoDetail{
+		UserID:   utils.Uint64ToStr(user.ID),
+		UserName: user.UserName,
+		Roles:    roleCodes,
+		Buttons:  buttons,
+	})
+}
+
+// RefreshToken exchange a refresh token for a new pair of tokens
+// @Summary Refresh token
+// @Description Validates the refresh token, and returns a new access token and refresh token. A refresh token
+// @Description can only be used once, reusing it revokes all the tokens of the login session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.RefreshTokenRequest true "refresh token"
+// @Success 200 {object} types.RefreshTokenReply{}
+// @Router /api/v1/auth/refreshToken [post]
+func (h *authHandler) RefreshToken(c *gin.Context) {
+	form := &types.RefreshTokenRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	claims, err := auth.ParseToken(form.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		logger.Warn("ParseToken error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrRefreshTokenAuth)
+		return
+	}
+	userID, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrRefreshTokenAuth)
+		return
+	}
+	familyID, err := auth.GetFamilyID(claims)
+	if err != nil {
+		logger.Warn("GetFamilyID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrRefreshTokenAuth)
+		return
+	}
+	tokenID, err := auth.GetTokenID(claims)
+	if err != nil {
+		logger.Warn("GetTokenID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrRefreshTokenAuth)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	user, err := h.usersDao.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRefreshTokenAuth)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("userID", userID))
+		}
+		return
+	}
+	if user.Status == model.StatusDisabled {
+		logger.Warn("RefreshToken user disabled", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrRefreshTokenAuth)
+		return
+	}
+
+	tokens, err := auth.GenerateTokens(user.ID, user.UserName, familyID)
+	if err != nil {
+		logger.Error("GenerateTokens error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGenerateToken)
+		return
+	}
+	// the new refresh token replaces the old one, only one of concurrent refreshes succeeds
+	err = h.tokenCache.Rotate(ctx, familyID, tokenID, tokens.RefreshTokenID, auth.RefreshTokenExpire())
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrTokenReused):
+			logger.Warn("refresh token reused, the token family is revoked", logger.Any("userID", userID),
+				logger.String("familyID", familyID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRefreshTokenAuth)
+		case errors.Is(err, database.ErrCacheNotFound):
+			logger.Warn("token family not found", logger.Any("userID", userID), logger.String("familyID", familyID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRefreshTokenAuth)
+		default:
+			responseDBError(c, "Rotate error", err, logger.Any("userID", userID))
+		}
+		return
+	}
+
+	response.Success(c, &types.LoginTokenDetail{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// Logout end the login session of the access token
+// @Summary Logout
+// @Description Revokes the access token and the refresh token of the current login session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.LogoutReply{}
+// @Router /api/v1/auth/logout [post]
+// @Security BearerAuth
+func (h *authHandler) Logout(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+	familyID, err := auth.GetFamilyID(claims)
+	if err != nil {
+		logger.Warn("GetFamilyID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	err = h.tokenCache.DelFamily(middleware.WrapCtx(c), familyID)
+	if err != nil {
+		responseDBError(c, "DelFamily error", err, logger.String("uid", claims.UID))
+		return
+	}
+	response.Success(c)
+}
+
+// VerifyTwoFactor exchange a challenge token and a two-factor code for the tokens
+// @Summary Verify two-factor code
+// @Description Second step of the login of a user with two-factor authentication, the Authorization header carries
+// @Description the challenge token returned by the login api. The code is a totp code or an unused recovery code,
+// @Description each code can only be used once. Failed and reused codes are counted like failed logins.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.VerifyTwoFactorRequest true "two-factor code"
+// @Success 200 {object} types.VerifyTwoFactorReply{}
+// @Router /api/v1/auth/2fa/verify [post]
+// @Security BearerAuth
+func (h *authHandler) VerifyTwoFactor(c *gin.Context) {
+	form := &types.VerifyTwoFactorRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	user, isChallenge, ok := h.getTokenUser(c)
+	if !ok {
+		return
+	}
+	if !isChallenge || !user.TotpEnabled {
+		logger.Warn("VerifyTwoFactor without a pending challenge", logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrTwoFactorChallenge)
+		return
+	}
+	clientIP := c.ClientIP()
+	if !h.checkLoginLimit(c, user.ID, user.UserName, clientIP) {
+		return
+	}
+
+	secret, err := auth.DecryptSecret(user.TotpSecret)
+	if err != nil {
+		logger.Error("DecryptSecret error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	ctx := middleware.WrapCtx(c)
+	// a code is accepted only once, the dao rejects a totp code whose step is already used and a recovery
+	// code that a concurrent request has used
+	if step, ok := auth.MatchTOTP(secret, form.Code, time.Now()); ok {
+		err = h.usersDao.UseTOTPStep(ctx, user.ID, step)
+	} else {
+		remaining, ok := auth.UseRecoveryCode(user.TotpRecoveryCodes, form.Code)
+		if !ok {
+			logger.Warn("VerifyTwoFactor code mismatch", logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+			h.loginFailed(c, user.UserName, clientIP)
+			h.writeLoginLog(c, user.ID, user.UserName, loginReasonWrongTwoFactorCode)
+			response.Error(c, ecode.ErrTwoFactorCode)
+			return
+		}
+		err = h.usersDao.UseRecoveryCode(ctx, user.ID, user.TotpRecoveryCodes, remaining)
+		if err == nil {
+			logger.Info("recovery code used", logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		}
+	}
+	if err != nil {
+		if errors.Is(err, dao.ErrTwoFactorCodeUsed) {
+			logger.Warn("VerifyTwoFactor code reused", logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+			h.loginFailed(c, user.UserName, clientIP)
+			h.writeLoginLog(c, user.ID, user.UserName, loginReasonReusedTwoFactorCode)
+			response.Error(c, ecode.ErrTwoFactorCode)
+		} else {
+			responseDBError(c, "VerifyTwoFactor error", err, logger.Any("userID", user.ID))
+		}
+		return
+	}
+
+	h.loginSucceeded(c, user.UserName)
+	tokens, ok := newLoginTokens(c, h.tokenCache, user)
+	if !ok {
+		return
+	}
+	h.writeLoginLog(c, user.ID, user.UserName, "")
+	response.Success(c, tokens)
+}
+
+// EnrollTwoFactor create a new two-factor secret for the user
+// @Summary Enroll two-factor authentication
+// @Description Creates a new totp secret for the logged-in user, or for the user of an enroll challenge token returned
+// @Description by the login api. The secret takes effect after it is confirmed with a code, enrolling again replaces
+// @Description an unconfirmed secret.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.EnrollTwoFactorReply{}
+// @Router /api/v1/auth/2fa/enroll [post]
+// @Security BearerAuth
+func (h *authHandler) EnrollTwoFactor(c *gin.Context) {
+	user, _, ok := h.getTokenUser(c)
+	if !ok {
+		return
+	}
+	if user.TotpEnabled {
+		response.Error(c, ecode.ErrTwoFactorEnabled)
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		logger.Error("NewTOTPSecret error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	encrypted, err := auth.EncryptSecret(secret)
+	if err != nil {
+		logger.Error("EncryptSecret error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	err = h.usersDao.UpdateTwoFactor(middleware.WrapCtx(c), &model.Users{ID: user.ID, TotpSecret: encrypted})
+	if err != nil {
+		responseDBError(c, "UpdateTwoFactor error", err, logger.Any("userID", user.ID))
+		return
+	}
+
+	response.Success(c, &types.TwoFactorSecretDetail{
+		Secret:     secret,
+		OtpauthURL: auth.TOTPURL(user.UserName, secret),
+	})
+}
+
+// ConfirmTwoFactor enable two-factor authentication with a code of the enrolled secret
+// @Summary Confirm two-factor authentication
+// @Description Checks a code of the secret created by the enroll api and enables two-factor authentication, the
+// @Description response contains the recovery codes, they are only shown once. If the Authorization header carries
+// @Description an enroll challenge token, the response also contains the tokens of the login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.ConfirmTwoFactorRequest true "two-factor code"
+// @Success 200 {object} types.ConfirmTwoFactorReply{}
+// @Router /api/v1/auth/2fa/confirm [post]
+// @Security BearerAuth
+func (h *authHandler) ConfirmTwoFactor(c *gin.Context) {
+	form := &types.ConfirmTwoFactorRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	user, isChallenge, ok := h.getTokenUser(c)
+	if !ok {
+		return
+	}
+	if user.TotpEnabled {
+		response.Error(c, ecode.ErrTwoFactorEnabled)
+		return
+	}
+	if user.TotpSecret == "" {
+		response.Error(c, ecode.ErrTwoFactorNotEnrolled)
+		return
+	}
+
+	secret, err := auth.DecryptSecret(user.TotpSecret)
+	if err != nil {
+		logger.Error("DecryptSecret error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	step, ok := auth.MatchTOTP(secret, form.Code, time.Now())
+	if !ok {
+		logger.Warn("ConfirmTwoFactor code mismatch", logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrTwoFactorCode)
+		return
+	}
+
+	codes, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		logger.Error("NewRecoveryCodes error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	err = h.usersDao.UpdateTwoFactor(middleware.WrapCtx(c), &model.Users{
+		ID:                user.ID,
+		TotpSecret:        user.TotpSecret,
+		TotpEnabled:       true,
+		TotpRecoveryCodes: hashes,
+		TotpLastStep:      step, // the code of the confirmation cannot be used to login again
+	})
+	if err != nil {
+		responseDBError(c, "UpdateTwoFactor error", err, logger.Any("userID", user.ID))
+		return
+	}
+
+	detail := &types.TwoFactorConfirmDetail{RecoveryCodes: codes}
+	if isChallenge {
+		// the enrollment was the pending step of a login
+		h.loginSucceeded(c, user.UserName)
+		tokens, ok := newLoginTokens(c, h.tokenCache, user)
+		if !ok {
+			return
+		}
+		h.writeLoginLog(c, user.ID, user.UserName, "")
+		detail.Token = tokens.Token
+		detail.RefreshToken = tokens.RefreshToken
+	}
+	response.Success(c, detail)
+}
+
+// ForgotPassword send a password reset email
+// @Summary Forgot password
+// @Description Sends an email with a one-time password reset link to the user with the given email. The response is the
+// @Description same whether a user has the email or not, so that the api cannot be used to find the registered emails.
+// @Description Only a few emails are sent to an address within a while, and a client ip that makes too many requests gets 429.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.ForgotPasswordRequest true "email"
+// @Success 200 {object} types.ForgotPasswordReply{}
+// @Router /api/v1/auth/forgotPassword [post]
+func (h *authHandler) ForgotPassword(c *gin.Context) {
+	form := &types.ForgotPasswordRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	ok, err := h.resetLimiter.AllowIP(ctx, c.ClientIP())
+	if err != nil {
+		responseDBError(c, "resetLimiter.AllowIP error", err)
+		return
+	}
+	if !ok {
+		logger.Warn("ForgotPassword too many requests", logger.String("ip", c.ClientIP()), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.TooManyRequests)
+		return
+	}
+	// the limit of an email is not told to the client, the response is the same as for a sent email
+	ok, err = h.resetLimiter.AllowEmail(ctx, form.Email)
+	if err != nil {
+		responseDBError(c, "resetLimiter.AllowEmail error", err)
+		return
+	}
+	if !ok {
+		logger.Warn("ForgotPassword too many emails", logger.String("email", form.Email), middleware.GCtxRequestIDField(c))
+		response.Success(c)
+		return
+	}
+
+	// the email is looked up and sent in the background, so neither the response nor its time tells
+	// whether a user has the email
+	go h.sendPasswordReset(form.Email, middleware.GCtxRequestIDField(c))
+	response.Success(c)
+}
+
+// ResetPassword set a new password with a password reset token
+// @Summary Reset password
+// @Description Sets the new password of the user the reset token was sent to, a reset token can only be used once.
+// @Description All the login sessions of the user are ended, and the failed logins of the user are forgotten.
+// @Description The password of a disabled user is not reset.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body types.ResetPasswordRequest true "reset token and new password"
+// @Success 200 {object} types.ResetPasswordReply{}
+// @Router /api/v1/auth/resetPassword [post]
+func (h *authHandler) ResetPassword(c *gin.Context) {
+	form := &types.ResetPasswordRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	userID, err := h.passwordResets.Take(ctx, auth.HashResetToken(form.Token))
+	if err != nil {
+		if errors.Is(err, database.ErrCacheNotFound) {
+			logger.Warn("passwordResets.Take not found", middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrResetTokenAuth)
+		} else {
+			responseDBError(c, "passwordResets.Take error", err)
+		}
+		return
+	}
+	user, err := h.usersDao.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrResetTokenAuth)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("userID", userID))
+		}
+		return
+	}
+	// a disabled user cannot get in with a new password either, the token stays spent
+	if user.Status == model.StatusDisabled {
+		logger.Warn("ResetPassword user disabled", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrUserDisabled)
+		return
+	}
+
+	password, err := auth.HashPassword(form.NewPassword)
+	if err != nil {
+		logger.Warn("HashPassword error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	// changing the password revokes all the tokens of the user
+	err = h.usersDao.UpdateByID(ctx, &model.Users{ID: userID, Password: password})
+	if err != nil {
+		responseDBError(c, "UpdateByID error", err, logger.Any("userID", userID))
+		return
+	}
+	h.loginSucceeded(c, user.UserName)
+
+	response.Success(c)
+}
+
+// get the user of the access token or challenge token, it responds and returns false on failure or if the user is disabled
+func (h *authHandler) getTokenUser(c *gin.Context) (user *model.Users, isChallenge bool, ok bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return nil, false, false
+	}
+	userID, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return nil, false, false
+	}
+
+	user, err = h.usersDao.GetByID(middleware.WrapCtx(c), userID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.Unauthorized)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("userID", userID))
+		}
+		return nil, false, false
+	}
+	if user.Status == model.StatusDisabled {
+		logger.Warn("user disabled", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrUserDisabled)
+		return nil, false, false
+	}
+
+	return user, auth.CheckTokenType(claims, auth.TokenTypeChallenge) == nil, true
+}
+
+// check that the user name and client ip are not locked, and wait the delay of the previous failures,
+// it responds and returns false if the login must stop. userID is 0 if the user is not known yet.
+func (h *authHandler) checkLoginLimit(c *gin.Context, userID uint64, userName string, clientIP string) bool {
+	delay, err := h.loginLimiter.Check(middleware.WrapCtx(c), userName, clientIP)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			logger.Warn("Login account locked", logger.String("userName", userName), logger.String("ip", clientIP), middleware.GCtxRequestIDField(c))
+			h.writeLoginLog(c, userID, userName, loginReasonAccountLocked)
+			response.Error(c, ecode.ErrLoginLocked)
+		case errors.Is(err, auth.ErrIPBlocked):
+			logger.Warn("Login client ip blocked", logger.String("userName", userName), logger.String("ip", clientIP), middleware.GCtxRequestIDField(c))
+			h.writeLoginLog(c, userID, userName, loginReasonIPBlocked)
+			response.Error(c, ecode.ErrLoginIPBlocked)
+		default:
+			responseDBError(c, "loginLimiter.Check error", err, logger.String("userName", userName))
+		}
+		return false
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-c.Request.Context().Done(): // the client has gone
+			return false
+		}
+	}
+	return true
+}
+
+// forget the failures of the user name, a failure of the cache does not change the response
+func (h *authHandler) loginSucceeded(c *gin.Context, userName string) {
+	err := h.loginLimiter.Succeed(middleware.WrapCtx(c), userName)
+	if err != nil {
+		logger.Warn("loginLimiter.Succeed error", logger.Err(err), logger.String("userName", userName), middleware.GCtxRequestIDField(c))
+	}
+}
+
+// start a new token family for the user, it responds and returns false on failure
+func newLoginTokens(c *gin.Context, tokenCache cache.TokenCache, user *model.Users) (*types.LoginTokenDetail, bool) {
+	familyID, err := auth.NewTokenID()
+	if err != nil {
+		logger.Error("NewTokenID error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGenerateToken)
+		return nil, false
+	}
+	tokens, err := auth.GenerateTokens(user.ID, user.UserName, familyID)
+	if err != nil {
+		logger.Error("GenerateTokens error", logger.Err(err), logger.Any("userID", user.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGenerateToken)
+		return nil, false
+	}
+	family := &cache.TokenFamily{
+		ID:        familyID,
+		UserID:    user.ID,
+		UserName:  user.UserName,
+		IP:        c.ClientIP(),
+		UserAgent: getUserAgent(c),
+	}
+	err = tokenCache.CreateFamily(middleware.WrapCtx(c), family, tokens.RefreshTokenID, auth.RefreshTokenExpire())
+	if err != nil {
+		responseDBError(c, "CreateFamily error", err, logger.Any("userID", user.ID))
+		return nil, false
+	}
+
+	return &types.LoginTokenDetail{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, true
+}
+
+// get the pending two-factor step of a login, it is empty if the password is enough
+func (h *authHandler) getTwoFactorPurpose(ctx context.Context, user *model.Users) (string, error) {
+	if user.TotpEnabled {
+		return auth.ChallengeVerify, nil
+	}
+
+	roleIDs, err := h.userRolesDao.GetRoleIDsByUserID(ctx, user.ID)
+	if err != nil || len(roleIDs) == 0 {
+		return "", err
+	}
+	roles, err := h.rolesDao.GetByIDs(ctx, roleIDs)
+	if err != nil {
+		return "", err
+	}
+	for _, role := range roles {
+		if role.Status != model.StatusDisabled && role.RequireTwoFactor != nil && *role.RequireTwoFactor {
+			return auth.ChallengeEnroll, nil
+		}
+	}
+	return "", nil
+}
+
+// count a failed login, a failure of the cache does not change the response
+func (h *authHandler) loginFailed(c *gin.Context, userName string, clientIP string) {
+	err := h.loginLimiter.Fail(middleware.WrapCtx(c), userName, clientIP)
+	if err != nil {
+		logger.Warn("loginLimiter.Fail error", logger.Err(err), logger.String("userName", userName), middleware.GCtxRequestIDField(c))
+	}
+}
+
+// write a login log, reason is empty for a successful login. A failure of the database does not change the response.
+func (h *authHandler) writeLoginLog(c *gin.Context, userID uint64, userName string, reason string) {
+	result := loginResultSuccess
+	if reason != "" {
+		result = loginResultFailure
+	}
+	err := h.loginLogsDao.Create(middleware.WrapCtx(c), &model.LoginLogs{
+		UserID:    userID,
+		UserName:  userName,
+		IP:        c.ClientIP(),
+		UserAgent: getUserAgent(c),
+		Result:    result,
+		Reason:    reason,
+	})
+	if err != nil {
+		logger.Warn("loginLogsDao.Create error", logger.Err(err), logger.String("userName", userName), middleware.GCtxRequestIDField(c))
+	}
+}
+
+// get the user agent of the request, truncated to maxUserAgentLen bytes
+func getUserAgent(c *gin.Context) string {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLen], "")
+	}
+	return userAgent
+}
+
+func (h *authHandler) rehashPassword(ctx context.Context, user *model.Users, password string) {
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		logger.Warn("HashPassword error", logger.Err(err), logger.Any("userID", user.ID))
+		return
+	}
+	// the password is the same, the other sessions of the user are kept
+	err = h.usersDao.RehashPassword(ctx, user.ID, user.Password, hashed)
+	if err != nil {
+		logger.Warn("RehashPassword error", logger.Err(err), logger.Any("userID", user.ID))
+	}
+}
+
+// get the role codes of the user from user_roles and roles, the disabled roles are left out
+func (h *authHandler) getRoleCodes(ctx context.Context, userID uint64) ([]string, error) {
+	roleIDs, err := h.userRolesDao.GetRoleIDsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roleCodes := []string{}
+	if len(roleIDs) == 0 {
+		return roleCodes, nil
+	}
+
+	roles, err := h.rolesDao.GetByIDs(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range roleIDs {
+		if role, ok := roles[id]; ok && role.Status != model.StatusDisabled {
+			roleCodes = append(roleCodes, role.RoleCode)
+		}
+	}
+
+	return roleCodes, nil
+}
+
+// look up the user of the email, start a password reset and send its token, it runs after the
+// response of the forgot password api, so the errors are only logged.
+func (h *authHandler) sendPasswordReset(email string, requestIDField logger.Field) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+	defer cancel()
+
+	user, err := h.usersDao.GetByUserEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Info("GetByUserEmail not found", logger.String("email", email), requestIDField)
+		} else {
+			logger.Error("GetByUserEmail error", logger.Err(err), logger.String("email", email), requestIDField)
+		}
+		return
+	}
+
+	token, hash, err := auth.NewResetToken()
+	if err != nil {
+		logger.Error("NewResetToken error", logger.Err(err), logger.Any("userID", user.ID), requestIDField)
+		return
+	}
+	expire := auth.PasswordResetExpire()
+	err = h.passwordResets.Set(ctx, user.ID, hash, expire)
+	if err != nil {
+		logger.Error("passwordResets.Set error", logger.Err(err), logger.Any("userID", user.ID), requestIDField)
+		return
+	}
+
+	err = h.mailer.Send(ctx, newPasswordResetMessage(user, token, expire))
+	if err != nil {
+		logger.Error("mailer.Send error", logger.Err(err), logger.Any("userID", user.ID), requestIDField)
+	}
+}
+
+func newPasswordResetMessage(user *model.Users, token string, expire time.Duration) *mailer.Message {
+	name := user.NickName
+	if name == "" {
+		name = user.UserName
+	}
+	appName := config.Get().App.Name
+
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "Hello %s,\n\n", name)
+	fmt.Fprintf(body, "We received a request to reset the password of your %s account %s.\n\n", appName, user.UserName)
+	if link := auth.PasswordResetURL(token); link != "" {
+		fmt.Fprintf(body, "Open the link below to set a new password:\n\n%s\n\n", link)
+	} else {
+		fmt.Fprintf(body, "Use the reset token below to set a new password:\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(body, "It expires in %d minutes and can only be used once. ", int(expire.Minutes()))
+	body.WriteString("If you did not request a password reset, you can ignore this email, your password is not changed.\n")
+
+	return &mailer.Message{
+		To:      []string{user.UserEmail},
+		Subject: "[" + appName + "] Reset your password",
+		Body:    body.String(),
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+
+	"godemo/internal/auth"
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+var (
+	// same as REG_USER_NAME of web/src/constants/reg.ts
+	userNameRegexp = regexp.MustCompile(`^[\x{4E00}-\x{9FA5}a-zA-Z0-9_-]{4,16}$`)
+	// same as REG_PHONE of web/src/constants/reg.ts
+	phoneRegexp = regexp.MustCompile(`^1(3[0-9]|4[01456789]|5[012356789]|6[2567]|7[0-8]|8[0-9]|9[012356789])[0-9]{8}$`)
+	// an absolute route path of the web client without trailing slash, such as /manage/user or /detail/:id
+	menuPathRegexp = regexp.MustCompile(`^(/:?[a-zA-Z0-9_-]+)+$`)
+)
+
+// register the custom rules of the binding tags of the request types, and name the fields of the
+// validation errors by their json or form names
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(getRequestFieldName)
+	_ = v.RegisterValidation("userName", matchRegexp(userNameRegexp))
+	_ = v.RegisterValidation("phone", matchRegexp(phoneRegexp))
+	_ = v.RegisterValidation("menuPath", matchRegexp(menuPathRegexp))
+	_ = v.RegisterValidation("password", validatePassword)
+}
+
+// bcrypt limits the length of a password in bytes, max counts characters, a password of
+// multi-byte characters may pass max and still be too long to hash
+func validatePassword(fl validator.FieldLevel) bool {
+	return len(fl.Field().String()) <= auth.MaxPasswordBytes
+}
+
+func matchRegexp(re *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return re.MatchString(fl.Field().String())
+	}
+}
+
+func getRequestFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// responseInvalidParams respond ecode.InvalidParams for a failure of ShouldBindJSON or ShouldBindQuery,
+// the data lists the fields that are not valid.
+func responseInvalidParams(c *gin.Context, err error) {
+	response.Error(c, ecode.InvalidParams, &types.InvalidParamsDetail{Fields: getFieldErrors(err)})
+}
+
+func getFieldErrors(err error) []*types.FieldError {
+	fields := []*types.FieldError{}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			fields = append(fields, &types.FieldError{
+				Field:  getFieldPath(fe.Namespace()),
+				Reason: fe.Tag(),
+				Param:  fe.Param(),
+			})
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		fields = append(fields, &types.FieldError{
+			Field:  typeError.Field,
+			Reason: "type",
+			Param:  typeError.Type.String(),
+		})
+	}
+	return fields
+}
+
+// get the json path of a field from its namespace, such as ListUsersRequest.Params.columns[0].name,
+// the request struct and the embedded structs have no json name and are left out.
+func getFieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	path := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" && unicode.IsUpper([]rune(part)[0]) {
+			continue
+		}
+		path = append(path, part)
+	}
+	return strings.Join(path, ".")
+}
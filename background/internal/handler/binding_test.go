@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+func bindTestRequest(t *testing.T, body string, form any) (*types.InvalidParamsDetail, int) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	err := c.ShouldBindJSON(form)
+	if err == nil {
+		return nil, 0
+	}
+	responseInvalidParams(c, err)
+
+	result := &struct {
+		Code int                        `json:"code"`
+		Data *types.InvalidParamsDetail `json:"data"`
+	}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	return result.Data, result.Code
+}
+
+func Test_responseInvalidParams(t *testing.T) {
+	detail, code := bindTestRequest(t, `{"userName":"ab","password":"123456","userGender":"3","userPhone":"123","userEmail":"a@b"}`,
+		&types.CreateUsersRequest{})
+	assert.Equal(t, ecode.InvalidParams.Code(), code)
+	assert.ElementsMatch(t, []*types.FieldError{
+		{Field: "userName", Reason: "userName"},
+		{Field: "userGender", Reason: "oneof", Param: "1 2"},
+		{Field: "userPhone", Reason: "phone"},
+		{Field: "userEmail", Reason: "email"},
+	}, detail.Fields)
+
+	detail, _ = bindTestRequest(t, `{"name":"home","path":"home/"}`, &types.CreateMenusRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "path", Reason: "menuPath"}}, detail.Fields)
+
+	detail, _ = bindTestRequest(t, `{"filename":"a.png","url":"/a.png","size":-1}`, &types.CreateFilesRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "size", Reason: "min", Param: "0"}}, detail.Fields)
+
+	detail, _ = bindTestRequest(t, `{"roleName":"admin"}`, &types.CreateRolesRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "roleCode", Reason: "required"}}, detail.Fields)
+
+	// the fields of the embedded query params
+	detail, _ = bindTestRequest(t, `{"page":0,"limit":0}`, &types.ListUserssRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "limit", Reason: "gte", Param: "1"}}, detail.Fields)
+
+	detail, _ = bindTestRequest(t, `{"name":1}`, &types.CreatePermissionsRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "name", Reason: "type", Param: "string"}}, detail.Fields)
+
+	detail, _ = bindTestRequest(t, `{`, &types.CreatePermissionsRequest{})
+	assert.Empty(t, detail.Fields)
+
+	// 30 characters of 3 bytes pass max=64 but not the 72 bytes of bcrypt
+	detail, _ = bindTestRequest(t, `{"token":"abc","newPassword":"`+strings.Repeat("密", 30)+`"}`, &types.ResetPasswordRequest{})
+	assert.Equal(t, []*types.FieldError{{Field: "newPassword", Reason: "password"}}, detail.Fields)
+	detail, _ = bindTestRequest(t, `{"token":"abc","newPassword":"`+strings.Repeat("密", 24)+`"}`, &types.ResetPasswordRequest{})
+	assert.Nil(t, detail)
+
+	detail, _ = bindTestRequest(t, `{"name":"home","path":"/detail/:id"}`, &types.CreateMenusRequest{})
+	assert.Nil(t, detail)
+	detail, _ = bindTestRequest(t, `{"userName":"张三_01","password":"123456","userPhone":"13800138000","userEmail":"a@b.com"}`,
+		&types.CreateUsersRequest{})
+	assert.Nil(t, detail)
+}
+
+func Test_getFieldPath(t *testing.T) {
+	assert.Equal(t, "userName", getFieldPath("CreateUsersRequest.userName"))
+	assert.Equal(t, "columns[0].name", getFieldPath("ListUserssRequest.Params.columns[0].name"))
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/dao"
+	"godemo/internal/dberr"
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+// responseDBError respond the error of a dao or cache call, the error is classified by dberr, such as a
+// duplicate key is a conflict and a lost connection is service unavailable. The raw error is only logged,
+// as an error if the server is at fault and as a warning if the request is.
+func responseDBError(c *gin.Context, msg string, err error, fields ...logger.Field) {
+	fields = append(fields, logger.Err(err), middleware.GCtxRequestIDField(c))
+
+	// the dao knows the field of a unique value
+	var conflictErr *dao.ConflictError
+	if errors.As(err, &conflictErr) {
+		logger.Warn(msg, fields...)
+		response.Error(c, ecode.Conflict.RewriteMsg(conflictErr.Error()), &types.ConflictDetail{
+			Fields: []*types.FieldError{{Field: conflictErr.Field, Reason: "unique"}},
+		})
+		return
+	}
+
+	// the dao knows the column of the query params that is not valid, the conversion error is only logged
+	var queryParamsErr *dao.QueryParamsError
+	if errors.As(err, &queryParamsErr) {
+		logger.Warn(msg, fields...)
+		response.Error(c, ecode.InvalidParams, &types.InvalidParamsDetail{
+			Fields: []*types.FieldError{{Field: queryParamsErr.Field, Reason: queryParamsErr.Reason}},
+		})
+		return
+	}
+
+	// the record has been changed since the If-Match precondition
+	if errors.Is(err, dao.ErrVersionMismatch) {
+		logger.Warn(msg, fields...)
+		responsePreconditionFailed(c)
+		return
+	}
+
+	e := dberr.Classify(err)
+	if !e.Internal {
+		logger.Warn(msg, fields...)
+		response.Error(c, e.Code)
+		return
+	}
+	logger.Error(msg, fields...)
+	if e.Code.Code() == ecode.InternalServerError.Code() {
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	response.Error(c, e.Code)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/dao"
+	"godemo/internal/dberr"
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+func dbErrorTestResponse(t *testing.T, err error, data any) (int, int, string) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	responseDBError(c, "test error", err)
+
+	result := &struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data any    `json:"data"`
+	}{Data: data}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	return w.Code, result.Code, result.Msg
+}
+
+func Test_responseDBError(t *testing.T) {
+	detail := &types.ConflictDetail{}
+	status, code, msg := dbErrorTestResponse(t, fmt.Errorf("create: %w", &dao.ConflictError{Field: "userName"}), detail)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, ecode.Conflict.Code(), code)
+	assert.Equal(t, "userName already exists", msg)
+	assert.Equal(t, []*types.FieldError{{Field: "userName", Reason: "unique"}}, detail.Fields)
+
+	_, code, msg = dbErrorTestResponse(t, &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'user_name' at row 1"}, nil)
+	assert.Equal(t, ecode.InvalidParams.Code(), code)
+	assert.Equal(t, "a value is too long", msg)
+
+	// the conversion error is not shown to the client
+	invalid := &types.InvalidParamsDetail{}
+	_, code, msg = dbErrorTestResponse(t, &dao.QueryParamsError{Field: "columns[1].name", Reason: "unknown",
+		Err: errors.New("field name 'password' is not allowed")}, invalid)
+	assert.Equal(t, ecode.InvalidParams.Code(), code)
+	assert.Equal(t, ecode.InvalidParams.Msg(), msg)
+	assert.Equal(t, []*types.FieldError{{Field: "columns[1].name", Reason: "unknown"}}, invalid.Fields)
+
+	_, code, msg = dbErrorTestResponse(t, fmt.Errorf("%w: %v", dberr.ErrQueryParams, errors.New("unknown column")), nil)
+	assert.Equal(t, ecode.InvalidParams.Code(), code)
+	assert.Equal(t, "the query params are not valid", msg)
+
+	_, code, _ = dbErrorTestResponse(t, &mysql.MySQLError{Number: 1040}, nil)
+	assert.Equal(t, ecode.ServiceUnavailable.Code(), code)
+
+	status, _, _ = dbErrorTestResponse(t, errors.New("unknown"), nil)
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
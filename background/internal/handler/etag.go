@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/dao"
+	"godemo/internal/ecode"
+)
+
+// The conditional requests of a record, the entity tag of a record is its version column. GetByID responds
+// 304 Not Modified if If-None-Match matches the record, UpdateByID, PatchByID and DeleteByID change the record
+// only if If-Match matches it, otherwise they respond 412 Precondition Failed.
+
+// formatETag the strong entity tag of a record version
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag get the record version of a strong entity tag
+func parseETag(tag string) (uint64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil
+}
+
+// writeETag set the ETag header of a record, it responds 304 Not Modified and returns true if the record
+// matches If-None-Match. A soft deleted record has no entity tag, as it cannot be changed.
+func writeETag(c *gin.Context, version uint64, deletedAt gorm.DeletedAt) bool {
+	if deletedAt.Valid {
+		return false
+	}
+	etag := formatETag(version)
+	c.Header("ETag", etag)
+
+	// weak comparison, the W/ prefix is ignored
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// bindIfMatch get the precondition of If-Match as the update option of the dao, the record is changed only if
+// its version is one of the entity tags, or only if it exists for "*". It responds 412 Precondition Failed if
+// no entity tag can match a record, such as a weak one.
+func bindIfMatch(c *gin.Context) ([]dao.UpdateOption, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, true
+	}
+
+	var versions []uint64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return []dao.UpdateOption{dao.WithVersion()}, true
+		}
+		// strong comparison, a weak entity tag never matches
+		if version, ok := parseETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		logger.Warn("If-Match has no valid entity tag", logger.String("ifMatch", header), middleware.GCtxRequestIDField(c))
+		responsePreconditionFailed(c)
+		return nil, false
+	}
+	return []dao.UpdateOption{dao.WithVersion(versions...)}, true
+}
+
+// responsePreconditionFailed respond 412 Precondition Failed, the client gets the record again for its
+// current entity tag
+func responsePreconditionFailed(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, &response.Result{
+		Code: ecode.ErrPreconditionFailed.Code(),
+		Msg:  ecode.ErrPreconditionFailed.Msg(),
+		Data: &struct{}{},
+	})
+}
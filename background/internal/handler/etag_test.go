@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"godemo/internal/ecode"
+)
+
+func newETagTestContext(header string, value string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		c.Request.Header.Set(header, value)
+	}
+	return c, w
+}
+
+func Test_writeETag(t *testing.T) {
+	c, w := newETagTestContext("If-None-Match", "")
+	assert.False(t, writeETag(c, 3, gorm.DeletedAt{}))
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	for _, value := range []string{`"3"`, `"2", W/"3"`, "*"} {
+		c, w = newETagTestContext("If-None-Match", value)
+		assert.True(t, writeETag(c, 3, gorm.DeletedAt{}), value)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	}
+
+	c, _ = newETagTestContext("If-None-Match", `"2"`)
+	assert.False(t, writeETag(c, 3, gorm.DeletedAt{}))
+
+	// a soft deleted record has no entity tag
+	c, w = newETagTestContext("If-None-Match", "*")
+	assert.False(t, writeETag(c, 3, gorm.DeletedAt{Time: time.Now(), Valid: true}))
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func Test_bindIfMatch(t *testing.T) {
+	c, _ := newETagTestContext("If-Match", "")
+	opts, ok := bindIfMatch(c)
+	assert.True(t, ok)
+	assert.Empty(t, opts)
+
+	for _, value := range []string{`"3"`, `"2", "3"`, `W/"2", "3"`, "*"} {
+		c, _ = newETagTestContext("If-Match", value)
+		opts, ok = bindIfMatch(c)
+		assert.True(t, ok, value)
+		assert.Len(t, opts, 1)
+	}
+
+	// no entity tag can match, weak entity tags are not compared
+	for _, value := range []string{`W/"3"`, "3", `"abc"`} {
+		c, w := newETagTestContext("If-Match", value)
+		_, ok = bindIfMatch(c)
+		assert.False(t, ok, value)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Contains(t, w.Body.String(), ecode.ErrPreconditionFailed.Msg())
+	}
+}
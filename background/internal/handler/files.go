@@ -1,7 +1,19 @@
 package handler
 
 import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,27 +23,46 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/auth"
 	"godemo/internal/cache"
+	"godemo/internal/config"
 	"godemo/internal/dao"
 	"godemo/internal/database"
 	"godemo/internal/ecode"
 	"godemo/internal/model"
+	"godemo/internal/storage"
 	"godemo/internal/types"
 )
 
 var _ FilesHandler = (*filesHandler)(nil)
 
+// the fields of a files that can be patched
+var filesPatchFields = map[string]patchField{
+	"filename": {column: "filename", required: true},
+	"url":      {column: "url", required: true},
+	"size":     {column: "size"},
+	"mimeType": {column: "mime_type"},
+	"userID":   {column: "user_id"},
+}
+
 // FilesHandler defining the handler interface
 type FilesHandler interface {
 	Create(c *gin.Context)
 	DeleteByID(c *gin.Context)
 	UpdateByID(c *gin.Context)
+	PatchByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	RestoreByID(c *gin.Context)
+	PurgeByID(c *gin.Context)
+	Upload(c *gin.Context)
+	Download(c *gin.Context)
 }
 
 type filesHandler struct {
-	iDao dao.FilesDao
+	iDao          dao.FilesDao
+	storage       storage.Storage
+	redirectHosts []string // hosts of the external urls that Download redirects to
 }
 
 // NewFilesHandler creating the handler interface
@@ -41,6 +72,8 @@ func NewFilesHandler() FilesHandler {
 			database.GetDB(), // db driver is mysql
 			cache.NewFilesCache(database.GetCacheType()),
 		),
+		storage:       storage.Get(),
+		redirectHosts: config.Get().Storage.RedirectHosts,
 	}
 }
 
@@ -59,7 +92,7 @@ func (h *filesHandler) Create(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
@@ -74,8 +107,7 @@ func (h *filesHandler) Create(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	err = h.iDao.Create(ctx, files)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "Create error", err, logger.Any("form", form))
 		return
 	}
 
@@ -89,7 +121,9 @@ func (h *filesHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.DeleteFilesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/files/{id} [delete]
 // @Security BearerAuth
 func (h *filesHandler) DeleteByID(c *gin.Context) {
@@ -98,12 +132,15 @@ func (h *filesHandler) DeleteByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	err := h.iDao.DeleteByID(ctx, id, opts...)
 	if err != nil {
-		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "DeleteByID error", err, logger.Any("id", id))
 		return
 	}
 
@@ -118,7 +155,9 @@ func (h *filesHandler) DeleteByID(c *gin.Context) {
 // @Produce json
 // @Param id path string true "id"
 // @Param data body types.UpdateFilesByIDRequest true "files information"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.UpdateFilesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/files/{id} [put]
 // @Security BearerAuth
 func (h *filesHandler) UpdateByID(c *gin.Context) {
@@ -127,12 +166,16 @@ func (h *filesHandler) UpdateByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	form := &types.UpdateFilesByIDRequest{}
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 	form.ID = id
@@ -146,10 +189,48 @@ func (h *filesHandler) UpdateByID(c *gin.Context) {
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
 	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByID(ctx, files)
+	err = h.iDao.UpdateByID(ctx, files, opts...)
+	if err != nil {
+		responseDBError(c, "UpdateByID error", err, logger.Any("form", form))
+		return
+	}
+
+	response.Success(c)
+}
+
+// PatchByID patch a files by id
+// @Summary Patch a files by id
+// @Description Updates the specified files by given id in the path with a JSON Merge Patch (RFC 7396), the members of the patch are written even if they are zero values, and a null member resets the field.
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.UpdateFilesByIDRequest true "files information, only the members to change"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
+// @Success 200 {object} types.UpdateFilesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
+// @Router /api/v1/files/{id} [patch]
+// @Security BearerAuth
+func (h *filesHandler) PatchByID(c *gin.Context) {
+	_, id, isAbort := getFilesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+
+	columns, ok := bindMergePatch(c, &types.UpdateFilesByIDRequest{}, filesPatchFields)
+	if !ok {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PatchByID(ctx, id, columns, opts...)
 	if err != nil {
-		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "PatchByID error", err, logger.Any("id", id))
 		return
 	}
 
@@ -161,9 +242,13 @@ func (h *filesHandler) UpdateByID(c *gin.Context) {
 // @Description Gets detailed information of a files specified by the given id in the path.
 // @Tags files
 // @Param id path string true "id"
+// @Param includeDeleted query bool false "also get the soft deleted record"
+// @Param If-None-Match header string false "entity tag of the cached record, 304 Not Modified is responded if it is still current"
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.GetFilesByIDReply{}
+// @Header 200 {string} ETag "entity tag of the record, it is not set for a soft deleted record"
+// @Success 304 "the record is not modified"
 // @Router /api/v1/files/{id} [get]
 // @Security BearerAuth
 func (h *filesHandler) GetByID(c *gin.Context) {
@@ -174,18 +259,22 @@ func (h *filesHandler) GetByID(c *gin.Context) {
 	}
 
 	ctx := middleware.WrapCtx(c)
-	files, err := h.iDao.GetByID(ctx, id)
+	files, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(c.Query("includeDeleted") == "true"))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
 		} else {
-			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
 		}
 		return
 	}
 
+	// the client has the current record
+	if writeETag(c, files.Version, files.DeletedAt) {
+		return
+	}
+
 	data := &types.FilesObjDetail{}
 	err = copier.Copy(data, files)
 	if err != nil {
@@ -193,6 +282,9 @@ func (h *filesHandler) GetByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if files.DeletedAt.Valid {
+		data.DeletedAt = &files.DeletedAt.Time
+	}
 
 	response.Success(c, gin.H{"files": data})
 }
@@ -212,15 +304,14 @@ func (h *filesHandler) List(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	filess, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	filess, total, err := h.iDao.GetByColumns(ctx, &form.Params, dao.WithIncludeDeleted(form.IncludeDeleted))
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
 		return
 	}
 
@@ -236,6 +327,247 @@ func (h *filesHandler) List(c *gin.Context) {
 	})
 }
 
+// RestoreByID restore a soft deleted files by id
+// @Summary Restore a soft deleted files by id
+// @Description Restores the soft deleted files identified by the given id in the path.
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.RestoreFilesByIDReply{}
+// @Router /api/v1/files/{id}/restore [post]
+// @Security BearerAuth
+func (h *filesHandler) RestoreByID(c *gin.Context) {
+	_, id, isAbort := getFilesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.RestoreByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("RestoreByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "RestoreByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	response.Success(c)
+}
+
+// PurgeByID permanently delete a soft deleted files by id
+// @Summary Purge a soft deleted files by id
+// @Description Permanently deletes the files identified by the given id in the path, the files must be soft deleted first.
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.PurgeFilesByIDReply{}
+// @Router /api/v1/files/{id}/purge [delete]
+// @Security BearerAuth
+func (h *filesHandler) PurgeByID(c *gin.Context) {
+	_, id, isAbort := getFilesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	files, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(true))
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	err = h.iDao.PurgeByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("PurgeByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "PurgeByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	// the content of an uploaded file is kept after a soft delete, it is removed together with the record,
+	// the url is written by the metadata api and is never used as a storage key
+	if files.StorageKey != "" {
+		if err = h.storage.Delete(ctx, files.StorageKey); err != nil {
+			logger.Warn("storage.Delete error", logger.Err(err), logger.String("key", files.StorageKey), middleware.GCtxRequestIDField(c))
+		}
+	}
+
+	response.Success(c)
+}
+
+// Upload a file
+// @Summary Upload a file
+// @Description Streams the file in the multipart form field "file" to the storage backend and creates the files record,
+// @Description the size, mime type and user id are filled in by the server.
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "file content"
+// @Success 200 {object} types.UploadFilesReply{}
+// @Router /api/v1/files/upload [post]
+// @Security BearerAuth
+func (h *filesHandler) Upload(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+	userID, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	part, err := nextFilePart(c.Request)
+	if err != nil {
+		logger.Warn("nextFilePart error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg("the multipart form field 'file' is required"))
+		return
+	}
+	defer part.Close() //nolint
+
+	filename := cleanFilename(part.FileName())
+	key, err := newFileKey(filename)
+	if err != nil {
+		logger.Error("newFileKey error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrUploadFiles)
+		return
+	}
+
+	// the mime type is detected from the content, the extension is only used when the content is not recognized
+	content := bufio.NewReaderSize(part, sniffLen)
+	head, _ := content.Peek(sniffLen)
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/octet-stream" {
+		if t := mime.TypeByExtension(path.Ext(filename)); t != "" {
+			mimeType = t
+		}
+	}
+
+	var reader io.Reader = content
+	maxSize := int64(config.Get().Storage.MaxUploadSize) << 20
+	if maxSize > 0 {
+		reader = io.LimitReader(content, maxSize+1) // read one more byte to detect the oversize file
+	}
+
+	ctx := middleware.WrapCtx(c)
+	size, err := h.storage.Save(ctx, key, reader)
+	if err != nil {
+		logger.Error("storage.Save error", logger.Err(err), logger.String("key", key), middleware.GCtxRequestIDField(c))
+		_ = h.storage.Delete(ctx, key)
+		response.Error(c, ecode.ErrUploadFiles)
+		return
+	}
+	if maxSize > 0 && size > maxSize {
+		logger.Warn("upload file is too large", logger.String("filename", filename), logger.Int64("maxSize", maxSize), middleware.GCtxRequestIDField(c))
+		_ = h.storage.Delete(ctx, key)
+		response.Error(c, ecode.InvalidParams.RewriteMsg(fmt.Sprintf("the file exceeds the maximum upload size of %dMB", config.Get().Storage.MaxUploadSize)))
+		return
+	}
+
+	files := &model.Files{
+		Filename:   filename,
+		URL:        key,
+		Size:       size,
+		MimeType:   mimeType,
+		UserID:     userID,
+		StorageKey: key,
+	}
+	err = h.iDao.Create(ctx, files)
+	if err != nil {
+		_ = h.storage.Delete(ctx, key)
+		responseDBError(c, "Create error", err, logger.Any("files", files))
+		return
+	}
+
+	data, err := convertFiles(files)
+	if err != nil {
+		response.Error(c, ecode.ErrUploadFiles)
+		return
+	}
+
+	response.Success(c, data)
+}
+
+// Download the content of a file
+// @Summary Download a file by id
+// @Description Responds the content of the file as an attachment and supports Range requests, a file that is not
+// @Description uploaded is redirected to its url only if the host of the url is in storage.redirectHosts of the configuration.
+// @Tags files
+// @Produce octet-stream
+// @Param id path string true "id"
+// @Param Range header string false "byte range, e.g. bytes=0-1023"
+// @Success 200 {file} file "file content"
+// @Success 206 {file} file "partial file content"
+// @Router /api/v1/files/{id}/download [get]
+// @Security BearerAuth
+func (h *filesHandler) Download(c *gin.Context) {
+	_, id, isAbort := getFilesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	files, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	if files.StorageKey == "" {
+		// a file created by the metadata api has no content in the storage, its url is only followed to
+		// a known host, so that the api cannot be used to redirect to any site
+		if isRedirectFileURL(files.URL, h.redirectHosts) {
+			c.Redirect(http.StatusFound, files.URL)
+			return
+		}
+		logger.Warn("Download no content", logger.Any("id", id), logger.String("url", files.URL), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.NotFound)
+		return
+	}
+
+	obj, err := h.storage.Open(ctx, files.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			logger.Warn("storage.Open not found", logger.Err(err), logger.String("key", files.StorageKey), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			logger.Error("storage.Open error", logger.Err(err), logger.String("key", files.StorageKey), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrDownloadFiles)
+		}
+		return
+	}
+	defer obj.Close() //nolint
+
+	// the mime type is given by the uploader, the content must not be sniffed or run as a page of this site
+	if files.MimeType != "" {
+		c.Header("Content-Type", files.MimeType)
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": files.Filename}))
+	// ServeContent handles Range, If-Range and If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, files.Filename, obj.ModTime(), obj)
+}
+
 func getFilesIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -254,6 +586,9 @@ func convertFiles(files *model.Files) (*types.FilesObjDetail, error) {
 		return nil, err
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if files.DeletedAt.Valid {
+		data.DeletedAt = &files.DeletedAt.Time
+	}
 
 	return data, nil
 }
@@ -270,3 +605,74 @@ func convertFiless(fromValues []*model.Files) ([]*types.FilesObjDetail, error) {
 
 	return toValues, nil
 }
+
+// the number of bytes used by http.DetectContentType
+const sniffLen = 512
+
+// nextFilePart read the multipart body until the "file" part, the content of the part is not buffered
+func nextFilePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
+// keep the base name of the uploaded file, some clients send the full path of the file
+func cleanFilename(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == "/" {
+		filename = "unnamed"
+	}
+	// the length of the filename column is in characters, a multi-byte character is not split
+	if runes := []rune(filename); len(runes) > 255 {
+		filename = string(runes[len(runes)-255:])
+	}
+	return filename
+}
+
+// the storage key of an uploaded file is <yyyy>/<mm>/<dd>/<random><ext>, the name given by the
+// client is not used in the key, so that it cannot collide with or point outside the storage.
+func newFileKey(filename string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+	for _, r := range ext[min(1, len(ext)):] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			ext = ""
+			break
+		}
+	}
+	if len(ext) > 16 {
+		ext = ""
+	}
+
+	return time.Now().Format("2006/01/02") + "/" + hex.EncodeToString(buf) + ext, nil
+}
+
+// files created by the metadata api may point to an external url, they have no storage key. The url is
+// redirected to only if it is an http(s) url of one of the hosts.
+func isRedirectFileURL(rawURL string, hosts []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
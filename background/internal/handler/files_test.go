@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +24,7 @@ import (
 	"godemo/internal/dao"
 	"godemo/internal/database"
 	"godemo/internal/model"
+	"godemo/internal/storage"
 	"godemo/internal/types"
 )
 
@@ -41,7 +48,12 @@ func newFilesHandler() *gotest.Handler {
 
 	// init mock handler
 	h := gotest.NewHandler(d, testData)
-	h.IHandler = &filesHandler{iDao: d.IDao.(dao.FilesDao)}
+	// init mock storage
+	fileStorage, err := storage.NewLocal(filepath.Join(os.TempDir(), "godemo_files_test"))
+	if err != nil {
+		panic(err)
+	}
+	h.IHandler = &filesHandler{iDao: d.IDao.(dao.FilesDao), storage: fileStorage}
 	iHandler := h.IHandler.(FilesHandler)
 
 	testFns := []gotest.RouterInfo{
@@ -75,6 +87,24 @@ func newFilesHandler() *gotest.Handler {
 			Path:        "/files/list",
 			HandlerFunc: iHandler.List,
 		},
+		{
+			FuncName:    "RestoreByID",
+			Method:      http.MethodPost,
+			Path:        "/files/:id/restore",
+			HandlerFunc: iHandler.RestoreByID,
+		},
+		{
+			FuncName:    "PurgeByID",
+			Method:      http.MethodDelete,
+			Path:        "/files/:id/purge",
+			HandlerFunc: iHandler.PurgeByID,
+		},
+		{
+			FuncName:    "Download",
+			Method:      http.MethodGet,
+			Path:        "/files/:id/download",
+			HandlerFunc: iHandler.Download,
+		},
 	}
 
 	h.GoRunHTTPServer(testFns)
@@ -110,11 +140,11 @@ func Test_filesHandler_DeleteByID(t *testing.T) {
 	h := newFilesHandler()
 	defer h.Close()
 	testData := h.TestData.(*model.Files)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	h.MockDao.SQLMock.ExpectBegin()
 	h.MockDao.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID). // adjusted for the amount of test data
+		WithArgs(h.MockDao.AnyTime, testData.ID). // adjusted for the amount of test data
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	h.MockDao.SQLMock.ExpectCommit()
 
@@ -209,7 +239,7 @@ func Test_filesHandler_List(t *testing.T) {
 	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
 
 	result := &httpcli.StdResult{}
-	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListFilessRequest{query.Params{
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListFilessRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "ignore count", // ignore test count
@@ -226,7 +256,7 @@ func Test_filesHandler_List(t *testing.T) {
 	assert.NoError(t, err)
 
 	// get error test
-	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListFilessRequest{query.Params{
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListFilessRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "unknown-column",
@@ -234,6 +264,165 @@ func Test_filesHandler_List(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_filesHandler_RestoreByID(t *testing.T) {
+	h := newFilesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Files)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("RestoreByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 0), nil)
+	assert.NoError(t, err)
+
+	// restore error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_filesHandler_PurgeByID(t *testing.T) {
+	h := newFilesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Files)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "storage_key"}).
+		AddRow(testData.ID, "test/purge.txt", "test/purge.txt")
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("PurgeByID", testData.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 0))
+	assert.NoError(t, err)
+
+	// purge error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 111))
+	assert.Error(t, err)
+}
+
+func Test_filesHandler_Download(t *testing.T) {
+	h := newFilesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Files)
+	fileStorage := h.IHandler.(*filesHandler).storage
+	key := "test/download.txt"
+	_, err := fileStorage.Save(context.Background(), key, strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "filename", "url", "mime_type", "storage_key"}).
+		AddRow(testData.ID, "download.txt", key, "text/plain", key)
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+
+	req, err := http.NewRequest(http.MethodGet, h.GetRequestURL("Download", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", "bytes=0-4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() //nolint
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "bytes 0-4/11", resp.Header.Get("Content-Range"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "download.txt")
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+
+	// the url of a file created by the metadata api is not a storage key
+	rows = sqlmock.NewRows([]string{"id", "filename", "url"}).
+		AddRow(testData.ID, "download.txt", key)
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+	result := &httpcli.StdResult{}
+	err = httpcli.Get(result, h.GetRequestURL("Download", testData.ID))
+	assert.Error(t, err)
+
+	// zero id error test
+	err = httpcli.Get(result, h.GetRequestURL("Download", 0))
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Get(result, h.GetRequestURL("Download", 111))
+	assert.Error(t, err)
+}
+
+func Test_newFileKey(t *testing.T) {
+	key, err := newFileKey("photo.JPG")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2}/[0-9a-f]{32}\.jpg$`, key)
+
+	key, err = newFileKey("../../etc/passwd")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2}/[0-9a-f]{32}$`, key)
+
+	key, err = newFileKey("archive.tar.g/z")
+	assert.NoError(t, err)
+	assert.NotContains(t, key[len("2006/01/02/"):], "/")
+}
+
+func Test_cleanFilename(t *testing.T) {
+	assert.Equal(t, "report.pdf", cleanFilename("report.pdf"))
+	assert.Equal(t, "report.pdf", cleanFilename(`C:\Users\me\report.pdf`))
+	assert.Equal(t, "passwd", cleanFilename("../../etc/passwd"))
+	assert.Equal(t, "unnamed", cleanFilename(""))
+
+	// the name is truncated by characters, the end with the extension is kept
+	name := cleanFilename(strings.Repeat("文", 300) + ".pdf")
+	assert.True(t, utf8.ValidString(name))
+	assert.Equal(t, 255, utf8.RuneCountInString(name))
+	assert.True(t, strings.HasSuffix(name, "文.pdf"))
+}
+
+func Test_isRedirectFileURL(t *testing.T) {
+	assert.False(t, isRedirectFileURL("https://cdn.example.com/a.png", nil))
+
+	hosts := []string{"cdn.example.com"}
+	assert.True(t, isRedirectFileURL("https://cdn.example.com/a.png", hosts))
+	assert.True(t, isRedirectFileURL("http://CDN.example.com:8080/a.png", hosts))
+	assert.False(t, isRedirectFileURL("https://evil.example.com/a.png", hosts))
+	assert.False(t, isRedirectFileURL("https://cdn.example.com@evil.example.com/a.png", hosts))
+	assert.False(t, isRedirectFileURL("//cdn.example.com/a.png", hosts))
+	assert.False(t, isRedirectFileURL("javascript://cdn.example.com/%0aalert(1)", hosts))
+	assert.False(t, isRedirectFileURL("2026/01/02/a.png", hosts))
+}
+
 func TestNewFilesHandler(t *testing.T) {
 	defer func() {
 		recover()
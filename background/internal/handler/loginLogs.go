@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/copier"
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+var _ LoginLogsHandler = (*loginLogsHandler)(nil)
+
+// LoginLogsHandler defining the handler interface, the login logs are written by the auth handler and can only be read
+type LoginLogsHandler interface {
+	List(c *gin.Context)
+}
+
+type loginLogsHandler struct {
+	iDao dao.LoginLogsDao
+}
+
+// NewLoginLogsHandler creating the handler interface
+func NewLoginLogsHandler() LoginLogsHandler {
+	return &loginLogsHandler{
+		iDao: dao.NewLoginLogsDao(database.GetDB()),
+	}
+}
+
+// List get a paginated list of login logs by custom conditions
+// @Summary Get a paginated list of login logs by custom conditions
+// @Description Returns a paginated list of the successful and failed logins based on query filters, such as userName
+// @Description or ip, including page number and size.
+// @Tags loginLogs
+// @Accept json
+// @Produce json
+// @Param data body types.Params true "query parameters"
+// @Success 200 {object} types.ListLoginLogsReply{}
+// @Router /api/v1/loginLogs/list [post]
+// @Security BearerAuth
+func (h *loginLogsHandler) List(c *gin.Context) {
+	form := &types.ListLoginLogsRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	loginLogs, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	if err != nil {
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
+		return
+	}
+
+	data, err := convertLoginLogs(loginLogs)
+	if err != nil {
+		response.Error(c, ecode.ErrListLoginLogs)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"loginLogs": data,
+		"total":     total,
+	})
+}
+
+func convertLoginLog(record *model.LoginLogs) (*types.LoginLogsObjDetail, error) {
+	data := &types.LoginLogsObjDetail{}
+	err := copier.Copy(data, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func convertLoginLogs(fromValues []*model.LoginLogs) ([]*types.LoginLogsObjDetail, error) {
+	toValues := []*types.LoginLogsObjDetail{}
+	for _, v := range fromValues {
+		data, err := convertLoginLog(v)
+		if err != nil {
+			return nil, err
+		}
+		toValues = append(toValues, data)
+	}
+
+	return toValues, nil
+}
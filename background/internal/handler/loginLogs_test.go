@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/httpcli"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"godemo/internal/dao"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+func newLoginLogsHandler() *gotest.Handler {
+	testData := &model.LoginLogs{}
+	testData.ID = 1
+
+	// init mock dao
+	d := gotest.NewDao(nil, testData)
+	d.IDao = dao.NewLoginLogsDao(d.DB)
+
+	// init mock handler
+	h := gotest.NewHandler(d, testData)
+	h.IHandler = &loginLogsHandler{iDao: d.IDao.(dao.LoginLogsDao)}
+	iHandler := h.IHandler.(LoginLogsHandler)
+
+	testFns := []gotest.RouterInfo{
+		{
+			FuncName:    "List",
+			Method:      http.MethodPost,
+			Path:        "/loginLogs/list",
+			HandlerFunc: iHandler.List,
+		},
+	}
+
+	h.GoRunHTTPServer(testFns)
+
+	time.Sleep(time.Millisecond * 200)
+	return h
+}
+
+func Test_loginLogsHandler_List(t *testing.T) {
+	h := newLoginLogsHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.LoginLogs)
+
+	// column names and corresponding data
+	rows := sqlmock.NewRows([]string{"id", "user_name", "ip", "result", "reason"}).
+		AddRow(testData.ID, "admin", "127.0.0.1", "failure", "wrongPassword")
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListLoginLogsRequest{Params: query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "ignore count", // ignore test count
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// nil params error test
+	err = httpcli.Post(result, h.GetRequestURL("List"), nil)
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListLoginLogsRequest{Params: query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "unknown-column",
+	}})
+	assert.Error(t, err)
+}
+
+func TestNewLoginLogsHandler(t *testing.T) {
+	defer func() {
+		recover()
+	}()
+	_ = NewLoginLogsHandler()
+}
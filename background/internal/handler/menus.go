@@ -21,13 +21,26 @@ import (
 
 var _ MenusHandler = (*menusHandler)(nil)
 
+// the fields of a menus that can be patched
+var menusPatchFields = map[string]patchField{
+	"name":       {column: "name", required: true},
+	"path":       {column: "path", required: true},
+	"icon":       {column: "icon"},
+	"parentID":   {column: "parent_id"},
+	"order":      {column: "order"},
+	"permission": {column: "permission"},
+}
+
 // MenusHandler defining the handler interface
 type MenusHandler interface {
 	Create(c *gin.Context)
 	DeleteByID(c *gin.Context)
 	UpdateByID(c *gin.Context)
+	PatchByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	RestoreByID(c *gin.Context)
+	PurgeByID(c *gin.Context)
 }
 
 type menusHandler struct {
@@ -59,7 +72,7 @@ func (h *menusHandler) Create(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
@@ -74,8 +87,7 @@ func (h *menusHandler) Create(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	err = h.iDao.Create(ctx, menus)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "Create error", err, logger.Any("form", form))
 		return
 	}
 
@@ -89,7 +101,9 @@ func (h *menusHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.DeleteMenusByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/menus/{id} [delete]
 // @Security BearerAuth
 func (h *menusHandler) DeleteByID(c *gin.Context) {
@@ -98,12 +112,15 @@ func (h *menusHandler) DeleteByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	err := h.iDao.DeleteByID(ctx, id, opts...)
 	if err != nil {
-		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "DeleteByID error", err, logger.Any("id", id))
 		return
 	}
 
@@ -118,7 +135,9 @@ func (h *menusHandler) DeleteByID(c *gin.Context) {
 // @Produce json
 // @Param id path string true "id"
 // @Param data body types.UpdateMenusByIDRequest true "menus information"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.UpdateMenusByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/menus/{id} [put]
 // @Security BearerAuth
 func (h *menusHandler) UpdateByID(c *gin.Context) {
@@ -127,12 +146,16 @@ func (h *menusHandler) UpdateByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	form := &types.UpdateMenusByIDRequest{}
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 	form.ID = id
@@ -146,10 +169,48 @@ func (h *menusHandler) UpdateByID(c *gin.Context) {
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
 	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByID(ctx, menus)
+	err = h.iDao.UpdateByID(ctx, menus, opts...)
+	if err != nil {
+		responseDBError(c, "UpdateByID error", err, logger.Any("form", form))
+		return
+	}
+
+	response.Success(c)
+}
+
+// PatchByID patch a menus by id
+// @Summary Patch a menus by id
+// @Description Updates the specified menus by given id in the path with a JSON Merge Patch (RFC 7396), the members of the patch are written even if they are zero values, and a null member resets the field.
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.UpdateMenusByIDRequest true "menus information, only the members to change"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
+// @Success 200 {object} types.UpdateMenusByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
+// @Router /api/v1/menus/{id} [patch]
+// @Security BearerAuth
+func (h *menusHandler) PatchByID(c *gin.Context) {
+	_, id, isAbort := getMenusIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+
+	columns, ok := bindMergePatch(c, &types.UpdateMenusByIDRequest{}, menusPatchFields)
+	if !ok {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PatchByID(ctx, id, columns, opts...)
 	if err != nil {
-		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "PatchByID error", err, logger.Any("id", id))
 		return
 	}
 
@@ -161,9 +222,13 @@ func (h *menusHandler) UpdateByID(c *gin.Context) {
 // @Description Gets detailed information of a menus specified by the given id in the path.
 // @Tags menus
 // @Param id path string true "id"
+// @Param includeDeleted query bool false "also get the soft deleted record"
+// @Param If-None-Match header string false "entity tag of the cached record, 304 Not Modified is responded if it is still current"
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.GetMenusByIDReply{}
+// @Header 200 {string} ETag "entity tag of the record, it is not set for a soft deleted record"
+// @Success 304 "the record is not modified"
 // @Router /api/v1/menus/{id} [get]
 // @Security BearerAuth
 func (h *menusHandler) GetByID(c *gin.Context) {
@@ -174,18 +239,22 @@ func (h *menusHandler) GetByID(c *gin.Context) {
 	}
 
 	ctx := middleware.WrapCtx(c)
-	menus, err := h.iDao.GetByID(ctx, id)
+	menus, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(c.Query("includeDeleted") == "true"))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
 		} else {
-			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
 		}
 		return
 	}
 
+	// the client has the current record
+	if writeETag(c, menus.Version, menus.DeletedAt) {
+		return
+	}
+
 	data := &types.MenusObjDetail{}
 	err = copier.Copy(data, menus)
 	if err != nil {
@@ -193,6 +262,9 @@ func (h *menusHandler) GetByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if menus.DeletedAt.Valid {
+		data.DeletedAt = &menus.DeletedAt.Time
+	}
 
 	response.Success(c, gin.H{"menus": data})
 }
@@ -212,15 +284,14 @@ func (h *menusHandler) List(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	menuss, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	menuss, total, err := h.iDao.GetByColumns(ctx, &form.Params, dao.WithIncludeDeleted(form.IncludeDeleted))
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
 		return
 	}
 
@@ -236,6 +307,68 @@ func (h *menusHandler) List(c *gin.Context) {
 	})
 }
 
+// RestoreByID restore a soft deleted menus by id
+// @Summary Restore a soft deleted menus by id
+// @Description Restores the soft deleted menus identified by the given id in the path.
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.RestoreMenusByIDReply{}
+// @Router /api/v1/menus/{id}/restore [post]
+// @Security BearerAuth
+func (h *menusHandler) RestoreByID(c *gin.Context) {
+	_, id, isAbort := getMenusIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.RestoreByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("RestoreByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "RestoreByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	response.Success(c)
+}
+
+// PurgeByID permanently delete a soft deleted menus by id
+// @Summary Purge a soft deleted menus by id
+// @Description Permanently deletes the menus identified by the given id in the path, the menus must be soft deleted first.
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.PurgeMenusByIDReply{}
+// @Router /api/v1/menus/{id}/purge [delete]
+// @Security BearerAuth
+func (h *menusHandler) PurgeByID(c *gin.Context) {
+	_, id, isAbort := getMenusIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PurgeByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("PurgeByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "PurgeByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	response.Success(c)
+}
+
 func getMenusIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -254,6 +387,9 @@ func convertMenus(menus *model.Menus) (*types.MenusObjDetail, error) {
 		return nil, err
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if menus.DeletedAt.Valid {
+		data.DeletedAt = &menus.DeletedAt.Time
+	}
 
 	return data, nil
 }
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+// patchField is a field of a resource that can be changed by a JSON Merge Patch
+type patchField struct {
+	column   string
+	required bool // the field cannot be removed by null or set to a zero value, such as a name
+}
+
+// bindMergePatch bind a JSON Merge Patch (RFC 7396) body, form is the update request of the resource.
+// The members of the patch are decoded and validated by the json names and binding tags of form, a null
+// member resets the field to its zero value. Only the fields listed in fields can be patched.
+// It returns the columns to write, a column is present even if its value is zero, and responds
+// ecode.InvalidParams with the fields that are not valid if the patch cannot be applied.
+func bindMergePatch(c *gin.Context, form any, fields map[string]patchField) (map[string]interface{}, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger.Warn("ReadAll error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return nil, false
+	}
+	members := map[string]json.RawMessage{}
+	err = json.Unmarshal(body, &members)
+	if err != nil {
+		logger.Warn("bindMergePatch error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return nil, false
+	}
+
+	// the patch is decoded without the null members, which are not validated
+	names := make([]string, 0, len(members))
+	values := map[string]json.RawMessage{}
+	fieldErrors := []*types.FieldError{}
+	for name, value := range members {
+		names = append(names, name)
+		if _, ok := fields[name]; !ok {
+			fieldErrors = append(fieldErrors, &types.FieldError{Field: name, Reason: "unknown"})
+			continue
+		}
+		if !isJSONNull(value) {
+			values[name] = value
+		}
+	}
+	sort.Strings(names)
+	if len(fieldErrors) > 0 {
+		sort.Slice(fieldErrors, func(i, j int) bool { return fieldErrors[i].Field < fieldErrors[j].Field })
+		logger.Warn("bindMergePatch error: unknown fields", logger.Any("fields", names), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams, &types.InvalidParamsDetail{Fields: fieldErrors})
+		return nil, false
+	}
+	data, _ := json.Marshal(values)
+	err = json.Unmarshal(data, form)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(form)
+	}
+	if err != nil {
+		logger.Warn("bindMergePatch error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return nil, false
+	}
+
+	formValue := reflect.ValueOf(form).Elem()
+	columns := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		value, ok := getJSONFieldValue(formValue, name)
+		if !ok {
+			continue
+		}
+		if fields[name].required && value.IsZero() {
+			fieldErrors = append(fieldErrors, &types.FieldError{Field: name, Reason: "required"})
+			continue
+		}
+		columns[fields[name].column] = value.Interface()
+	}
+	if len(fieldErrors) > 0 {
+		logger.Warn("bindMergePatch error: required fields", logger.Any("fields", names), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams, &types.InvalidParamsDetail{Fields: fieldErrors})
+		return nil, false
+	}
+
+	return columns, true
+}
+
+func isJSONNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+// get the value of the struct field with the json name, a pointer field is dereferenced and a nil
+// pointer is the zero value of its element
+func getJSONFieldValue(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		jsonName, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if jsonName != name {
+			continue
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Zero(value.Type().Elem()), true
+			}
+			value = value.Elem()
+		}
+		return value, true
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+func patchTestRequest(t *testing.T, body string, form any, fields map[string]patchField) (map[string]interface{}, *types.InvalidParamsDetail) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+
+	columns, ok := bindMergePatch(c, form, fields)
+	if ok {
+		return columns, nil
+	}
+
+	result := &struct {
+		Code int                        `json:"code"`
+		Data *types.InvalidParamsDetail `json:"data"`
+	}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.Equal(t, ecode.InvalidParams.Code(), result.Code)
+	if result.Data == nil {
+		result.Data = &types.InvalidParamsDetail{}
+	}
+	return nil, result.Data
+}
+
+func Test_bindMergePatch(t *testing.T) {
+	// the null and zero values are written, the omitted members are not
+	columns, detail := patchTestRequest(t, `{"nickName":null,"userEmail":"","userGender":"2"}`,
+		&types.UpdateUsersByIDRequest{}, usersPatchFields)
+	assert.Nil(t, detail)
+	assert.Equal(t, map[string]interface{}{"nick_name": "", "user_email": "", "user_gender": "2"}, columns)
+
+	// a pointer field is dereferenced, null is its zero value
+	columns, detail = patchTestRequest(t, `{"requireTwoFactor":false,"parentID":null}`,
+		&types.UpdateRolesByIDRequest{}, rolesPatchFields)
+	assert.Nil(t, detail)
+	assert.Equal(t, map[string]interface{}{"require_two_factor": false, "parent_id": uint64(0)}, columns)
+
+	columns, detail = patchTestRequest(t, `{"size":0,"userID":3}`, &types.UpdateFilesByIDRequest{}, filesPatchFields)
+	assert.Nil(t, detail)
+	assert.Equal(t, map[string]interface{}{"size": int64(0), "user_id": uint64(3)}, columns)
+
+	columns, detail = patchTestRequest(t, `{}`, &types.UpdatePermissionsByIDRequest{}, permissionsPatchFields)
+	assert.Nil(t, detail)
+	assert.Empty(t, columns)
+
+	// the fields that are not in the whitelist
+	_, detail = patchTestRequest(t, `{"password":"123456","id":2,"nickName":"tom"}`,
+		&types.UpdateUsersByIDRequest{}, usersPatchFields)
+	assert.Equal(t, []*types.FieldError{{Field: "id", Reason: "unknown"}, {Field: "password", Reason: "unknown"}}, detail.Fields)
+
+	// a required field cannot be removed
+	_, detail = patchTestRequest(t, `{"name":null,"code":""}`, &types.UpdatePermissionsByIDRequest{}, permissionsPatchFields)
+	assert.Equal(t, []*types.FieldError{{Field: "code", Reason: "required"}, {Field: "name", Reason: "required"}}, detail.Fields)
+
+	// the binding rules of the update request
+	_, detail = patchTestRequest(t, `{"userEmail":"a@b","status":"3"}`, &types.UpdateUsersByIDRequest{}, usersPatchFields)
+	assert.ElementsMatch(t, []*types.FieldError{{Field: "userEmail", Reason: "email"}, {Field: "status", Reason: "oneof", Param: "1 2"}}, detail.Fields)
+	_, detail = patchTestRequest(t, `{"size":"1"}`, &types.UpdateFilesByIDRequest{}, filesPatchFields)
+	assert.Equal(t, []*types.FieldError{{Field: "size", Reason: "type", Param: "int64"}}, detail.Fields)
+
+	// the patch must be an object
+	_, detail = patchTestRequest(t, `[1]`, &types.UpdateFilesByIDRequest{}, filesPatchFields)
+	assert.Empty(t, detail.Fields)
+}
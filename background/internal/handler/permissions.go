@@ -21,17 +21,29 @@ import (
 
 var _ PermissionsHandler = (*permissionsHandler)(nil)
 
+// the fields of a permissions that can be patched
+var permissionsPatchFields = map[string]patchField{
+	"name":        {column: "name", required: true},
+	"code":        {column: "code", required: true},
+	"description": {column: "description"},
+}
+
 // PermissionsHandler defining the handler interface
 type PermissionsHandler interface {
 	Create(c *gin.Context)
 	DeleteByID(c *gin.Context)
 	UpdateByID(c *gin.Context)
+	PatchByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	CheckAvailability(c *gin.Context)
+	RestoreByID(c *gin.Context)
+	PurgeByID(c *gin.Context)
 }
 
 type permissionsHandler struct {
-	iDao dao.PermissionsDao
+	iDao               dao.PermissionsDao
+	userPermissionsDao dao.UserPermissionsDao
 }
 
 // NewPermissionsHandler creating the handler interface
@@ -41,6 +53,10 @@ func NewPermissionsHandler() PermissionsHandler {
 			database.GetDB(), // db driver is mysql
 			cache.NewPermissionsCache(database.GetCacheType()),
 		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
 	}
 }
 
@@ -59,7 +75,7 @@ func (h *permissionsHandler) Create(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
@@ -74,8 +90,7 @@ func (h *permissionsHandler) Create(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	err = h.iDao.Create(ctx, permissions)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "Create error", err, logger.Any("form", form))
 		return
 	}
 
@@ -89,7 +104,9 @@ func (h *permissionsHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.DeletePermissionsByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/permissions/{id} [delete]
 // @Security BearerAuth
 func (h *permissionsHandler) DeleteByID(c *gin.Context) {
@@ -98,15 +115,22 @@ func (h *permissionsHandler) DeleteByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	err := h.iDao.DeleteByID(ctx, id, opts...)
 	if err != nil {
-		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "DeleteByID error", err, logger.Any("id", id))
 		return
 	}
 
+	// the permission codes of the users are cached, the change applies to the users who have a role with the permission at once
+	if err = h.userPermissionsDao.DeleteCacheByPermissionID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByPermissionID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
 	response.Success(c)
 }
 
@@ -118,7 +142,9 @@ func (h *permissionsHandler) DeleteByID(c *gin.Context) {
 // @Produce json
 // @Param id path string true "id"
 // @Param data body types.UpdatePermissionsByIDRequest true "permissions information"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.UpdatePermissionsByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/permissions/{id} [put]
 // @Security BearerAuth
 func (h *permissionsHandler) UpdateByID(c *gin.Context) {
@@ -127,12 +153,16 @@ func (h *permissionsHandler) UpdateByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	form := &types.UpdatePermissionsByIDRequest{}
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 	form.ID = id
@@ -146,13 +176,59 @@ func (h *permissionsHandler) UpdateByID(c *gin.Context) {
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
 	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByID(ctx, permissions)
+	err = h.iDao.UpdateByID(ctx, permissions, opts...)
+	if err != nil {
+		responseDBError(c, "UpdateByID error", err, logger.Any("form", form))
+		return
+	}
+
+	// the permission codes of the users are cached, the change applies to the users who have a role with the permission at once
+	if err = h.userPermissionsDao.DeleteCacheByPermissionID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByPermissionID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	response.Success(c)
+}
+
+// PatchByID patch a permissions by id
+// @Summary Patch a permissions by id
+// @Description Updates the specified permissions by given id in the path with a JSON Merge Patch (RFC 7396), the members of the patch are written even if they are zero values, and a null member resets the field.
+// @Tags permissions
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.UpdatePermissionsByIDRequest true "permissions information, only the members to change"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
+// @Success 200 {object} types.UpdatePermissionsByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
+// @Router /api/v1/permissions/{id} [patch]
+// @Security BearerAuth
+func (h *permissionsHandler) PatchByID(c *gin.Context) {
+	_, id, isAbort := getPermissionsIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+
+	columns, ok := bindMergePatch(c, &types.UpdatePermissionsByIDRequest{}, permissionsPatchFields)
+	if !ok {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PatchByID(ctx, id, columns, opts...)
 	if err != nil {
-		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "PatchByID error", err, logger.Any("id", id))
 		return
 	}
 
+	// the permission codes of the users are cached, the change applies to the users who have a role with the permission at once
+	if err = h.userPermissionsDao.DeleteCacheByPermissionID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByPermissionID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
 	response.Success(c)
 }
 
@@ -161,9 +237,13 @@ func (h *permissionsHandler) UpdateByID(c *gin.Context) {
 // @Description Gets detailed information of a permissions specified by the given id in the path.
 // @Tags permissions
 // @Param id path string true "id"
+// @Param includeDeleted query bool false "also get the soft deleted record"
+// @Param If-None-Match header string false "entity tag of the cached record, 304 Not Modified is responded if it is still current"
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.GetPermissionsByIDReply{}
+// @Header 200 {string} ETag "entity tag of the record, it is not set for a soft deleted record"
+// @Success 304 "the record is not modified"
 // @Router /api/v1/permissions/{id} [get]
 // @Security BearerAuth
 func (h *permissionsHandler) GetByID(c *gin.Context) {
@@ -174,18 +254,22 @@ func (h *permissionsHandler) GetByID(c *gin.Context) {
 	}
 
 	ctx := middleware.WrapCtx(c)
-	permissions, err := h.iDao.GetByID(ctx, id)
+	permissions, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(c.Query("includeDeleted") == "true"))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
 		} else {
-			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
 		}
 		return
 	}
 
+	// the client has the current record
+	if writeETag(c, permissions.Version, permissions.DeletedAt) {
+		return
+	}
+
 	data := &types.PermissionsObjDetail{}
 	err = copier.Copy(data, permissions)
 	if err != nil {
@@ -193,6 +277,9 @@ func (h *permissionsHandler) GetByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if permissions.DeletedAt.Valid {
+		data.DeletedAt = &permissions.DeletedAt.Time
+	}
 
 	response.Success(c, gin.H{"permissions": data})
 }
@@ -212,15 +299,14 @@ func (h *permissionsHandler) List(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	permissionss, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	permissionss, total, err := h.iDao.GetByColumns(ctx, &form.Params, dao.WithIncludeDeleted(form.IncludeDeleted))
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
 		return
 	}
 
@@ -236,6 +322,108 @@ func (h *permissionsHandler) List(c *gin.Context) {
 	})
 }
 
+// CheckAvailability check whether the value of a unique field is available
+// @Summary Check whether a permission code is available
+// @Description Returns whether the value of a unique field is not used by another permission, the soft deleted ones included,
+// @Description so that the forms can validate the value before submitting. excludeID is the permission being edited.
+// @Tags permissions
+// @Accept json
+// @Produce json
+// @Param field query string true "code"
+// @Param value query string true "value of the field"
+// @Param excludeID query string false "id of the permission being edited"
+// @Success 200 {object} types.CheckAvailabilityReply{}
+// @Router /api/v1/permissions/availability [get]
+// @Security BearerAuth
+func (h *permissionsHandler) CheckAvailability(c *gin.Context) {
+	form := &types.CheckPermissionsAvailabilityRequest{}
+	err := c.ShouldBindQuery(form)
+	if err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	available, err := h.iDao.CheckAvailability(ctx, form.Field, form.Value, form.ExcludeID)
+	if err != nil {
+		responseDBError(c, "CheckAvailability error", err, logger.Any("form", form))
+		return
+	}
+
+	response.Success(c, gin.H{"available": available})
+}
+
+// RestoreByID restore a soft deleted permissions by id
+// @Summary Restore a soft deleted permissions by id
+// @Description Restores the soft deleted permissions identified by the given id in the path.
+// @Tags permissions
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.RestorePermissionsByIDReply{}
+// @Router /api/v1/permissions/{id}/restore [post]
+// @Security BearerAuth
+func (h *permissionsHandler) RestoreByID(c *gin.Context) {
+	_, id, isAbort := getPermissionsIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.RestoreByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("RestoreByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "RestoreByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	// the permission codes of the users are cached, the change applies to the users who have a role with the permission at once
+	if err = h.userPermissionsDao.DeleteCacheByPermissionID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByPermissionID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	response.Success(c)
+}
+
+// PurgeByID permanently delete a soft deleted permissions by id
+// @Summary Purge a soft deleted permissions by id
+// @Description Permanently deletes the permissions identified by the given id in the path, the permissions must be soft deleted first.
+// @Tags permissions
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.PurgePermissionsByIDReply{}
+// @Router /api/v1/permissions/{id}/purge [delete]
+// @Security BearerAuth
+func (h *permissionsHandler) PurgeByID(c *gin.Context) {
+	_, id, isAbort := getPermissionsIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PurgeByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("PurgeByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "PurgeByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	// the permission codes of the users are cached, the change applies to the users who have a role with the permission at once
+	if err = h.userPermissionsDao.DeleteCacheByPermissionID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByPermissionID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	response.Success(c)
+}
+
 func getPermissionsIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -254,6 +442,9 @@ func convertPermissions(permissions *model.Permissions) (*types.PermissionsObjDe
 		return nil, err
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if permissions.DeletedAt.Valid {
+		data.DeletedAt = &permissions.DeletedAt.Time
+	}
 
 	return data, nil
 }
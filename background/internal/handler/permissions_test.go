@@ -41,7 +41,10 @@ func newPermissionsHandler() *gotest.Handler {
 
 	// init mock handler
 	h := gotest.NewHandler(d, testData)
-	h.IHandler = &permissionsHandler{iDao: d.IDao.(dao.PermissionsDao)}
+	h.IHandler = &permissionsHandler{
+		iDao:               d.IDao.(dao.PermissionsDao),
+		userPermissionsDao: dao.NewUserPermissionsDao(d.DB, nil),
+	}
 	iHandler := h.IHandler.(PermissionsHandler)
 
 	testFns := []gotest.RouterInfo{
@@ -75,6 +78,18 @@ func newPermissionsHandler() *gotest.Handler {
 			Path:        "/permissions/list",
 			HandlerFunc: iHandler.List,
 		},
+		{
+			FuncName:    "RestoreByID",
+			Method:      http.MethodPost,
+			Path:        "/permissions/:id/restore",
+			HandlerFunc: iHandler.RestoreByID,
+		},
+		{
+			FuncName:    "PurgeByID",
+			Method:      http.MethodDelete,
+			Path:        "/permissions/:id/purge",
+			HandlerFunc: iHandler.PurgeByID,
+		},
 	}
 
 	h.GoRunHTTPServer(testFns)
@@ -110,11 +125,11 @@ func Test_permissionsHandler_DeleteByID(t *testing.T) {
 	h := newPermissionsHandler()
 	defer h.Close()
 	testData := h.TestData.(*model.Permissions)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	h.MockDao.SQLMock.ExpectBegin()
 	h.MockDao.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID). // adjusted for the amount of test data
+		WithArgs(h.MockDao.AnyTime, testData.ID). // adjusted for the amount of test data
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	h.MockDao.SQLMock.ExpectCommit()
 
@@ -209,7 +224,7 @@ func Test_permissionsHandler_List(t *testing.T) {
 	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
 
 	result := &httpcli.StdResult{}
-	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListPermissionssRequest{query.Params{
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListPermissionssRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "ignore count", // ignore test count
@@ -226,7 +241,7 @@ func Test_permissionsHandler_List(t *testing.T) {
 	assert.NoError(t, err)
 
 	// get error test
-	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListPermissionssRequest{query.Params{
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListPermissionssRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "unknown-column",
@@ -234,6 +249,64 @@ func Test_permissionsHandler_List(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_permissionsHandler_RestoreByID(t *testing.T) {
+	h := newPermissionsHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Permissions)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("RestoreByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 0), nil)
+	assert.NoError(t, err)
+
+	// restore error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_permissionsHandler_PurgeByID(t *testing.T) {
+	h := newPermissionsHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Permissions)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("PurgeByID", testData.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 0))
+	assert.NoError(t, err)
+
+	// purge error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 111))
+	assert.Error(t, err)
+}
+
 func TestNewPermissionsHandler(t *testing.T) {
 	defer func() {
 		recover()
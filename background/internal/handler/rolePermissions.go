@@ -5,11 +5,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/go-dev-frame/sponge/pkg/copier"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/response"
 	"github.com/go-dev-frame/sponge/pkg/logger"
-	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"godemo/internal/cache"
 	"godemo/internal/dao"
@@ -23,15 +21,16 @@ var _ RolePermissionsHandler = (*rolePermissionsHandler)(nil)
 
 // RolePermissionsHandler defining the handler interface
 type RolePermissionsHandler interface {
-	Create(c *gin.Context)
-	DeleteByRoleID(c *gin.Context)
-	UpdateByRoleID(c *gin.Context)
 	GetByRoleID(c *gin.Context)
-	List(c *gin.Context)
+	ReplaceByRoleID(c *gin.Context)
+	GetEffectiveByRoleID(c *gin.Context)
 }
 
 type rolePermissionsHandler struct {
-	iDao dao.RolePermissionsDao
+	iDao               dao.RolePermissionsDao
+	rolesDao           dao.RolesDao
+	permissionsDao     dao.PermissionsDao
+	userPermissionsDao dao.UserPermissionsDao
 }
 
 // NewRolePermissionsHandler creating the handler interface
@@ -40,235 +39,222 @@ func NewRolePermissionsHandler() RolePermissionsHandler {
 		iDao: dao.NewRolePermissionsDao(
 			database.GetDB(), // db driver is mysql
 			cache.NewRolePermissionsCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		rolesDao: dao.NewRolesDao(
+			database.GetDB(),
+			cache.NewRolesCache(database.GetCacheType()),
+		),
+		permissionsDao: dao.NewPermissionsDao(
+			database.GetDB(),
+			cache.NewPermissionsCache(database.GetCacheType()),
+		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
 		),
 	}
 }
 
-// Create a new rolePermissions
-// @Summary Create a new rolePermissions
-// @Description Creates a new rolePermissions entity using the provided data in the request body.
+// GetByRoleID get all the permissions of a role
+// @Summary Get all the permissions of a role
+// @Description Returns the ids of all the permissions of the role specified by the given id in the path.
 // @Tags rolePermissions
+// @Param id path string true "role id"
 // @Accept json
 // @Produce json
-// @Param data body types.CreateRolePermissionsRequest true "rolePermissions information"
-// @Success 200 {object} types.CreateRolePermissionsReply{}
-// @Router /api/v1/rolePermissions [post]
+// @Success 200 {object} types.GetRolePermissionsByRoleIDReply{}
+// @Router /api/v1/roles/{id}/permissions [get]
 // @Security BearerAuth
-func (h *rolePermissionsHandler) Create(c *gin.Context) {
-	form := &types.CreateRolePermissionsRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+func (h *rolePermissionsHandler) GetByRoleID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
+	if isAbort {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
-	rolePermissions := &model.RolePermissions{}
-	err = copier.Copy(rolePermissions, form)
+	ctx := middleware.WrapCtx(c)
+	_, err := h.rolesDao.GetByID(ctx, id)
 	if err != nil {
-		response.Error(c, ecode.ErrCreateRolePermissions)
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
 		return
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
-	err = h.iDao.Create(ctx, rolePermissions)
+	permissionIDs, err := h.iDao.GetPermissionIDsByRoleID(ctx, id)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		logger.Error("GetPermissionIDsByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetByRoleIDRolePermissions)
 		return
 	}
 
-	response.Success(c, gin.H{"roleID": rolePermissions.RoleID})
+	response.Success(c, &types.RolePermissionsDetail{
+		RoleID:        id,
+		PermissionIDs: permissionIDs,
+	})
 }
 
-// DeleteByRoleID delete a rolePermissions by roleID
-// @Summary Delete a rolePermissions by roleID
-// @Description Deletes a existing rolePermissions identified by the given roleID in the path.
+// ReplaceByRoleID replace all the permissions of a role
+// @Summary Replace all the permissions of a role
+// @Description Replaces the permissions of the role specified by the given id in the path with the given set in one transaction.
+// @Description A permission the role does not have yet can only be added if the caller has it.
 // @Tags rolePermissions
 // @Accept json
 // @Produce json
-// @Param roleID path string true "roleID"
-// @Success 200 {object} types.DeleteRolePermissionsByRoleIDReply{}
-// @Router /api/v1/rolePermissions/{roleID} [delete]
+// @Param id path string true "role id"
+// @Param data body types.ReplaceRolePermissionsRequest true "permission ids"
+// @Success 200 {object} types.ReplaceRolePermissionsByRoleIDReply{}
+// @Router /api/v1/roles/{id}/permissions [put]
 // @Security BearerAuth
-func (h *rolePermissionsHandler) DeleteByRoleID(c *gin.Context) {
-	roleID, isAbort := getRolePermissionsRoleIDFromPath(c)
+func (h *rolePermissionsHandler) ReplaceByRoleID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
 	if isAbort {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
-	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByRoleID(ctx, roleID)
+	form := &types.ReplaceRolePermissionsRequest{}
+	err := c.ShouldBindJSON(form)
 	if err != nil {
-		logger.Error("DeleteByRoleID error", logger.Err(err), logger.Any("roleID", roleID), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
 		return
 	}
+	permissionIDs := uniqueIDs(form.PermissionIDs)
 
-	response.Success(c)
-}
-
-// UpdateByRoleID update a rolePermissions by roleID
-// @Summary Update a rolePermissions by roleID
-// @Description Updates the specified rolePermissions by given roleID in the path, support partial update.
-// @Tags rolePermissions
-// @Accept json
-// @Produce json
-// @Param roleID path string true "roleID"
-// @Param data body types.UpdateRolePermissionsByRoleIDRequest true "rolePermissions information"
-// @Success 200 {object} types.UpdateRolePermissionsByRoleIDReply{}
-// @Router /api/v1/rolePermissions/{roleID} [put]
-// @Security BearerAuth
-func (h *rolePermissionsHandler) UpdateByRoleID(c *gin.Context) {
-	roleID, isAbort := getRolePermissionsRoleIDFromPath(c)
-	if isAbort {
-		response.Error(c, ecode.InvalidParams)
+	ctx := middleware.WrapCtx(c)
+	_, err = h.rolesDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
 		return
 	}
 
-	form := &types.UpdateRolePermissionsByRoleIDRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
-		return
+	// all the permissions must exist
+	records := map[uint64]*model.Permissions{}
+	if len(permissionIDs) > 0 {
+		records, err = h.permissionsDao.GetByIDs(ctx, permissionIDs)
+		if err != nil {
+			responseDBError(c, "GetByIDs error", err, logger.Any("permissionIDs", permissionIDs))
+			return
+		}
+		if len(records) != len(permissionIDs) {
+			logger.Warn("some permissions do not exist", logger.Any("permissionIDs", permissionIDs), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidParams.RewriteMsg("some permissions do not exist"))
+			return
+		}
 	}
-	form.RoleID = roleID
 
-	rolePermissions := &model.RolePermissions{}
-	err = copier.Copy(rolePermissions, form)
-	if err != nil {
-		response.Error(c, ecode.ErrUpdateByRoleIDRolePermissions)
+	if !h.checkGrantable(c, id, records) {
 		return
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByRoleID(ctx, rolePermissions)
+	// the dao deletes the cached permission codes of all users who have the role or inherit from it
+	err = h.iDao.ReplaceByRoleID(ctx, id, permissionIDs)
 	if err != nil {
-		logger.Error("UpdateByRoleID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "ReplaceByRoleID error", err, logger.Any("id", id), logger.Any("permissionIDs", permissionIDs))
 		return
 	}
 
 	response.Success(c)
 }
 
-// GetByRoleID get a rolePermissions by roleID
-// @Summary Get a rolePermissions by roleID
-// @Description Gets detailed information of a rolePermissions specified by the given roleID in the path.
-// @Tags rolePermissions
-// @Param roleID path string true "roleID"
-// @Accept json
-// @Produce json
-// @Success 200 {object} types.GetRolePermissionsByRoleIDReply{}
-// @Router /api/v1/rolePermissions/{roleID} [get]
-// @Security BearerAuth
-func (h *rolePermissionsHandler) GetByRoleID(c *gin.Context) {
-	roleID, isAbort := getRolePermissionsRoleIDFromPath(c)
-	if isAbort {
-		response.Error(c, ecode.InvalidParams)
-		return
-	}
-
+// check that the caller has the permissions that are added to the role, so that role:manage cannot be turned into
+// more permissions, such as adding permission:manage to the caller's own role. The permissions the role already has
+// are not checked, so that they can be kept by a caller who has less permissions. It responds and returns false on failure.
+func (h *rolePermissionsHandler) checkGrantable(c *gin.Context, roleID uint64, permissions map[uint64]*model.Permissions) bool {
 	ctx := middleware.WrapCtx(c)
-	rolePermissions, err := h.iDao.GetByRoleID(ctx, roleID)
+	current, err := h.iDao.GetPermissionIDsByRoleID(ctx, roleID)
 	if err != nil {
-		if errors.Is(err, database.ErrRecordNotFound) {
-			logger.Warn("GetByRoleID not found", logger.Err(err), logger.Any("roleID", roleID), middleware.GCtxRequestIDField(c))
-			response.Error(c, ecode.NotFound)
-		} else {
-			logger.Error("GetByRoleID error", logger.Err(err), logger.Any("roleID", roleID), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
-		}
-		return
+		responseDBError(c, "GetPermissionIDsByRoleID error", err, logger.Any("id", roleID))
+		return false
 	}
 
-	data := &types.RolePermissionsObjDetail{}
-	err = copier.Copy(data, rolePermissions)
-	if err != nil {
-		response.Error(c, ecode.ErrGetByRoleIDRolePermissions)
-		return
+	held := make(map[uint64]struct{}, len(current))
+	for _, permissionID := range current {
+		held[permissionID] = struct{}{}
+	}
+	added := make([]*dao.EffectivePermission, 0, len(permissions))
+	for permissionID, permission := range permissions {
+		if _, ok := held[permissionID]; !ok {
+			added = append(added, &dao.EffectivePermission{Permissions: *permission, RoleID: roleID})
+		}
+	}
+	if len(added) == 0 {
+		return true
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	response.Success(c, gin.H{"rolePermissions": data})
+	callerID, codes, ok := getCallerCodes(c, h.userPermissionsDao)
+	if !ok {
+		return false
+	}
+	if missing := missingCodes(codes, added); len(missing) > 0 {
+		logger.Warn("permissions are not grantable", logger.Any("userID", callerID), logger.Any("roleID", roleID),
+			logger.Any("missing", missing), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrPermissionNotGrantable)
+		return false
+	}
+	return true
 }
 
-// List get a paginated list of rolePermissions by custom conditions
-// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-// @Summary Get a paginated list of rolePermissions by custom conditions
-// @Description Returns a paginated list of rolePermissions based on query filters, including page number and size.
+// GetEffectiveByRoleID get the effective permissions of a role
+// @Summary Get the effective permissions of a role
+// @Description Returns the permissions the role grants to its users, the ones of the role itself and the ones it inherits
+// @Description from its ancestors. The inheritance stops at a disabled ancestor.
 // @Tags rolePermissions
+// @Param id path string true "role id"
 // @Accept json
 // @Produce json
-// @Param data body types.Params true "query parameters"
-// @Success 200 {object} types.ListRolePermissionsReply{}
-// @Router /api/v1/rolePermissions/list [post]
+// @Success 200 {object} types.GetEffectivePermissionsByRoleIDReply{}
+// @Router /api/v1/roles/{id}/permissions/effective [get]
 // @Security BearerAuth
-func (h *rolePermissionsHandler) List(c *gin.Context) {
-	form := &types.ListRolePermissionsRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+func (h *rolePermissionsHandler) GetEffectiveByRoleID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
+	if isAbort {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	rolePermissions, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	_, err := h.rolesDao.GetByID(ctx, id)
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
 		return
 	}
 
-	data, err := convertRolePermissions(rolePermissions)
+	permissions, err := h.iDao.GetEffectiveByRoleID(ctx, id)
 	if err != nil {
-		response.Error(c, ecode.ErrListRolePermissions)
+		logger.Error("GetEffectiveByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetEffectiveRolePermissions)
 		return
 	}
 
-	response.Success(c, gin.H{
-		"rolePermissions": data,
-		"total":           total,
-	})
-}
-
-func getRolePermissionsRoleIDFromPath(c *gin.Context) (uint64, bool) {
-	roleIDStr := c.Param("roleID")
-
-	roleID, err := utils.StrToUint64E(roleIDStr)
-	if err != nil || roleIDStr == "" {
-		logger.Warn("StrToUint64E error: ", logger.String("roleIDStr", roleIDStr), middleware.GCtxRequestIDField(c))
-		return 0, true
+	data := make([]*types.EffectivePermissionDetail, 0, len(permissions))
+	for _, permission := range permissions {
+		data = append(data, &types.EffectivePermissionDetail{
+			ID:          permission.ID,
+			Name:        permission.Name,
+			Code:        permission.Code,
+			Description: permission.Description,
+			Inherited:   permission.RoleID != id,
+			FromRoleID:  permission.RoleID,
+		})
 	}
-	return roleID, false
-
-}
-
-func convertRolePermissions1(rolePermissions *model.RolePermissions) (*types.RolePermissionsObjDetail, error) {
-	data := &types.RolePermissionsObjDetail{}
-	err := copier.Copy(data, rolePermissions)
-	if err != nil {
-		return nil, err
-	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
-
-	return data, nil
-}
-
-func convertRolePermissions(fromValues []*model.RolePermissions) ([]*types.RolePermissionsObjDetail, error) {
-	toValues := []*types.RolePermissionsObjDetail{}
-	for _, v := range fromValues {
-		data, err := convertRolePermissions1(v)
-		if err != nil {
-			return nil, err
-		}
-		toValues = append(toValues, data)
-	}
-
-	return toValues, nil
+	response.Success(c, &types.EffectivePermissionsDetail{
+		RoleID:      id,
+		Permissions: data,
+	})
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/dao"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+)
+
+// a role:manage caller cannot add a permission it does not have to a role, such as its own
+func Test_rolePermissionsHandler_ReplaceByRoleID_NotGrantable(t *testing.T) {
+	db := newSeededTestDB(t)
+	ctx := context.Background()
+
+	rolesDao := dao.NewRolesDao(db, nil)
+	rolePermissionsDao := dao.NewRolePermissionsDao(db, nil, nil)
+	// the manager role has user:manage and role:manage
+	manager := &model.Roles{RoleName: "manager", RoleCode: "manager", Status: model.StatusEnabled}
+	assert.NoError(t, rolesDao.Create(ctx, manager))
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, manager.ID, []uint64{1, 2}))
+	caller := &model.Users{UserName: "caller", Password: "hash", Status: model.StatusEnabled}
+	assert.NoError(t, dao.NewUsersDao(db, nil, nil).Create(ctx, caller))
+	assert.NoError(t, dao.NewUserRolesDao(db, nil, nil).ReplaceByUserID(ctx, caller.ID, []uint64{manager.ID}))
+
+	h := &rolePermissionsHandler{
+		iDao:               rolePermissionsDao,
+		rolesDao:           rolesDao,
+		permissionsDao:     dao.NewPermissionsDao(db, nil),
+		userPermissionsDao: dao.NewUserPermissionsDao(db, nil),
+	}
+	// permission:manage is not held by the caller
+	assert.Equal(t, ecode.ErrPermissionNotGrantable.Code(),
+		callAs(t, caller.ID, h.ReplaceByRoleID, http.MethodPut, manager.ID, `{"permissionIDs":[1,2,3]}`))
+	permissionIDs, err := rolePermissionsDao.GetPermissionIDsByRoleID(ctx, manager.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2}, permissionIDs)
+
+	// the permissions of the caller can be added and removed
+	assert.Equal(t, 0, callAs(t, caller.ID, h.ReplaceByRoleID, http.MethodPut, 3, `{"permissionIDs":[1,2]}`))
+	assert.Equal(t, 0, callAs(t, caller.ID, h.ReplaceByRoleID, http.MethodPut, manager.ID, `{"permissionIDs":[1]}`))
+	// the permissions the role already has are kept even if the caller does not have them
+	assert.Equal(t, 0, callAs(t, caller.ID, h.ReplaceByRoleID, http.MethodPut, 1, `{"permissionIDs":[1,2,3,4,5,6]}`))
+}
@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"github.com/go-dev-frame/sponge/pkg/copier"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
@@ -21,26 +23,59 @@ import (
 
 var _ RolesHandler = (*rolesHandler)(nil)
 
+// the fields of a roles that can be patched
+var rolesPatchFields = map[string]patchField{
+	"roleName":         {column: "role_name", required: true},
+	"roleCode":         {column: "role_code", required: true},
+	"roleDesc":         {column: "role_desc"},
+	"status":           {column: "status", required: true},
+	"requireTwoFactor": {column: "require_two_factor"},
+	"parentID":         {column: "parent_id"},
+}
+
 // RolesHandler defining the handler interface
 type RolesHandler interface {
 	Create(c *gin.Context)
 	DeleteByID(c *gin.Context)
 	UpdateByID(c *gin.Context)
+	PatchByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	CheckAvailability(c *gin.Context)
+	RestoreByID(c *gin.Context)
+	PurgeByID(c *gin.Context)
 }
 
 type rolesHandler struct {
-	iDao dao.RolesDao
+	db                 *gorm.DB // a purge removes the role from its permissions and users in one transaction
+	iDao               dao.RolesDao
+	rolePermissionsDao dao.RolePermissionsDao
+	userRolesDao       dao.UserRolesDao
+	userPermissionsDao dao.UserPermissionsDao
 }
 
 // NewRolesHandler creating the handler interface
 func NewRolesHandler() RolesHandler {
 	return &rolesHandler{
+		db: database.GetDB(),
 		iDao: dao.NewRolesDao(
 			database.GetDB(), // db driver is mysql
 			cache.NewRolesCache(database.GetCacheType()),
 		),
+		rolePermissionsDao: dao.NewRolePermissionsDao(
+			database.GetDB(),
+			cache.NewRolePermissionsCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		userRolesDao: dao.NewUserRolesDao(
+			database.GetDB(),
+			cache.NewUserRolesCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
 	}
 }
 
@@ -59,7 +94,7 @@ func (h *rolesHandler) Create(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
@@ -74,8 +109,12 @@ func (h *rolesHandler) Create(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	err = h.iDao.Create(ctx, roles)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		if errors.Is(err, dao.ErrRoleParentNotFound) {
+			logger.Warn("Create parent not found", logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleParentNotFound)
+			return
+		}
+		responseDBError(c, "Create error", err, logger.Any("form", form))
 		return
 	}
 
@@ -89,7 +128,9 @@ func (h *rolesHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.DeleteRolesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/roles/{id} [delete]
 // @Security BearerAuth
 func (h *rolesHandler) DeleteByID(c *gin.Context) {
@@ -98,14 +139,21 @@ func (h *rolesHandler) DeleteByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	err := h.iDao.DeleteByID(ctx, id, opts...)
 	if err != nil {
-		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "DeleteByID error", err, logger.Any("id", id))
 		return
 	}
+	// the permissions of the role no longer apply to its users
+	if err = h.userPermissionsDao.DeleteCacheByRoleID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
 
 	response.Success(c)
 }
@@ -113,12 +161,15 @@ func (h *rolesHandler) DeleteByID(c *gin.Context) {
 // UpdateByID update a roles by id
 // @Summary Update a roles by id
 // @Description Updates the specified roles by given id in the path, support partial update.
+// @Description The parent can only be changed to a role whose effective permissions the caller all has.
 // @Tags roles
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
 // @Param data body types.UpdateRolesByIDRequest true "roles information"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.UpdateRolesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/roles/{id} [put]
 // @Security BearerAuth
 func (h *rolesHandler) UpdateByID(c *gin.Context) {
@@ -127,12 +178,16 @@ func (h *rolesHandler) UpdateByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	form := &types.UpdateRolesByIDRequest{}
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 	form.ID = id
@@ -146,12 +201,106 @@ func (h *rolesHandler) UpdateByID(c *gin.Context) {
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
 	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByID(ctx, roles)
+	changes := map[string]interface{}{}
+	if form.Status != "" {
+		changes["status"] = form.Status
+	}
+	if form.ParentID != nil {
+		changes["parent_id"] = *form.ParentID
+	}
+	permissionsChanged, newParentID, err := h.isPermissionsChanged(ctx, id, changes)
+	if err != nil {
+		responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		return
+	}
+	if !h.checkParentGrantable(c, newParentID) {
+		return
+	}
+	err = h.iDao.UpdateByID(ctx, roles, opts...)
+	if err != nil {
+		switch {
+		case errors.Is(err, dao.ErrRoleParentNotFound):
+			logger.Warn("UpdateByID parent not found", logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleParentNotFound)
+		case errors.Is(err, dao.ErrRoleParentCycle):
+			logger.Warn("UpdateByID parent makes a cycle", logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleParentCycle)
+		default:
+			responseDBError(c, "UpdateByID error", err, logger.Any("form", form))
+		}
+		return
+	}
+	// a disabled role contributes no permissions to its users and descendants, and a new parent
+	// changes the inherited permissions
+	if permissionsChanged {
+		if err = h.userPermissionsDao.DeleteCacheByRoleID(ctx, id); err != nil {
+			logger.Warn("DeleteCacheByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+	}
+
+	response.Success(c)
+}
+
+// PatchByID patch a roles by id
+// @Summary Patch a roles by id
+// @Description Updates the specified roles by given id in the path with a JSON Merge Patch (RFC 7396), the members of the patch are written even if they are zero values, and a null member resets the field.
+// @Description The parent can only be changed to a role whose effective permissions the caller all has.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.UpdateRolesByIDRequest true "roles information, only the members to change"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
+// @Success 200 {object} types.UpdateRolesByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
+// @Router /api/v1/roles/{id} [patch]
+// @Security BearerAuth
+func (h *rolesHandler) PatchByID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+
+	columns, ok := bindMergePatch(c, &types.UpdateRolesByIDRequest{}, rolesPatchFields)
+	if !ok {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	permissionsChanged, newParentID, err := h.isPermissionsChanged(ctx, id, columns)
+	if err != nil {
+		responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		return
+	}
+	if !h.checkParentGrantable(c, newParentID) {
+		return
+	}
+	err = h.iDao.PatchByID(ctx, id, columns, opts...)
 	if err != nil {
-		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		switch {
+		case errors.Is(err, dao.ErrRoleParentNotFound):
+			logger.Warn("PatchByID parent not found", logger.Any("columns", columns), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleParentNotFound)
+		case errors.Is(err, dao.ErrRoleParentCycle):
+			logger.Warn("PatchByID parent makes a cycle", logger.Any("columns", columns), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleParentCycle)
+		default:
+			responseDBError(c, "PatchByID error", err, logger.Any("id", id))
+		}
 		return
 	}
+	// a disabled role contributes no permissions to its users and descendants, and a new parent
+	// changes the inherited permissions
+	if permissionsChanged {
+		if err = h.userPermissionsDao.DeleteCacheByRoleID(ctx, id); err != nil {
+			logger.Warn("DeleteCacheByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+	}
 
 	response.Success(c)
 }
@@ -161,9 +310,13 @@ func (h *rolesHandler) UpdateByID(c *gin.Context) {
 // @Description Gets detailed information of a roles specified by the given id in the path.
 // @Tags roles
 // @Param id path string true "id"
+// @Param includeDeleted query bool false "also get the soft deleted record"
+// @Param If-None-Match header string false "entity tag of the cached record, 304 Not Modified is responded if it is still current"
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.GetRolesByIDReply{}
+// @Header 200 {string} ETag "entity tag of the record, it is not set for a soft deleted record"
+// @Success 304 "the record is not modified"
 // @Router /api/v1/roles/{id} [get]
 // @Security BearerAuth
 func (h *rolesHandler) GetByID(c *gin.Context) {
@@ -174,18 +327,22 @@ func (h *rolesHandler) GetByID(c *gin.Context) {
 	}
 
 	ctx := middleware.WrapCtx(c)
-	roles, err := h.iDao.GetByID(ctx, id)
+	roles, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(c.Query("includeDeleted") == "true"))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
 		} else {
-			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
 		}
 		return
 	}
 
+	// the client has the current record
+	if writeETag(c, roles.Version, roles.DeletedAt) {
+		return
+	}
+
 	data := &types.RolesObjDetail{}
 	err = copier.Copy(data, roles)
 	if err != nil {
@@ -193,6 +350,9 @@ func (h *rolesHandler) GetByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if roles.DeletedAt.Valid {
+		data.DeletedAt = &roles.DeletedAt.Time
+	}
 
 	response.Success(c, gin.H{"roles": data})
 }
@@ -212,15 +372,14 @@ func (h *rolesHandler) List(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	roless, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	roless, total, err := h.iDao.GetByColumns(ctx, &form.Params, dao.WithIncludeDeleted(form.IncludeDeleted))
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
 		return
 	}
 
@@ -236,6 +395,151 @@ func (h *rolesHandler) List(c *gin.Context) {
 	})
 }
 
+// CheckAvailability check whether the value of a unique field is available
+// @Summary Check whether a role code is available
+// @Description Returns whether the value of a unique field is not used by another role, the soft deleted ones included,
+// @Description so that the forms can validate the value before submitting. excludeID is the role being edited.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param field query string true "roleCode"
+// @Param value query string true "value of the field"
+// @Param excludeID query string false "id of the role being edited"
+// @Success 200 {object} types.CheckAvailabilityReply{}
+// @Router /api/v1/roles/availability [get]
+// @Security BearerAuth
+func (h *rolesHandler) CheckAvailability(c *gin.Context) {
+	form := &types.CheckRolesAvailabilityRequest{}
+	err := c.ShouldBindQuery(form)
+	if err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	available, err := h.iDao.CheckAvailability(ctx, form.Field, form.Value, form.ExcludeID)
+	if err != nil {
+		responseDBError(c, "CheckAvailability error", err, logger.Any("form", form))
+		return
+	}
+
+	response.Success(c, gin.H{"available": available})
+}
+
+// RestoreByID restore a soft deleted roles by id
+// @Summary Restore a soft deleted roles by id
+// @Description Restores the soft deleted roles identified by the given id in the path.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.RestoreRolesByIDReply{}
+// @Router /api/v1/roles/{id}/restore [post]
+// @Security BearerAuth
+func (h *rolesHandler) RestoreByID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.RestoreByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("RestoreByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "RestoreByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	// the permissions of the role apply to its users again
+	if err = h.userPermissionsDao.DeleteCacheByRoleID(ctx, id); err != nil {
+		logger.Warn("DeleteCacheByRoleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	response.Success(c)
+}
+
+// PurgeByID permanently delete a soft deleted roles by id
+// @Summary Purge a soft deleted roles by id
+// @Description Permanently deletes the roles identified by the given id in the path, the roles must be soft deleted first.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.PurgeRolesByIDReply{}
+// @Router /api/v1/roles/{id}/purge [delete]
+// @Security BearerAuth
+func (h *rolesHandler) PurgeByID(c *gin.Context) {
+	_, id, isAbort := getRolesIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	// the permissions of the role and the role of its users are deleted with it, so that no row refers to the purged id,
+	// the dao deletes the cached permission codes of the users
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := h.iDao.PurgeByTx(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := h.rolePermissionsDao.DeleteByTx(ctx, tx, id); err != nil {
+			return err
+		}
+		_, err := h.userRolesDao.DeleteRoleByTx(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("PurgeByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "PurgeByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	response.Success(c)
+}
+
+// isPermissionsChanged report whether the status or the parent in columns differs from the stored role, it is
+// read before the update so that writing the same values again does not clear the cached permissions of its users.
+// newParentID is the parent the role is moved to, 0 if the parent is kept or removed.
+func (h *rolesHandler) isPermissionsChanged(ctx context.Context, id uint64, columns map[string]interface{}) (changed bool, newParentID uint64, err error) {
+	// the columns of bindMergePatch have the pointer fields dereferenced, a null parent is 0
+	status, hasStatus := columns["status"].(string)
+	parentID, hasParent := columns["parent_id"].(uint64)
+	if !hasStatus && !hasParent {
+		return false, 0, nil
+	}
+
+	stored, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		return false, 0, err
+	}
+	changed = hasStatus && status != stored.Status
+	var storedParent uint64
+	if stored.ParentID != nil {
+		storedParent = *stored.ParentID
+	}
+	if hasParent && parentID != storedParent {
+		return true, parentID, nil
+	}
+	return changed, 0, nil
+}
+
+// check that the caller has all the effective permissions of the new parent of a role, which the role and its
+// users would inherit, so that role:manage cannot be used to put a role, such as the caller's own, under a
+// role with more permissions like super_admin. It responds and returns false on failure.
+func (h *rolesHandler) checkParentGrantable(c *gin.Context, newParentID uint64) bool {
+	if newParentID == 0 {
+		return true
+	}
+	return checkRolesGrantable(c, h.rolePermissionsDao, h.userPermissionsDao, []uint64{newParentID})
+}
+
 func getRolesIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -254,6 +558,9 @@ func convertRoles(roles *model.Roles) (*types.RolesObjDetail, error) {
 		return nil, err
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if roles.DeletedAt.Valid {
+		data.DeletedAt = &roles.DeletedAt.Time
+	}
 
 	return data, nil
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"godemo/internal/cache"
 	"godemo/internal/dao"
 	"godemo/internal/database"
+	"godemo/internal/ecode"
 	"godemo/internal/model"
 	"godemo/internal/types"
 )
@@ -41,7 +43,13 @@ func newRolesHandler() *gotest.Handler {
 
 	// init mock handler
 	h := gotest.NewHandler(d, testData)
-	h.IHandler = &rolesHandler{iDao: d.IDao.(dao.RolesDao)}
+	h.IHandler = &rolesHandler{
+		db:                 d.DB,
+		iDao:               d.IDao.(dao.RolesDao),
+		rolePermissionsDao: dao.NewRolePermissionsDao(d.DB, nil, nil),
+		userRolesDao:       dao.NewUserRolesDao(d.DB, nil, nil),
+		userPermissionsDao: dao.NewUserPermissionsDao(d.DB, nil),
+	}
 	iHandler := h.IHandler.(RolesHandler)
 
 	testFns := []gotest.RouterInfo{
@@ -75,6 +83,18 @@ func newRolesHandler() *gotest.Handler {
 			Path:        "/roles/list",
 			HandlerFunc: iHandler.List,
 		},
+		{
+			FuncName:    "RestoreByID",
+			Method:      http.MethodPost,
+			Path:        "/roles/:id/restore",
+			HandlerFunc: iHandler.RestoreByID,
+		},
+		{
+			FuncName:    "PurgeByID",
+			Method:      http.MethodDelete,
+			Path:        "/roles/:id/purge",
+			HandlerFunc: iHandler.PurgeByID,
+		},
 	}
 
 	h.GoRunHTTPServer(testFns)
@@ -110,11 +130,11 @@ func Test_rolesHandler_DeleteByID(t *testing.T) {
 	h := newRolesHandler()
 	defer h.Close()
 	testData := h.TestData.(*model.Roles)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
 	h.MockDao.SQLMock.ExpectBegin()
 	h.MockDao.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID). // adjusted for the amount of test data
+		WithArgs(h.MockDao.AnyTime, testData.ID). // adjusted for the amount of test data
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	h.MockDao.SQLMock.ExpectCommit()
 
@@ -166,6 +186,82 @@ func Test_rolesHandler_UpdateByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_rolesHandler_isPermissionsChanged(t *testing.T) {
+	h := newRolesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Roles)
+	ctx := context.Background()
+	parentID, otherID := uint64(2), uint64(3)
+	stored := &model.Roles{ID: testData.ID, Status: model.StatusEnabled, ParentID: &parentID}
+	assert.NoError(t, h.MockDao.Cache.ICache.(cache.RolesCache).Set(ctx, testData.ID, stored, time.Minute))
+	handler := h.IHandler.(*rolesHandler)
+
+	tests := []struct {
+		name        string
+		columns     map[string]interface{}
+		changed     bool
+		newParentID uint64
+	}{
+		{"no status or parent", map[string]interface{}{"role_name": "admin"}, false, 0},
+		{"same status", map[string]interface{}{"status": model.StatusEnabled}, false, 0},
+		{"same parent", map[string]interface{}{"status": model.StatusEnabled, "parent_id": parentID}, false, 0},
+		{"new status", map[string]interface{}{"status": model.StatusDisabled}, true, 0},
+		{"new status and same parent", map[string]interface{}{"status": model.StatusDisabled, "parent_id": parentID}, true, 0},
+		{"new parent", map[string]interface{}{"parent_id": otherID}, true, otherID},
+		{"parent removed", map[string]interface{}{"parent_id": uint64(0)}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, newParentID, err := handler.isPermissionsChanged(ctx, testData.ID, tt.columns)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.newParentID, newParentID)
+		})
+	}
+}
+
+// a role:manage caller cannot put a role under a parent with permissions the caller does not have
+func Test_rolesHandler_UpdateByID_ParentNotGrantable(t *testing.T) {
+	db := newSeededTestDB(t)
+	ctx := context.Background()
+
+	rolesDao := dao.NewRolesDao(db, nil)
+	rolePermissionsDao := dao.NewRolePermissionsDao(db, nil, nil)
+	userRolesDao := dao.NewUserRolesDao(db, nil, nil)
+	// the seeded super_admin role has all the permissions, the manager role has user:manage and role:manage
+	manager := &model.Roles{RoleName: "manager", RoleCode: "manager", Status: model.StatusEnabled}
+	helper := &model.Roles{RoleName: "helper", RoleCode: "helper", Status: model.StatusEnabled}
+	for _, role := range []*model.Roles{manager, helper} {
+		assert.NoError(t, rolesDao.Create(ctx, role))
+	}
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, manager.ID, []uint64{1, 2}))
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, helper.ID, []uint64{1}))
+	caller := &model.Users{UserName: "caller", Password: "hash", Status: model.StatusEnabled}
+	assert.NoError(t, dao.NewUsersDao(db, nil, nil).Create(ctx, caller))
+	assert.NoError(t, userRolesDao.ReplaceByUserID(ctx, caller.ID, []uint64{manager.ID}))
+
+	h := &rolesHandler{
+		db:                 db,
+		iDao:               rolesDao,
+		rolePermissionsDao: rolePermissionsDao,
+		userRolesDao:       userRolesDao,
+		userPermissionsDao: dao.NewUserPermissionsDao(db, nil),
+	}
+	notGrantable := ecode.ErrRoleNotGrantable.Code()
+	assert.Equal(t, notGrantable, callAs(t, caller.ID, h.UpdateByID, http.MethodPut, manager.ID, `{"parentID":1}`))
+	assert.Equal(t, notGrantable, callAs(t, caller.ID, h.PatchByID, http.MethodPatch, manager.ID, `{"parentID":1}`))
+	record, err := rolesDao.GetByID(ctx, manager.ID)
+	assert.NoError(t, err)
+	assert.True(t, record.ParentID == nil || *record.ParentID == 0)
+
+	// a parent with no more permissions than the caller can be set, and removed
+	assert.Equal(t, 0, callAs(t, caller.ID, h.PatchByID, http.MethodPatch, manager.ID, `{"parentID":`+utils.Uint64ToStr(helper.ID)+`}`))
+	record, err = rolesDao.GetByID(ctx, manager.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, helper.ID, *record.ParentID)
+	assert.Equal(t, 0, callAs(t, caller.ID, h.UpdateByID, http.MethodPut, manager.ID, `{"parentID":0}`))
+}
+
 func Test_rolesHandler_GetByID(t *testing.T) {
 	h := newRolesHandler()
 	defer h.Close()
@@ -209,7 +305,7 @@ func Test_rolesHandler_List(t *testing.T) {
 	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
 
 	result := &httpcli.StdResult{}
-	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListRolessRequest{query.Params{
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListRolessRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "ignore count", // ignore test count
@@ -226,7 +322,7 @@ func Test_rolesHandler_List(t *testing.T) {
 	assert.NoError(t, err)
 
 	// get error test
-	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListRolessRequest{query.Params{
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListRolessRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "unknown-column",
@@ -234,6 +330,74 @@ func Test_rolesHandler_List(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_rolesHandler_RestoreByID(t *testing.T) {
+	h := newRolesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Roles)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("RestoreByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 0), nil)
+	assert.NoError(t, err)
+
+	// restore error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_rolesHandler_PurgeByID(t *testing.T) {
+	h := newRolesHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Roles)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	// the permissions and the users of the role are deleted in the same transaction
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(2, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("PurgeByID", testData.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 0))
+	assert.NoError(t, err)
+
+	// purge error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 111))
+	assert.Error(t, err)
+}
+
 func TestNewRolesHandler(t *testing.T) {
 	defer func() {
 		recover()
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/config"
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+	"godemo/internal/types"
+)
+
+var _ RouteHandler = (*routeHandler)(nil)
+
+// RouteHandler defining the handler interface
+type RouteHandler interface {
+	GetConstantRoutes(c *gin.Context)
+	GetUserRoutes(c *gin.Context)
+	IsRouteExist(c *gin.Context)
+}
+
+type routeHandler struct {
+	menusDao           dao.MenusDao
+	userPermissionsDao dao.UserPermissionsDao
+}
+
+// NewRouteHandler creating the handler interface
+func NewRouteHandler() RouteHandler {
+	return &routeHandler{
+		menusDao: dao.NewMenusDao(
+			database.GetDB(), // db driver is mysql
+			cache.NewMenusCache(database.GetCacheType()),
+		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+	}
+}
+
+// constant routes do not require login, they are the same as the constant routes of web/src/router/elegant/routes.ts
+var constantRoutes = []*types.MenuRoute{
+	{
+		ID:        "403",
+		Name:      "403",
+		Path:      "/403",
+		Component: "layout.blank$view.403",
+		Meta:      types.RouteMeta{Title: "403", I18nKey: "route.403", Constant: true, HideInMenu: true},
+	},
+	{
+		ID:        "404",
+		Name:      "404",
+		Path:      "/404",
+		Component: "layout.blank$view.404",
+		Meta:      types.RouteMeta{Title: "404", I18nKey: "route.404", Constant: true, HideInMenu: true},
+	},
+	{
+		ID:        "500",
+		Name:      "500",
+		Path:      "/500",
+		Component: "layout.blank$view.500",
+		Meta:      types.RouteMeta{Title: "500", I18nKey: "route.500", Constant: true, HideInMenu: true},
+	},
+	{
+		ID:        "iframe-page",
+		Name:      "iframe-page",
+		Path:      "/iframe-page/:url",
+		Component: "layout.base$view.iframe-page",
+		Props:     true,
+		Meta:      types.RouteMeta{Title: "iframe-page", I18nKey: "route.iframe-page", Constant: true, HideInMenu: true, KeepAlive: true},
+	},
+	{
+		ID:        "login",
+		Name:      "login",
+		Path:      "/login/:module(pwd-login|code-login|register|reset-pwd|bind-wechat)?",
+		Component: "layout.blank$view.login",
+		Props:     true,
+		Meta:      types.RouteMeta{Title: "login", I18nKey: "route.login", Constant: true, HideInMenu: true},
+	},
+}
+
+// GetConstantRoutes get the routes that do not require login
+// @Summary Get constant routes
+// @Description Returns the routes that can be accessed without login, such as login and error pages.
+// @Tags route
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.GetConstantRoutesReply{}
+// @Router /api/v1/route/getConstantRoutes [get]
+func (h *routeHandler) GetConstantRoutes(c *gin.Context) {
+	response.Success(c, constantRoutes)
+}
+
+// GetUserRoutes get the route tree of the logged-in user
+// @Summary Get user routes
+// @Description Builds the route tree from the menus table sorted by order, menus whose permission the user does not have are removed.
+// @Tags route
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.GetUserRoutesReply{}
+// @Router /api/v1/route/getUserRoutes [get]
+// @Security BearerAuth
+func (h *routeHandler) GetUserRoutes(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+	userID, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	codes, err := h.userPermissionsDao.GetCodesByUserID(ctx, userID)
+	if err != nil {
+		logger.Error("GetCodesByUserID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetUserRoutesRoute)
+		return
+	}
+	menus, err := h.menusDao.GetAll(ctx)
+	if err != nil {
+		logger.Error("GetAll error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetUserRoutesRoute)
+		return
+	}
+
+	permissions := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		permissions[code] = struct{}{}
+	}
+	routes := buildMenuRoutes(menus, func(permission string) bool {
+		_, ok := permissions[permission]
+		return ok
+	})
+
+	response.Success(c, &types.UserRouteDetail{
+		Routes: routes,
+		Home:   getHomeRouteName(routes, config.Get().Route.Home),
+	})
+}
+
+// IsRouteExist check whether the route exists
+// @Summary Check whether the route exists
+// @Description Checks the route name against the constant routes and all menus, regardless of the permissions of the user,
+// @Description the web client uses it to tell a route without access (403) from a missing route (404).
+// @Tags route
+// @Accept json
+// @Produce json
+// @Param routeName query string true "route name"
+// @Success 200 {object} types.IsRouteExistReply{}
+// @Router /api/v1/route/isRouteExist [get]
+// @Security BearerAuth
+func (h *routeHandler) IsRouteExist(c *gin.Context) {
+	form := &types.IsRouteExistRequest{}
+	err := c.ShouldBindQuery(form)
+	if err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	for _, route := range constantRoutes {
+		if route.Name == form.RouteName {
+			response.Success(c, true)
+			return
+		}
+	}
+
+	menus, err := h.menusDao.GetAll(middleware.WrapCtx(c))
+	if err != nil {
+		logger.Error("GetAll error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrIsRouteExistRoute)
+		return
+	}
+	routes := buildMenuRoutes(menus, func(string) bool { return true })
+
+	response.Success(c, findMenuRoute(routes, form.RouteName) != nil)
+}
+
+// buildMenuRoutes build the route tree from menus, the menus must be sorted by order. A menu is kept when its
+// permission is empty or hasPermission returns true, the children of a removed menu are removed too, and a
+// menu that has children in the table but none left after filtering is removed as well.
+func buildMenuRoutes(menus []*model.Menus, hasPermission func(permission string) bool) []*types.MenuRoute {
+	isParent := make(map[uint64]bool)
+	for _, menu := range menus {
+		if menu.ParentID != 0 {
+			isParent[menu.ParentID] = true
+		}
+	}
+
+	nodes := make(map[uint64]*types.MenuRoute, len(menus))
+	dirs := make(map[*types.MenuRoute]bool)
+	for _, menu := range menus {
+		if menu.Permission != "" && !hasPermission(menu.Permission) {
+			continue
+		}
+		node := &types.MenuRoute{
+			ID:   utils.Uint64ToStr(menu.ID),
+			Name: menu.Name,
+			Path: menu.Path,
+			Meta: types.RouteMeta{
+				Title:   menu.Name,
+				I18nKey: "route." + menu.Name,
+				Icon:    menu.Icon,
+				Order:   menu.Order,
+			},
+		}
+		nodes[menu.ID] = node
+		dirs[node] = isParent[menu.ID]
+	}
+
+	// link the nodes in the order of menus, so the children keep the order too
+	routes := []*types.MenuRoute{}
+	for _, menu := range menus {
+		node, ok := nodes[menu.ID]
+		if !ok {
+			continue
+		}
+		if menu.ParentID == 0 {
+			routes = append(routes, node)
+		} else if parent, ok := nodes[menu.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return pruneMenuRoutes(routes, dirs, true)
+}
+
+// remove the directory routes without children, and set the component by the level of the route
+func pruneMenuRoutes(routes []*types.MenuRoute, dirs map[*types.MenuRoute]bool, isTopLevel bool) []*types.MenuRoute {
+	result := make([]*types.MenuRoute, 0, len(routes))
+	for _, route := range routes {
+		route.Children = pruneMenuRoutes(route.Children, dirs, false)
+		hasChildren := len(route.Children) > 0
+		if !hasChildren && dirs[route] {
+			continue
+		}
+		if !hasChildren {
+			route.Children = nil
+		}
+
+		switch {
+		case isTopLevel && hasChildren:
+			route.Component = "layout.base"
+		case isTopLevel:
+			route.Component = "layout.base$view." + route.Name
+		case hasChildren:
+			route.Component = "" // a middle level route has no component
+		default:
+			route.Component = "view." + route.Name
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+func findMenuRoute(routes []*types.MenuRoute, name string) *types.MenuRoute {
+	for _, route := range routes {
+		if route.Name == name {
+			return route
+		}
+		if found := findMenuRoute(route.Children, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// the home route must be a leaf route of the tree, if the configured home is not accessible, use the first leaf route
+func getHomeRouteName(routes []*types.MenuRoute, home string) string {
+	if route := findMenuRoute(routes, home); route != nil && len(route.Children) == 0 {
+		return home
+	}
+	for route := firstMenuRoute(routes); route != nil; route = firstMenuRoute(route.Children) {
+		if len(route.Children) == 0 {
+			return route.Name
+		}
+	}
+	return home
+}
+
+func firstMenuRoute(routes []*types.MenuRoute) *types.MenuRoute {
+	if len(routes) == 0 {
+		return nil
+	}
+	return routes[0]
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/model"
+)
+
+func Test_buildMenuRoutes(t *testing.T) {
+	// sorted by order
+	menus := []*model.Menus{
+		{ID: 1, Name: "home", Path: "/home", Order: 1},
+		{ID: 4, Name: "test_banana", Path: "/test/banana", ParentID: 2, Order: 1},
+		{ID: 3, Name: "test_apple", Path: "/test/apple", ParentID: 2, Order: 2, Permission: "menu:manage"},
+		{ID: 5, Name: "manage", Path: "/manage", Order: 5},
+		{ID: 6, Name: "manage_user", Path: "/manage/user", ParentID: 5, Order: 1, Permission: "user:manage"},
+		{ID: 2, Name: "test", Path: "/test", Order: 10},
+		{ID: 7, Name: "orphan", Path: "/orphan", ParentID: 100, Order: 11},
+	}
+
+	// all permissions
+	routes := buildMenuRoutes(menus, func(string) bool { return true })
+	assert.Len(t, routes, 3)
+	assert.Equal(t, "home", routes[0].Name)
+	assert.Equal(t, "layout.base$view.home", routes[0].Component)
+	assert.Equal(t, "manage", routes[1].Name)
+	assert.Equal(t, "layout.base", routes[1].Component)
+	assert.Equal(t, "view.manage_user", routes[1].Children[0].Component)
+	assert.Equal(t, "test", routes[2].Name)
+	assert.Equal(t, []string{"test_banana", "test_apple"}, []string{routes[2].Children[0].Name, routes[2].Children[1].Name})
+	assert.Nil(t, findMenuRoute(routes, "orphan"))
+
+	// no permissions, the manage directory has no children left
+	routes = buildMenuRoutes(menus, func(string) bool { return false })
+	assert.Len(t, routes, 2)
+	assert.Nil(t, findMenuRoute(routes, "manage"))
+	assert.Nil(t, findMenuRoute(routes, "test_apple"))
+	assert.NotNil(t, findMenuRoute(routes, "test_banana"))
+}
+
+func Test_getHomeRouteName(t *testing.T) {
+	menus := []*model.Menus{
+		{ID: 2, Name: "test", Path: "/test", Order: 10},
+		{ID: 4, Name: "test_banana", Path: "/test/banana", ParentID: 2, Order: 1},
+	}
+	routes := buildMenuRoutes(menus, func(string) bool { return true })
+
+	assert.Equal(t, "test_banana", getHomeRouteName(routes, "test_banana"))
+	// not a leaf route
+	assert.Equal(t, "test_banana", getHomeRouteName(routes, "test"))
+	// not accessible
+	assert.Equal(t, "test_banana", getHomeRouteName(routes, "home"))
+	// no routes
+	assert.Equal(t, "home", getHomeRouteName(nil, "home"))
+}
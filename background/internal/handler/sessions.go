@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/types"
+)
+
+var _ SessionsHandler = (*sessionsHandler)(nil)
+
+// SessionsHandler defining the handler interface, a session is a token family of the token cache,
+// kicking a session revokes all its tokens immediately.
+type SessionsHandler interface {
+	List(c *gin.Context)
+	DeleteByID(c *gin.Context)
+	DeleteByUserID(c *gin.Context)
+}
+
+type sessionsHandler struct {
+	tokenCache cache.TokenCache
+}
+
+// NewSessionsHandler creating the handler interface
+func NewSessionsHandler() SessionsHandler {
+	return &sessionsHandler{
+		tokenCache: cache.NewTokenCache(database.GetCacheType()),
+	}
+}
+
+// List get the online sessions
+// @Summary Get the online sessions
+// @Description Returns the active login sessions, of all the users or of the user given by userID, the latest login comes first.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param userID query string false "user id"
+// @Success 200 {object} types.ListSessionsReply{}
+// @Router /api/v1/sessions [get]
+// @Security BearerAuth
+func (h *sessionsHandler) List(c *gin.Context) {
+	form := &types.ListSessionsRequest{}
+	err := c.ShouldBindQuery(form)
+	if err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	families, err := h.tokenCache.ListFamilies(middleware.WrapCtx(c), form.UserID)
+	if err != nil {
+		logger.Error("ListFamilies error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrListSessions)
+		return
+	}
+
+	// the session of the request, an api key has none
+	var currentID string
+	if claims, ok := middleware.GetClaims(c); ok {
+		currentID, _ = auth.GetFamilyID(claims)
+	}
+	data := make([]*types.SessionsObjDetail, 0, len(families))
+	for _, family := range families {
+		data = append(data, &types.SessionsObjDetail{
+			ID:          family.ID,
+			UserID:      family.UserID,
+			UserName:    family.UserName,
+			IP:          family.IP,
+			UserAgent:   family.UserAgent,
+			CreatedAt:   family.CreatedAt,
+			RefreshedAt: family.RefreshedAt,
+			ExpiresAt:   family.ExpiresAt,
+			Current:     family.ID == currentID,
+		})
+	}
+
+	response.Success(c, gin.H{
+		"sessions": data,
+		"total":    len(data),
+	})
+}
+
+// DeleteByID kick a session by id
+// @Summary Kick a session by id
+// @Description Ends the login session identified by the given id in the path, its access token and refresh token are revoked immediately.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.DeleteSessionByIDReply{}
+// @Router /api/v1/sessions/{id} [delete]
+// @Security BearerAuth
+func (h *sessionsHandler) DeleteByID(c *gin.Context) {
+	id := c.Param("id")
+	ctx := middleware.WrapCtx(c)
+	family, err := h.tokenCache.GetFamily(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrCacheNotFound) {
+			logger.Warn("GetFamily not found", logger.String("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetFamily error", err, logger.String("id", id))
+		}
+		return
+	}
+
+	err = h.tokenCache.DelFamily(ctx, id)
+	if err != nil {
+		responseDBError(c, "DelFamily error", err, logger.String("id", id))
+		return
+	}
+	logger.Info("session kicked", logger.String("id", id), logger.Any("userID", family.UserID), middleware.GCtxRequestIDField(c))
+	response.Success(c)
+}
+
+// DeleteByUserID kick all the sessions of a user
+// @Summary Kick all the sessions of a user
+// @Description Ends all the login sessions of the user identified by the given user id in the path, all the access
+// @Description tokens and refresh tokens of the user are revoked immediately.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param userID path string true "user id"
+// @Success 200 {object} types.DeleteSessionsByUserIDReply{}
+// @Router /api/v1/sessions/users/{userID} [delete]
+// @Security BearerAuth
+func (h *sessionsHandler) DeleteByUserID(c *gin.Context) {
+	userIDStr := c.Param("userID")
+	userID, err := utils.StrToUint64E(userIDStr)
+	if err != nil || userID == 0 {
+		logger.Warn("StrToUint64E error: ", logger.String("userIDStr", userIDStr), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	err = h.tokenCache.DelUserFamilies(middleware.WrapCtx(c), userID)
+	if err != nil {
+		responseDBError(c, "DelUserFamilies error", err, logger.Any("userID", userID))
+		return
+	}
+	logger.Info("sessions of the user kicked", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+	response.Success(c)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/httpcli"
+
+	"godemo/internal/cache"
+	"godemo/internal/database"
+)
+
+func newSessionsHandler() (*gotest.Handler, cache.TokenCache) {
+	tokenCache := cache.NewTokenCache(&database.CacheType{CType: "memory"})
+	ctx := context.Background()
+	_ = tokenCache.CreateFamily(ctx, &cache.TokenFamily{ID: "family1", UserID: 1, UserName: "admin", IP: "127.0.0.1"}, "token1", time.Hour)
+	_ = tokenCache.CreateFamily(ctx, &cache.TokenFamily{ID: "family2", UserID: 1, UserName: "admin", IP: "127.0.0.2"}, "token2", time.Hour)
+	_ = tokenCache.CreateFamily(ctx, &cache.TokenFamily{ID: "family3", UserID: 2, UserName: "guest", IP: "127.0.0.3"}, "token3", time.Hour)
+
+	// init mock handler
+	d := gotest.NewDao(nil, nil)
+	h := gotest.NewHandler(d, nil)
+	h.IHandler = &sessionsHandler{tokenCache: tokenCache}
+	iHandler := h.IHandler.(SessionsHandler)
+
+	testFns := []gotest.RouterInfo{
+		{
+			FuncName:    "List",
+			Method:      http.MethodGet,
+			Path:        "/sessions",
+			HandlerFunc: iHandler.List,
+		},
+		{
+			FuncName:    "DeleteByID",
+			Method:      http.MethodDelete,
+			Path:        "/sessions/:id",
+			HandlerFunc: iHandler.DeleteByID,
+		},
+		{
+			FuncName:    "DeleteByUserID",
+			Method:      http.MethodDelete,
+			Path:        "/sessions/users/:userID",
+			HandlerFunc: iHandler.DeleteByUserID,
+		},
+	}
+
+	h.GoRunHTTPServer(testFns)
+
+	time.Sleep(time.Millisecond * 200)
+	return h, tokenCache
+}
+
+func Test_sessionsHandler_List(t *testing.T) {
+	h, _ := newSessionsHandler()
+	defer h.Close()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Get(result, h.GetRequestURL("List"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+	assert.EqualValues(t, 3, result.Data.(map[string]interface{})["total"])
+
+	err = httpcli.Get(result, h.GetRequestURL("List"), httpcli.WithParams(map[string]interface{}{"userID": 1}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, result.Data.(map[string]interface{})["total"])
+
+	// invalid user id error test
+	err = httpcli.Get(result, h.GetRequestURL("List"), httpcli.WithParams(map[string]interface{}{"userID": "x"}))
+	assert.NoError(t, err)
+	assert.NotZero(t, result.Code)
+}
+
+func Test_sessionsHandler_DeleteByID(t *testing.T) {
+	h, tokenCache := newSessionsHandler()
+	defer h.Close()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("DeleteByID", "family1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+	families, err := tokenCache.ListFamilies(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+
+	// not found error test
+	err = httpcli.Delete(result, h.GetRequestURL("DeleteByID", "family1"))
+	assert.NoError(t, err)
+	assert.NotZero(t, result.Code)
+}
+
+func Test_sessionsHandler_DeleteByUserID(t *testing.T) {
+	h, tokenCache := newSessionsHandler()
+	defer h.Close()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("DeleteByUserID", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+	families, err := tokenCache.ListFamilies(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	assert.Equal(t, "family3", families[0].ID)
+
+	// zero id error test
+	err = httpcli.Delete(result, h.GetRequestURL("DeleteByUserID", 0))
+	assert.NoError(t, err)
+	assert.NotZero(t, result.Code)
+}
+
+func TestNewSessionsHandler(t *testing.T) {
+	defer func() {
+		recover()
+	}()
+	_ = NewSessionsHandler()
+}
@@ -5,17 +5,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/go-dev-frame/sponge/pkg/copier"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/response"
 	"github.com/go-dev-frame/sponge/pkg/logger"
-	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/auth"
 	"godemo/internal/cache"
 	"godemo/internal/dao"
 	"godemo/internal/database"
 	"godemo/internal/ecode"
-	"godemo/internal/model"
 	"godemo/internal/types"
 )
 
@@ -23,15 +21,16 @@ var _ UserRolesHandler = (*userRolesHandler)(nil)
 
 // UserRolesHandler defining the handler interface
 type UserRolesHandler interface {
-	Create(c *gin.Context)
-	DeleteByUserID(c *gin.Context)
-	UpdateByUserID(c *gin.Context)
 	GetByUserID(c *gin.Context)
-	List(c *gin.Context)
+	ReplaceByUserID(c *gin.Context)
 }
 
 type userRolesHandler struct {
-	iDao dao.UserRolesDao
+	iDao               dao.UserRolesDao
+	usersDao           dao.UsersDao
+	rolesDao           dao.RolesDao
+	rolePermissionsDao dao.RolePermissionsDao
+	userPermissionsDao dao.UserPermissionsDao
 }
 
 // NewUserRolesHandler creating the handler interface
@@ -40,235 +39,258 @@ func NewUserRolesHandler() UserRolesHandler {
 		iDao: dao.NewUserRolesDao(
 			database.GetDB(), // db driver is mysql
 			cache.NewUserRolesCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		usersDao: dao.NewUsersDao(
+			database.GetDB(),
+			cache.NewUsersCache(database.GetCacheType()),
+			cache.NewTokenCache(database.GetCacheType()),
+		),
+		rolesDao: dao.NewRolesDao(
+			database.GetDB(),
+			cache.NewRolesCache(database.GetCacheType()),
+		),
+		rolePermissionsDao: dao.NewRolePermissionsDao(
+			database.GetDB(),
+			cache.NewRolePermissionsCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
 		),
 	}
 }
 
-// Create a new userRoles
-// @Summary Create a new userRoles
-// @Description Creates a new userRoles entity using the provided data in the request body.
+// GetByUserID get all the roles of a user
+// @Summary Get all the roles of a user
+// @Description Returns the ids of all the roles of the user specified by the given id in the path.
 // @Tags userRoles
+// @Param id path string true "user id"
 // @Accept json
 // @Produce json
-// @Param data body types.CreateUserRolesRequest true "userRoles information"
-// @Success 200 {object} types.CreateUserRolesReply{}
-// @Router /api/v1/userRoles [post]
+// @Success 200 {object} types.GetUserRolesByUserIDReply{}
+// @Router /api/v1/users/{id}/roles [get]
 // @Security BearerAuth
-func (h *userRolesHandler) Create(c *gin.Context) {
-	form := &types.CreateUserRolesRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+func (h *userRolesHandler) GetByUserID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
-	userRoles := &model.UserRoles{}
-	err = copier.Copy(userRoles, form)
+	ctx := middleware.WrapCtx(c)
+	_, err := h.usersDao.GetByID(ctx, id)
 	if err != nil {
-		response.Error(c, ecode.ErrCreateUserRoles)
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
 		return
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
-	err = h.iDao.Create(ctx, userRoles)
+	roleIDs, err := h.iDao.GetRoleIDsByUserID(ctx, id)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		logger.Error("GetRoleIDsByUserID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrGetByUserIDUserRoles)
 		return
 	}
 
-	response.Success(c, gin.H{"userID": userRoles.UserID})
+	response.Success(c, &types.UserRolesDetail{
+		UserID:  id,
+		RoleIDs: roleIDs,
+	})
 }
 
-// DeleteByUserID delete a userRoles by userID
-// @Summary Delete a userRoles by userID
-// @Description Deletes a existing userRoles identified by the given userID in the path.
+// ReplaceByUserID replace all the roles of a user
+// @Summary Replace all the roles of a user
+// @Description Replaces the roles of the user specified by the given id in the path with the given set in one transaction.
+// @Description A role the user does not have yet can only be given if the caller has all of its permissions, including the inherited ones.
 // @Tags userRoles
 // @Accept json
 // @Produce json
-// @Param userID path string true "userID"
-// @Success 200 {object} types.DeleteUserRolesByUserIDReply{}
-// @Router /api/v1/userRoles/{userID} [delete]
+// @Param id path string true "user id"
+// @Param data body types.ReplaceUserRolesRequest true "role ids"
+// @Success 200 {object} types.ReplaceUserRolesByUserIDReply{}
+// @Router /api/v1/users/{id}/roles [put]
 // @Security BearerAuth
-func (h *userRolesHandler) DeleteByUserID(c *gin.Context) {
-	userID, isAbort := getUserRolesUserIDFromPath(c)
+func (h *userRolesHandler) ReplaceByUserID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
 	if isAbort {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
-	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByUserID(ctx, userID)
+	form := &types.ReplaceUserRolesRequest{}
+	err := c.ShouldBindJSON(form)
 	if err != nil {
-		logger.Error("DeleteByUserID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
 		return
 	}
+	roleIDs := uniqueIDs(form.RoleIDs)
 
-	response.Success(c)
-}
-
-// UpdateByUserID update a userRoles by userID
-// @Summary Update a userRoles by userID
-// @Description Updates the specified userRoles by given userID in the path, support partial update.
-// @Tags userRoles
-// @Accept json
-// @Produce json
-// @Param userID path string true "userID"
-// @Param data body types.UpdateUserRolesByUserIDRequest true "userRoles information"
-// @Success 200 {object} types.UpdateUserRolesByUserIDReply{}
-// @Router /api/v1/userRoles/{userID} [put]
-// @Security BearerAuth
-func (h *userRolesHandler) UpdateByUserID(c *gin.Context) {
-	userID, isAbort := getUserRolesUserIDFromPath(c)
-	if isAbort {
-		response.Error(c, ecode.InvalidParams)
+	ctx := middleware.WrapCtx(c)
+	_, err = h.usersDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
 		return
 	}
 
-	form := &types.UpdateUserRolesByUserIDRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
-		return
+	// all the roles must exist
+	if len(roleIDs) > 0 {
+		records, err := h.rolesDao.GetByIDs(ctx, roleIDs)
+		if err != nil {
+			responseDBError(c, "GetByIDs error", err, logger.Any("roleIDs", roleIDs))
+			return
+		}
+		if len(records) != len(roleIDs) {
+			logger.Warn("some roles do not exist", logger.Any("roleIDs", roleIDs), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidParams.RewriteMsg("some roles do not exist"))
+			return
+		}
 	}
-	form.UserID = userID
 
-	userRoles := &model.UserRoles{}
-	err = copier.Copy(userRoles, form)
-	if err != nil {
-		response.Error(c, ecode.ErrUpdateByUserIDUserRoles)
+	if !h.checkGrantable(c, id, roleIDs) {
 		return
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByUserID(ctx, userRoles)
+	// the dao deletes the cached permission codes of the user
+	err = h.iDao.ReplaceByUserID(ctx, id, roleIDs)
 	if err != nil {
-		logger.Error("UpdateByUserID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "ReplaceByUserID error", err, logger.Any("id", id), logger.Any("roleIDs", roleIDs))
 		return
 	}
 
 	response.Success(c)
 }
 
-// GetByUserID get a userRoles by userID
-// @Summary Get a userRoles by userID
-// @Description Gets detailed information of a userRoles specified by the given userID in the path.
-// @Tags userRoles
-// @Param userID path string true "userID"
-// @Accept json
-// @Produce json
-// @Success 200 {object} types.GetUserRolesByUserIDReply{}
-// @Router /api/v1/userRoles/{userID} [get]
-// @Security BearerAuth
-func (h *userRolesHandler) GetByUserID(c *gin.Context) {
-	userID, isAbort := getUserRolesUserIDFromPath(c)
-	if isAbort {
-		response.Error(c, ecode.InvalidParams)
-		return
-	}
-
+// check that the caller has all the permissions of the roles that are given to the user, so that user:manage cannot
+// be turned into more permissions, such as giving super_admin to oneself. The roles the user already has are not
+// checked, so that they can be kept by a caller who has less permissions. It responds and returns false on failure.
+func (h *userRolesHandler) checkGrantable(c *gin.Context, userID uint64, roleIDs []uint64) bool {
 	ctx := middleware.WrapCtx(c)
-	userRoles, err := h.iDao.GetByUserID(ctx, userID)
+	current, err := h.iDao.GetRoleIDsByUserID(ctx, userID)
 	if err != nil {
-		if errors.Is(err, database.ErrRecordNotFound) {
-			logger.Warn("GetByUserID not found", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
-			response.Error(c, ecode.NotFound)
-		} else {
-			logger.Error("GetByUserID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
-		}
-		return
+		responseDBError(c, "GetRoleIDsByUserID error", err, logger.Any("id", userID))
+		return false
 	}
 
-	data := &types.UserRolesObjDetail{}
-	err = copier.Copy(data, userRoles)
-	if err != nil {
-		response.Error(c, ecode.ErrGetByUserIDUserRoles)
-		return
+	held := make(map[uint64]struct{}, len(current))
+	for _, roleID := range current {
+		held[roleID] = struct{}{}
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
-
-	response.Success(c, gin.H{"userRoles": data})
+	added := make([]uint64, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		if _, ok := held[roleID]; !ok {
+			added = append(added, roleID)
+		}
+	}
+	return checkRolesGrantable(c, h.rolePermissionsDao, h.userPermissionsDao, added)
 }
 
-// List get a paginated list of userRoles by custom conditions
-// For more details, please refer to https://go-sponge.com/component/data/custom-page-query.html
-// @Summary Get a paginated list of userRoles by custom conditions
-// @Description Returns a paginated list of userRoles based on query filters, including page number and size.
-// @Tags userRoles
-// @Accept json
-// @Produce json
-// @Param data body types.Params true "query parameters"
-// @Success 200 {object} types.ListUserRolesReply{}
-// @Router /api/v1/userRoles/list [post]
-// @Security BearerAuth
-func (h *userRolesHandler) List(c *gin.Context) {
-	form := &types.ListUserRolesRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
-		return
+// check that the caller has all the effective permissions of the roles, it responds ecode.ErrRoleNotGrantable
+// and returns false if a role has a permission that the caller does not have.
+func checkRolesGrantable(c *gin.Context, rolePermissionsDao dao.RolePermissionsDao,
+	userPermissionsDao dao.UserPermissionsDao, roleIDs []uint64) bool {
+	if len(roleIDs) == 0 {
+		return true
+	}
+	callerID, codes, ok := getCallerCodes(c, userPermissionsDao)
+	if !ok {
+		return false
 	}
 
 	ctx := middleware.WrapCtx(c)
-	userRoles, total, err := h.iDao.GetByColumns(ctx, &form.Params)
-	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
-		return
+	for _, roleID := range roleIDs {
+		permissions, err := rolePermissionsDao.GetEffectiveByRoleID(ctx, roleID)
+		if err != nil {
+			responseDBError(c, "GetEffectiveByRoleID error", err, logger.Any("roleID", roleID))
+			return false
+		}
+		if missing := missingCodes(codes, permissions); len(missing) > 0 {
+			logger.Warn("role is not grantable", logger.Any("userID", callerID), logger.Any("roleID", roleID),
+				logger.Any("missing", missing), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrRoleNotGrantable)
+			return false
+		}
 	}
+	return true
+}
 
-	data, err := convertUserRoles(userRoles)
+// get the id and the permission codes of the caller, a request authenticated by an api key has only the
+// permissions of the key. It responds and returns false on failure.
+func getCallerCodes(c *gin.Context, userPermissionsDao dao.UserPermissionsDao) (uint64, []string, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return 0, nil, false
+	}
+	callerID, err := auth.GetUserID(claims)
 	if err != nil {
-		response.Error(c, ecode.ErrListUserRoles)
-		return
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Unauthorized)
+		return 0, nil, false
 	}
 
-	response.Success(c, gin.H{
-		"userRoles": data,
-		"total":     total,
-	})
+	codes, err := userPermissionsDao.GetCodesByUserID(middleware.WrapCtx(c), callerID)
+	if err != nil {
+		responseDBError(c, "GetCodesByUserID error", err, logger.Any("userID", callerID))
+		return 0, nil, false
+	}
+	if auth.IsAPIKeyClaims(claims) {
+		codes = intersectCodes(codes, auth.GetAPIKeyPermissions(claims))
+	}
+	return callerID, codes, true
 }
 
-func getUserRolesUserIDFromPath(c *gin.Context) (uint64, bool) {
-	userIDStr := c.Param("userID")
-
-	userID, err := utils.StrToUint64E(userIDStr)
-	if err != nil || userIDStr == "" {
-		logger.Warn("StrToUint64E error: ", logger.String("userIDStr", userIDStr), middleware.GCtxRequestIDField(c))
-		return 0, true
+// get the codes of permissions that are not in codes
+func missingCodes(codes []string, permissions []*dao.EffectivePermission) []string {
+	own := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		own[code] = struct{}{}
 	}
-	return userID, false
-
+	missing := []string{}
+	for _, p := range permissions {
+		if _, ok := own[p.Code]; !ok {
+			missing = append(missing, p.Code)
+		}
+	}
+	return missing
 }
 
-func convertUserRoles1(userRoles *model.UserRoles) (*types.UserRolesObjDetail, error) {
-	data := &types.UserRolesObjDetail{}
-	err := copier.Copy(data, userRoles)
-	if err != nil {
-		return nil, err
+// get the codes that are in both a and b
+func intersectCodes(a []string, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, code := range b {
+		in[code] = struct{}{}
 	}
-	// Note: if copier.Copy cannot assign a value to a field, add it here
-
-	return data, nil
+	result := []string{}
+	for _, code := range a {
+		if _, ok := in[code]; ok {
+			result = append(result, code)
+		}
+	}
+	return result
 }
 
-func convertUserRoles(fromValues []*model.UserRoles) ([]*types.UserRolesObjDetail, error) {
-	toValues := []*types.UserRolesObjDetail{}
-	for _, v := range fromValues {
-		data, err := convertUserRoles1(v)
-		if err != nil {
-			return nil, err
+// remove the duplicate and zero ids, the order of the first occurrence is kept
+func uniqueIDs(ids []uint64) []uint64 {
+	result := make([]uint64, 0, len(ids))
+	seen := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
 		}
-		toValues = append(toValues, data)
+		seen[id] = struct{}{}
+		result = append(result, id)
 	}
-
-	return toValues, nil
+	return result
 }
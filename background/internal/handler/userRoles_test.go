@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/dao"
+	"godemo/internal/model"
+)
+
+func Test_missingCodes(t *testing.T) {
+	permissions := []*dao.EffectivePermission{
+		{Permissions: model.Permissions{Code: "user:manage"}},
+		{Permissions: model.Permissions{Code: "role:manage"}},
+	}
+	assert.Equal(t, []string{}, missingCodes([]string{"role:manage", "user:manage", "menu:manage"}, permissions))
+	assert.Equal(t, []string{"role:manage"}, missingCodes([]string{"user:manage"}, permissions))
+	assert.Equal(t, []string{}, missingCodes(nil, nil))
+}
+
+func Test_intersectCodes(t *testing.T) {
+	assert.Equal(t, []string{"user:manage"}, intersectCodes([]string{"user:manage", "role:manage"}, []string{"user:manage"}))
+	assert.Equal(t, []string{}, intersectCodes([]string{"user:manage"}, nil))
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
 	"github.com/go-dev-frame/sponge/pkg/copier"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
@@ -11,6 +15,7 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/auth"
 	"godemo/internal/cache"
 	"godemo/internal/dao"
 	"godemo/internal/database"
@@ -21,17 +26,52 @@ import (
 
 var _ UsersHandler = (*usersHandler)(nil)
 
+// the fields of a users that can be patched, the password and two-factor have their own endpoints
+var usersPatchFields = map[string]patchField{
+	"userName":   {column: "user_name", required: true},
+	"userGender": {column: "user_gender"},
+	"nickName":   {column: "nick_name"},
+	"userPhone":  {column: "user_phone"},
+	"userEmail":  {column: "user_email"},
+	"status":     {column: "status", required: true},
+}
+
+// the fields a user can change of its own profile, the other ones such as status are changed by a user:manage holder
+var mePatchFields = map[string]patchField{
+	"userGender": {column: "user_gender"},
+	"nickName":   {column: "nick_name"},
+	"userPhone":  {column: "user_phone"},
+	"userEmail":  {column: "user_email"},
+}
+
 // UsersHandler defining the handler interface
 type UsersHandler interface {
 	Create(c *gin.Context)
 	DeleteByID(c *gin.Context)
 	UpdateByID(c *gin.Context)
+	PatchByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	CheckAvailability(c *gin.Context)
+	RestoreByID(c *gin.Context)
+	PurgeByID(c *gin.Context)
+	LogoutByID(c *gin.Context)
+	UnlockByID(c *gin.Context)
+	ResetTwoFactorByID(c *gin.Context)
+
+	GetMe(c *gin.Context)
+	UpdateMe(c *gin.Context)
+	ChangePassword(c *gin.Context)
 }
 
 type usersHandler struct {
-	iDao dao.UsersDao
+	iDao               dao.UsersDao
+	userRolesDao       dao.UserRolesDao
+	rolePermissionsDao dao.RolePermissionsDao
+	userPermissionsDao dao.UserPermissionsDao
+	apiKeysDao         dao.APIKeysDao
+	tokenCache         cache.TokenCache
+	loginLimiter       *auth.LoginLimiter
 }
 
 // NewUsersHandler creating the handler interface
@@ -40,7 +80,25 @@ func NewUsersHandler() UsersHandler {
 		iDao: dao.NewUsersDao(
 			database.GetDB(), // db driver is mysql
 			cache.NewUsersCache(database.GetCacheType()),
+			cache.NewTokenCache(database.GetCacheType()),
+		),
+		userRolesDao: dao.NewUserRolesDao(
+			database.GetDB(),
+			cache.NewUserRolesCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		rolePermissionsDao: dao.NewRolePermissionsDao(
+			database.GetDB(),
+			cache.NewRolePermissionsCache(database.GetCacheType()),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
 		),
+		userPermissionsDao: dao.NewUserPermissionsDao(
+			database.GetDB(),
+			cache.NewUserPermissionsCache(database.GetCacheType()),
+		),
+		apiKeysDao:   dao.NewAPIKeysDao(database.GetDB()),
+		tokenCache:   cache.NewTokenCache(database.GetCacheType()),
+		loginLimiter: auth.NewLoginLimiter(cache.NewLoginFailuresCache(database.GetCacheType())),
 	}
 }
 
@@ -59,7 +117,7 @@ func (h *usersHandler) Create(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
@@ -70,12 +128,17 @@ func (h *usersHandler) Create(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	users.Password, err = auth.HashPassword(form.Password)
+	if err != nil {
+		logger.Warn("HashPassword error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrCreateUsers)
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
 	err = h.iDao.Create(ctx, users)
 	if err != nil {
-		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "Create error", err, logger.String("userName", form.UserName))
 		return
 	}
 
@@ -89,7 +152,9 @@ func (h *usersHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.DeleteUsersByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/users/{id} [delete]
 // @Security BearerAuth
 func (h *usersHandler) DeleteByID(c *gin.Context) {
@@ -98,12 +163,18 @@ func (h *usersHandler) DeleteByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+	if !h.checkManageable(c, id) {
+		return
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	err := h.iDao.DeleteByID(ctx, id, opts...)
 	if err != nil {
-		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "DeleteByID error", err, logger.Any("id", id))
 		return
 	}
 
@@ -113,12 +184,15 @@ func (h *usersHandler) DeleteByID(c *gin.Context) {
 // UpdateByID update a users by id
 // @Summary Update a users by id
 // @Description Updates the specified users by given id in the path, support partial update.
+// @Description The password, email and status can only be changed if the caller has all the permissions of the roles of the users.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
 // @Param data body types.UpdateUsersByIDRequest true "users information"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
 // @Success 200 {object} types.UpdateUsersByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
 // @Router /api/v1/users/{id} [put]
 // @Security BearerAuth
 func (h *usersHandler) UpdateByID(c *gin.Context) {
@@ -127,15 +201,25 @@ func (h *usersHandler) UpdateByID(c *gin.Context) {
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
 
 	form := &types.UpdateUsersByIDRequest{}
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 	form.ID = id
+	// the password, email and status let the caller log in as the user
+	if form.Password != "" || form.UserEmail != "" || form.Status != "" {
+		if !h.checkManageable(c, id) {
+			return
+		}
+	}
 
 	users := &model.Users{}
 	err = copier.Copy(users, form)
@@ -144,14 +228,97 @@ func (h *usersHandler) UpdateByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if form.Password != "" {
+		users.Password, err = auth.HashPassword(form.Password)
+		if err != nil {
+			logger.Warn("HashPassword error", logger.Err(err), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrUpdateByIDUsers)
+			return
+		}
+	}
 
 	ctx := middleware.WrapCtx(c)
-	err = h.iDao.UpdateByID(ctx, users)
+	statusChanged := false
+	if form.Status != "" {
+		if statusChanged, err = h.isStatusChanged(ctx, id, form.Status); err != nil {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+			return
+		}
+	}
+	err = h.iDao.UpdateByID(ctx, users, opts...)
 	if err != nil {
-		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "UpdateByID error", err, logger.Any("id", id))
 		return
 	}
+	// a disabled user has no permissions, the tokens of the user are revoked by the dao
+	if statusChanged {
+		if err = h.userPermissionsDao.DeleteCacheByUserID(ctx, id); err != nil {
+			logger.Warn("DeleteCacheByUserID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+	}
+
+	response.Success(c)
+}
+
+// PatchByID patch a users by id
+// @Summary Patch a users by id
+// @Description Updates the specified users by given id in the path with a JSON Merge Patch (RFC 7396), the members of the patch are written even if they are zero values, and a null member resets the field.
+// @Description The email and status can only be changed if the caller has all the permissions of the roles of the users.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.UpdateUsersByIDRequest true "users information, only the members to change"
+// @Param If-Match header string false "entity tag of GetByID, the record is changed only if it is still current"
+// @Success 200 {object} types.UpdateUsersByIDReply{}
+// @Failure 412 {object} types.Result "the record has been changed since the entity tag"
+// @Router /api/v1/users/{id} [patch]
+// @Security BearerAuth
+func (h *usersHandler) PatchByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	opts, ok := bindIfMatch(c)
+	if !ok {
+		return
+	}
+
+	columns, ok := bindMergePatch(c, &types.UpdateUsersByIDRequest{}, usersPatchFields)
+	if !ok {
+		return
+	}
+	// the email and status let the caller log in as the user
+	_, hasEmail := columns["user_email"]
+	if _, hasStatus := columns["status"]; hasEmail || hasStatus {
+		if !h.checkManageable(c, id) {
+			return
+		}
+	}
+
+	ctx := middleware.WrapCtx(c)
+	statusChanged := false
+	if status, ok := columns["status"]; ok {
+		var err error
+		// a null member resets the status to its zero value
+		newStatus, _ := status.(string)
+		if statusChanged, err = h.isStatusChanged(ctx, id, newStatus); err != nil {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+			return
+		}
+	}
+	err := h.iDao.PatchByID(ctx, id, columns, opts...)
+	if err != nil {
+		responseDBError(c, "PatchByID error", err, logger.Any("id", id))
+		return
+	}
+	// a disabled user has no permissions, the tokens of the user are revoked by the dao
+	if statusChanged {
+		if err = h.userPermissionsDao.DeleteCacheByUserID(ctx, id); err != nil {
+			logger.Warn("DeleteCacheByUserID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+	}
 
 	response.Success(c)
 }
@@ -161,9 +328,13 @@ func (h *usersHandler) UpdateByID(c *gin.Context) {
 // @Description Gets detailed information of a users specified by the given id in the path.
 // @Tags users
 // @Param id path string true "id"
+// @Param includeDeleted query bool false "also get the soft deleted record"
+// @Param If-None-Match header string false "entity tag of the cached record, 304 Not Modified is responded if it is still current"
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.GetUsersByIDReply{}
+// @Header 200 {string} ETag "entity tag of the record, it is not set for a soft deleted record"
+// @Success 304 "the record is not modified"
 // @Router /api/v1/users/{id} [get]
 // @Security BearerAuth
 func (h *usersHandler) GetByID(c *gin.Context) {
@@ -174,18 +345,22 @@ func (h *usersHandler) GetByID(c *gin.Context) {
 	}
 
 	ctx := middleware.WrapCtx(c)
-	users, err := h.iDao.GetByID(ctx, id)
+	users, err := h.iDao.GetByID(ctx, id, dao.WithIncludeDeleted(c.Query("includeDeleted") == "true"))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
 		} else {
-			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
-			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
 		}
 		return
 	}
 
+	// the client has the current record
+	if writeETag(c, users.Version, users.DeletedAt) {
+		return
+	}
+
 	data := &types.UsersObjDetail{}
 	err = copier.Copy(data, users)
 	if err != nil {
@@ -193,6 +368,9 @@ func (h *usersHandler) GetByID(c *gin.Context) {
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if users.DeletedAt.Valid {
+		data.DeletedAt = &users.DeletedAt.Time
+	}
 
 	response.Success(c, gin.H{"users": data})
 }
@@ -212,15 +390,14 @@ func (h *usersHandler) List(c *gin.Context) {
 	err := c.ShouldBindJSON(form)
 	if err != nil {
 		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+		responseInvalidParams(c, err)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	userss, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	userss, total, err := h.iDao.GetByColumns(ctx, &form.Params, dao.WithIncludeDeleted(form.IncludeDeleted))
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
-		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		responseDBError(c, "GetByColumns error", err, logger.Any("form", form))
 		return
 	}
 
@@ -236,6 +413,133 @@ func (h *usersHandler) List(c *gin.Context) {
 	})
 }
 
+// CheckAvailability check whether the value of a unique field is available
+// @Summary Check whether a user name or email is available
+// @Description Returns whether the value of a unique field is not used by another user, the soft deleted ones included,
+// @Description so that the forms can validate the value before submitting. excludeID is the user being edited.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param field query string true "userName or userEmail"
+// @Param value query string true "value of the field"
+// @Param excludeID query string false "id of the user being edited"
+// @Success 200 {object} types.CheckAvailabilityReply{}
+// @Router /api/v1/users/availability [get]
+// @Security BearerAuth
+func (h *usersHandler) CheckAvailability(c *gin.Context) {
+	form := &types.CheckUsersAvailabilityRequest{}
+	err := c.ShouldBindQuery(form)
+	if err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		responseInvalidParams(c, err)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	available, err := h.iDao.CheckAvailability(ctx, form.Field, form.Value, form.ExcludeID)
+	if err != nil {
+		responseDBError(c, "CheckAvailability error", err, logger.Any("form", form))
+		return
+	}
+
+	response.Success(c, gin.H{"available": available})
+}
+
+// RestoreByID restore a soft deleted users by id
+// @Summary Restore a soft deleted users by id
+// @Description Restores the soft deleted users identified by the given id in the path.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.RestoreUsersByIDReply{}
+// @Router /api/v1/users/{id}/restore [post]
+// @Security BearerAuth
+func (h *usersHandler) RestoreByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.RestoreByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("RestoreByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "RestoreByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	response.Success(c)
+}
+
+// PurgeByID permanently delete a soft deleted users by id
+// @Summary Purge a soft deleted users by id
+// @Description Permanently deletes the users identified by the given id in the path, the users must be soft deleted first.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.PurgeUsersByIDReply{}
+// @Router /api/v1/users/{id}/purge [delete]
+// @Security BearerAuth
+func (h *usersHandler) PurgeByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if !h.checkManageable(c, id) {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PurgeByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("PurgeByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "PurgeByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	// the roles and api keys of a purged user are no longer needed
+	if err = h.userRolesDao.DeleteByUserID(ctx, id); err != nil {
+		logger.Warn("DeleteByUserID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	if err = h.apiKeysDao.DeleteByUserID(ctx, id); err != nil {
+		logger.Warn("apiKeysDao.DeleteByUserID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	}
+	response.Success(c)
+}
+
+// check that the caller has all the effective permissions of the roles of the user before the user is deleted,
+// logged out or unlocked, or its credentials or status are changed, so that user:manage cannot be used against
+// a user with more permissions, such as a super_admin. It responds and returns false on failure.
+func (h *usersHandler) checkManageable(c *gin.Context, id uint64) bool {
+	ctx := middleware.WrapCtx(c)
+	roleIDs, err := h.userRolesDao.GetRoleIDsByUserID(ctx, id)
+	if err != nil {
+		responseDBError(c, "GetRoleIDsByUserID error", err, logger.Any("id", id))
+		return false
+	}
+	return checkRolesGrantable(c, h.rolePermissionsDao, h.userPermissionsDao, roleIDs)
+}
+
+// isStatusChanged report whether status differs from the stored status of the user, it is read before the
+// update so that writing the same status again does not clear the cached permissions of the user
+func (h *usersHandler) isStatusChanged(ctx context.Context, id uint64, status string) (bool, error) {
+	stored, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return stored.Status != status, nil
+}
+
 func getUsersIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -254,6 +558,9 @@ func convertUsers(users *model.Users) (*types.UsersObjDetail, error) {
 		return nil, err
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
+	if users.DeletedAt.Valid {
+		data.DeletedAt = &users.DeletedAt.Time
+	}
 
 	return data, nil
 }
@@ -270,3 +577,316 @@ func convertUserss(fromValues []*model.Users) ([]*types.UsersObjDetail, error) {
 
 	return toValues, nil
 }
+
+// LogoutByID force a users to logout by id
+// @Summary Force a users to logout by id
+// @Description Revokes all the tokens issued to the users identified by the given id in the path, the users must login again.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.LogoutUsersByIDReply{}
+// @Router /api/v1/users/{id}/logout [post]
+// @Security BearerAuth
+func (h *usersHandler) LogoutByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	_, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	if !h.checkManageable(c, id) {
+		return
+	}
+
+	err = h.tokenCache.DelUserFamilies(ctx, id)
+	if err != nil {
+		responseDBError(c, "DelUserFamilies error", err, logger.Any("id", id))
+		return
+	}
+	response.Success(c)
+}
+
+// UnlockByID unlock the login of a users by id
+// @Summary Unlock the login of a users by id
+// @Description Clears the failed logins of the users identified by the given id in the path, so that a users locked by too many failed logins can login again immediately.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.UnlockUsersByIDReply{}
+// @Router /api/v1/users/{id}/unlock [post]
+// @Security BearerAuth
+func (h *usersHandler) UnlockByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	user, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	if !h.checkManageable(c, id) {
+		return
+	}
+
+	err = h.loginLimiter.Unlock(ctx, user.UserName)
+	if err != nil {
+		responseDBError(c, "loginLimiter.Unlock error", err, logger.Any("id", id))
+		return
+	}
+	response.Success(c)
+}
+
+// ResetTwoFactorByID reset the two-factor authentication of a users by id
+// @Summary Reset the two-factor authentication of a users by id
+// @Description Removes the totp secret and recovery codes of the users identified by the given id in the path, e.g. after the users lost its device, and revokes its tokens. If a role of the users requires two-factor authentication, the users enrolls again at the next login.
+// @Description The caller must have all the permissions of the roles of the users.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.ResetTwoFactorUsersByIDReply{}
+// @Router /api/v1/users/{id}/2fa/reset [post]
+// @Security BearerAuth
+func (h *usersHandler) ResetTwoFactorByID(c *gin.Context) {
+	_, id, isAbort := getUsersIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	_, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+	if !h.checkManageable(c, id) {
+		return
+	}
+
+	err = h.iDao.UpdateTwoFactor(ctx, &model.Users{ID: id})
+	if err != nil {
+		responseDBError(c, "UpdateTwoFactor error", err, logger.Any("id", id))
+		return
+	}
+	err = h.tokenCache.DelUserFamilies(ctx, id)
+	if err != nil {
+		responseDBError(c, "DelUserFamilies error", err, logger.Any("id", id))
+		return
+	}
+	response.Success(c)
+}
+
+// GetMe get the profile of the logged-in user
+// @Summary Get my profile
+// @Description Gets detailed information of the user the access token belongs to.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.GetMeReply{}
+// @Router /api/v1/users/me [get]
+// @Security BearerAuth
+func (h *usersHandler) GetMe(c *gin.Context) {
+	id, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	users, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.Unauthorized)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+
+	data, err := convertUsers(users)
+	if err != nil {
+		response.Error(c, ecode.ErrGetByIDUsers)
+		return
+	}
+
+	response.Success(c, gin.H{"users": data})
+}
+
+// UpdateMe update the profile of the logged-in user
+// @Summary Update my profile
+// @Description Updates the nick name, gender, phone and email of the user the access token belongs to,
+// @Description the other fields such as userName, password and status cannot be changed by the user itself.
+// @Description The members of the body are written even if they are empty, an empty value or null clears the field, and an omitted member is not changed.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param data body types.UpdateMeRequest true "profile information"
+// @Success 200 {object} types.UpdateMeReply{}
+// @Router /api/v1/users/me [put]
+// @Security BearerAuth
+func (h *usersHandler) UpdateMe(c *gin.Context) {
+	id, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	// the fields that are not in mePatchFields are refused, and an empty value is written so that a field can be cleared
+	columns, ok := bindMergePatch(c, &types.UpdateMeRequest{}, mePatchFields)
+	if !ok {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.PatchByID(ctx, id, columns)
+	if err != nil {
+		responseDBError(c, "PatchByID error", err, logger.Any("id", id))
+		return
+	}
+
+	response.Success(c)
+}
+
+// ChangePassword change the password of the logged-in user
+// @Summary Change my password
+// @Description Changes the password of the user the access token belongs to, the current password is required.
+// @Description All the login sessions of the user are ended, and a new pair of tokens is returned to the caller.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param data body types.ChangePasswordRequest true "current and new password"
+// @Success 200 {object} types.ChangePasswordReply{}
+// @Router /api/v1/users/me/password [put]
+// @Security BearerAuth
+func (h *usersHandler) ChangePassword(c *gin.Context) {
+	id, ok := getMeID(c)
+	if !ok {
+		response.Error(c, ecode.Unauthorized)
+		return
+	}
+
+	form := &types.ChangePasswordRequest{}
+	if !shouldBindJSONStrict(c, form) {
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	users, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.Unauthorized)
+		} else {
+			responseDBError(c, "GetByID error", err, logger.Any("id", id))
+		}
+		return
+	}
+
+	// a wrong current password counts as a failed login, so that it cannot be guessed with a stolen token
+	clientIP := c.ClientIP()
+	if _, err = h.loginLimiter.Check(ctx, users.UserName, clientIP); err != nil {
+		logger.Warn("loginLimiter.Check error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			response.Error(c, ecode.ErrLoginLocked)
+		case errors.Is(err, auth.ErrIPBlocked):
+			response.Error(c, ecode.ErrLoginIPBlocked)
+		default:
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return
+	}
+	if match, _ := auth.VerifyPassword(form.OldPassword, users.Password); !match {
+		logger.Warn("VerifyPassword mismatch", logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		if err = h.loginLimiter.Fail(ctx, users.UserName, clientIP); err != nil {
+			logger.Warn("loginLimiter.Fail error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+		response.Error(c, ecode.ErrPasswordUsers)
+		return
+	}
+
+	password, err := auth.HashPassword(form.NewPassword)
+	if err != nil {
+		logger.Warn("HashPassword error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrUpdateByIDUsers)
+		return
+	}
+	// changing the password revokes all the tokens of the user, including the ones of this request
+	err = h.iDao.UpdateByID(ctx, &model.Users{ID: id, Password: password})
+	if err != nil {
+		responseDBError(c, "UpdateByID error", err, logger.Any("id", id))
+		return
+	}
+
+	tokens, ok := newLoginTokens(c, h.tokenCache, users)
+	if !ok {
+		return
+	}
+	response.Success(c, tokens)
+}
+
+// get the id of the logged-in user from the claims of the access token
+func getMeID(c *gin.Context) (uint64, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return 0, false
+	}
+	id, err := auth.GetUserID(claims)
+	if err != nil {
+		logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+		return 0, false
+	}
+	return id, true
+}
+
+// bind the json body and reject the fields that are not in obj, it responds and returns false on failure.
+// It is used by the self-service apis, so that a field like status is refused instead of silently ignored.
+func shouldBindJSONStrict(c *gin.Context, obj any) bool {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(obj)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(obj)
+	}
+	if err != nil {
+		logger.Warn("shouldBindJSONStrict error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+			response.Error(c, ecode.InvalidParams.RewriteMsg(field+" cannot be changed"), &types.InvalidParamsDetail{
+				Fields: []*types.FieldError{{Field: strings.Trim(field, `"`), Reason: "unknown"}},
+			})
+		} else {
+			responseInvalidParams(c, err)
+		}
+		return false
+	}
+	return true
+}
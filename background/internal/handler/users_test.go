@@ -1,22 +1,35 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 
 	"github.com/go-dev-frame/sponge/pkg/copier"
 	"github.com/go-dev-frame/sponge/pkg/gotest"
 	"github.com/go-dev-frame/sponge/pkg/httpcli"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
+	"godemo/internal/auth"
 	"godemo/internal/cache"
 	"godemo/internal/dao"
 	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/migration"
 	"godemo/internal/model"
 	"godemo/internal/types"
 )
@@ -37,11 +50,19 @@ func newUsersHandler() *gotest.Handler {
 
 	// init mock dao
 	d := gotest.NewDao(c, testData)
-	d.IDao = dao.NewUsersDao(d.DB, c.ICache.(cache.UsersCache))
+	d.IDao = dao.NewUsersDao(d.DB, c.ICache.(cache.UsersCache), nil)
 
 	// init mock handler
 	h := gotest.NewHandler(d, testData)
-	h.IHandler = &usersHandler{iDao: d.IDao.(dao.UsersDao)}
+	h.IHandler = &usersHandler{
+		iDao:               d.IDao.(dao.UsersDao),
+		userRolesDao:       dao.NewUserRolesDao(d.DB, nil, nil),
+		rolePermissionsDao: dao.NewRolePermissionsDao(d.DB, nil, nil),
+		userPermissionsDao: dao.NewUserPermissionsDao(d.DB, nil),
+		apiKeysDao:         dao.NewAPIKeysDao(d.DB),
+		tokenCache:         cache.NewTokenCache(&database.CacheType{CType: "memory"}),
+		loginLimiter:       auth.NewLoginLimiter(cache.NewLoginFailuresCache(&database.CacheType{CType: "memory"})),
+	}
 	iHandler := h.IHandler.(UsersHandler)
 
 	testFns := []gotest.RouterInfo{
@@ -75,6 +96,42 @@ func newUsersHandler() *gotest.Handler {
 			Path:        "/users/list",
 			HandlerFunc: iHandler.List,
 		},
+		{
+			FuncName:    "CheckAvailability",
+			Method:      http.MethodGet,
+			Path:        "/users/availability",
+			HandlerFunc: iHandler.CheckAvailability,
+		},
+		{
+			FuncName:    "RestoreByID",
+			Method:      http.MethodPost,
+			Path:        "/users/:id/restore",
+			HandlerFunc: iHandler.RestoreByID,
+		},
+		{
+			FuncName:    "PurgeByID",
+			Method:      http.MethodDelete,
+			Path:        "/users/:id/purge",
+			HandlerFunc: iHandler.PurgeByID,
+		},
+		{
+			FuncName:    "LogoutByID",
+			Method:      http.MethodPost,
+			Path:        "/users/:id/logout",
+			HandlerFunc: iHandler.LogoutByID,
+		},
+		{
+			FuncName:    "UnlockByID",
+			Method:      http.MethodPost,
+			Path:        "/users/:id/unlock",
+			HandlerFunc: iHandler.UnlockByID,
+		},
+		{
+			FuncName:    "ResetTwoFactorByID",
+			Method:      http.MethodPost,
+			Path:        "/users/:id/2fa/reset",
+			HandlerFunc: iHandler.ResetTwoFactorByID,
+		},
 	}
 
 	h.GoRunHTTPServer(testFns)
@@ -83,6 +140,13 @@ func newUsersHandler() *gotest.Handler {
 	return h
 }
 
+// the user has no roles, so that any caller can manage it
+func expectNoUserRoles(h *gotest.Handler, id uint64) {
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}))
+}
+
 func Test_usersHandler_Create(t *testing.T) {
 	h := newUsersHandler()
 	defer h.Close()
@@ -110,11 +174,12 @@ func Test_usersHandler_DeleteByID(t *testing.T) {
 	h := newUsersHandler()
 	defer h.Close()
 	testData := h.TestData.(*model.Users)
-	expectedSQLForDeletion := "DELETE .*"
+	expectedSQLForDeletion := "UPDATE .*" // soft delete
 
+	expectNoUserRoles(h, testData.ID)
 	h.MockDao.SQLMock.ExpectBegin()
 	h.MockDao.SQLMock.ExpectExec(expectedSQLForDeletion).
-		WithArgs(testData.ID). // adjusted for the amount of test data
+		WithArgs(h.MockDao.AnyTime, testData.ID). // adjusted for the amount of test data
 		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
 	h.MockDao.SQLMock.ExpectCommit()
 
@@ -166,6 +231,136 @@ func Test_usersHandler_UpdateByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_usersHandler_UpdateByID_Status(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+	ctx := context.Background()
+	usersCache := h.MockDao.Cache.ICache.(cache.UsersCache)
+	permissionsCache := cache.NewUserPermissionsCache(&database.CacheType{CType: "memory"})
+	h.IHandler.(*usersHandler).userPermissionsDao = dao.NewUserPermissionsDao(h.MockDao.DB, permissionsCache)
+
+	update := func(status string) {
+		assert.NoError(t, usersCache.Set(ctx, testData.ID, &model.Users{ID: testData.ID, Status: model.StatusEnabled}, time.Minute))
+		assert.NoError(t, permissionsCache.Set(ctx, testData.ID, []string{"users:list"}, time.Minute))
+		// the user has no roles, any caller may change its status
+		h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+			WillReturnRows(sqlmock.NewRows([]string{"role_id"}))
+		h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+			WillReturnRows(sqlmock.NewRows([]string{"password", "status"}).AddRow("", model.StatusEnabled))
+		h.MockDao.SQLMock.ExpectBegin()
+		h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+			WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+		h.MockDao.SQLMock.ExpectCommit()
+
+		result := &httpcli.StdResult{}
+		err := httpcli.Put(result, h.GetRequestURL("UpdateByID", testData.ID), &types.UpdateUsersByIDRequest{Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Code != 0 {
+			t.Fatalf("%+v", result)
+		}
+	}
+
+	// the same status is sent again, the cached permissions are kept
+	update(model.StatusEnabled)
+	codes, err := permissionsCache.Get(ctx, testData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users:list"}, codes)
+
+	// the status is changed
+	update(model.StatusDisabled)
+	_, err = permissionsCache.Get(ctx, testData.ID)
+	assert.Error(t, err)
+}
+
+// newSeededTestDB open a sqlite database with the migrations applied, it has the seeded roles and permissions
+func newSeededTestDB(t *testing.T) *gorm.DB {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "test.db"), sqlite.WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sgorm.CloseDB(db) })
+	m, err := migration.NewMigrator(db, sgorm.DBDriverSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// callAs call the handler for the record id as the user callerID, it returns the code of the response
+func callAs(t *testing.T, callerID uint64, fn gin.HandlerFunc, method string, id uint64, body string) int {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/"+utils.Uint64ToStr(id), strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: utils.Uint64ToStr(id)}}
+	c.Set("claims", &jwt.Claims{UID: utils.Uint64ToStr(callerID)})
+	fn(c)
+	result := &httpcli.StdResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	return result.Code
+}
+
+// a user:manage caller cannot delete, log out or unlock a user with more permissions, nor change its credentials or status
+func Test_usersHandler_UpdateByID_NotManageable(t *testing.T) {
+	db := newSeededTestDB(t)
+	ctx := context.Background()
+
+	usersDao := dao.NewUsersDao(db, nil, nil)
+	userRolesDao := dao.NewUserRolesDao(db, nil, nil)
+	rolePermissionsDao := dao.NewRolePermissionsDao(db, nil, nil)
+	// the seeded super_admin role has all the permissions, the manager role has only user:manage
+	manager := &model.Roles{RoleName: "manager", RoleCode: "manager", Status: model.StatusEnabled}
+	assert.NoError(t, db.Create(manager).Error)
+	assert.NoError(t, rolePermissionsDao.ReplaceByRoleID(ctx, manager.ID, []uint64{1}))
+	admin := &model.Users{UserName: "admin", Password: "hash", UserEmail: "admin@example.com", Status: model.StatusEnabled}
+	caller := &model.Users{UserName: "caller", Password: "hash", Status: model.StatusEnabled}
+	staff := &model.Users{UserName: "staff", Password: "hash", Status: model.StatusEnabled}
+	for _, user := range []*model.Users{admin, caller, staff} {
+		assert.NoError(t, usersDao.Create(ctx, user))
+	}
+	assert.NoError(t, userRolesDao.ReplaceByUserID(ctx, admin.ID, []uint64{1}))
+	assert.NoError(t, userRolesDao.ReplaceByUserID(ctx, caller.ID, []uint64{manager.ID}))
+	assert.NoError(t, userRolesDao.ReplaceByUserID(ctx, staff.ID, []uint64{manager.ID}))
+
+	h := &usersHandler{
+		iDao:               usersDao,
+		userRolesDao:       userRolesDao,
+		rolePermissionsDao: rolePermissionsDao,
+		userPermissionsDao: dao.NewUserPermissionsDao(db, nil),
+	}
+	call := func(fn gin.HandlerFunc, method string, id uint64, body string) int {
+		return callAs(t, caller.ID, fn, method, id, body)
+	}
+
+	notGrantable := ecode.ErrRoleNotGrantable.Code()
+	assert.Equal(t, notGrantable, call(h.UpdateByID, http.MethodPut, admin.ID, `{"password":"new-password"}`))
+	assert.Equal(t, notGrantable, call(h.UpdateByID, http.MethodPut, admin.ID, `{"userEmail":"caller@example.com"}`))
+	assert.Equal(t, notGrantable, call(h.PatchByID, http.MethodPatch, admin.ID, `{"status":"2"}`))
+	assert.Equal(t, notGrantable, call(h.ResetTwoFactorByID, http.MethodPost, admin.ID, ``))
+	assert.Equal(t, notGrantable, call(h.LogoutByID, http.MethodPost, admin.ID, ``))
+	assert.Equal(t, notGrantable, call(h.UnlockByID, http.MethodPost, admin.ID, ``))
+	assert.Equal(t, notGrantable, call(h.DeleteByID, http.MethodDelete, admin.ID, ``))
+	assert.Equal(t, notGrantable, call(h.PurgeByID, http.MethodDelete, admin.ID, ``))
+	record, err := usersDao.GetByID(ctx, admin.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash", record.Password)
+	assert.Equal(t, "admin@example.com", record.UserEmail)
+	assert.Equal(t, model.StatusEnabled, record.Status)
+
+	// the other fields of the admin can still be changed
+	assert.Equal(t, 0, call(h.UpdateByID, http.MethodPut, admin.ID, `{"nickName":"boss"}`))
+	// a user with no more permissions than the caller can be managed
+	assert.Equal(t, 0, call(h.UpdateByID, http.MethodPut, staff.ID, `{"password":"new-password"}`))
+	record, err = usersDao.GetByID(ctx, staff.ID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "hash", record.Password)
+}
+
 func Test_usersHandler_GetByID(t *testing.T) {
 	h := newUsersHandler()
 	defer h.Close()
@@ -209,7 +404,7 @@ func Test_usersHandler_List(t *testing.T) {
 	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
 
 	result := &httpcli.StdResult{}
-	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserssRequest{query.Params{
+	err := httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserssRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "ignore count", // ignore test count
@@ -226,7 +421,7 @@ func Test_usersHandler_List(t *testing.T) {
 	assert.NoError(t, err)
 
 	// get error test
-	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserssRequest{query.Params{
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserssRequest{Params: query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "unknown-column",
@@ -234,9 +429,262 @@ func Test_usersHandler_List(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_usersHandler_CheckAvailability(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT count.*").
+		WithArgs("tom", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Get(result, h.GetRequestURL("CheckAvailability")+"?field=userName&value=tom&excludeID=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+	assert.Equal(t, map[string]interface{}{"available": false}, result.Data)
+
+	// not a unique field
+	err = httpcli.Get(result, h.GetRequestURL("CheckAvailability")+"?field=nickName&value=tom")
+	assert.NoError(t, err)
+	assert.NotZero(t, result.Code)
+
+	// get error test
+	err = httpcli.Get(result, h.GetRequestURL("CheckAvailability")+"?field=userEmail&value=tom@example.com")
+	assert.Error(t, err)
+}
+
+func Test_usersHandler_RestoreByID(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(nil, h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("RestoreByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 0), nil)
+	assert.NoError(t, err)
+
+	// restore error test
+	err = httpcli.Post(result, h.GetRequestURL("RestoreByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_usersHandler_PurgeByID(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+
+	expectNoUserRoles(h, testData.ID)
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+	// the roles of the user are deleted too
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("DELETE .*").
+		WithArgs(testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Delete(result, h.GetRequestURL("PurgeByID", testData.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 0))
+	assert.NoError(t, err)
+
+	// purge error test
+	err = httpcli.Delete(result, h.GetRequestURL("PurgeByID", 111))
+	assert.Error(t, err)
+}
+
+func Test_usersHandler_LogoutByID(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+
+	// column names and corresponding data
+	rows := sqlmock.NewRows([]string{"id"}).
+		AddRow(testData.ID)
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+	expectNoUserRoles(h, testData.ID)
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("LogoutByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("LogoutByID", 0), nil)
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("LogoutByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_usersHandler_UnlockByID(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+
+	// column names and corresponding data
+	rows := sqlmock.NewRows([]string{"id", "user_name"}).
+		AddRow(testData.ID, "admin")
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+	expectNoUserRoles(h, testData.ID)
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("UnlockByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("UnlockByID", 0), nil)
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("UnlockByID", 111), nil)
+	assert.Error(t, err)
+}
+
+func Test_usersHandler_ResetTwoFactorByID(t *testing.T) {
+	h := newUsersHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.Users)
+
+	// column names and corresponding data
+	rows := sqlmock.NewRows([]string{"id"}).
+		AddRow(testData.ID)
+
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+		WithArgs(testData.ID, 1).
+		WillReturnRows(rows)
+	expectNoUserRoles(h, testData.ID)
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(false, 0, "", "", h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+
+	result := &httpcli.StdResult{}
+	err := httpcli.Post(result, h.GetRequestURL("ResetTwoFactorByID", testData.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != 0 {
+		t.Fatalf("%+v", result)
+	}
+
+	// zero id error test
+	err = httpcli.Post(result, h.GetRequestURL("ResetTwoFactorByID", 0), nil)
+	assert.NoError(t, err)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("ResetTwoFactorByID", 111), nil)
+	assert.Error(t, err)
+}
+
 func TestNewUsersHandler(t *testing.T) {
 	defer func() {
 		recover()
 	}()
 	_ = NewUsersHandler()
 }
+
+func Test_usersHandler_UpdateMe(t *testing.T) {
+	db := newSeededTestDB(t)
+	ctx := context.Background()
+
+	usersDao := dao.NewUsersDao(db, nil, nil)
+	me := &model.Users{UserName: "tom", Password: "hash", NickName: "Tom", UserPhone: "13800138000",
+		UserEmail: "tom@example.com", UserGender: "1", Status: model.StatusEnabled}
+	assert.NoError(t, usersDao.Create(ctx, me))
+	h := &usersHandler{iDao: usersDao}
+
+	// an empty value and null clear the field, an omitted member is not changed
+	assert.Equal(t, 0, callAs(t, me.ID, h.UpdateMe, http.MethodPut, me.ID, `{"nickName":"","userPhone":null,"userGender":"2"}`))
+	record, err := usersDao.GetByID(ctx, me.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "", record.NickName)
+	assert.Equal(t, "", record.UserPhone)
+	assert.Equal(t, "2", record.UserGender)
+	assert.Equal(t, "tom@example.com", record.UserEmail)
+
+	// the fields managed by user:manage are refused, and the values are still validated
+	invalidParams := ecode.InvalidParams.Code()
+	assert.Equal(t, invalidParams, callAs(t, me.ID, h.UpdateMe, http.MethodPut, me.ID, `{"nickName":"tom","status":"2"}`))
+	assert.Equal(t, invalidParams, callAs(t, me.ID, h.UpdateMe, http.MethodPut, me.ID, `{"userEmail":"tom"}`))
+	record, err = usersDao.GetByID(ctx, me.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "", record.NickName)
+	assert.Equal(t, model.StatusEnabled, record.Status)
+}
+
+func Test_shouldBindJSONStrict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{name: "allowed fields", body: `{"nickName":"admin","userEmail":"admin@example.com"}`, want: true},
+		{name: "sensitive field", body: `{"nickName":"admin","status":"2"}`, want: false},
+		{name: "user name", body: `{"userName":"root"}`, want: false},
+		{name: "invalid json", body: `{"nickName":`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/users/me", strings.NewReader(tt.body))
+
+			form := &types.UpdateMeRequest{}
+			assert.Equal(t, tt.want, shouldBindJSONStrict(c, form))
+		})
+	}
+
+	// binding tags are still validated
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/users/me/password", strings.NewReader(`{"newPassword":"123456"}`))
+	assert.False(t, shouldBindJSONStrict(c, &types.ChangePasswordRequest{}))
+}
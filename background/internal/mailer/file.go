@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Mailer = (*File)(nil)
+
+// File saves every email as an .eml file of a local directory instead of sending it, the files can
+// be opened by any mail client. It is used for development and tests.
+type File struct {
+	dir  string
+	from string
+}
+
+// NewFile create a file mailer, the directory is created if it does not exist
+func NewFile(dir string, from string) (*File, error) {
+	if dir == "" {
+		dir = "./mails"
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(absDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: absDir, from: from}, nil
+}
+
+// Send save the message as a new file, the file name starts with the time so the files sort by time
+func (f *File) Send(_ context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := buildMessage(f.from, msg, now)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(f.dir, name), data, 0o600)
+}
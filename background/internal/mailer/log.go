@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+var _ Mailer = (*Log)(nil)
+
+// Log writes every email to the log instead of sending it, it is used for development and tests.
+// The body may contain secrets such as reset links, so it must not be used in production.
+type Log struct {
+	from string
+}
+
+// NewLog create a log mailer
+func NewLog(from string) *Log {
+	return &Log{from: from}
+}
+
+// Send write the message to the log
+func (l *Log) Send(_ context.Context, msg *Message) error {
+	// build the message anyway, so that an invalid message fails like with the other mailers
+	if _, err := buildMessage(l.from, msg, time.Now()); err != nil {
+		return err
+	}
+	logger.Info("mail",
+		logger.String("from", l.from),
+		logger.Any("to", msg.To),
+		logger.String("subject", msg.Subject),
+		logger.String("body", msg.Body),
+	)
+	return nil
+}
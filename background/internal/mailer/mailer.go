@@ -0,0 +1,134 @@
+// Package mailer provides the backends that send emails, such as the password reset emails.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"godemo/internal/config"
+)
+
+const (
+	// DriverSMTP sends the emails through an smtp server
+	DriverSMTP = "smtp"
+	// DriverFile saves the emails as files of a local directory, for development and tests
+	DriverFile = "file"
+	// DriverLog writes the emails to the log, for development and tests
+	DriverLog = "log"
+)
+
+var (
+	// ErrNoRecipient the message has no recipient
+	ErrNoRecipient = errors.New("mailer: message has no recipient")
+
+	defaultMailer Mailer
+	mailerOnce    sync.Once
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer is a backend that sends emails
+type Mailer interface {
+	// Send the message, the sender is the from address of the mailer
+	Send(ctx context.Context, msg *Message) error
+}
+
+// InitMailer create the mail backend from the configuration
+func InitMailer() {
+	cfg := config.Get().Mail
+	switch strings.ToLower(cfg.Driver) {
+	case DriverSMTP:
+		defaultMailer = NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From)
+	case DriverFile:
+		m, err := NewFile(cfg.File.Dir, cfg.From)
+		if err != nil {
+			panic("InitMailer error: " + err.Error())
+		}
+		defaultMailer = m
+	case DriverLog, "":
+		defaultMailer = NewLog(cfg.From)
+	default:
+		panic("InitMailer error, unsupported mail driver '" + cfg.Driver +
+			"', please modify the 'mail' configuration at yaml file")
+	}
+}
+
+// Get get the mail backend
+func Get() Mailer {
+	if defaultMailer == nil {
+		mailerOnce.Do(func() {
+			InitMailer()
+		})
+	}
+
+	return defaultMailer
+}
+
+// build the message in the internet message format (RFC 5322), the body is quoted-printable
+// encoded so that any utf-8 text can be sent.
+func buildMessage(from string, msg *Message, now time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, ErrNoRecipient
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address: %w", err)
+	}
+	to := make([]string, 0, len(msg.To))
+	for _, v := range msg.To {
+		addr, err := mail.ParseAddress(v)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: invalid recipient address: %w", err)
+		}
+		to = append(to, addr.String())
+	}
+
+	buf := &bytes.Buffer{}
+	writeHeader := func(key string, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	writeHeader("From", fromAddr.String())
+	writeHeader("To", strings.Join(to, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", newMessageID(fromAddr.Address))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(buf)
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err = w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">"
+}
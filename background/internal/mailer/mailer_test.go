@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"godemo/internal/config"
+)
+
+func Test_buildMessage(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := &Message{
+		To:      []string{"Tom <tom@example.com>", "jerry@example.com"},
+		Subject: "Réinitialiser le mot de passe",
+		Body:    "line 1\nline 2",
+	}
+	data, err := buildMessage("godemo <no-reply@example.com>", msg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	assert.Contains(t, s, "From: \"godemo\" <no-reply@example.com>\r\n")
+	assert.Contains(t, s, "To: \"Tom\" <tom@example.com>, <jerry@example.com>\r\n")
+	assert.Contains(t, s, "Subject: =?utf-8?q?R=C3=A9initialiser_le_mot_de_passe?=\r\n")
+	assert.Contains(t, s, "Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n")
+	assert.Contains(t, s, "Message-ID: <")
+	assert.True(t, strings.HasSuffix(s, "\r\n\r\nline 1\r\nline 2"))
+
+	_, err = buildMessage("no-reply@example.com", &Message{Subject: "x"}, now)
+	assert.ErrorIs(t, err, ErrNoRecipient)
+	_, err = buildMessage("invalid", msg, now)
+	assert.Error(t, err)
+	_, err = buildMessage("no-reply@example.com", &Message{To: []string{"invalid"}}, now)
+	assert.Error(t, err)
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+	m, err := NewFile(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Send(context.Background(), &Message{To: []string{"tom@example.com"}, Subject: "hello", Body: "world"})
+	assert.NoError(t, err)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".eml"))
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.Contains(t, string(data), "Subject: hello\r\n")
+
+	err = m.Send(context.Background(), &Message{Subject: "hello"})
+	assert.ErrorIs(t, err, ErrNoRecipient)
+}
+
+func TestLog(t *testing.T) {
+	m := NewLog("no-reply@example.com")
+	err := m.Send(context.Background(), &Message{To: []string{"tom@example.com"}, Subject: "hello", Body: "world"})
+	assert.NoError(t, err)
+	err = m.Send(context.Background(), &Message{Subject: "hello"})
+	assert.ErrorIs(t, err, ErrNoRecipient)
+}
+
+func TestGet(t *testing.T) {
+	config.Set(&config.Config{Mail: config.Mail{Driver: "log", From: "no-reply@example.com"}})
+	defer func() {
+		defaultMailer = nil
+		config.Set(nil)
+	}()
+	assert.IsType(t, &Log{}, Get())
+
+	config.Set(&config.Config{Mail: config.Mail{Driver: "unknown"}})
+	assert.Panics(t, InitMailer)
+}
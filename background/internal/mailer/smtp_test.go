@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a minimal smtp server that accepts one message without authentication
+func runSMTPServer(t *testing.T) (int, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		tc := textproto.NewConn(conn)
+		defer tc.Close()
+		_ = tc.PrintfLine("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				_ = tc.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				_ = tc.PrintfLine("250 OK")
+			case "DATA":
+				_ = tc.PrintfLine("354 go ahead")
+				data, err := tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- strings.Join(envelope, "\n") + "\n" + string(data)
+				_ = tc.PrintfLine("250 OK")
+			case "QUIT":
+				_ = tc.PrintfLine("221 bye")
+				return
+			default:
+				_ = tc.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTP(t *testing.T) {
+	port, received := runSMTPServer(t)
+	m := NewSMTP("127.0.0.1", port, "", "", "godemo <no-reply@example.com>")
+
+	err := m.Send(context.Background(), &Message{
+		To:      []string{"Tom <tom@example.com>"},
+		Subject: "hello",
+		Body:    "world",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := <-received
+	assert.Contains(t, data, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, data, "RCPT TO:<tom@example.com>")
+	assert.Contains(t, data, "Subject: hello\n")
+	assert.Contains(t, data, "\nworld")
+
+	// the connection fails
+	m = NewSMTP("127.0.0.1", 1, "", "", "no-reply@example.com")
+	err = m.Send(context.Background(), &Message{To: []string{"tom@example.com"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+// Package migration provides the versioned database schema migrations, the sql files are
+// embedded in the binary and the applied versions are recorded in the schema_migrations table.
+//
+// The migrations of a driver live in sql/<driver>/ and are named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, versions are applied in ascending order. Every driver must have
+// the same versions. A statement ends with a semicolon at the end of a line.
+//
+// A statement that follows the line "-- +if-column-missing <table> <column>" runs only if the
+// table has no such column, it adds a column to a table that CREATE TABLE IF NOT EXISTS did
+// not create, such as a table of a database that adopts the migrations.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+)
+
+//go:embed sql
+var sqlFS embed.FS
+
+// Migration a versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load read the migrations of the database driver, sorted by version
+func Load(driver string) ([]*Migration, error) {
+	return load(sqlFS, path.Join("sql", driverDir(driver)))
+}
+
+func driverDir(driver string) string {
+	switch strings.ToLower(driver) {
+	case sgorm.DBDriverMysql, sgorm.DBDriverTidb:
+		return sgorm.DBDriverMysql
+	default:
+		return strings.ToLower(driver)
+	}
+}
+
+func load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations found in %s: %v", dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name %s/%s", dir, entry.Name())
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version %s/%s", dir, entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d has two names %s and %s", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.UpSQL = string(content)
+		} else {
+			m.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" || m.DownSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// the condition line of a statement that adds a column if it is missing
+const ifColumnMissing = "-- +if-column-missing"
+
+// split the sql into statements, a statement ends with a semicolon at the end of a line,
+// comment lines and blank statements are dropped, a condition line is kept at the start of its statement.
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || (strings.HasPrefix(trimmed, "--") && !strings.HasPrefix(trimmed, ifColumnMissing)) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// parse the condition line of a statement, it returns the table and column of the condition and the
+// statement without the line, ok is false if the statement has no condition.
+func parseIfColumnMissing(statement string) (table string, column string, rest string, ok bool) {
+	line, rest, _ := strings.Cut(statement, "\n")
+	args, found := strings.CutPrefix(strings.TrimSpace(line), ifColumnMissing)
+	if !found {
+		return "", "", statement, false
+	}
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		return "", "", statement, false
+	}
+	return fields[0], fields[1], strings.TrimSpace(rest), true
+}
@@ -0,0 +1,293 @@
+package migration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+
+	"godemo/internal/model"
+)
+
+func TestLoad(t *testing.T) {
+	var versions []int64
+	for _, driver := range []string{sgorm.DBDriverMysql, sgorm.DBDriverPostgresql, sgorm.DBDriverSqlite} {
+		migrations, err := Load(driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vs []int64
+		for _, m := range migrations {
+			vs = append(vs, m.Version)
+		}
+		if versions == nil {
+			versions = vs
+		}
+		// every driver must have the same migrations
+		assert.Equal(t, versions, vs, driver)
+	}
+
+	migrations, err := Load(sgorm.DBDriverTidb)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, len(versions))
+
+	_, err = Load("unknown")
+	assert.Error(t, err)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":     {"sql/x/1_init.sql": {Data: []byte("x")}},
+		"missing down": {"sql/x/1_init.up.sql": {Data: []byte("x")}},
+		"two names": {
+			"sql/x/1_a.up.sql":   {Data: []byte("x")},
+			"sql/x/1_a.down.sql": {Data: []byte("x")},
+			"sql/x/1_b.up.sql":   {Data: []byte("x")},
+		},
+		"zero version": {
+			"sql/x/0_a.up.sql":   {Data: []byte("x")},
+			"sql/x/0_a.down.sql": {Data: []byte("x")},
+		},
+	}
+	for name, fsys := range tests {
+		_, err := load(fsys, "sql/x")
+		assert.Error(t, err, name)
+	}
+
+	migrations, err := load(fstest.MapFS{
+		"sql/x/10_b.up.sql":   {Data: []byte("b")},
+		"sql/x/10_b.down.sql": {Data: []byte("b")},
+		"sql/x/2_a.up.sql":    {Data: []byte("a")},
+		"sql/x/2_a.down.sql":  {Data: []byte("a")},
+	}, "sql/x")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, int64(10), migrations[1].Version)
+}
+
+func Test_splitStatements(t *testing.T) {
+	sql := `-- comment
+CREATE TABLE a (
+  id int, -- trailing comment;x
+  name varchar(10)
+);
+
+INSERT INTO a VALUES (1, 'a;b');
+SELECT 1`
+	statements := splitStatements(sql)
+	assert.Len(t, statements, 3)
+	assert.Contains(t, statements[0], "name varchar(10)")
+	assert.Equal(t, "INSERT INTO a VALUES (1, 'a;b');", statements[1])
+	assert.Equal(t, "SELECT 1", statements[2])
+
+	statements = splitStatements(`-- comment
+-- +if-column-missing menus permission
+ALTER TABLE menus ADD COLUMN permission varchar(255);`)
+	assert.Len(t, statements, 1)
+	table, column, rest, ok := parseIfColumnMissing(statements[0])
+	assert.True(t, ok)
+	assert.Equal(t, "menus", table)
+	assert.Equal(t, "permission", column)
+	assert.Equal(t, "ALTER TABLE menus ADD COLUMN permission varchar(255);", rest)
+
+	_, _, rest, ok = parseIfColumnMissing("SELECT 1;")
+	assert.False(t, ok)
+	assert.Equal(t, "SELECT 1;", rest)
+}
+
+func TestMigrator(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "migrate.db"), sqlite.WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sgorm.CloseDB(db) //nolint
+	ctx := context.Background()
+
+	m, err := NewMigrator(db, sgorm.DBDriverSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(m.migrations)
+
+	pending, err := m.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, total)
+
+	done, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, total)
+
+	// the schema and seed data can be used by the models
+	var count int64
+	assert.NoError(t, db.Model(&model.Permissions{}).Count(&count).Error)
+	assert.NotZero(t, count)
+	permission := &model.Permissions{Name: "test", Code: "test:code"}
+	assert.NoError(t, db.Create(permission).Error)
+	assert.Greater(t, permission.ID, uint64(count))
+	assert.Error(t, db.Create(&model.Permissions{Name: "test", Code: "test:code"}).Error) // the code is unique
+	assert.NoError(t, db.Create(&model.APIKeys{UserID: 1, Name: "test", KeyPrefix: "gdk_test", KeyHash: "hash"}).Error)
+	assert.NoError(t, db.Create(&model.AuditLogs{Entity: "users", EntityID: 1, Action: "create", Diff: "{}"}).Error)
+	assert.NoError(t, db.Create(&model.LoginLogs{UserName: "admin", IP: "127.0.0.1", Result: "success"}).Error)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// nothing to apply twice
+	done, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, 0)
+
+	// roll back the last migration and apply it again
+	done, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, m.migrations[total-1].Version, done[0].Version)
+	pending, err = m.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	done, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+
+	// a migration applied after a higher version is rolled back first
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, db.Model(&model.SchemaMigrations{}).Where("version = ?", m.migrations[2].Version).
+		Update("applied_at", &later).Error)
+	done, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, m.migrations[2].Version, done[0].Version)
+	done, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+
+	// roll back everything, the tables are dropped
+	done, err = m.Down(ctx, total+1)
+	assert.NoError(t, err)
+	assert.Len(t, done, total)
+	assert.False(t, db.Migrator().HasTable(&model.Users{}))
+	assert.False(t, db.Migrator().HasTable(&model.APIKeys{}))
+	assert.False(t, db.Migrator().HasTable(&model.AuditLogs{}))
+	assert.False(t, db.Migrator().HasTable(&model.LoginLogs{}))
+}
+
+// the schema and seed data of the former Database/mysql.sql
+const baselineSQL = `CREATE TABLE files (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  filename varchar(255) NOT NULL,
+  url varchar(255) NOT NULL,
+  size bigint,
+  mime_type varchar(100),
+  user_id bigint
+);
+CREATE TABLE menus (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  name varchar(255) NOT NULL,
+  path varchar(255) NOT NULL,
+  icon varchar(255),
+  parent_id bigint,
+  "order" integer
+);
+CREATE TABLE permissions (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  name varchar(255) NOT NULL,
+  code varchar(255) NOT NULL,
+  description text
+);
+CREATE TABLE role_permissions (
+  role_id bigint NOT NULL,
+  permission_id bigint NOT NULL,
+  PRIMARY KEY (role_id, permission_id)
+);
+CREATE TABLE roles (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  role_name varchar(255) NOT NULL,
+  role_code varchar(255) NOT NULL,
+  role_desc text,
+  status varchar(10)
+);
+CREATE TABLE user_roles (
+  user_id bigint NOT NULL,
+  role_id bigint NOT NULL,
+  PRIMARY KEY (user_id, role_id)
+);
+CREATE TABLE users (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  user_name varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  user_gender varchar(10),
+  nick_name varchar(255),
+  user_phone varchar(20),
+  user_email varchar(255),
+  status varchar(10)
+);
+INSERT INTO permissions (id, name, code, description) VALUES
+(1, '用户管理', 'user:manage', '管理用户'),
+(2, '角色管理', 'role:manage', '管理角色'),
+(3, '权限管理', 'permission:manage', '管理权限'),
+(4, '菜单管理', 'menu:manage', '管理菜单'),
+(5, '文件管理', 'file:manage', '管理文件');
+INSERT INTO role_permissions (role_id, permission_id) VALUES (1, 1), (1, 2), (1, 3), (1, 4), (1, 5);
+INSERT INTO roles (id, role_name, role_code, role_desc, status) VALUES
+(1, '超级管理员', 'super_admin', '系统超级管理员', '1'),
+(2, '管理员', 'admin', '普通管理员', '1'),
+(3, '普通用户', 'user', '普通用户', '1');
+INSERT INTO users (id, user_name, password, status) VALUES (1, 'admin', 'hash', '1');`
+
+func TestMigrator_AdoptBaseline(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "baseline.db"), sqlite.WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sgorm.CloseDB(db) //nolint
+	ctx := context.Background()
+	for _, statement := range splitStatements(baselineSQL) {
+		if err = db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.False(t, db.Migrator().HasColumn(&model.Menus{}, "permission"))
+
+	m, err := NewMigrator(db, sgorm.DBDriverSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, len(m.migrations))
+
+	// the tables of the baseline have the columns of the models
+	for _, table := range []interface{}{&model.Files{}, &model.Menus{}, &model.Permissions{}, &model.Roles{}, &model.Users{}} {
+		assert.NoError(t, db.Model(table).Limit(1).Find(table).Error)
+	}
+	menu := &model.Menus{}
+	assert.NoError(t, db.Where("permission = ?", "menu:manage").First(menu).Error)
+	assert.Equal(t, "test_apple", menu.Name)
+	user := &model.Users{}
+	assert.NoError(t, db.First(user, 1).Error)
+	assert.Equal(t, "admin", user.UserName)
+}
@@ -0,0 +1,179 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"godemo/internal/model"
+)
+
+// Migrator apply and roll back the migrations of a database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// Status the state of a migration in the database, AppliedAt is nil for a pending migration
+type Status struct {
+	*Migration
+	AppliedAt *time.Time
+}
+
+// NewMigrator create a migrator with the embedded migrations of the database driver
+func NewMigrator(db *gorm.DB, driver string) (*Migrator, error) {
+	migrations, err := Load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Status list all the migrations with their applied time, sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := &Status{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending list the migrations that have not been applied, sorted by version
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up apply all the pending migrations in ascending order, it stops at the first failure and
+// returns the migrations applied before it. Each migration runs in a transaction, note that
+// mysql commits DDL statements implicitly, so a failed mysql migration may be partially applied.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, migration := range pending {
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.UpSQL); err != nil {
+				return err
+			}
+			now := time.Now()
+			return tx.Create(&model.SchemaMigrations{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: &now,
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down roll back the last steps applied migrations in the reverse order they were applied, which is
+// not the descending version order if a migration of a lower version was applied after a higher one.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	records, err := m.lastApplied(ctx, steps)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var done []*Migration
+	for _, record := range records {
+		migration, ok := byVersion[record.Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d_%s is applied but not found, it cannot be rolled back", record.Version, record.Name)
+		}
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.DownSQL); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", migration.Version).Delete(&model.SchemaMigrations{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// get the last limit applied migrations, the latest first, the migrations applied in the same second
+// are ordered by version, which is the order Up applies them.
+func (m *Migrator) lastApplied(ctx context.Context, limit int) ([]*model.SchemaMigrations, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if _, err := m.applied(ctx); err != nil {
+		return nil, err
+	}
+
+	var records []*model.SchemaMigrations
+	err := m.db.WithContext(ctx).Order("applied_at DESC").Order("version DESC").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// get the applied migrations by version, the schema_migrations table is created if it does not exist
+func (m *Migrator) applied(ctx context.Context) (map[int64]*model.SchemaMigrations, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&model.SchemaMigrations{}); err != nil {
+		return nil, fmt.Errorf("create schema_migrations table: %v", err)
+	}
+
+	var records []*model.SchemaMigrations
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]*model.SchemaMigrations, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func exec(tx *gorm.DB, sql string) error {
+	for _, statement := range splitStatements(sql) {
+		if table, column, rest, ok := parseIfColumnMissing(statement); ok {
+			if tx.Migrator().HasColumn(table, column) {
+				continue
+			}
+			statement = rest
+		}
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,11 +1,7 @@
--- 创建数据库
-CREATE DATABASE IF NOT EXISTS soybean CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+-- the tables are created only if they do not exist, so that a database created by the former
+-- Database/mysql.sql can adopt the migrations.
 
-USE soybean;
-
-
-DROP TABLE IF EXISTS `files`;
-CREATE TABLE `files` (
+CREATE TABLE IF NOT EXISTS `files` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -18,8 +14,7 @@ CREATE TABLE `files` (
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `menus`;
-CREATE TABLE `menus` (
+CREATE TABLE IF NOT EXISTS `menus` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -29,11 +24,11 @@ CREATE TABLE `menus` (
   `icon` varchar(255) DEFAULT NULL,
   `parent_id` bigint unsigned DEFAULT NULL,
   `order` int DEFAULT NULL,
+  `permission` varchar(255) DEFAULT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `permissions`;
-CREATE TABLE `permissions` (
+CREATE TABLE IF NOT EXISTS `permissions` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -42,17 +37,15 @@ CREATE TABLE `permissions` (
   `code` varchar(255) NOT NULL,
   `description` text,
   PRIMARY KEY (`id`)
-) ENGINE=InnoDB AUTO_INCREMENT=6 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `role_permissions`;
-CREATE TABLE `role_permissions` (
+CREATE TABLE IF NOT EXISTS `role_permissions` (
   `role_id` bigint unsigned NOT NULL,
   `permission_id` bigint unsigned NOT NULL,
   PRIMARY KEY (`role_id`,`permission_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `roles`;
-CREATE TABLE `roles` (
+CREATE TABLE IF NOT EXISTS `roles` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -62,17 +55,15 @@ CREATE TABLE `roles` (
   `role_desc` text,
   `status` varchar(10) DEFAULT NULL,
   PRIMARY KEY (`id`)
-) ENGINE=InnoDB AUTO_INCREMENT=4 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `user_roles`;
-CREATE TABLE `user_roles` (
+CREATE TABLE IF NOT EXISTS `user_roles` (
   `user_id` bigint unsigned NOT NULL,
   `role_id` bigint unsigned NOT NULL,
   PRIMARY KEY (`user_id`,`role_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
-DROP TABLE IF EXISTS `users`;
-CREATE TABLE `users` (
+CREATE TABLE IF NOT EXISTS `users` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -86,32 +77,3 @@ CREATE TABLE `users` (
   `status` varchar(10) DEFAULT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
-
-INSERT INTO `permissions` (`id`, `created_at`, `updated_at`, `deleted_at`, `name`, `code`, `description`) VALUES
-(1, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '用户管理', 'user:manage', '管理用户'),
-(2, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '角色管理', 'role:manage', '管理角色'),
-(3, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '权限管理', 'permission:manage', '管理权限'),
-(4, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '菜单管理', 'menu:manage', '管理菜单'),
-(5, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '文件管理', 'file:manage', '管理文件');
-
-INSERT INTO `role_permissions` (`role_id`, `permission_id`) VALUES
-(1, 1),
-(1, 2),
-(1, 3),
-(1, 4),
-(1, 5);
-
-INSERT INTO `roles` (`id`, `created_at`, `updated_at`, `deleted_at`, `role_name`, `role_code`, `role_desc`, `status`) VALUES
-(1, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '超级管理员', 'super_admin', '系统超级管理员', '1'),
-(2, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '管理员', 'admin', '普通管理员', '1'),
-(3, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '普通用户', 'user', '普通用户', '1');
-
-
-
-/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
-/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;
-/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS */;
-/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
-/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
-/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
-/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
\ No newline at end of file
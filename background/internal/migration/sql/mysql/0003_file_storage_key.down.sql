@@ -0,0 +1,2 @@
+ALTER TABLE `files`
+  DROP COLUMN `storage_key`;
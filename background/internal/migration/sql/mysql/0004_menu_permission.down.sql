@@ -0,0 +1,2 @@
+-- the permission column is kept, 0001_init_schema creates it on a new database
+UPDATE `menus` SET `permission` = NULL WHERE `id` = 3 AND `path` = '/test/apple' AND `permission` = 'menu:manage';
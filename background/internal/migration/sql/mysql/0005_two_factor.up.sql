@@ -0,0 +1,8 @@
+-- totp_secret is the encrypted RFC 6238 secret, totp_recovery_codes the hashes of the unused recovery codes
+ALTER TABLE `users`
+  ADD COLUMN `totp_secret` varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN `totp_enabled` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `totp_recovery_codes` text;
+
+ALTER TABLE `roles`
+  ADD COLUMN `require_two_factor` tinyint(1) NOT NULL DEFAULT 0;
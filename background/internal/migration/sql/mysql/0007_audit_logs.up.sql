@@ -0,0 +1,22 @@
+-- actor_id is 0 for an anonymous request, diff is a json object of the changed fields.
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `actor_id` bigint unsigned NOT NULL DEFAULT 0,
+  `actor_name` varchar(255) NOT NULL DEFAULT '',
+  `request_id` varchar(64) NOT NULL DEFAULT '',
+  `entity` varchar(64) NOT NULL,
+  `entity_id` bigint unsigned NOT NULL,
+  `action` varchar(20) NOT NULL,
+  `diff` text,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_logs_entity` (`entity`, `entity_id`),
+  KEY `idx_audit_logs_actor_id` (`actor_id`),
+  KEY `idx_audit_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT IGNORE INTO `permissions` (`id`, `created_at`, `updated_at`, `deleted_at`, `name`, `code`, `description`) VALUES
+(7, '2026-10-16 12:00:00', '2026-10-16 12:00:00', NULL, '审计日志', 'audit:view', '查看审计日志');
+
+INSERT IGNORE INTO `role_permissions` (`role_id`, `permission_id`) VALUES
+(1, 7);
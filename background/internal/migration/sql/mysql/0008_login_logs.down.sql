@@ -0,0 +1,4 @@
+DELETE FROM `role_permissions` WHERE `permission_id` = 8;
+DELETE FROM `permissions` WHERE `id` = 8;
+
+DROP TABLE IF EXISTS `login_logs`;
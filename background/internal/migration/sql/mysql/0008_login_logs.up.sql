@@ -0,0 +1,21 @@
+-- user_id is 0 if the user name does not exist, reason tells why a login failed.
+CREATE TABLE IF NOT EXISTS `login_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `user_id` bigint unsigned NOT NULL DEFAULT 0,
+  `user_name` varchar(255) NOT NULL DEFAULT '',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `user_agent` varchar(512) NOT NULL DEFAULT '',
+  `result` varchar(20) NOT NULL,
+  `reason` varchar(64) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `idx_login_logs_user_id` (`user_id`),
+  KEY `idx_login_logs_ip` (`ip`),
+  KEY `idx_login_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT IGNORE INTO `permissions` (`id`, `created_at`, `updated_at`, `deleted_at`, `name`, `code`, `description`) VALUES
+(8, '2026-10-16 12:00:00', '2026-10-16 12:00:00', NULL, '在线会话', 'session:manage', '查看和踢出在线会话');
+
+INSERT IGNORE INTO `role_permissions` (`role_id`, `permission_id`) VALUES
+(1, 8);
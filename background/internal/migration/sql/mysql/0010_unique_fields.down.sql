@@ -0,0 +1,12 @@
+ALTER TABLE `permissions`
+  DROP INDEX `uk_permissions_code`;
+
+ALTER TABLE `roles`
+  DROP INDEX `uk_roles_role_code`;
+
+ALTER TABLE `users`
+  DROP INDEX `uk_users_user_email`,
+  DROP INDEX `uk_users_user_name`;
+
+ALTER TABLE `users`
+  DROP COLUMN `user_email_key`;
@@ -0,0 +1,16 @@
+-- the unique indexes include the soft deleted records, so that a deleted record can always be restored.
+-- an empty email is not unique, the index is on the generated column user_email_key which is NULL for
+-- an empty email, so that any number of users can have none. A generated column works on mysql 5.7 and tidb,
+-- an index on an expression needs mysql 8.0.13.
+ALTER TABLE `users`
+  ADD COLUMN `user_email_key` varchar(255) GENERATED ALWAYS AS (NULLIF(`user_email`, '')) VIRTUAL;
+
+ALTER TABLE `users`
+  ADD UNIQUE KEY `uk_users_user_name` (`user_name`),
+  ADD UNIQUE KEY `uk_users_user_email` (`user_email_key`);
+
+ALTER TABLE `roles`
+  ADD UNIQUE KEY `uk_roles_role_code` (`role_code`);
+
+ALTER TABLE `permissions`
+  ADD UNIQUE KEY `uk_permissions_code` (`code`);
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE role_id = 1 AND permission_id IN (1, 2, 3, 4, 5, 6);
+DELETE FROM roles WHERE id IN (1, 2, 3);
+DELETE FROM permissions WHERE id IN (1, 2, 3, 4, 5, 6);
+DELETE FROM menus WHERE id IN (1, 2, 3, 4);
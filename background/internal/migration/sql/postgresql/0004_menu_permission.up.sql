@@ -0,0 +1,7 @@
+-- the menus created by Database/mysql.sql have no permission column, 0001_init_schema skips that table,
+-- so the column is added here, and the permission of the seeded menu is set once the column exists.
+-- +if-column-missing menus permission
+ALTER TABLE menus
+  ADD COLUMN permission varchar(255);
+
+UPDATE menus SET permission = 'menu:manage' WHERE id = 3 AND path = '/test/apple' AND permission IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE roles
+  DROP COLUMN require_two_factor;
+
+ALTER TABLE users
+  DROP COLUMN totp_recovery_codes,
+  DROP COLUMN totp_enabled,
+  DROP COLUMN totp_secret;
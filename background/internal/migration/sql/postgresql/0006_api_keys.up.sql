@@ -0,0 +1,18 @@
+-- key_hash is the sha256 of the key, the key itself is only shown once when it is created.
+-- permissions are the comma separated permission codes the key is limited to.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id bigserial NOT NULL,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  user_id bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  key_prefix varchar(20) NOT NULL,
+  key_hash char(64) NOT NULL,
+  permissions text,
+  expires_at timestamp,
+  last_used_at timestamp,
+  PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
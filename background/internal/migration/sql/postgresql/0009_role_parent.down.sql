@@ -0,0 +1,2 @@
+ALTER TABLE roles
+  DROP COLUMN parent_id;
@@ -0,0 +1,3 @@
+-- parent_id is the role whose permissions are inherited, 0 for a role without parent
+ALTER TABLE roles
+  ADD COLUMN parent_id bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- totp_last_step is the time step of the last accepted totp code, a code of the same or an earlier step is rejected
+-- A database that applied 0005_two_factor while it also added the column has it already.
+-- +if-column-missing users totp_last_step
+ALTER TABLE users
+  ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,71 @@
+CREATE TABLE IF NOT EXISTS files (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  filename varchar(255) NOT NULL,
+  url varchar(255) NOT NULL,
+  size bigint,
+  mime_type varchar(100),
+  user_id bigint
+);
+
+CREATE TABLE IF NOT EXISTS menus (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  name varchar(255) NOT NULL,
+  path varchar(255) NOT NULL,
+  icon varchar(255),
+  parent_id bigint,
+  "order" integer,
+  permission varchar(255)
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  name varchar(255) NOT NULL,
+  code varchar(255) NOT NULL,
+  description text
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_id bigint NOT NULL,
+  permission_id bigint NOT NULL,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  role_name varchar(255) NOT NULL,
+  role_code varchar(255) NOT NULL,
+  role_desc text,
+  status varchar(10)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id bigint NOT NULL,
+  role_id bigint NOT NULL,
+  PRIMARY KEY (user_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  deleted_at timestamp,
+  user_name varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  user_gender varchar(10),
+  nick_name varchar(255),
+  user_phone varchar(20),
+  user_email varchar(255),
+  status varchar(10)
+);
@@ -0,0 +1,33 @@
+-- the rows are skipped if they already exist, so that a database seeded by the former
+-- Database/mysql.sql can adopt the migrations.
+
+INSERT INTO menus (id, created_at, updated_at, deleted_at, name, path, icon, parent_id, "order") VALUES
+(1, '2026-02-11 11:01:45', '2026-02-11 11:01:45', NULL, 'home', '/home', 'mdi:monitor-dashboard', 0, 1),
+(2, '2026-02-11 11:01:45', '2026-02-11 11:01:45', NULL, 'test', '/test', NULL, 0, 10),
+(3, '2026-02-11 11:01:45', '2026-02-11 11:01:45', NULL, 'test_apple', '/test/apple', NULL, 2, 2),
+(4, '2026-02-11 11:01:45', '2026-02-11 11:01:45', NULL, 'test_banana', '/test/banana', NULL, 2, 1)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions (id, created_at, updated_at, deleted_at, name, code, description) VALUES
+(1, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '用户管理', 'user:manage', '管理用户'),
+(2, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '角色管理', 'role:manage', '管理角色'),
+(3, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '权限管理', 'permission:manage', '管理权限'),
+(4, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '菜单管理', 'menu:manage', '管理菜单'),
+(5, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '文件管理', 'file:manage', '管理文件'),
+(6, '2026-02-11 11:01:27', '2026-02-11 11:01:27', NULL, '数据清除', 'data:purge', '永久删除已删除的数据')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id) VALUES
+(1, 1),
+(1, 2),
+(1, 3),
+(1, 4),
+(1, 5),
+(1, 6)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles (id, created_at, updated_at, deleted_at, role_name, role_code, role_desc, status) VALUES
+(1, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '超级管理员', 'super_admin', '系统超级管理员', '1'),
+(2, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '管理员', 'admin', '普通管理员', '1'),
+(3, '2026-02-11 11:01:09', '2026-02-11 11:01:09', NULL, '普通用户', 'user', '普通用户', '1')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE files DROP COLUMN storage_key;
@@ -0,0 +1,9 @@
+-- storage_key is the key of the uploaded content in the storage backend, only an upload sets it, so that
+-- the url written by the metadata api cannot make a download or a purge use the content of another file.
+-- A database created by the former Database/mysql.sql may have the column already.
+-- +if-column-missing files storage_key
+ALTER TABLE files ADD COLUMN storage_key varchar(255) NOT NULL DEFAULT '';
+-- the files uploaded before use their url as the key, a url shared by several records is not trusted
+UPDATE files SET storage_key = url
+WHERE storage_key = '' AND url <> '' AND url NOT LIKE 'http://%' AND url NOT LIKE 'https://%'
+  AND url IN (SELECT url FROM files GROUP BY url HAVING COUNT(*) = 1);
@@ -0,0 +1,5 @@
+ALTER TABLE roles DROP COLUMN require_two_factor;
+
+ALTER TABLE users DROP COLUMN totp_recovery_codes;
+ALTER TABLE users DROP COLUMN totp_enabled;
+ALTER TABLE users DROP COLUMN totp_secret;
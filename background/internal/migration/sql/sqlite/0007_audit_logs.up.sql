@@ -0,0 +1,24 @@
+-- actor_id is 0 for an anonymous request, diff is a json object of the changed fields.
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  actor_id bigint NOT NULL DEFAULT 0,
+  actor_name varchar(255) NOT NULL DEFAULT '',
+  request_id varchar(64) NOT NULL DEFAULT '',
+  entity varchar(64) NOT NULL,
+  entity_id bigint NOT NULL,
+  action varchar(20) NOT NULL,
+  diff text
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+INSERT INTO permissions (id, created_at, updated_at, deleted_at, name, code, description) VALUES
+(7, '2026-10-16 12:00:00', '2026-10-16 12:00:00', NULL, '审计日志', 'audit:view', '查看审计日志')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id) VALUES
+(1, 7)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+-- user_id is 0 if the user name does not exist, reason tells why a login failed.
+CREATE TABLE IF NOT EXISTS login_logs (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+  user_id bigint NOT NULL DEFAULT 0,
+  user_name varchar(255) NOT NULL DEFAULT '',
+  ip varchar(64) NOT NULL DEFAULT '',
+  user_agent varchar(512) NOT NULL DEFAULT '',
+  result varchar(20) NOT NULL,
+  reason varchar(64) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_logs_user_id ON login_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_login_logs_ip ON login_logs (ip);
+CREATE INDEX IF NOT EXISTS idx_login_logs_created_at ON login_logs (created_at);
+
+INSERT INTO permissions (id, created_at, updated_at, deleted_at, name, code, description) VALUES
+(8, '2026-10-16 12:00:00', '2026-10-16 12:00:00', NULL, '在线会话', 'session:manage', '查看和踢出在线会话')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id) VALUES
+(1, 8)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uk_permissions_code;
+DROP INDEX IF EXISTS uk_roles_role_code;
+DROP INDEX IF EXISTS uk_users_user_email;
+DROP INDEX IF EXISTS uk_users_user_name;
@@ -0,0 +1,6 @@
+-- the unique indexes include the soft deleted records, so that a deleted record can always be restored.
+-- an empty email is not unique, any number of users can have none.
+CREATE UNIQUE INDEX IF NOT EXISTS uk_users_user_name ON users (user_name);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_users_user_email ON users (user_email) WHERE user_email <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS uk_roles_role_code ON roles (role_code);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_permissions_code ON permissions (code);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE roles DROP COLUMN version;
+ALTER TABLE permissions DROP COLUMN version;
+ALTER TABLE menus DROP COLUMN version;
+ALTER TABLE files DROP COLUMN version;
@@ -0,0 +1,6 @@
+-- version is increased by every change of a record, it is the entity tag of the conditional requests
+ALTER TABLE users ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE roles ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE permissions ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE menus ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE files ADD COLUMN version bigint NOT NULL DEFAULT 1;
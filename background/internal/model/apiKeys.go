@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// APIKeys a personal access token of a user, used by scripts and service accounts instead of a login.
+// Only the hash of the key is stored, permissions are the comma separated permission codes the key is
+// limited to, a key never has more permissions than its user currently has.
+type APIKeys struct {
+	ID          uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt   *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt   *time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+	UserID      uint64     `gorm:"column:user_id;not null" json:"userID"`
+	Name        string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	KeyPrefix   string     `gorm:"column:key_prefix;type:varchar(20);not null" json:"keyPrefix"`
+	KeyHash     string     `gorm:"column:key_hash;type:char(64);not null" json:"keyHash"`
+	Permissions string     `gorm:"column:permissions;type:text" json:"permissions"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at;type:timestamp" json:"expiresAt"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at;type:timestamp" json:"lastUsedAt"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLogs a create, update or delete of a record made through the dao layer. The actor is the
+// logged-in user, 0 for an anonymous request, diff is a json object of the changed fields with
+// their old and new values, the values of the secret fields are redacted.
+type AuditLogs struct {
+	ID        uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ActorID   uint64     `gorm:"column:actor_id;not null;default:0" json:"actorID"`
+	ActorName string     `gorm:"column:actor_name;type:varchar(255);not null;default:''" json:"actorName"`
+	RequestID string     `gorm:"column:request_id;type:varchar(64);not null;default:''" json:"requestID"`
+	Entity    string     `gorm:"column:entity;type:varchar(64);not null" json:"entity"`
+	EntityID  uint64     `gorm:"column:entity_id;not null" json:"entityID"`
+	Action    string     `gorm:"column:action;type:varchar(20);not null" json:"action"`
+	Diff      string     `gorm:"column:diff;type:text" json:"diff"`
+}
+
+// AuditLogsColumnNames Whitelist for custom query fields to prevent sql injection attacks
+var AuditLogsColumnNames = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"actor_id":   true,
+	"actor_name": true,
+	"request_id": true,
+	"entity":     true,
+	"entity_id":  true,
+	"action":     true,
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginLogs a login attempt, user_id is 0 if the user name does not exist. result is success or failure,
+// reason tells why a login failed, such as wrongPassword or accountLocked.
+type LoginLogs struct {
+	ID        uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UserID    uint64     `gorm:"column:user_id;not null;default:0" json:"userID"`
+	UserName  string     `gorm:"column:user_name;type:varchar(255);not null;default:''" json:"userName"`
+	IP        string     `gorm:"column:ip;type:varchar(64);not null;default:''" json:"ip"`
+	UserAgent string     `gorm:"column:user_agent;type:varchar(512);not null;default:''" json:"userAgent"`
+	Result    string     `gorm:"column:result;type:varchar(20);not null" json:"result"`
+	Reason    string     `gorm:"column:reason;type:varchar(64);not null;default:''" json:"reason"`
+}
+
+// LoginLogsColumnNames Whitelist for custom query fields to prevent sql injection attacks
+var LoginLogsColumnNames = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"user_id":    true,
+	"user_name":  true,
+	"ip":         true,
+	"user_agent": true,
+	"result":     true,
+	"reason":     true,
+}
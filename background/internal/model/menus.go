@@ -2,18 +2,22 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Menus struct {
-	ID        uint64     `gorm:"column:id;type:bigint(20) unsigned;primary_key;AUTO_INCREMENT" json:"id"`
-	CreatedAt *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
-	UpdatedAt *time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
-	DeletedAt *time.Time `gorm:"column:deleted_at;type:timestamp" json:"deletedAt"`
-	Name      string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
-	Path      string     `gorm:"column:path;type:varchar(255);not null" json:"path"`
-	Icon      string     `gorm:"column:icon;type:varchar(255)" json:"icon"`
-	ParentID  uint64     `gorm:"column:parent_id;type:bigint(20) unsigned" json:"parentID"`
-	Order     int        `gorm:"column:order;type:int(11)" json:"order"`
+	ID         uint64         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt  *time.Time     `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt  *time.Time     `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at;type:timestamp" json:"deletedAt"`
+	Version    uint64         `gorm:"column:version;not null;default:1" json:"version"` // increased by every change, the entity tag of the record
+	Name       string         `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	Path       string         `gorm:"column:path;type:varchar(255);not null" json:"path"`
+	Icon       string         `gorm:"column:icon;type:varchar(255)" json:"icon"`
+	ParentID   uint64         `gorm:"column:parent_id" json:"parentID"`
+	Order      int            `gorm:"column:order" json:"order"`
+	Permission string         `gorm:"column:permission;type:varchar(255)" json:"permission"`
 }
 
 // MenusColumnNames Whitelist for custom query fields to prevent sql injection attacks
@@ -22,9 +26,11 @@ var MenusColumnNames = map[string]bool{
 	"created_at": true,
 	"updated_at": true,
 	"deleted_at": true,
+	"version":    true,
 	"name":       true,
 	"path":       true,
 	"icon":       true,
 	"parent_id":  true,
 	"order":      true,
+	"permission": true,
 }
@@ -2,16 +2,19 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Permissions struct {
-	ID          uint64     `gorm:"column:id;type:bigint(20) unsigned;primary_key;AUTO_INCREMENT" json:"id"`
-	CreatedAt   *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
-	UpdatedAt   *time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
-	DeletedAt   *time.Time `gorm:"column:deleted_at;type:timestamp" json:"deletedAt"`
-	Name        string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
-	Code        string     `gorm:"column:code;type:varchar(255);not null" json:"code"`
-	Description string     `gorm:"column:description;type:text" json:"description"`
+	ID          uint64         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt   *time.Time     `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt   *time.Time     `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;type:timestamp" json:"deletedAt"`
+	Version     uint64         `gorm:"column:version;not null;default:1" json:"version"` // increased by every change, the entity tag of the record
+	Name        string         `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	Code        string         `gorm:"column:code;type:varchar(255);not null" json:"code"`
+	Description string         `gorm:"column:description;type:text" json:"description"`
 }
 
 // PermissionsColumnNames Whitelist for custom query fields to prevent sql injection attacks
@@ -20,6 +23,7 @@ var PermissionsColumnNames = map[string]bool{
 	"created_at":  true,
 	"updated_at":  true,
 	"deleted_at":  true,
+	"version":     true,
 	"name":        true,
 	"code":        true,
 	"description": true,
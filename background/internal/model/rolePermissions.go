@@ -1,8 +1,9 @@
 package model
 
+// RolePermissions links a role to a permission, the primary key is (role_id, permission_id), so a role can hold many permissions
 type RolePermissions struct {
-	RoleID       uint64 `gorm:"column:role_id;type:bigint(20) unsigned;primary_key" json:"roleID"`
-	PermissionID uint64 `gorm:"column:permission_id;type:bigint(20) unsigned;not null" json:"permissionID"`
+	RoleID       uint64 `gorm:"column:role_id;primaryKey;autoIncrement:false" json:"roleID"`
+	PermissionID uint64 `gorm:"column:permission_id;primaryKey;autoIncrement:false" json:"permissionID"`
 }
 
 // RolePermissionsColumnNames Whitelist for custom query fields to prevent sql injection attacks
@@ -0,0 +1,12 @@
+package model
+
+import (
+	"time"
+)
+
+// SchemaMigrations records a migration of internal/migration that has been applied to the database
+type SchemaMigrations struct {
+	Version   int64      `gorm:"column:version;primaryKey;autoIncrement:false" json:"version"`
+	Name      string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	AppliedAt *time.Time `gorm:"column:applied_at;type:timestamp" json:"appliedAt"`
+}
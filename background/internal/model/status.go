@@ -0,0 +1,8 @@
+package model
+
+// the values of the status column of users and roles, the same as Api.Common.EnableStatus of the web client.
+// An empty status is treated as enabled, only a disabled user or role is refused.
+const (
+	StatusEnabled  = "1"
+	StatusDisabled = "2"
+)
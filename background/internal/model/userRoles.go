@@ -1,8 +1,9 @@
 package model
 
+// UserRoles links a user to a role, the primary key is (user_id, role_id), so a user can hold many roles
 type UserRoles struct {
-	UserID uint64 `gorm:"column:user_id;type:bigint(20) unsigned;primary_key" json:"userID"`
-	RoleID uint64 `gorm:"column:role_id;type:bigint(20) unsigned;not null" json:"roleID"`
+	UserID uint64 `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"userID"`
+	RoleID uint64 `gorm:"column:role_id;primaryKey;autoIncrement:false" json:"roleID"`
 }
 
 // UserRolesColumnNames Whitelist for custom query fields to prevent sql injection attacks
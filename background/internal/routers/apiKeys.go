@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		apiKeysRouter(group, handler.NewAPIKeysHandler())
+	})
+}
+
+func apiKeysRouter(group *gin.RouterGroup, h handler.APIKeysHandler) {
+	g := group.Group("/apiKeys")
+
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. The logged-in user manages its own api keys, no permission is required, but an
+	// api key cannot be used to manage the api keys.
+	g.Use(denyAPIKey())
+
+	g.POST("/", h.Create)          // [post] /api/v1/apiKeys
+	g.DELETE("/:id", h.DeleteByID) // [delete] /api/v1/apiKeys/:id
+	g.GET("/", h.List)             // [get] /api/v1/apiKeys
+}
@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		auditLogsRouter(group, handler.NewAuditLogsHandler())
+	})
+}
+
+func auditLogsRouter(group *gin.RouterGroup, h handler.AuditLogsHandler) {
+	g := group.Group("/auditLogs")
+
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. The caller must also have the audit:view permission.
+	g.Use(requirePermission("audit:view"))
+
+	g.POST("/list", h.List) // [post] /api/v1/auditLogs/list
+}
@@ -0,0 +1,46 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/auth"
+	"godemo/internal/handler"
+)
+
+type challengeRoute struct {
+	purpose string // purpose of the accepted challenge tokens
+	access  bool   // access tokens are accepted too
+}
+
+// challengeRoutes the routes that accept the challenge token returned by the login api when two-factor
+// authentication is pending, the key is the full route path registered in gin.
+var challengeRoutes = map[string]challengeRoute{
+	"/api/v1/auth/2fa/verify":  {purpose: auth.ChallengeVerify},
+	"/api/v1/auth/2fa/enroll":  {purpose: auth.ChallengeEnroll, access: true},
+	"/api/v1/auth/2fa/confirm": {purpose: auth.ChallengeEnroll, access: true},
+}
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		authRouter(group, handler.NewAuthHandler())
+	})
+}
+
+func authRouter(group *gin.RouterGroup, h handler.AuthHandler) {
+	g := group.Group("/auth")
+
+	// login, refreshToken, forgotPassword and resetPassword are in jwt.anonymousRoutes of the configuration file,
+	// getUserInfo and logout require an access token, the 2fa routes are in challengeRoutes.
+
+	g.POST("/login", h.Login)               // [post] /api/v1/auth/login
+	g.POST("/refreshToken", h.RefreshToken) // [post] /api/v1/auth/refreshToken
+	g.GET("/getUserInfo", h.GetUserInfo)    // [get] /api/v1/auth/getUserInfo
+	g.POST("/logout", h.Logout)             // [post] /api/v1/auth/logout
+
+	g.POST("/forgotPassword", h.ForgotPassword) // [post] /api/v1/auth/forgotPassword
+	g.POST("/resetPassword", h.ResetPassword)   // [post] /api/v1/auth/resetPassword
+
+	g.POST("/2fa/verify", h.VerifyTwoFactor)   // [post] /api/v1/auth/2fa/verify
+	g.POST("/2fa/enroll", h.EnrollTwoFactor)   // [post] /api/v1/auth/2fa/enroll
+	g.POST("/2fa/confirm", h.ConfirmTwoFactor) // [post] /api/v1/auth/2fa/confirm
+}
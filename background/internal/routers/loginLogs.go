@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		loginLogsRouter(group, handler.NewLoginLogsHandler())
+	})
+}
+
+func loginLogsRouter(group *gin.RouterGroup, h handler.LoginLogsHandler) {
+	g := group.Group("/loginLogs")
+
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. The login history is part of the audit trail, the caller must have the audit:view permission.
+	g.Use(requirePermission("audit:view"))
+
+	g.POST("/list", h.List) // [post] /api/v1/loginLogs/list
+}
@@ -15,17 +15,19 @@ func init() {
 func menusRouter(group *gin.RouterGroup, h handler.MenusHandler) {
 	g := group.Group("/menus")
 
-	// JWT authentication reference: https://go-sponge.com/component/transport/gin.html#jwt-authorization-middleware
-
-	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
-
-	// If jwt authentication is not required for all routes, authentication middleware can be added
-	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. To allow anonymous access to a route, add it to jwt.anonymousRoutes of the configuration file.
+	// The caller must also have the menu:manage permission.
+	g.Use(requirePermission("menu:manage"))
 
 	g.POST("/", h.Create)          // [post] /api/v1/menus
 	g.DELETE("/:id", h.DeleteByID) // [delete] /api/v1/menus/:id
 	g.PUT("/:id", h.UpdateByID)    // [put] /api/v1/menus/:id
+	g.PATCH("/:id", h.PatchByID)   // [patch] /api/v1/menus/:id
 	g.GET("/:id", h.GetByID)       // [get] /api/v1/menus/:id
 	g.POST("/list", h.List)        // [post] /api/v1/menus/list
+
+	g.POST("/:id/restore", h.RestoreByID) // [post] /api/v1/menus/:id/restore
+	// permanently deleting data also requires the data:purge permission
+	g.DELETE("/:id/purge", requirePermission("data:purge"), h.PurgeByID) // [delete] /api/v1/menus/:id/purge
 }
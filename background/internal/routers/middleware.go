@@ -0,0 +1,304 @@
+package routers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"godemo/internal/audit"
+	"godemo/internal/auth"
+	"godemo/internal/cache"
+	"godemo/internal/dao"
+	"godemo/internal/database"
+	"godemo/internal/ecode"
+	"godemo/internal/model"
+)
+
+// the last used time of an api key is written at most once in this interval
+const apiKeyLastUsedInterval = time.Minute
+
+// same key as middleware.Auth, so that middleware.GetClaims can be used in handlers
+const claimsKey = "claims"
+
+// jwtAuth verify the access token in the Authorization header, routes whose full path is in
+// anonymousRoutes are skipped, and the routes in challengeRoutes also accept a challenge token.
+// Expired tokens respond ecode.ErrTokenExpired so that the web client can refresh the token,
+// tokens whose family has been revoked (logout, forced logout, password or status change)
+// respond ecode.ErrTokenRevoked, all other failures respond ecode.ErrTokenInvalid. The user of an
+// access token is also checked, so a disabled user is refused even if its tokens were not revoked.
+//
+// An api key is accepted instead of an access token, in the X-API-Key header or as the bearer token,
+// the claims built from it are limited to the permissions of the key by requirePermission.
+func jwtAuth(anonymousRoutes []string) gin.HandlerFunc {
+	tokenCache := cache.NewTokenCache(database.GetCacheType())
+	apiKeysDao := dao.NewAPIKeysDao(database.GetDB())
+	usersDao := dao.NewUsersDao(
+		database.GetDB(),
+		cache.NewUsersCache(database.GetCacheType()),
+		tokenCache,
+	)
+	skipRoutes := make(map[string]struct{}, len(anonymousRoutes))
+	for _, route := range anonymousRoutes {
+		skipRoutes[route] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := skipRoutes[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+
+		if key := getRequestAPIKey(c); key != "" {
+			claims, ok := authAPIKey(c, apiKeysDao, usersDao, key)
+			if !ok {
+				c.Abort()
+				return
+			}
+			setClaims(c, claims)
+			c.Next()
+			return
+		}
+
+		claims, isChallenge, err := parseRequestToken(c)
+		if err != nil {
+			logger.Warn("jwtAuth error", logger.Err(err), logger.String("path", c.FullPath()), middleware.GCtxRequestIDField(c))
+			route, isChallengeRoute := challengeRoutes[c.FullPath()]
+			switch {
+			case isChallengeRoute && !route.access:
+				response.Error(c, ecode.ErrTwoFactorChallenge)
+			case errors.Is(err, jwt.ErrTokenExpired):
+				response.Error(c, ecode.ErrTokenExpired)
+			default:
+				response.Error(c, ecode.ErrTokenInvalid)
+			}
+			c.Abort()
+			return
+		}
+		if isChallenge {
+			// a challenge token is not part of a token family, it expires in a few minutes
+			setClaims(c, claims)
+			c.Next()
+			return
+		}
+
+		familyID, err := auth.GetFamilyID(claims)
+		if err != nil {
+			logger.Warn("GetFamilyID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrTokenInvalid)
+			c.Abort()
+			return
+		}
+		ok, err := tokenCache.ExistsFamily(middleware.WrapCtx(c), familyID)
+		if err != nil {
+			logger.Error("ExistsFamily error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			c.Abort()
+			return
+		}
+		if !ok {
+			logger.Warn("token family is revoked", logger.String("uid", claims.UID), logger.String("familyID", familyID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrTokenRevoked)
+			c.Abort()
+			return
+		}
+		userID, err := auth.GetUserID(claims)
+		if err != nil {
+			logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrTokenInvalid)
+			c.Abort()
+			return
+		}
+		if _, ok = getActiveUser(c, usersDao, userID, ecode.ErrTokenRevoked); !ok {
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// get the user of a token or an api key, it responds and returns false on failure or if the user is disabled,
+// notFound is responded if the user does not exist any more
+func getActiveUser(c *gin.Context, usersDao dao.UsersDao, userID uint64, notFound *errcode.Error) (*model.Users, bool) {
+	user, err := usersDao.GetByID(middleware.WrapCtx(c), userID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("user not found", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Error(c, notFound)
+		} else {
+			logger.Error("GetByID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return nil, false
+	}
+	if user.Status == model.StatusDisabled {
+		logger.Warn("user is disabled", logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrUserDisabled)
+		return nil, false
+	}
+	return user, true
+}
+
+// setClaims save the claims for the handlers, and the user as the actor of the changes written to the audit log
+func setClaims(c *gin.Context, claims *jwt.Claims) {
+	c.Set(claimsKey, claims)
+	if userID, err := auth.GetUserID(claims); err == nil {
+		actor := audit.Actor{UserID: userID, UserName: auth.GetUserName(claims)}
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+	}
+}
+
+var errMissingToken = errors.New("authorization header is missing or not a bearer token")
+
+// parse the bearer token as an access token, or as a challenge token if the route accepts it
+func parseRequestToken(c *gin.Context) (claims *jwt.Claims, isChallenge bool, err error) {
+	authorization := c.GetHeader(middleware.HeaderAuthorizationKey)
+	const prefix = "Bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return nil, false, errMissingToken
+	}
+	token := authorization[len(prefix):]
+
+	route, ok := challengeRoutes[c.FullPath()]
+	if !ok {
+		claims, err = auth.ParseToken(token, auth.TokenTypeAccess)
+		return claims, false, err
+	}
+	if route.access {
+		claims, err = auth.ParseToken(token, auth.TokenTypeAccess)
+		if !errors.Is(err, auth.ErrTokenType) {
+			return claims, false, err
+		}
+	}
+	claims, err = auth.ParseChallengeToken(token, route.purpose)
+	return claims, err == nil, err
+}
+
+// get the api key of the request, it is empty if the request carries an access token or nothing
+func getRequestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(auth.APIKeyHeader); key != "" {
+		return key
+	}
+	authorization := c.GetHeader(middleware.HeaderAuthorizationKey)
+	const prefix = "Bearer "
+	if len(authorization) > len(prefix) && strings.EqualFold(authorization[:len(prefix)], prefix) &&
+		auth.IsAPIKey(authorization[len(prefix):]) {
+		return authorization[len(prefix):]
+	}
+	return ""
+}
+
+// check the api key and build the claims of its user, it responds and returns false on failure
+func authAPIKey(c *gin.Context, apiKeysDao dao.APIKeysDao, usersDao dao.UsersDao, key string) (*jwt.Claims, bool) {
+	if _, ok := challengeRoutes[c.FullPath()]; ok {
+		response.Error(c, ecode.ErrAPIKeyNotAllowed)
+		return nil, false
+	}
+
+	ctx := middleware.WrapCtx(c)
+	apiKey, err := apiKeysDao.GetByKeyHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("api key not found", logger.String("path", c.FullPath()), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrAPIKeyInvalid)
+		} else {
+			logger.Error("GetByKeyHash error", logger.Err(err), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return nil, false
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		logger.Warn("api key has expired", logger.Any("apiKeyID", apiKey.ID), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.ErrAPIKeyInvalid)
+		return nil, false
+	}
+
+	user, ok := getActiveUser(c, usersDao, apiKey.UserID, ecode.ErrAPIKeyInvalid)
+	if !ok {
+		return nil, false
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedInterval {
+		if err = apiKeysDao.UpdateLastUsedAt(ctx, apiKey.ID, now); err != nil {
+			logger.Warn("UpdateLastUsedAt error", logger.Err(err), logger.Any("apiKeyID", apiKey.ID), middleware.GCtxRequestIDField(c))
+		}
+	}
+
+	return auth.NewAPIKeyClaims(user.ID, user.UserName, apiKey.ID, auth.SplitPermissions(apiKey.Permissions)), true
+}
+
+// denyAPIKey refuse the requests authenticated by an api key, it must run after jwtAuth. It protects the
+// apis that manage the account itself, so that a leaked api key cannot be turned into more access.
+func denyAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := middleware.GetClaims(c); ok && auth.IsAPIKeyClaims(claims) {
+			logger.Warn("api key is not allowed", logger.String("uid", claims.UID), logger.String("path", c.FullPath()), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.ErrAPIKeyNotAllowed)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePermission check that the logged-in user has the permission code, it must run after jwtAuth.
+// The permission codes of a user are the union of the codes of all its roles, they are cached per user
+// and the cache is deleted when user_roles or role_permissions change. A request authenticated by an
+// api key also needs the permission code in the permissions of the key.
+func requirePermission(code string) gin.HandlerFunc {
+	userPermissionsDao := dao.NewUserPermissionsDao(
+		database.GetDB(),
+		cache.NewUserPermissionsCache(database.GetCacheType()),
+	)
+
+	return func(c *gin.Context) {
+		claims, ok := middleware.GetClaims(c)
+		if !ok {
+			response.Error(c, ecode.Unauthorized)
+			c.Abort()
+			return
+		}
+		userID, err := auth.GetUserID(claims)
+		if err != nil {
+			logger.Warn("GetUserID error", logger.Err(err), logger.String("uid", claims.UID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.Unauthorized)
+			c.Abort()
+			return
+		}
+
+		codes, err := userPermissionsDao.GetCodesByUserID(middleware.WrapCtx(c), userID)
+		if err != nil {
+			logger.Error("GetCodesByUserID error", logger.Err(err), logger.Any("userID", userID), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+			c.Abort()
+			return
+		}
+		if hasPermission(codes, code) && (!auth.IsAPIKeyClaims(claims) || hasPermission(auth.GetAPIKeyPermissions(claims), code)) {
+			c.Next()
+			return
+		}
+
+		logger.Warn("permission denied", logger.Any("userID", userID), logger.String("permission", code), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Forbidden)
+		c.Abort()
+	}
+}
+
+func hasPermission(codes []string, code string) bool {
+	for _, v := range codes {
+		if v == code {
+			return true
+		}
+	}
+	return false
+}
@@ -13,19 +13,15 @@ func init() {
 }
 
 func rolePermissionsRouter(group *gin.RouterGroup, h handler.RolePermissionsHandler) {
-	g := group.Group("/rolePermissions")
+	g := group.Group("/roles")
 
-	// JWT authentication reference: https://go-sponge.com/component/transport/gin.html#jwt-authorization-middleware
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. To allow anonymous access to a route, add it to jwt.anonymousRoutes of the configuration file.
+	// The caller must also have the role:manage permission.
+	g.Use(requirePermission("role:manage"))
 
-	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
+	g.GET("/:id/permissions", h.GetByRoleID)     // [get] /api/v1/roles/:id/permissions
+	g.PUT("/:id/permissions", h.ReplaceByRoleID) // [put] /api/v1/roles/:id/permissions
 
-	// If jwt authentication is not required for all routes, authentication middleware can be added
-	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
-
-	g.POST("/", h.Create)                  // [post] /api/v1/rolePermissions
-	g.DELETE("/:roleID", h.DeleteByRoleID) // [delete] /api/v1/rolePermissions/:roleID
-	g.PUT("/:roleID", h.UpdateByRoleID)    // [put] /api/v1/rolePermissions/:roleID
-	g.GET("/:roleID", h.GetByRoleID)       // [get] /api/v1/rolePermissions/:roleID
-	g.POST("/list", h.List)                // [post] /api/v1/rolePermissions/list
+	g.GET("/:id/permissions/effective", h.GetEffectiveByRoleID) // [get] /api/v1/roles/:id/permissions/effective
 }
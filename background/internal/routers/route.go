@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		routeRouter(group, handler.NewRouteHandler())
+	})
+}
+
+func routeRouter(group *gin.RouterGroup, h handler.RouteHandler) {
+	g := group.Group("/route")
+
+	// getConstantRoutes is requested before login, it is in jwt.anonymousRoutes of the configuration file,
+	// the other routes require an access token.
+
+	g.GET("/getConstantRoutes", h.GetConstantRoutes) // [get] /api/v1/route/getConstantRoutes
+	g.GET("/getUserRoutes", h.GetUserRoutes)         // [get] /api/v1/route/getUserRoutes
+	g.GET("/isRouteExist", h.IsRouteExist)           // [get] /api/v1/route/isRouteExist
+}
@@ -99,15 +99,15 @@ func NewRouter() *gin.Engine {
 	r.GET("/codes", handlerfunc.ListCodes)
 
 	if config.Get().App.Env != "prod" {
-		r.GET("/config", gin.WrapF(errcode.ShowConfig([]byte(config.Show()))))
 		// register swagger routes, generate code via swag init
 		docs.SwaggerInfo.BasePath = ""
 		// access path /swagger/index.html
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
-	// register routers, middleware support
-	registerRouters(r, "/api/v1", apiV1RouterFns)
+	// register routers, middleware support, all /api/v1 routes require an access token
+	// except the anonymous routes configured in jwt.anonymousRoutes
+	registerRouters(r, "/api/v1", apiV1RouterFns, jwtAuth(config.Get().JWT.AnonymousRoutes))
 	// if you have other group routes you can add them here
 	// example:
 	//    registerRouters(r, "/api/v2", apiV2RouteFns, middleware.Auth())
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"godemo/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		sessionsRouter(group, handler.NewSessionsHandler())
+	})
+}
+
+func sessionsRouter(group *gin.RouterGroup, h handler.SessionsHandler) {
+	g := group.Group("/sessions")
+
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. The caller must also have the session:manage permission.
+	g.Use(requirePermission("session:manage"))
+
+	g.GET("/", h.List)                           // [get] /api/v1/sessions
+	g.DELETE("/:id", h.DeleteByID)               // [delete] /api/v1/sessions/:id
+	g.DELETE("/users/:userID", h.DeleteByUserID) // [delete] /api/v1/sessions/users/:userID
+}
@@ -13,19 +13,13 @@ func init() {
 }
 
 func userRolesRouter(group *gin.RouterGroup, h handler.UserRolesHandler) {
-	g := group.Group("/userRoles")
+	g := group.Group("/users")
 
-	// JWT authentication reference: https://go-sponge.com/component/transport/gin.html#jwt-authorization-middleware
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. To allow anonymous access to a route, add it to jwt.anonymousRoutes of the configuration file.
+	// The caller must also have the user:manage permission.
+	g.Use(requirePermission("user:manage"))
 
-	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
-
-	// If jwt authentication is not required for all routes, authentication middleware can be added
-	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
-
-	g.POST("/", h.Create)                  // [post] /api/v1/userRoles
-	g.DELETE("/:userID", h.DeleteByUserID) // [delete] /api/v1/userRoles/:userID
-	g.PUT("/:userID", h.UpdateByUserID)    // [put] /api/v1/userRoles/:userID
-	g.GET("/:userID", h.GetByUserID)       // [get] /api/v1/userRoles/:userID
-	g.POST("/list", h.List)                // [post] /api/v1/userRoles/list
+	g.GET("/:id/roles", h.GetByUserID)     // [get] /api/v1/users/:id/roles
+	g.PUT("/:id/roles", h.ReplaceByUserID) // [put] /api/v1/users/:id/roles
 }
@@ -13,19 +13,33 @@ func init() {
 }
 
 func usersRouter(group *gin.RouterGroup, h handler.UsersHandler) {
-	g := group.Group("/users")
-
-	// JWT authentication reference: https://go-sponge.com/component/transport/gin.html#jwt-authorization-middleware
+	// the logged-in user manages its own profile, no permission is required, but an api key cannot be used
+	me := group.Group("/users/me", denyAPIKey())
+	me.GET("", h.GetMe)                   // [get] /api/v1/users/me
+	me.PUT("", h.UpdateMe)                // [put] /api/v1/users/me
+	me.PUT("/password", h.ChangePassword) // [put] /api/v1/users/me/password
 
-	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
+	g := group.Group("/users")
 
-	// If jwt authentication is not required for all routes, authentication middleware can be added
-	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
+	// All the following routes use jwt authentication, which is applied to the whole /api/v1 group in
+	// registerRouters. To allow anonymous access to a route, add it to jwt.anonymousRoutes of the configuration file.
+	// The caller must also have the user:manage permission.
+	g.Use(requirePermission("user:manage"))
 
 	g.POST("/", h.Create)          // [post] /api/v1/users
 	g.DELETE("/:id", h.DeleteByID) // [delete] /api/v1/users/:id
 	g.PUT("/:id", h.UpdateByID)    // [put] /api/v1/users/:id
+	g.PATCH("/:id", h.PatchByID)   // [patch] /api/v1/users/:id
 	g.GET("/:id", h.GetByID)       // [get] /api/v1/users/:id
 	g.POST("/list", h.List)        // [post] /api/v1/users/list
+
+	// the forms check the unique fields before submitting
+	g.GET("/availability", h.CheckAvailability) // [get] /api/v1/users/availability
+
+	g.POST("/:id/restore", h.RestoreByID)          // [post] /api/v1/users/:id/restore
+	g.POST("/:id/logout", h.LogoutByID)            // [post] /api/v1/users/:id/logout
+	g.POST("/:id/unlock", h.UnlockByID)            // [post] /api/v1/users/:id/unlock
+	g.POST("/:id/2fa/reset", h.ResetTwoFactorByID) // [post] /api/v1/users/:id/2fa/reset
+	// permanently deleting data also requires the data:purge permission
+	g.DELETE("/:id/purge", requirePermission("data:purge"), h.PurgeByID) // [delete] /api/v1/users/:id/purge
 }
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "2026/01/02/abc.txt"
+
+	n, err := s.Save(ctx, key, strings.NewReader("hello world"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), n)
+
+	obj, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(11), obj.Size())
+	_, err = obj.Seek(6, io.SeekStart)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+	assert.NoError(t, obj.Close())
+
+	assert.NoError(t, s.Delete(ctx, key))
+	assert.NoError(t, s.Delete(ctx, key)) // deleting a missing object is not an error
+	_, err = s.Open(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a directory is not an object
+	_, err = s.Open(ctx, "2026")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside.txt", "..", "a/../../b", "a//b", "./a"} {
+		_, err = s.Save(ctx, key, strings.NewReader("x"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+		_, err = s.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+		assert.ErrorIs(t, s.Delete(ctx, key), ErrInvalidKey, key)
+	}
+}
+
+func TestLocal_SaveCanceled(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = s.Save(ctx, "canceled.txt", strings.NewReader("hello"))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.Open(context.Background(), "canceled.txt")
+	assert.ErrorIs(t, err, ErrNotFound) // no partial object is left
+}
+
+func TestNewLocal(t *testing.T) {
+	_, err := NewLocal("")
+	assert.Error(t, err)
+}
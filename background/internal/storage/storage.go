@@ -0,0 +1,74 @@
+// Package storage provides the backends that store the content of uploaded files.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"godemo/internal/config"
+)
+
+const (
+	// DriverLocal stores the files in a directory of the local disk
+	DriverLocal = "local"
+)
+
+var (
+	// ErrNotFound the object does not exist in the storage
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey the key is empty or points outside the storage
+	ErrInvalidKey = errors.New("storage: invalid object key")
+
+	defaultStorage Storage
+	storageOnce    sync.Once
+)
+
+// Storage is a backend that stores the content of files by key, the key is generated by the caller
+// and saved in the storage_key column of the files table.
+type Storage interface {
+	// Save write all the content of r to the object key, it returns the number of bytes written.
+	// An existing object with the same key is overwritten, a failed save leaves no object behind.
+	Save(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open the object for reading, it returns ErrNotFound if the object does not exist.
+	Open(ctx context.Context, key string) (Object, error)
+	// Delete the object, deleting an object that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Object is the content of a stored file, it supports seeking so that range requests can be served.
+type Object interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// InitStorage create the storage backend from the configuration
+func InitStorage() {
+	cfg := config.Get().Storage
+	switch strings.ToLower(cfg.Driver) {
+	case DriverLocal, "":
+		s, err := NewLocal(cfg.Local.Dir)
+		if err != nil {
+			panic("InitStorage error: " + err.Error())
+		}
+		defaultStorage = s
+	default:
+		panic("InitStorage error, unsupported storage driver '" + cfg.Driver +
+			"', please modify the 'storage' configuration at yaml file")
+	}
+}
+
+// Get get the storage backend
+func Get() Storage {
+	if defaultStorage == nil {
+		storageOnce.Do(func() {
+			InitStorage()
+		})
+	}
+
+	return defaultStorage
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"time"
+)
+
+var _ time.Time
+
+// Tip: suggested filling in the binding rules https://github.com/go-playground/validator in request struct fields tag.
+
+// CreateAPIKeysRequest request params
+type CreateAPIKeysRequest struct {
+	Name        string   `json:"name" binding:"required,max=255"`             // name that tells what the key is used for
+	Permissions []string `json:"permissions" binding:"required,min=1"`        // permission codes the key is limited to, the user must have them
+	ExpireDays  int      `json:"expireDays" binding:"required,min=1,max=365"` // the key expires after this many days
+}
+
+// APIKeysObjDetail detail, the key itself is never returned again after it is created
+type APIKeysObjDetail struct {
+	ID uint64 `json:"id"` // convert to uint64 id
+
+	CreatedAt   *time.Time `json:"createdAt"`
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"keyPrefix"` // first characters of the key, to tell the keys apart
+	Permissions []string   `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"` // empty if the key has never been used
+}
+
+// CreatedAPIKeysDetail detail
+type CreatedAPIKeysDetail struct {
+	APIKeysObjDetail
+	Key string `json:"key"` // the api key, only returned once, send it in the X-API-Key header
+}
+
+// CreateAPIKeysReply only for api docs
+type CreateAPIKeysReply struct {
+	Code int                  `json:"code"` // return code
+	Msg  string               `json:"msg"`  // return information description
+	Data CreatedAPIKeysDetail `json:"data"` // return data
+}
+
+// DeleteAPIKeysByIDReply only for api docs
+type DeleteAPIKeysByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// ListAPIKeysReply only for api docs
+type ListAPIKeysReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		APIKeys []APIKeysObjDetail `json:"apiKeys"`
+	} `json:"data"` // return data
+}
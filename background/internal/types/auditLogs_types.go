@@ -0,0 +1,38 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+var _ time.Time
+
+// AuditLogsObjDetail detail
+type AuditLogsObjDetail struct {
+	ID uint64 `json:"id"` // convert to uint64 id
+
+	CreatedAt *time.Time      `json:"createdAt"`
+	ActorID   uint64          `json:"actorID"`   // the logged-in user who made the change, 0 for an anonymous request
+	ActorName string          `json:"actorName"` // user name of the actor
+	RequestID string          `json:"requestID"` // id of the request that made the change
+	Entity    string          `json:"entity"`    // such as users, roles or userRoles
+	EntityID  uint64          `json:"entityID"`  // id of the changed record, the user or role id for userRoles and rolePermissions
+	Action    string          `json:"action"`    // create, update, delete, restore or purge
+	Diff      json.RawMessage `json:"diff"`      // the changed fields, such as {"nickName":{"old":"a","new":"b"}}, secrets are redacted
+}
+
+// ListAuditLogsRequest request params
+type ListAuditLogsRequest struct {
+	query.Params
+}
+
+// ListAuditLogsReply only for api docs
+type ListAuditLogsReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		AuditLogs []AuditLogsObjDetail `json:"auditLogs"`
+	} `json:"data"` // return data
+}
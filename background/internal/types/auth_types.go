@@ -0,0 +1,127 @@
+package types
+
+// LoginRequest request params
+type LoginRequest struct {
+	UserName string `json:"userName" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshTokenRequest request params
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LoginTokenDetail detail, same as Api.Auth.LoginToken of web client. When two-factor authentication
+// is pending the tokens are empty, and the challenge token must be used as the access token of the
+// /auth/2fa/verify api (twoFactor is verify) or the /auth/2fa/enroll and confirm apis (twoFactor is enroll).
+type LoginTokenDetail struct {
+	Token          string `json:"token"`                    // access token, value of the Authorization header is "Bearer token"
+	RefreshToken   string `json:"refreshToken"`             // refresh token, used to get a new access token
+	TwoFactor      string `json:"twoFactor,omitempty"`      // pending two-factor step, verify or enroll
+	ChallengeToken string `json:"challengeToken,omitempty"` // short-lived token of the pending two-factor step
+}
+
+// VerifyTwoFactorRequest request params
+type VerifyTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"` // totp code or unused recovery code
+}
+
+// ConfirmTwoFactorRequest request params
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"` // totp code of the enrolled secret
+}
+
+// ForgotPasswordRequest request params
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest request params
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"` // reset token of the email
+	NewPassword string `json:"newPassword" binding:"required,min=6,max=64,password"`
+}
+
+// TwoFactorSecretDetail detail
+type TwoFactorSecretDetail struct {
+	Secret     string `json:"secret"`     // base32 secret, for manual entry in the authenticator app
+	OtpauthURL string `json:"otpauthUrl"` // otpauth url, shown as a qr code for the authenticator app
+}
+
+// TwoFactorConfirmDetail detail
+type TwoFactorConfirmDetail struct {
+	RecoveryCodes []string `json:"recoveryCodes"`          // one-time codes that replace a totp code, only shown once
+	Token         string   `json:"token,omitempty"`        // set if confirmed with an enroll challenge token
+	RefreshToken  string   `json:"refreshToken,omitempty"` // set if confirmed with an enroll challenge token
+}
+
+// UserInfoDetail detail, same as Api.Auth.UserInfo of web client
+type UserInfoDetail struct {
+	UserID   string   `json:"userId"`
+	UserName string   `json:"userName"`
+	Roles    []string `json:"roles"`   // role codes
+	Buttons  []string `json:"buttons"` // permission codes
+}
+
+// LoginReply only for api docs
+type LoginReply struct {
+	Code int              `json:"code"` // return code
+	Msg  string           `json:"msg"`  // return information description
+	Data LoginTokenDetail `json:"data"` // return data
+}
+
+// RefreshTokenReply only for api docs
+type RefreshTokenReply struct {
+	Code int              `json:"code"` // return code
+	Msg  string           `json:"msg"`  // return information description
+	Data LoginTokenDetail `json:"data"` // return data
+}
+
+// GetUserInfoReply only for api docs
+type GetUserInfoReply struct {
+	Code int            `json:"code"` // return code
+	Msg  string         `json:"msg"`  // return information description
+	Data UserInfoDetail `json:"data"` // return data
+}
+
+// LogoutReply only for api docs
+type LogoutReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// VerifyTwoFactorReply only for api docs
+type VerifyTwoFactorReply struct {
+	Code int              `json:"code"` // return code
+	Msg  string           `json:"msg"`  // return information description
+	Data LoginTokenDetail `json:"data"` // return data
+}
+
+// EnrollTwoFactorReply only for api docs
+type EnrollTwoFactorReply struct {
+	Code int                   `json:"code"` // return code
+	Msg  string                `json:"msg"`  // return information description
+	Data TwoFactorSecretDetail `json:"data"` // return data
+}
+
+// ConfirmTwoFactorReply only for api docs
+type ConfirmTwoFactorReply struct {
+	Code int                    `json:"code"` // return code
+	Msg  string                 `json:"msg"`  // return information description
+	Data TwoFactorConfirmDetail `json:"data"` // return data
+}
+
+// ForgotPasswordReply only for api docs
+type ForgotPasswordReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// ResetPasswordReply only for api docs
+type ResetPasswordReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
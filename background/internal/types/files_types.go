@@ -12,10 +12,10 @@ var _ time.Time
 
 // CreateFilesRequest request params
 type CreateFilesRequest struct {
-	Filename string `json:"filename" binding:""`
-	URL      string `json:"url" binding:""`
-	Size     int64  `json:"size" binding:""`
-	MimeType string `json:"mimeType" binding:""`
+	Filename string `json:"filename" binding:"required,max=255"`
+	URL      string `json:"url" binding:"required,max=255"`
+	Size     int64  `json:"size" binding:"min=0"`
+	MimeType string `json:"mimeType" binding:"max=100"`
 	UserID   uint64 `json:"userID" binding:""`
 }
 
@@ -23,10 +23,10 @@ type CreateFilesRequest struct {
 type UpdateFilesByIDRequest struct {
 	ID uint64 `json:"id" binding:""` // uint64 id
 
-	Filename string `json:"filename" binding:""`
-	URL      string `json:"url" binding:""`
-	Size     int64  `json:"size" binding:""`
-	MimeType string `json:"mimeType" binding:""`
+	Filename string `json:"filename" binding:"omitempty,max=255"`
+	URL      string `json:"url" binding:"omitempty,max=255"`
+	Size     int64  `json:"size" binding:"min=0"`
+	MimeType string `json:"mimeType" binding:"max=100"`
 	UserID   uint64 `json:"userID" binding:""`
 }
 
@@ -36,6 +36,7 @@ type FilesObjDetail struct {
 
 	CreatedAt *time.Time `json:"createdAt"`
 	UpdatedAt *time.Time `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 	Filename  string     `json:"filename"`
 	URL       string     `json:"url"`
 	Size      int64      `json:"size"`
@@ -75,9 +76,32 @@ type GetFilesByIDReply struct {
 	} `json:"data"` // return data
 }
 
+// RestoreFilesByIDReply only for api docs
+type RestoreFilesByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// PurgeFilesByIDReply only for api docs
+type PurgeFilesByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// UploadFilesReply only for api docs
+type UploadFilesReply struct {
+	Code int            `json:"code"` // return code
+	Msg  string         `json:"msg"`  // return information description
+	Data FilesObjDetail `json:"data"` // return data
+}
+
 // ListFilessRequest request params
 type ListFilessRequest struct {
 	query.Params
+
+	IncludeDeleted bool `json:"includeDeleted"` // also list the soft deleted records
 }
 
 // ListFilessReply only for api docs
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+var _ time.Time
+
+// LoginLogsObjDetail detail
+type LoginLogsObjDetail struct {
+	ID uint64 `json:"id"` // convert to uint64 id
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UserID    uint64     `json:"userID"` // 0 if the user name does not exist
+	UserName  string     `json:"userName"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"userAgent"`
+	Result    string     `json:"result"` // success or failure
+	Reason    string     `json:"reason"` // why the login failed: userNotFound, wrongPassword, userDisabled, accountLocked, ipBlocked, wrongTwoFactorCode or reusedTwoFactorCode
+}
+
+// ListLoginLogsRequest request params
+type ListLoginLogsRequest struct {
+	query.Params
+}
+
+// ListLoginLogsReply only for api docs
+type ListLoginLogsReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		LoginLogs []LoginLogsObjDetail `json:"loginLogs"`
+	} `json:"data"` // return data
+}
@@ -12,35 +12,39 @@ var _ time.Time
 
 // CreateMenusRequest request params
 type CreateMenusRequest struct {
-	Name     string `json:"name" binding:""`
-	Path     string `json:"path" binding:""`
-	Icon     string `json:"icon" binding:""`
-	ParentID uint64 `json:"parentID" binding:""`
-	Order    int    `json:"order" binding:""`
+	Name       string `json:"name" binding:"required,max=255"`
+	Path       string `json:"path" binding:"required,max=255,menuPath"`
+	Icon       string `json:"icon" binding:"max=255"`
+	ParentID   uint64 `json:"parentID" binding:""`
+	Order      int    `json:"order" binding:""`
+	Permission string `json:"permission" binding:"max=255"` // permission code required to see the menu, empty means every logged-in user
 }
 
 // UpdateMenusByIDRequest request params
 type UpdateMenusByIDRequest struct {
 	ID uint64 `json:"id" binding:""` // uint64 id
 
-	Name     string `json:"name" binding:""`
-	Path     string `json:"path" binding:""`
-	Icon     string `json:"icon" binding:""`
-	ParentID uint64 `json:"parentID" binding:""`
-	Order    int    `json:"order" binding:""`
+	Name       string `json:"name" binding:"omitempty,max=255"`
+	Path       string `json:"path" binding:"omitempty,max=255,menuPath"`
+	Icon       string `json:"icon" binding:"max=255"`
+	ParentID   uint64 `json:"parentID" binding:""`
+	Order      int    `json:"order" binding:""`
+	Permission string `json:"permission" binding:"max=255"` // permission code required to see the menu, empty means every logged-in user
 }
 
 // MenusObjDetail detail
 type MenusObjDetail struct {
 	ID uint64 `json:"id"` // convert to uint64 id
 
-	CreatedAt *time.Time `json:"createdAt"`
-	UpdatedAt *time.Time `json:"updatedAt"`
-	Name      string     `json:"name"`
-	Path      string     `json:"path"`
-	Icon      string     `json:"icon"`
-	ParentID  uint64     `json:"parentID"`
-	Order     int        `json:"order"`
+	CreatedAt  *time.Time `json:"createdAt"`
+	UpdatedAt  *time.Time `json:"updatedAt"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	Icon       string     `json:"icon"`
+	ParentID   uint64     `json:"parentID"`
+	Order      int        `json:"order"`
+	Permission string     `json:"permission"`
 }
 
 // CreateMenusReply only for api docs
@@ -75,9 +79,25 @@ type GetMenusByIDReply struct {
 	} `json:"data"` // return data
 }
 
+// RestoreMenusByIDReply only for api docs
+type RestoreMenusByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// PurgeMenusByIDReply only for api docs
+type PurgeMenusByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
 // ListMenussRequest request params
 type ListMenussRequest struct {
 	query.Params
+
+	IncludeDeleted bool `json:"includeDeleted"` // also list the soft deleted records
 }
 
 // ListMenussReply only for api docs
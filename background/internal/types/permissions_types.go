@@ -12,18 +12,18 @@ var _ time.Time
 
 // CreatePermissionsRequest request params
 type CreatePermissionsRequest struct {
-	Name        string `json:"name" binding:""`
-	Code        string `json:"code" binding:""`
-	Description string `json:"description" binding:""`
+	Name        string `json:"name" binding:"required,max=255"`
+	Code        string `json:"code" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=1000"`
 }
 
 // UpdatePermissionsByIDRequest request params
 type UpdatePermissionsByIDRequest struct {
 	ID uint64 `json:"id" binding:""` // uint64 id
 
-	Name        string `json:"name" binding:""`
-	Code        string `json:"code" binding:""`
-	Description string `json:"description" binding:""`
+	Name        string `json:"name" binding:"omitempty,max=255"`
+	Code        string `json:"code" binding:"omitempty,max=255"`
+	Description string `json:"description" binding:"max=1000"`
 }
 
 // PermissionsObjDetail detail
@@ -32,6 +32,7 @@ type PermissionsObjDetail struct {
 
 	CreatedAt   *time.Time `json:"createdAt"`
 	UpdatedAt   *time.Time `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
 	Name        string     `json:"name"`
 	Code        string     `json:"code"`
 	Description string     `json:"description"`
@@ -69,9 +70,25 @@ type GetPermissionsByIDReply struct {
 	} `json:"data"` // return data
 }
 
+// RestorePermissionsByIDReply only for api docs
+type RestorePermissionsByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// PurgePermissionsByIDReply only for api docs
+type PurgePermissionsByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
 // ListPermissionssRequest request params
 type ListPermissionssRequest struct {
 	query.Params
+
+	IncludeDeleted bool `json:"includeDeleted"` // also list the soft deleted records
 }
 
 // ListPermissionssReply only for api docs
@@ -82,3 +99,10 @@ type ListPermissionssReply struct {
 		Permissionss []PermissionsObjDetail `json:"permissionss"`
 	} `json:"data"` // return data
 }
+
+// CheckPermissionsAvailabilityRequest request params, excludeID is the record being edited, 0 for a new record
+type CheckPermissionsAvailabilityRequest struct {
+	Field     string `form:"field" binding:"required,oneof=code"`
+	Value     string `form:"value" binding:"required"`
+	ExcludeID uint64 `form:"excludeID"`
+}
@@ -1,75 +1,49 @@
 package types
 
-import (
-	"time"
-
-	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
-)
-
-var _ time.Time
-
-// Tip: suggested filling in the binding rules https://github.com/go-playground/validator in request struct fields tag.
+// ReplaceRolePermissionsRequest request params
+type ReplaceRolePermissionsRequest struct {
+	PermissionIDs []uint64 `json:"permissionIDs" binding:"required"` // all the permission ids of the role, an empty list removes all permissions
+}
 
-// CreateRolePermissionsRequest request params
-type CreateRolePermissionsRequest struct {
-	RoleID       uint64 `json:"roleID" binding:""`
-	PermissionID uint64 `json:"permissionID" binding:""`
+// RolePermissionsDetail detail
+type RolePermissionsDetail struct {
+	RoleID        uint64   `json:"roleID"`
+	PermissionIDs []uint64 `json:"permissionIDs"`
 }
 
-// UpdateRolePermissionsByRoleIDRequest request params
-type UpdateRolePermissionsByRoleIDRequest struct {
-	RoleID       uint64 `json:"roleID" binding:""`
-	PermissionID uint64 `json:"permissionID" binding:""`
+// GetRolePermissionsByRoleIDReply only for api docs
+type GetRolePermissionsByRoleIDReply struct {
+	Code int                   `json:"code"` // return code
+	Msg  string                `json:"msg"`  // return information description
+	Data RolePermissionsDetail `json:"data"` // return data
 }
 
-// RolePermissionsObjDetail detail
-type RolePermissionsObjDetail struct {
-	RoleID       uint64 `json:"roleID"`
-	PermissionID uint64 `json:"permissionID"`
+// EffectivePermissionDetail a permission of the effective set of a role
+type EffectivePermissionDetail struct {
+	ID          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Inherited   bool   `json:"inherited"`  // false if the permission is granted to the role itself
+	FromRoleID  uint64 `json:"fromRoleID"` // the role the permission is granted to, the nearest ancestor if it is inherited
 }
 
-// CreateRolePermissionsReply only for api docs
-type CreateRolePermissionsReply struct {
-	Code int    `json:"code"` // return code
-	Msg  string `json:"msg"`  // return information description
-	Data struct {
-		RoleID uint64 `json:"roleID"`
-	} `json:"data"` // return data
+// EffectivePermissionsDetail detail
+type EffectivePermissionsDetail struct {
+	RoleID      uint64                       `json:"roleID"`
+	Permissions []*EffectivePermissionDetail `json:"permissions"`
 }
 
-// DeleteRolePermissionsByRoleIDReply only for api docs
-type DeleteRolePermissionsByRoleIDReply struct {
-	Code int      `json:"code"` // return code
-	Msg  string   `json:"msg"`  // return information description
-	Data struct{} `json:"data"` // return data
+// GetEffectivePermissionsByRoleIDReply only for api docs
+type GetEffectivePermissionsByRoleIDReply struct {
+	Code int                        `json:"code"` // return code
+	Msg  string                     `json:"msg"`  // return information description
+	Data EffectivePermissionsDetail `json:"data"` // return data
 }
 
-// UpdateRolePermissionsByRoleIDReply only for api docs
-type UpdateRolePermissionsByRoleIDReply struct {
+// ReplaceRolePermissionsByRoleIDReply only for api docs
+type ReplaceRolePermissionsByRoleIDReply struct {
 	Code int      `json:"code"` // return code
 	Msg  string   `json:"msg"`  // return information description
 	Data struct{} `json:"data"` // return data
 }
-
-// GetRolePermissionsByRoleIDReply only for api docs
-type GetRolePermissionsByRoleIDReply struct {
-	Code int    `json:"code"` // return code
-	Msg  string `json:"msg"`  // return information description
-	Data struct {
-		RolePermissions RolePermissionsObjDetail `json:"rolePermissions"`
-	} `json:"data"` // return data
-}
-
-// ListRolePermissionsRequest request params
-type ListRolePermissionsRequest struct {
-	query.Params
-}
-
-// ListRolePermissionsReply only for api docs
-type ListRolePermissionsReply struct {
-	Code int    `json:"code"` // return code
-	Msg  string `json:"msg"`  // return information description
-	Data struct {
-		RolePermissions []RolePermissionsObjDetail `json:"rolePermissions"`
-	} `json:"data"` // return data
-}
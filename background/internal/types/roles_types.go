@@ -12,32 +12,39 @@ var _ time.Time
 
 // CreateRolesRequest request params
 type CreateRolesRequest struct {
-	RoleName string `json:"roleName" binding:""`
-	RoleCode string `json:"roleCode" binding:""`
-	RoleDesc string `json:"roleDesc" binding:""`
-	Status   string `json:"status" binding:""`
+	RoleName         string `json:"roleName" binding:"required,max=255"`
+	RoleCode         string `json:"roleCode" binding:"required,max=255"`
+	RoleDesc         string `json:"roleDesc" binding:"max=1000"`
+	Status           string `json:"status" binding:"omitempty,oneof=1 2"` // 1: enabled, 2: disabled
+	RequireTwoFactor bool   `json:"requireTwoFactor" binding:""`          // members must use two-factor authentication
+	ParentID         uint64 `json:"parentID" binding:""`                  // the role whose permissions are inherited, 0 for none
 }
 
 // UpdateRolesByIDRequest request params
 type UpdateRolesByIDRequest struct {
 	ID uint64 `json:"id" binding:""` // uint64 id
 
-	RoleName string `json:"roleName" binding:""`
-	RoleCode string `json:"roleCode" binding:""`
-	RoleDesc string `json:"roleDesc" binding:""`
-	Status   string `json:"status" binding:""`
+	RoleName         string  `json:"roleName" binding:"omitempty,max=255"`
+	RoleCode         string  `json:"roleCode" binding:"omitempty,max=255"`
+	RoleDesc         string  `json:"roleDesc" binding:"max=1000"`
+	Status           string  `json:"status" binding:"omitempty,oneof=1 2"` // 1: enabled, 2: disabled
+	RequireTwoFactor *bool   `json:"requireTwoFactor" binding:""`          // not updated if omitted
+	ParentID         *uint64 `json:"parentID" binding:""`                  // not updated if omitted, 0 removes the parent
 }
 
 // RolesObjDetail detail
 type RolesObjDetail struct {
 	ID uint64 `json:"id"` // convert to uint64 id
 
-	CreatedAt *time.Time `json:"createdAt"`
-	UpdatedAt *time.Time `json:"updatedAt"`
-	RoleName  string     `json:"roleName"`
-	RoleCode  string     `json:"roleCode"`
-	RoleDesc  string     `json:"roleDesc"`
-	Status    string     `json:"status"`
+	CreatedAt        *time.Time `json:"createdAt"`
+	UpdatedAt        *time.Time `json:"updatedAt"`
+	DeletedAt        *time.Time `json:"deletedAt,omitempty"`
+	RoleName         string     `json:"roleName"`
+	RoleCode         string     `json:"roleCode"`
+	RoleDesc         string     `json:"roleDesc"`
+	Status           string     `json:"status"`
+	RequireTwoFactor bool       `json:"requireTwoFactor"`
+	ParentID         uint64     `json:"parentID"` // the role whose permissions are inherited, 0 for none
 }
 
 // CreateRolesReply only for api docs
@@ -72,9 +79,25 @@ type GetRolesByIDReply struct {
 	} `json:"data"` // return data
 }
 
+// RestoreRolesByIDReply only for api docs
+type RestoreRolesByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// PurgeRolesByIDReply only for api docs
+type PurgeRolesByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
 // ListRolessRequest request params
 type ListRolessRequest struct {
 	query.Params
+
+	IncludeDeleted bool `json:"includeDeleted"` // also list the soft deleted records
 }
 
 // ListRolessReply only for api docs
@@ -85,3 +108,10 @@ type ListRolessReply struct {
 		Roless []RolesObjDetail `json:"roless"`
 	} `json:"data"` // return data
 }
+
+// CheckRolesAvailabilityRequest request params, excludeID is the record being edited, 0 for a new record
+type CheckRolesAvailabilityRequest struct {
+	Field     string `form:"field" binding:"required,oneof=roleCode"`
+	Value     string `form:"value" binding:"required"`
+	ExcludeID uint64 `form:"excludeID"`
+}
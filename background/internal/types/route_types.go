@@ -0,0 +1,55 @@
+package types
+
+// RouteMeta meta of a route, same as RouteMeta of the web client
+type RouteMeta struct {
+	Title      string `json:"title"`
+	I18nKey    string `json:"i18nKey,omitempty"`
+	Icon       string `json:"icon,omitempty"`
+	Order      int    `json:"order,omitempty"`
+	Constant   bool   `json:"constant,omitempty"`   // constant routes do not require login
+	HideInMenu bool   `json:"hideInMenu,omitempty"` // the route is registered, but not shown in the menu
+	KeepAlive  bool   `json:"keepAlive,omitempty"`
+}
+
+// MenuRoute a node of the route tree, same as Api.Route.MenuRoute of the web client
+type MenuRoute struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Path      string       `json:"path"`
+	Component string       `json:"component,omitempty"` // e.g. layout.base$view.home, layout.base, view.manage_user
+	Props     bool         `json:"props,omitempty"`
+	Meta      RouteMeta    `json:"meta"`
+	Children  []*MenuRoute `json:"children,omitempty"`
+}
+
+// UserRouteDetail detail, same as Api.Route.UserRoute of the web client
+type UserRouteDetail struct {
+	Routes []*MenuRoute `json:"routes"`
+	Home   string       `json:"home"` // route name of the home page
+}
+
+// IsRouteExistRequest request params
+type IsRouteExistRequest struct {
+	RouteName string `form:"routeName" binding:"required"`
+}
+
+// GetConstantRoutesReply only for api docs
+type GetConstantRoutesReply struct {
+	Code int          `json:"code"` // return code
+	Msg  string       `json:"msg"`  // return information description
+	Data []*MenuRoute `json:"data"` // return data
+}
+
+// GetUserRoutesReply only for api docs
+type GetUserRoutesReply struct {
+	Code int             `json:"code"` // return code
+	Msg  string          `json:"msg"`  // return information description
+	Data UserRouteDetail `json:"data"` // return data
+}
+
+// IsRouteExistReply only for api docs
+type IsRouteExistReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data bool   `json:"data"` // return data
+}
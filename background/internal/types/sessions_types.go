@@ -0,0 +1,50 @@
+package types
+
+import (
+	"time"
+)
+
+var _ time.Time
+
+// ListSessionsRequest request params
+type ListSessionsRequest struct {
+	UserID uint64 `form:"userID"` // only list the sessions of the user, all the sessions if it is empty
+}
+
+// SessionsObjDetail detail, a session is the login of a user, all the tokens issued from the login belong to it
+type SessionsObjDetail struct {
+	ID string `json:"id"`
+
+	UserID      uint64    `json:"userID"`
+	UserName    string    `json:"userName"`
+	IP          string    `json:"ip"`          // client ip of the login
+	UserAgent   string    `json:"userAgent"`   // user agent of the login
+	CreatedAt   time.Time `json:"createdAt"`   // time of the login
+	RefreshedAt time.Time `json:"refreshedAt"` // last time the tokens were refreshed
+	ExpiresAt   time.Time `json:"expiresAt"`   // the session ends if it is not refreshed before this time
+	Current     bool      `json:"current"`     // the session of the request
+}
+
+// ListSessionsReply only for api docs
+type ListSessionsReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Sessions []SessionsObjDetail `json:"sessions"`
+		Total    int                 `json:"total"`
+	} `json:"data"` // return data
+}
+
+// DeleteSessionByIDReply only for api docs
+type DeleteSessionByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// DeleteSessionsByUserIDReply only for api docs
+type DeleteSessionsByUserIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
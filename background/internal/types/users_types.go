@@ -12,41 +12,56 @@ var _ time.Time
 
 // CreateUsersRequest request params
 type CreateUsersRequest struct {
-	UserName   string `json:"userName" binding:""`
-	Password   string `json:"password" binding:""`
-	UserGender string `json:"userGender" binding:""`
-	NickName   string `json:"nickName" binding:""`
-	UserPhone  string `json:"userPhone" binding:""`
-	UserEmail  string `json:"userEmail" binding:""`
-	Status     string `json:"status" binding:""`
+	UserName   string `json:"userName" binding:"required,userName"`
+	Password   string `json:"password" binding:"required,min=6,max=64,password"`
+	UserGender string `json:"userGender" binding:"omitempty,oneof=1 2"` // 1: male, 2: female
+	NickName   string `json:"nickName" binding:"max=255"`
+	UserPhone  string `json:"userPhone" binding:"omitempty,phone"`
+	UserEmail  string `json:"userEmail" binding:"omitempty,email,max=255"`
+	Status     string `json:"status" binding:"omitempty,oneof=1 2"` // 1: enabled, 2: disabled
 }
 
 // UpdateUsersByIDRequest request params
 type UpdateUsersByIDRequest struct {
 	ID uint64 `json:"id" binding:""` // uint64 id
 
-	UserName   string `json:"userName" binding:""`
-	Password   string `json:"password" binding:""`
-	UserGender string `json:"userGender" binding:""`
-	NickName   string `json:"nickName" binding:""`
-	UserPhone  string `json:"userPhone" binding:""`
-	UserEmail  string `json:"userEmail" binding:""`
-	Status     string `json:"status" binding:""`
+	UserName   string `json:"userName" binding:"omitempty,userName"`
+	Password   string `json:"password" binding:"omitempty,min=6,max=64,password"`
+	UserGender string `json:"userGender" binding:"omitempty,oneof=1 2"` // 1: male, 2: female
+	NickName   string `json:"nickName" binding:"max=255"`
+	UserPhone  string `json:"userPhone" binding:"omitempty,phone"`
+	UserEmail  string `json:"userEmail" binding:"omitempty,email,max=255"`
+	Status     string `json:"status" binding:"omitempty,oneof=1 2"` // 1: enabled, 2: disabled
+}
+
+// UpdateMeRequest request params, the fields a user may change of its own profile, an empty value clears the field
+type UpdateMeRequest struct {
+	NickName   string `json:"nickName" binding:"max=255"`
+	UserGender string `json:"userGender" binding:"omitempty,oneof=1 2"` // 1: male, 2: female
+	UserPhone  string `json:"userPhone" binding:"omitempty,phone"`
+	UserEmail  string `json:"userEmail" binding:"omitempty,email,max=255"`
+}
+
+// ChangePasswordRequest request params
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"` // current password
+	NewPassword string `json:"newPassword" binding:"required,min=6,max=64,password"`
 }
 
 // UsersObjDetail detail
 type UsersObjDetail struct {
 	ID uint64 `json:"id"` // convert to uint64 id
 
-	CreatedAt  *time.Time `json:"createdAt"`
-	UpdatedAt  *time.Time `json:"updatedAt"`
-	UserName   string     `json:"userName"`
-	Password   string     `json:"password"`
-	UserGender string     `json:"userGender"`
-	NickName   string     `json:"nickName"`
-	UserPhone  string     `json:"userPhone"`
-	UserEmail  string     `json:"userEmail"`
-	Status     string     `json:"status"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	UserName    string     `json:"userName"`
+	UserGender  string     `json:"userGender"`
+	NickName    string     `json:"nickName"`
+	UserPhone   string     `json:"userPhone"`
+	UserEmail   string     `json:"userEmail"`
+	Status      string     `json:"status"`
+	TotpEnabled bool       `json:"totpEnabled"`
 }
 
 // CreateUsersReply only for api docs
@@ -81,9 +96,70 @@ type GetUsersByIDReply struct {
 	} `json:"data"` // return data
 }
 
+// RestoreUsersByIDReply only for api docs
+type RestoreUsersByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// PurgeUsersByIDReply only for api docs
+type PurgeUsersByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// LogoutUsersByIDReply only for api docs
+type LogoutUsersByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// UnlockUsersByIDReply only for api docs
+type UnlockUsersByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// ResetTwoFactorUsersByIDReply only for api docs
+type ResetTwoFactorUsersByIDReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// GetMeReply only for api docs
+type GetMeReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Users UsersObjDetail `json:"users"`
+	} `json:"data"` // return data
+}
+
+// UpdateMeReply only for api docs
+type UpdateMeReply struct {
+	Code int      `json:"code"` // return code
+	Msg  string   `json:"msg"`  // return information description
+	Data struct{} `json:"data"` // return data
+}
+
+// ChangePasswordReply only for api docs, all the other login sessions are ended,
+// the caller continues with the new tokens.
+type ChangePasswordReply struct {
+	Code int              `json:"code"` // return code
+	Msg  string           `json:"msg"`  // return information description
+	Data LoginTokenDetail `json:"data"` // return data
+}
+
 // ListUserssRequest request params
 type ListUserssRequest struct {
 	query.Params
+
+	IncludeDeleted bool `json:"includeDeleted"` // also list the soft deleted records
 }
 
 // ListUserssReply only for api docs
@@ -94,3 +170,10 @@ type ListUserssReply struct {
 		Userss []UsersObjDetail `json:"userss"`
 	} `json:"data"` // return data
 }
+
+// CheckUsersAvailabilityRequest request params, excludeID is the record being edited, 0 for a new record
+type CheckUsersAvailabilityRequest struct {
+	Field     string `form:"field" binding:"required,oneof=userName userEmail"`
+	Value     string `form:"value" binding:"required"`
+	ExcludeID uint64 `form:"excludeID"`
+}
@@ -0,0 +1,30 @@
+package types
+
+// FieldError a field of the request that is not valid, the web client highlights the input of the field
+type FieldError struct {
+	Field  string `json:"field"`           // json name of the field, such as userEmail or columns[0].name
+	Reason string `json:"reason"`          // the failed rule, such as required, email, oneof or max, type if the json type is wrong
+	Param  string `json:"param,omitempty"` // the parameter of the rule, such as "1 2" of oneof=1 2
+}
+
+// InvalidParamsDetail the data of an ecode.InvalidParams response, fields is empty if the body is not valid json
+type InvalidParamsDetail struct {
+	Fields []*FieldError `json:"fields"`
+}
+
+// ConflictDetail the data of an ecode.Conflict response, the fields whose values are used by another record
+type ConflictDetail struct {
+	Fields []*FieldError `json:"fields"` // the reason is unique
+}
+
+// AvailabilityDetail detail
+type AvailabilityDetail struct {
+	Available bool `json:"available"` // the value is not used by another record
+}
+
+// CheckAvailabilityReply only for api docs
+type CheckAvailabilityReply struct {
+	Code int                `json:"code"` // return code
+	Msg  string             `json:"msg"`  // return information description
+	Data AvailabilityDetail `json:"data"` // return data
+}
@@ -37,6 +37,11 @@ export function fetchRefreshToken(refreshToken: string) {
   });
 }
 
+/** Logout, the tokens of the current login session are revoked */
+export function fetchLogout() {
+  return request({ url: '/auth/logout', method: 'post' });
+}
+
 /**
  * return custom backend error
  *
@@ -8,6 +8,10 @@ declare namespace Api {
     interface LoginToken {
       token: string;
       refreshToken: string;
+      /** pending two-factor step of the login, the tokens are empty when it is set */
+      twoFactor?: 'verify' | 'enroll';
+      /** short-lived token of the pending two-factor step, sent as the access token of the /auth/2fa apis */
+      challengeToken?: string;
     }
 
     interface UserInfo {